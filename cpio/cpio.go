@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strconv"
 )
 
@@ -40,10 +41,10 @@ type Meta struct {
 	Checksum  uint64
 }
 
-func NewMetadata(rd *bytes.Reader) (m *Meta, err error) {
+func NewMetadata(rd io.Reader) (m *Meta, err error) {
 	magic_bytes := make([]byte, CPIO_NEW_HEADER_MAGIC_SIZE)
 
-	_, err = rd.Read(magic_bytes)
+	_, err = io.ReadFull(rd, magic_bytes)
 	if err != nil {
 		return
 	}
@@ -76,7 +77,7 @@ func NewMetadata(rd *bytes.Reader) (m *Meta, err error) {
 	}
 
 	for _, pv := range table {
-		_, err = rd.Read(field)
+		_, err = io.ReadFull(rd, field)
 		if err != nil {
 			return
 		}
@@ -92,13 +93,28 @@ func NewMetadata(rd *bytes.Reader) (m *Meta, err error) {
 type File struct {
 	Metadata *Meta
 	Name     string
-	data     []byte
+	// Offset of the file data in the archive
+	Offset int64
+	data   []byte
+}
+
+func NewFile(meta *Meta, name string, data []byte) (file *File) {
+	file = new(File)
+	file.Metadata = meta
+	file.Name = name
+	file.data = data
+
+	return
 }
 
 func (f *File) Write(w io.Writer) (n int, err error) {
 	return w.Write(f.data)
 }
 
+func (f *File) Bytes() []byte {
+	return f.data
+}
+
 func (f *File) MD5() [md5.Size]byte {
 	return md5.Sum(f.data)
 }
@@ -108,66 +124,106 @@ func (f *File) SHA256() [sha256.Size]byte {
 }
 
 type CPIOReader struct {
-	reader *bytes.Reader
+	reader *countReader
+	// unread data and padding of the last entry
+	skip int64
+}
+
+type countReader struct {
+	reader io.Reader
+	offset int64
+}
+
+func (rd *countReader) Read(b []byte) (n int, err error) {
+	n, err = rd.reader.Read(b)
+	rd.offset += int64(n)
+
+	return
 }
 
 func NewCPIOReader(cpiodata []byte) (rd *CPIOReader) {
+	return NewCPIOStreamReader(bytes.NewReader(cpiodata))
+}
+
+func NewCPIOStreamReader(reader io.Reader) (rd *CPIOReader) {
 	rd = new(CPIOReader)
-	rd.reader = bytes.NewReader(cpiodata)
+	rd.reader = &countReader{reader: reader}
 
 	return
 }
 
-func (rd *CPIOReader) GetFile() (file *File, err error) {
+// Offset returns the number of bytes consumed from the archive
+func (rd *CPIOReader) Offset() int64 {
+	return rd.reader.offset
+}
+
+// Next reads the header of the next entry, but not its data.
+// The data is skipped by the following call of Next or GetFile.
+func (rd *CPIOReader) Next() (file *File, err error) {
+	if rd.skip > 0 {
+		_, err = io.CopyN(ioutil.Discard, rd.reader, rd.skip)
+		if err != nil {
+			return
+		}
+		rd.skip = 0
+	}
+
 	file = new(File)
 	file.Metadata, err = NewMetadata(rd.reader)
 	if err != nil {
 		return
 	}
 
-	var name []byte
-	for {
-		b, rerr := rd.reader.ReadByte()
-		if rerr != nil {
-			return
-		}
-		if b == 0 {
-			break
-		}
-		name = append(name, b)
+	if file.Metadata.Namesize == 0 {
+		return nil, fmt.Errorf("CPIO entry has no name")
 	}
-	file.Name = string(name)
+
+	name := make([]byte, file.Metadata.Namesize)
+	_, err = io.ReadFull(rd.reader, name)
+	if err != nil {
+		return
+	}
+
+	// Last byte of name is NULL byte. Ignore it.
+	file.Name = string(bytes.TrimRight(name, "\x00"))
 
 	if file.Name == "TRAILER!!!" {
 		return nil, io.EOF
 	}
 
-	// last NULL byte is already read. So plus 1
-	for total := file.Metadata.Size + len(name) + 1; total%4 != 0; total++ {
-		_, err = rd.reader.ReadByte()
+	for total := file.Metadata.Size + len(name); total%4 != 0; total++ {
+		_, err = io.ReadFull(rd.reader, name[:1])
 		if err != nil {
 			return
 		}
 	}
 
-	file.data = make([]byte, file.Metadata.Filesize)
+	file.Offset = rd.reader.offset
+	rd.skip = int64(file.Metadata.Filesize)
+	for rd.skip%4 != 0 {
+		rd.skip++
+	}
+
+	return
+}
 
-	n, err := rd.reader.Read(file.data)
+func (rd *CPIOReader) GetFile() (file *File, err error) {
+	file, err = rd.Next()
 	if err != nil {
 		return
 	}
 
-	if uint64(n) != file.Metadata.Filesize {
-		err = fmt.Errorf("Cannot read file data. less than %d", file.Metadata.Filesize)
-		return
-	}
+	file.data = make([]byte, file.Metadata.Filesize)
 
-	for ; n%4 != 0; n++ {
-		_, err = rd.reader.ReadByte()
-		if err != nil {
-			return
+	n, err := io.ReadFull(rd.reader, file.data)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Cannot read file data. less than %d", file.Metadata.Filesize)
 		}
+		return
 	}
 
+	rd.skip -= int64(n)
+
 	return
 }
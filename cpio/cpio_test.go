@@ -0,0 +1,147 @@
+package cpio
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	name string
+	mode uint64
+	data string
+}
+
+var testEntries = []testEntry{
+	{"./usr", 040755, ""},
+	{"./usr/bin/foo", 0100755, "#!/bin/sh\n"},
+	{"./usr/share/foo/a", 0100644, "abc"},
+	{"./usr/share/foo/empty", 0100644, ""},
+	{"./usr/bin/bar", 0120777, "foo"},
+}
+
+func writeArchive(t *testing.T) []byte {
+	var buffer bytes.Buffer
+	w := NewCPIOWriter(&buffer)
+
+	for i, entry := range testEntries {
+		meta := &Meta{Ino: uint64(i + 1), Mode: entry.mode, Nlink: 1, Mtime: 1600000000, Filesize: uint64(len(entry.data))}
+		if err := w.WriteHeader(meta, entry.name); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Size() != int64(buffer.Len()) || buffer.Len()%4 != 0 {
+		t.Errorf("Size() = %d, %d bytes are written", w.Size(), buffer.Len())
+	}
+
+	return buffer.Bytes()
+}
+
+func TestReadWrite(t *testing.T) {
+	archive := writeArchive(t)
+
+	reader := NewCPIOReader(archive)
+	for i := 0; ; i++ {
+		file, err := reader.GetFile()
+		if err == io.EOF {
+			if i != len(testEntries) {
+				t.Errorf("%d entries are read", i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry := testEntries[i]
+		if file.Name != entry.name || file.Metadata.Mode != entry.mode || string(file.Bytes()) != entry.data {
+			t.Errorf("Entry %d = %s %o %q", i, file.Name, file.Metadata.Mode, file.Bytes())
+		}
+		if file.Metadata.Ino != uint64(i+1) || file.Metadata.Mtime != 1600000000 || file.Metadata.Type != CPIO_NEW_ASCII {
+			t.Errorf("Entry %d: %+v", i, file.Metadata)
+		}
+		if string(archive[file.Offset:file.Offset+int64(len(entry.data))]) != entry.data {
+			t.Errorf("Entry %d: data is not at offset %d", i, file.Offset)
+		}
+	}
+
+	// Padding after the name of the trailer is not read
+	if reader.Offset() > int64(len(archive)) || reader.Offset() <= int64(len(archive))-4 {
+		t.Errorf("Offset() = %d of %d bytes", reader.Offset(), len(archive))
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Data is skipped by Next, also from a stream
+	reader := NewCPIOStreamReader(bytes.NewReader(writeArchive(t)))
+
+	var names []string
+	for {
+		file, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, file.Name)
+	}
+
+	if len(names) != len(testEntries) || names[2] != testEntries[2].name {
+		t.Errorf("Next() = %v", names)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	archive := writeArchive(t)
+	firstData := strings.Index(string(archive), "#!/bin/sh")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"old format", append([]byte("070707"), archive[6:]...)},
+		{"not hex", append([]byte("070701zzzzzzzz"), archive[14:]...)},
+		{"truncated header", archive[:50]},
+		{"truncated data", archive[:firstData+3]},
+	}
+
+	for _, test := range tests {
+		reader := NewCPIOReader(test.data)
+		var err error
+		for err == nil {
+			_, err = reader.GetFile()
+		}
+		if err == io.EOF {
+			t.Errorf("%s: GetFile() succeeded", test.name)
+		}
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	var buffer bytes.Buffer
+	w := NewCPIOWriter(&buffer)
+
+	if err := w.WriteHeader(&Meta{Mode: 0100644, Nlink: 1, Filesize: 3}, "./foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("abcd")); err == nil {
+		t.Errorf("Write() of data larger than the entry succeeded")
+	}
+	if err := w.WriteHeader(&Meta{Mode: 0100644, Nlink: 1}, "./bar"); err == nil {
+		t.Errorf("WriteHeader() before data of the last entry succeeded")
+	}
+	if err := w.Close(); err == nil {
+		t.Errorf("Close() before data of the last entry succeeded")
+	}
+	if err := w.WriteHeader(&Meta{Filesize: CPIO_NEW_MAX_FILESIZE + 1}, "./large"); err == nil {
+		t.Errorf("WriteHeader() of a large file succeeded")
+	}
+}
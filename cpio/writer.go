@@ -0,0 +1,97 @@
+package cpio
+
+import (
+	"fmt"
+	"io"
+)
+
+// Maximum size of a file in the new ASCII format
+const CPIO_NEW_MAX_FILESIZE = 0xffffffff
+
+const cpioTrailer = "TRAILER!!!"
+
+// CPIOWriter writes an archive in the new ASCII format, same as payloads of rpm
+type CPIOWriter struct {
+	writer io.Writer
+	// Size of the archive written
+	size int64
+	// Size of the data to write for the last entry
+	remaining int64
+}
+
+func NewCPIOWriter(writer io.Writer) *CPIOWriter {
+	return &CPIOWriter{writer: writer}
+}
+
+// Size returns the number of bytes written, with the trailer after Close
+func (w *CPIOWriter) Size() int64 {
+	return w.size
+}
+
+func (w *CPIOWriter) write(b []byte) (err error) {
+	n, err := w.writer.Write(b)
+	w.size += int64(n)
+
+	return
+}
+
+func (w *CPIOWriter) pad() (err error) {
+	if w.size%4 != 0 {
+		err = w.write(make([]byte, 4-w.size%4))
+	}
+
+	return
+}
+
+// WriteHeader writes the header of an entry. Data of Filesize bytes shall be written next.
+func (w *CPIOWriter) WriteHeader(meta *Meta, name string) (err error) {
+	if w.remaining != 0 {
+		return fmt.Errorf("%d bytes of the last entry are not written", w.remaining)
+	}
+	if meta.Filesize > CPIO_NEW_MAX_FILESIZE {
+		return fmt.Errorf("%s is too large for cpio: %d bytes", name, meta.Filesize)
+	}
+
+	magic := "070701"
+	if meta.Type == CPIO_NEW_CRC {
+		magic = "070702"
+	}
+
+	header := fmt.Sprintf("%s%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+		magic, meta.Ino, meta.Mode, meta.Uid, meta.Gid, meta.Nlink, meta.Mtime, meta.Filesize,
+		meta.Devmajor, meta.Devminor, meta.Rdevmajor, meta.Rdevminor, len(name)+1, meta.Checksum)
+
+	err = w.write([]byte(header + name + "\x00"))
+	if err != nil {
+		return
+	}
+
+	w.remaining = int64(meta.Filesize)
+
+	return w.pad()
+}
+
+// Write writes data of the entry
+func (w *CPIOWriter) Write(b []byte) (n int, err error) {
+	if int64(len(b)) > w.remaining {
+		return 0, fmt.Errorf("Data exceeds the size of the entry")
+	}
+
+	n, err = w.writer.Write(b)
+	w.size += int64(n)
+	w.remaining -= int64(n)
+	if err == nil && w.remaining == 0 {
+		err = w.pad()
+	}
+
+	return
+}
+
+// Close writes the trailer, the underlying writer is not closed
+func (w *CPIOWriter) Close() (err error) {
+	if w.remaining != 0 {
+		return fmt.Errorf("%d bytes of the last entry are not written", w.remaining)
+	}
+
+	return w.WriteHeader(&Meta{Nlink: 1}, cpioTrailer)
+}
@@ -0,0 +1,367 @@
+package drpm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"strings"
+)
+
+//
+// Rebuilding the target package from the old package file and the delta
+//
+
+// expandSequence returns indexes of files of the old package in the sequence, which is
+// numbers of 3 bit nibbles with the continuation bit, alternately to skip files and to
+// take one more than the number of files
+func expandSequence(seq []byte, nfiles int) (indexes []int, err error) {
+	var numbers []uint64
+	var value uint64
+
+	for i := 0; i < len(seq)*2; i++ {
+		nibble := seq[i/2] >> 4
+		if i%2 == 1 {
+			nibble = seq[i/2] & 0x0f
+		}
+
+		value = value<<3 | uint64(nibble&7)
+		if nibble&8 != 0 {
+			continue
+		}
+		numbers = append(numbers, value)
+		value = 0
+	}
+
+	position := uint64(0)
+	for i := 0; i+1 < len(numbers); i += 2 {
+		position += numbers[i]
+		if position+numbers[i+1] >= uint64(nfiles) {
+			return nil, fmt.Errorf("Sequence has files which are not in the old package")
+		}
+		for j := uint64(0); j <= numbers[i+1]; j++ {
+			indexes = append(indexes, int(position))
+			position++
+		}
+	}
+
+	return
+}
+
+// oldData makes data which copies of the delta read, cpio entries of files in the
+// sequence without owners, times and inodes
+func (delta *Delta) oldData(old *rpmlib.PackageFile) (data []byte, err error) {
+	files, err := old.Header.Files()
+	if err != nil {
+		return
+	}
+
+	indexes, err := expandSequence(delta.Sequence[16:], len(files))
+	if err != nil {
+		return
+	}
+
+	err = old.LoadPayload()
+	if err != nil {
+		return
+	}
+
+	// Data of hard links is only in the last entry of the payload
+	contents := make(map[string][]byte)
+	reader := cpio.NewCPIOReader(old.Payload.Cpio())
+	for {
+		file, read_err := reader.GetFile()
+		if read_err == io.EOF {
+			break
+		}
+		if read_err != nil {
+			return nil, read_err
+		}
+		if file.Metadata.Filesize > 0 {
+			contents["/"+strings.TrimPrefix(strings.TrimPrefix(file.Name, "."), "/")] = file.Bytes()
+		}
+	}
+	links := make(map[int32][]byte)
+	for _, file := range files {
+		if content, found := contents[file.Path]; found {
+			links[file.Inode] = content
+		}
+	}
+
+	var buffer bytes.Buffer
+	writer := cpio.NewCPIOWriter(&buffer)
+	for _, index := range indexes {
+		file := files[index]
+
+		var content []byte
+		switch uint16(file.Mode) & rpmlib.S_IFMT {
+		case rpmlib.S_IFREG:
+			content = links[file.Inode]
+			if int64(len(content)) != int64(uint32(file.Size)) {
+				return nil, fmt.Errorf("%s is not in the payload of the old package", file.Path)
+			}
+		case rpmlib.S_IFLNK:
+			content = []byte(file.LinkTo)
+		}
+
+		rdev := uint16(file.RDevice)
+		meta := &cpio.Meta{
+			Mode:      uint64(uint16(file.Mode)),
+			Nlink:     1,
+			Filesize:  uint64(len(content)),
+			Rdevmajor: uint64(rdev >> 8),
+			Rdevminor: uint64(rdev & 0xff),
+		}
+		err = writer.WriteHeader(meta, "."+file.Path)
+		if err == nil && len(content) > 0 {
+			_, err = writer.Write(content)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// buildPayload runs instructions of the delta, which copy data from the old data with
+// the add block, and from data of the delta
+func (delta *Delta) buildPayload(oldData []byte) (payload []byte, err error) {
+	var add io.ReadCloser
+	if len(delta.AddBlock) > 0 {
+		_, add, err = decompressor(bufio.NewReader(bytes.NewReader(delta.AddBlock)))
+		if err != nil {
+			return
+		}
+		defer add.Close()
+	}
+
+	var offset int64
+	out := delta.Out
+	inData := delta.InData
+
+	for _, in := range delta.In {
+		if in.Offset > int64(len(out)) {
+			return nil, fmt.Errorf("Delta has less copies from the old data than instructions")
+		}
+
+		for _, copy := range out[:in.Offset] {
+			offset += copy.Offset
+			end := offset + int64(copy.Length)
+			if offset < 0 || end > int64(len(oldData)) {
+				return nil, fmt.Errorf("Copy of %d bytes at %d exceeds the old data", copy.Length, offset)
+			}
+
+			start := len(payload)
+			payload = append(payload, oldData[offset:end]...)
+			if add != nil {
+				addition := make([]byte, copy.Length)
+				_, err = io.ReadFull(add, addition)
+				if err != nil {
+					return nil, fmt.Errorf("Add block is broken: %s", err)
+				}
+				for i, b := range addition {
+					payload[start+i] += b
+				}
+			}
+			offset = end
+		}
+		out = out[in.Offset:]
+
+		if uint64(in.Length) > uint64(len(inData)) {
+			return nil, fmt.Errorf("Copy of %d bytes exceeds data of the delta", in.Length)
+		}
+		payload = append(payload, inData[:in.Length]...)
+		inData = inData[in.Length:]
+	}
+
+	if len(out) > 0 || len(inData) > 0 {
+		return nil, fmt.Errorf("Delta has data which instructions do not use")
+	}
+
+	return
+}
+
+// targetHeader returns the target header, of which the payload format is restored to "cpio"
+func (delta *Delta) targetHeader() (header []byte, err error) {
+	header = append([]byte{}, delta.headerBytes...)
+
+	indexes := delta.Header.Indexes()
+	for _, index := range indexes {
+		if index.Tag != rpmlib.RPMTAG_PAYLOADFORMAT {
+			continue
+		}
+
+		offset := 16 + len(indexes)*16 + int(index.Offset)
+		if offset < 0 || offset+4 > len(header) {
+			return nil, fmt.Errorf("Payload format of the target header is broken")
+		}
+		if string(header[offset:offset+4]) == "drpm" {
+			copy(header[offset:], "cpio")
+		}
+	}
+
+	return
+}
+
+// compressPayload compresses the payload same as the target. Gzip has candidates since
+// payloads written by zlib and by Go differ in the operating system of the gzip header.
+func (delta *Delta) compressPayload(payload []byte) (candidates [][]byte, err error) {
+	level := int(delta.TargetCompression >> 8 & 0xff)
+
+	switch delta.TargetCompression & 0xff {
+	case COMP_UNCOMPRESSED:
+		candidates = append(candidates, payload)
+	case COMP_GZIP:
+		if level == 0 {
+			level = gzip.BestCompression
+		}
+		for _, system := range []byte{3, 255} {
+			var buffer bytes.Buffer
+			writer, level_err := gzip.NewWriterLevel(&buffer, level)
+			if level_err != nil {
+				return nil, level_err
+			}
+			writer.Header.OS = system
+			writer.Write(payload)
+			writer.Close()
+			candidates = append(candidates, buffer.Bytes())
+		}
+	case COMP_ZSTD:
+		if level == 0 {
+			level = 19
+		}
+		encoder, encoder_err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		if encoder_err != nil {
+			return nil, encoder_err
+		}
+		candidates = append(candidates, encoder.EncodeAll(payload, nil))
+	default:
+		err = fmt.Errorf("Payload compression %s of the target cannot be reproduced, only uncompressed, gzip and zstd payloads are rebuilt",
+			CompressionName(delta.TargetCompression))
+	}
+
+	return
+}
+
+// verifyHeader checks the target header and the uncompressed payload with
+// digests of the target signature and the header
+func (delta *Delta) verifyHeader(signature *rpmlib.Signature, header []byte, payload []byte) (err error) {
+	sha256sum := sha256.Sum256(header)
+	if digest, get_err := signature.GetString(rpmlib.RPMSIGTAG_SHA256); get_err == nil && digest != hex.EncodeToString(sha256sum[:]) {
+		return fmt.Errorf("Target header does not match the SHA256 digest of the signature")
+	}
+	sha1sum := sha1.Sum(header)
+	if digest, get_err := signature.GetString(rpmlib.RPMSIGTAG_SHA1); get_err == nil && digest != hex.EncodeToString(sha1sum[:]) {
+		return fmt.Errorf("Target header does not match the SHA1 digest of the signature")
+	}
+
+	// Digests of the payload are SHA256 unless the algorithm is given
+	algorithm, algo_err := delta.Header.GetInt32(rpmlib.RPMTAG_PAYLOADDIGESTALGO)
+	digests, get_err := delta.Header.GetStringArray(rpmlib.RPMTAG_PAYLOADDIGESTALT)
+	if get_err == nil && len(digests) > 0 && (algo_err != nil || algorithm == rpmlib.PGPHASHALGO_SHA256) {
+		payloadsum := sha256.Sum256(payload)
+		if digests[0] != hex.EncodeToString(payloadsum[:]) {
+			return fmt.Errorf("Rebuilt payload does not match the payload digest of the target header")
+		}
+	}
+
+	return
+}
+
+// verifyPackage checks the target package file with the MD5 and the size of the signature and the delta
+func (delta *Delta) verifyPackage(signature *rpmlib.Signature, header []byte, compressed []byte) (err error) {
+	digest := md5.New()
+	digest.Write(header)
+	digest.Write(compressed)
+	if sigmd5, md5_err := signature.MD5(); md5_err == nil && !bytes.Equal(sigmd5, digest.Sum(nil)) {
+		return fmt.Errorf("Target package does not match the MD5 digest of the signature")
+	}
+	if size, size_err := signature.Size(); size_err == nil && int64(size) != int64(len(header)+len(compressed)) {
+		return fmt.Errorf("Target package does not match the size of the signature")
+	}
+
+	whole := md5.New()
+	whole.Write(delta.Lead)
+	whole.Write(header)
+	whole.Write(compressed)
+	if !bytes.Equal(whole.Sum(nil), delta.TargetMD5) {
+		return fmt.Errorf("Target package does not match the MD5 digest of the delta")
+	}
+	if delta.TargetSize != 0 && int64(delta.TargetSize) != int64(len(delta.Lead)+len(header)+len(compressed)) {
+		return fmt.Errorf("Target package does not match the size of the delta")
+	}
+
+	return
+}
+
+// Apply rebuilds the target package from the old package file and writes it to w.
+// The package is written only after it is verified with digests of the target signature.
+func (delta *Delta) Apply(old *rpmlib.PackageFile, w io.Writer) (err error) {
+	nevr := old.Header.Name() + "-" + old.Header.EVR().String()
+	if nevr != delta.SourceNEVR {
+		return fmt.Errorf("Delta is for %s, not for %s", delta.SourceNEVR, nevr)
+	}
+	if delta.CompressedHeaderLength != 0 || len(delta.OffsetAdjustments) != 0 {
+		return fmt.Errorf("Deltas of compressed headers and of adjusted offsets are not supported")
+	}
+
+	signature, err := rpmlib.ScanSignature(bytes.NewReader(delta.Lead[rpmlib.LeadSize:]))
+	if err != nil {
+		return fmt.Errorf("Signature of the target is broken: %s", err)
+	}
+
+	oldData, err := delta.oldData(old)
+	if err != nil {
+		return
+	}
+	if uint64(len(oldData)) < delta.OutLength {
+		return fmt.Errorf("Old data has %d bytes, less than %d bytes of the delta", len(oldData), delta.OutLength)
+	}
+
+	payload, err := delta.buildPayload(oldData)
+	if err != nil {
+		return
+	}
+
+	header, err := delta.targetHeader()
+	if err != nil {
+		return
+	}
+
+	err = delta.verifyHeader(signature, header, payload)
+	if err != nil {
+		return
+	}
+
+	candidates, err := delta.compressPayload(payload)
+	if err != nil {
+		return
+	}
+
+	for _, compressed := range candidates {
+		err = delta.verifyPackage(signature, header, compressed)
+		if err != nil {
+			continue
+		}
+
+		for _, data := range [][]byte{delta.Lead, header, compressed} {
+			_, err = w.Write(data)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	// The payload is verified, but the compressor does not reproduce the target
+	return fmt.Errorf("Payload is rebuilt, but the compressed payload differs from the target: %s", err)
+}
@@ -0,0 +1,215 @@
+package drpm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/binary"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExpandSequence(t *testing.T) {
+	tests := []struct {
+		seq     []byte
+		nfiles  int
+		indexes []int
+	}{
+		// Take 1 file, skip 1 file and take 2 files
+		{[]byte{0x00, 0x11}, 4, []int{0, 2, 3}},
+		// 9 is 8|1, which continues with 0: skip 8 files and take 1
+		{[]byte{0x90, 0x00}, 10, []int{8}},
+		{nil, 3, nil},
+	}
+
+	for _, test := range tests {
+		indexes, err := expandSequence(test.seq, test.nfiles)
+		if err != nil {
+			t.Errorf("expandSequence(%x): %s", test.seq, err)
+			continue
+		}
+		if len(indexes) != len(test.indexes) {
+			t.Errorf("expandSequence(%x) = %v, want %v", test.seq, indexes, test.indexes)
+			continue
+		}
+		for i := range indexes {
+			if indexes[i] != test.indexes[i] {
+				t.Errorf("expandSequence(%x) = %v, want %v", test.seq, indexes, test.indexes)
+				break
+			}
+		}
+	}
+
+	if _, err := expandSequence([]byte{0x02}, 2); err == nil {
+		t.Errorf("expandSequence() succeeded with files which are not in the package")
+	}
+}
+
+// encodeDelta writes the delta of version 3
+func encodeDelta(delta *Delta) []byte {
+	var body bytes.Buffer
+	u32 := func(value uint32) { binary.Write(&body, binary.BigEndian, value) }
+	u64 := func(value uint64) { binary.Write(&body, binary.BigEndian, value) }
+	block := func(data []byte) { u32(uint32(len(data))); body.Write(data) }
+
+	u32(deltaMagic | '3')
+	block([]byte(delta.SourceNEVR))
+	block(delta.Sequence)
+	body.Write(delta.TargetMD5)
+	u32(delta.TargetSize)
+	u32(delta.TargetCompression)
+	block(delta.TargetCompressionParameters)
+	u32(delta.CompressedHeaderLength)
+	u32(0)
+	block(delta.Lead)
+	u32(delta.PayloadFormatOffset)
+
+	u32(uint32(len(delta.In)))
+	u32(uint32(len(delta.Out)))
+	for _, in := range delta.In {
+		u32(uint32(in.Offset))
+	}
+	for _, in := range delta.In {
+		u32(in.Length)
+	}
+	for _, out := range delta.Out {
+		if out.Offset < 0 {
+			u32(0x80000000 | uint32(-out.Offset))
+		} else {
+			u32(uint32(out.Offset))
+		}
+	}
+	for _, out := range delta.Out {
+		u32(out.Length)
+	}
+
+	u64(delta.OutLength)
+	block(delta.AddBlock)
+	u64(uint64(len(delta.InData)))
+	body.Write(delta.InData)
+
+	return body.Bytes()
+}
+
+// newDelta returns the delta from foo-1.0 to foo-1.1 with the old package, and the
+// target package file. Data of the file which both have is copied from the old package.
+func newDelta(t *testing.T) (delta *Delta, old *rpmlib.PackageFile, target []byte) {
+	shared := strings.Repeat("shared data of foo\n", 20)
+	dir := t.TempDir()
+	oldFilename := rpmtest.Write(t, dir, rpmtest.Binary("foo", "1.0", "1",
+		rpmtest.Regular("/usr/share/foo/data", shared)))
+	targetFilename := rpmtest.Write(t, dir, rpmtest.Binary("foo", "1.1", "1",
+		rpmtest.Regular("/usr/share/foo/data", shared), rpmtest.Regular("/usr/share/foo/new", "new\n")))
+
+	file, err := os.Open(oldFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	old, err = rpmlib.OpenPackageFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target, err = ioutil.ReadFile(targetFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatureEnd, headerEnd := sectionEnds(target)
+	rd, err := gzip.NewReader(bytes.NewReader(target[headerEnd:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sequence takes the only file of the old package
+	targetMD5 := md5.Sum(target)
+	delta = &Delta{
+		SourceNEVR:        "foo-1.0-1",
+		Sequence:          append(make([]byte, 16), 0x00),
+		TargetMD5:         targetMD5[:],
+		TargetSize:        uint32(len(target)),
+		TargetCompression: COMP_GZIP | 9<<8,
+		Lead:              target[:signatureEnd],
+	}
+	oldData, err := delta.oldData(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := bytes.Index(payload, []byte(shared))
+	oldStart := bytes.Index(oldData, []byte(shared))
+	if start < 0 || oldStart < 0 {
+		t.Fatalf("Data of the file is not in the payloads")
+	}
+
+	delta.In = []Instruction{{0, uint32(start)}, {1, uint32(len(payload) - start - len(shared))}}
+	delta.Out = []Instruction{{int64(oldStart), uint32(len(shared))}}
+	delta.OutLength = uint64(len(oldData))
+	delta.InData = append(append([]byte{}, payload[:start]...), payload[start+len(shared):]...)
+
+	var data bytes.Buffer
+	data.Write(target[:headerEnd])
+	w := gzip.NewWriter(&data)
+	w.Write(encodeDelta(delta))
+	w.Close()
+
+	delta, err = Read(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return delta, old, target
+}
+
+func TestApply(t *testing.T) {
+	delta, old, target := newDelta(t)
+
+	var out bytes.Buffer
+	err := delta.Apply(old, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), target) {
+		t.Errorf("Apply() wrote %d bytes, which differ from the target of %d bytes", out.Len(), len(target))
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(delta *Delta)
+		err    string
+	}{
+		{"other old package", func(delta *Delta) { delta.SourceNEVR = "foo-0.9-1" }, "Delta is for foo-0.9-1"},
+		{"offset adjustments", func(delta *Delta) { delta.OffsetAdjustments = []OffsetAdjustment{{1, 1}} }, "not supported"},
+		{"short old data", func(delta *Delta) { delta.OutLength = 1 << 20 }, "Old data has"},
+		{"broken new data", func(delta *Delta) {
+			delta.InData = append([]byte{}, delta.InData...)
+			delta.InData[len(delta.InData)-1] ^= 0xff
+		}, "payload digest"},
+		{"unused new data", func(delta *Delta) { delta.InData = append(delta.InData, 0) }, "do not use"},
+		{"xz", func(delta *Delta) { delta.TargetCompression = COMP_XZ | 6<<8 }, "xz of the target cannot be reproduced"},
+		{"other target", func(delta *Delta) { delta.TargetMD5 = make([]byte, 16) }, "MD5 digest of the delta"},
+	}
+
+	for _, test := range tests {
+		delta, old, _ := newDelta(t)
+		test.modify(delta)
+
+		var out bytes.Buffer
+		err := delta.Apply(old, &out)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: Apply() = %v, want %q", test.name, err, test.err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%s: Apply() wrote %d bytes", test.name, out.Len())
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package drpm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"github.com/xi2/xz"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+//
+// Delta rpms of deltarpm, which rebuild a new package from an old package and the delta
+//
+
+// Compression algorithms of deltarpm, of which the level is in the second byte
+const (
+	COMP_UNCOMPRESSED = 0
+	COMP_GZIP         = 1
+	COMP_GZIP_RSYNC   = 2
+	COMP_BZIP2        = 3
+	COMP_LZMA         = 5
+	COMP_XZ           = 6
+	COMP_ZSTD         = 7
+)
+
+var compressionNames = map[uint32]string{
+	COMP_UNCOMPRESSED: "uncompressed",
+	COMP_GZIP:         "gzip",
+	COMP_GZIP_RSYNC:   "gzip rsyncable",
+	COMP_BZIP2:        "bzip2",
+	COMP_LZMA:         "lzma",
+	COMP_XZ:           "xz",
+	COMP_ZSTD:         "zstd",
+}
+
+// CompressionName returns the name of a compression of deltarpm, such as "gzip"
+func CompressionName(comp uint32) string {
+	name, found := compressionNames[comp&0xff]
+	if !found {
+		return fmt.Sprintf("unknown (%d)", comp&0xff)
+	}
+
+	return name
+}
+
+// Magic of the delta after the header of the target, "DLT" and the version
+const deltaMagic = 0x444c5400
+
+// Magic of rpm-only deltas, which have no lead and no signature
+var rpmOnlyMagic = []byte("drpm")
+
+// Instruction copies Length bytes. Offsets of copies from the old data are relative
+// to the end of the previous copy, and in-instructions have the number of copies in Offset.
+type Instruction struct {
+	Offset int64
+	Length uint32
+}
+
+// OffsetAdjustment moves data of the old payload at Offset by Adjust bytes, since version 3
+type OffsetAdjustment struct {
+	Offset uint32
+	Adjust int32
+}
+
+type Delta struct {
+	Version int
+	// Compression of the delta, such as "xz"
+	Compression string
+	// The target header, of which the payload format is "drpm" instead of "cpio"
+	Header      *rpmlib.Header
+	headerBytes []byte
+	// "name-version-release" of the old package, with "epoch:" before the version
+	SourceNEVR string
+	// MD5 of files of the old package, followed by the sequence of the files
+	Sequence []byte
+	// MD5 and the size of the target package file, the size is 0 for version 1
+	TargetMD5         []byte
+	TargetSize        uint32
+	TargetCompression uint32
+	// Parameters of the compressor to reproduce the payload
+	TargetCompressionParameters []byte
+	// Length of the target header compressed with the payload, 0 for deltas with the header
+	CompressedHeaderLength uint32
+	OffsetAdjustments      []OffsetAdjustment
+	// The lead and the signature of the target package
+	Lead []byte
+	// Offset of the payload format in the target header
+	PayloadFormatOffset uint32
+	In                  []Instruction
+	Out                 []Instruction
+	// Length of the old data which copies read
+	OutLength uint64
+	// Bytes added to data of copies from the old data, compressed
+	AddBlock []byte
+	InData   []byte
+}
+
+// decompressor detects the compression of data by the magic, and returns the reader
+// which the caller closes
+func decompressor(reader *bufio.Reader) (name string, rd io.ReadCloser, err error) {
+	magic, _ := reader.Peek(6)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		name = "gzip"
+		rd, err = gzip.NewReader(reader)
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		name = "bzip2"
+		rd = ioutil.NopCloser(bzip2.NewReader(reader))
+	case bytes.HasPrefix(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		name = "xz"
+		var xzReader *xz.Reader
+		xzReader, err = xz.NewReader(reader, 0)
+		if err == nil {
+			rd = ioutil.NopCloser(xzReader)
+		}
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		name = "zstd"
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			rd = decoder.IOReadCloser()
+		}
+	case len(magic) > 0 && magic[0] == 0x5d:
+		name = "lzma"
+		err = fmt.Errorf("Compression lzma is not supported")
+	default:
+		name = "uncompressed"
+		rd = ioutil.NopCloser(reader)
+	}
+
+	return
+}
+
+// deltaReader reads big endian numbers and blocks of the delta
+type deltaReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (rd *deltaReader) uint32() (value uint32) {
+	if rd.err == nil {
+		rd.err = binary.Read(rd.reader, binary.BigEndian, &value)
+	}
+
+	return
+}
+
+func (rd *deltaReader) uint64() (value uint64) {
+	if rd.err == nil {
+		rd.err = binary.Read(rd.reader, binary.BigEndian, &value)
+	}
+
+	return
+}
+
+// bytes reads a block, which is not allocated before it is read since lengths may be broken
+func (rd *deltaReader) bytes(length uint64) (data []byte) {
+	if rd.err != nil {
+		return
+	}
+
+	data, rd.err = ioutil.ReadAll(io.LimitReader(rd.reader, int64(length)))
+	if rd.err == nil && uint64(len(data)) != length {
+		rd.err = io.ErrUnexpectedEOF
+	}
+
+	return
+}
+
+// block reads a block after the length of 4 bytes
+func (rd *deltaReader) block() []byte {
+	return rd.bytes(uint64(rd.uint32()))
+}
+
+// count reads a number of entries, which is limited to detect broken deltas
+func (rd *deltaReader) count() (n uint32) {
+	n = rd.uint32()
+	if rd.err == nil && n > 0x1000000 {
+		rd.err = fmt.Errorf("Too many entries: %d", n)
+	}
+
+	return
+}
+
+// signed decodes a negative number stored as the absolute value with the highest bit
+func signed(value uint32) int64 {
+	if value&0x80000000 != 0 {
+		return -int64(value ^ 0x80000000)
+	}
+
+	return int64(value)
+}
+
+// Read reads a delta rpm, which is a package file of the target header with the delta as the payload
+func Read(reader io.Reader) (delta *Delta, err error) {
+	buffered := bufio.NewReader(reader)
+
+	magic, err := buffered.Peek(4)
+	if err != nil {
+		return
+	}
+	if bytes.Equal(magic, rpmOnlyMagic) {
+		return nil, fmt.Errorf("Rpm-only deltas are not supported")
+	}
+
+	_, err = rpmlib.ScanLead(buffered)
+	if err != nil {
+		return
+	}
+
+	signature, err := rpmlib.ScanSignature(buffered)
+	if err != nil {
+		return
+	}
+
+	// Data store size is always 8 byte boundary
+	if len(signature.Bytes())%8 != 0 {
+		_, err = buffered.Discard(8 - len(signature.Bytes())%8)
+		if err != nil {
+			return
+		}
+	}
+
+	delta = new(Delta)
+	delta.Header, err = rpmlib.ScanHeader(buffered)
+	if err != nil {
+		return nil, err
+	}
+	delta.headerBytes = delta.Header.Bytes()
+
+	name, rd, err := decompressor(buffered)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	delta.Compression = name
+
+	err = delta.readDelta(&deltaReader{reader: rd})
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func Open(filename string) (delta *Delta, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	delta, err = Read(file)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return
+}
+
+func (delta *Delta) readDelta(rd *deltaReader) (err error) {
+	version := rd.uint32()
+	if rd.err != nil {
+		return fmt.Errorf("Delta is broken: %s", rd.err)
+	}
+	if version&0xffffff00 != deltaMagic {
+		return fmt.Errorf("Not a delta rpm")
+	}
+	delta.Version = int(version&0xff) - '0'
+	if delta.Version < 1 || delta.Version > 3 {
+		return fmt.Errorf("Delta version %c is not supported", version&0xff)
+	}
+
+	delta.SourceNEVR = string(rd.block())
+	delta.Sequence = rd.block()
+	if rd.err == nil && len(delta.Sequence) < 16 {
+		return fmt.Errorf("Sequence is too short")
+	}
+	delta.TargetMD5 = rd.bytes(16)
+
+	if delta.Version > 1 {
+		delta.TargetSize = rd.uint32()
+		delta.TargetCompression = rd.uint32()
+		delta.TargetCompressionParameters = rd.block()
+	} else {
+		delta.TargetCompression = COMP_GZIP
+	}
+
+	if delta.Version > 2 {
+		delta.CompressedHeaderLength = rd.uint32()
+		delta.OffsetAdjustments = make([]OffsetAdjustment, rd.count())
+		for i := range delta.OffsetAdjustments {
+			delta.OffsetAdjustments[i].Offset = rd.uint32()
+		}
+		for i := range delta.OffsetAdjustments {
+			delta.OffsetAdjustments[i].Adjust = int32(signed(rd.uint32()))
+		}
+	}
+
+	delta.Lead = rd.block()
+	if rd.err == nil && int64(len(delta.Lead)) < rpmlib.LeadSize+16 {
+		return fmt.Errorf("Lead of the target is too short")
+	}
+	delta.PayloadFormatOffset = rd.uint32()
+
+	delta.In = make([]Instruction, rd.count())
+	delta.Out = make([]Instruction, rd.count())
+	for i := range delta.In {
+		delta.In[i].Offset = int64(rd.uint32())
+	}
+	for i := range delta.In {
+		delta.In[i].Length = rd.uint32()
+	}
+	for i := range delta.Out {
+		delta.Out[i].Offset = signed(rd.uint32())
+	}
+	for i := range delta.Out {
+		delta.Out[i].Length = rd.uint32()
+	}
+
+	// Lengths are 8 bytes since version 3
+	length := func() uint64 {
+		if delta.Version > 2 {
+			return rd.uint64()
+		}
+		return uint64(rd.uint32())
+	}
+
+	delta.OutLength = length()
+	delta.AddBlock = rd.block()
+	delta.InData = rd.bytes(length())
+
+	if rd.err != nil {
+		return fmt.Errorf("Delta is broken: %s", rd.err)
+	}
+
+	return
+}
+
+// SequenceID returns the identifier of the old package, same as <sequence> of prestodelta.xml
+func (delta *Delta) SequenceID() string {
+	return delta.SourceNEVR + "-" + hex.EncodeToString(delta.Sequence)
+}
+
+// TargetNEVRA returns the package which the delta builds
+func (delta *Delta) TargetNEVRA() rpmlib.NEVRA {
+	return delta.Header.NEVRA()
+}
+
+// PayloadLength returns the length of the uncompressed payload of the target
+func (delta *Delta) PayloadLength() (length uint64) {
+	for _, in := range delta.In {
+		length += uint64(in.Length)
+	}
+	for _, out := range delta.Out {
+		length += uint64(out.Length)
+	}
+
+	return
+}
@@ -0,0 +1,215 @@
+package drpm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// sectionEnds returns the end of the signature and of the header of a package file.
+// The signature is padded to 8 byte boundary, and the header follows.
+func sectionEnds(data []byte) (signatureEnd int, headerEnd int) {
+	offset := 96
+	for i := 0; i < 2; i++ {
+		nindex := binary.BigEndian.Uint32(data[offset+8:])
+		hsize := binary.BigEndian.Uint32(data[offset+12:])
+		offset += 16 + 16*int(nindex) + int(hsize)
+		if i == 0 {
+			if offset%8 != 0 {
+				offset += 8 - offset%8
+			}
+			signatureEnd = offset
+		}
+	}
+
+	return signatureEnd, offset
+}
+
+// targetPackage returns the lead, the signature and the header of a package,
+// which are followed by the delta instead of the payload in delta rpms
+func targetPackage(t *testing.T) []byte {
+	data, err := ioutil.ReadFile(rpmtest.Write(t, t.TempDir(), rpmtest.Binary("foo", "1.1", "1")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, headerEnd := sectionEnds(data)
+
+	return data[:headerEnd]
+}
+
+// deltaBody writes the delta of the version, which has 2 copies from the old data and 1 of new data
+func deltaBody(version int) []byte {
+	var body bytes.Buffer
+	u32 := func(value uint32) { binary.Write(&body, binary.BigEndian, value) }
+	block := func(data []byte) { u32(uint32(len(data))); body.Write(data) }
+	length := func(value uint64) {
+		if version > 2 {
+			binary.Write(&body, binary.BigEndian, value)
+		} else {
+			u32(uint32(value))
+		}
+	}
+
+	u32(deltaMagic | uint32('0'+version))
+	block([]byte("foo-1.0-1"))
+	block(append(bytes.Repeat([]byte{0xaa}, 16), 0x12, 0x30))
+	body.Write(bytes.Repeat([]byte{0xbb}, 16))
+	if version > 1 {
+		u32(4000)
+		u32(COMP_XZ | 6<<8)
+		block(nil)
+	}
+	if version > 2 {
+		u32(0)
+		u32(1)
+		u32(100)
+		u32(0x80000000 | 8)
+	}
+	block(make([]byte, 112))
+	u32(200)
+
+	// 1 in-instruction and 2 out-instructions
+	u32(1)
+	u32(2)
+	u32(1)
+	u32(5)
+	u32(10)
+	u32(0x80000000 | 4)
+	u32(300)
+	u32(200)
+	length(500)
+	block(nil)
+	length(5)
+	body.WriteString("hello")
+
+	return body.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	target := targetPackage(t)
+
+	for version := 1; version <= 3; version++ {
+		var data bytes.Buffer
+		data.Write(target)
+		w := gzip.NewWriter(&data)
+		w.Write(deltaBody(version))
+		w.Close()
+
+		delta, err := Read(&data)
+		if err != nil {
+			t.Errorf("Version %d: %s", version, err)
+			continue
+		}
+
+		if delta.Version != version || delta.Compression != "gzip" {
+			t.Errorf("Version %d: %d, %s", version, delta.Version, delta.Compression)
+		}
+		if nevra := delta.TargetNEVRA().String(); nevra != "foo-1.1-1.noarch" {
+			t.Errorf("Version %d: TargetNEVRA() = %s", version, nevra)
+		}
+		if id := delta.SequenceID(); id != "foo-1.0-1-"+strings.Repeat("aa", 16)+"1230" {
+			t.Errorf("Version %d: SequenceID() = %s", version, id)
+		}
+		if delta.PayloadLength() != 505 || len(delta.In) != 1 || len(delta.Out) != 2 {
+			t.Errorf("Version %d: PayloadLength() = %d", version, delta.PayloadLength())
+		}
+		if delta.Out[1].Offset != -4 || string(delta.InData) != "hello" || delta.OutLength != 500 {
+			t.Errorf("Version %d: %+v, %q", version, delta.Out, delta.InData)
+		}
+
+		switch version {
+		case 1:
+			if delta.TargetSize != 0 || CompressionName(delta.TargetCompression) != "gzip" {
+				t.Errorf("Version 1: %d, %d", delta.TargetSize, delta.TargetCompression)
+			}
+		case 3:
+			if len(delta.OffsetAdjustments) != 1 || delta.OffsetAdjustments[0] != (OffsetAdjustment{100, -8}) {
+				t.Errorf("Version 3: %+v", delta.OffsetAdjustments)
+			}
+			fallthrough
+		case 2:
+			if delta.TargetSize != 4000 || CompressionName(delta.TargetCompression) != "xz" ||
+				delta.TargetCompression>>8&0xff != 6 {
+				t.Errorf("Version %d: %d, %#x", version, delta.TargetSize, delta.TargetCompression)
+			}
+		}
+	}
+}
+
+func TestReadCompressions(t *testing.T) {
+	target := targetPackage(t)
+	body := deltaBody(3)
+
+	var compressed bytes.Buffer
+	encoder, err := zstd.NewWriter(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder.Write(body)
+	encoder.Close()
+
+	tests := []struct {
+		data        []byte
+		compression string
+	}{
+		{body, "uncompressed"},
+		{compressed.Bytes(), "zstd"},
+	}
+
+	for _, test := range tests {
+		delta, err := Read(bytes.NewReader(append(append([]byte{}, target...), test.data...)))
+		if err != nil || delta.Compression != test.compression {
+			t.Errorf("%s: %v", test.compression, err)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	target := targetPackage(t)
+	body := deltaBody(3)
+
+	unsupported := append([]byte{}, body...)
+	unsupported[3] = '4'
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"rpm-only", []byte("drpm0000")},
+		{"empty", nil},
+		{"not a delta", append(append([]byte{}, target...), []byte("0000 not a delta")...)},
+		{"version 4", append(append([]byte{}, target...), unsupported...)},
+		{"truncated", append(append([]byte{}, target...), body[:len(body)-3]...)},
+		{"lzma", append(append([]byte{}, target...), 0x5d, 0, 0, 0x80, 0)},
+	}
+
+	for _, test := range tests {
+		if _, err := Read(bytes.NewReader(test.data)); err == nil {
+			t.Errorf("%s: Read() succeeded", test.name)
+		}
+	}
+}
+
+func TestCompressionName(t *testing.T) {
+	tests := []struct {
+		comp uint32
+		name string
+	}{
+		{COMP_UNCOMPRESSED, "uncompressed"},
+		{COMP_GZIP_RSYNC, "gzip rsyncable"},
+		{COMP_ZSTD | 19<<8, "zstd"},
+		{4, "unknown (4)"},
+	}
+
+	for _, test := range tests {
+		if name := CompressionName(test.comp); name != test.name {
+			t.Errorf("CompressionName(%#x) = %s, want %s", test.comp, name, test.name)
+		}
+	}
+}
@@ -2,4 +2,9 @@ module github.com/pombredanne/gorpm-1
 
 go 1.17
 
-require github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/klauspost/compress v1.15.15
+	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/repodata"
+	"os"
+)
+
+// CreateRepo writes repository metadata for packages in the directory
+func CreateRepo(args []string) int {
+	options := repodata.DefaultOptions
+	noCache := false
+	noFilelistsExt := false
+
+	flags := flag.NewFlagSet("createrepo", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm createrepo [options] <directory>\n")
+		flags.PrintDefaults()
+	}
+	flags.IntVar(&options.ChangelogLimit, "changelog-limit", options.ChangelogLimit,
+		"Number of latest changelogs for each package, all if negative.")
+	flags.BoolVar(&noCache, "no-cache", false, "Read all packages, not using the cache in repodata.")
+	flags.BoolVar(&noFilelistsExt, "no-filelists-ext", false, "Do not write filelists-ext.xml.")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	options.UseCache = !noCache
+	options.FilelistsExt = !noFilelistsExt
+
+	packages, reused, err := repodata.CreateRepo(flags.Arg(0), options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	fmt.Printf("%d packages, %d from cache\n", len(packages), reused)
+
+	return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmdiff"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fieldValue formats a value of a header field in a line
+func fieldValue(value string) string {
+	if strings.ContainsAny(value, "\n\t") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+func printDiff(diff *rpmdiff.PackageDiff) {
+	fmt.Printf("--- %s\n+++ %s\n", diff.Old, diff.New)
+
+	for _, field := range diff.Fields {
+		fmt.Printf("changed     %s %s -> %s\n", field.Field, fieldValue(field.Old), fieldValue(field.New))
+	}
+
+	for _, dep := range diff.Dependencies {
+		switch {
+		case dep.New == nil:
+			fmt.Printf("removed     %s %s\n", dep.Kind, dep.Old)
+		case dep.Old == nil:
+			fmt.Printf("added       %s %s\n", dep.Kind, dep.New)
+		default:
+			fmt.Printf("changed     %s %s -> %s\n", dep.Kind, dep.Old, dep.New)
+		}
+	}
+
+	for _, file := range diff.Files {
+		switch file.Change {
+		case rpmdiff.FileChanged:
+			fmt.Printf("%-11s %s\n", file.Letters(), file.Path)
+		default:
+			fmt.Printf("%-11s %s\n", file.Change, file.Path)
+		}
+	}
+
+	for _, file := range diff.Files {
+		fmt.Print(file.Diff)
+	}
+}
+
+// openPackage opens the package file, which the caller closes after the payload is read
+func openPackage(filename string) (file *os.File, pkg *rpmlib.PackageFile, err error) {
+	file, err = os.Open(filename)
+	if err != nil {
+		return
+	}
+
+	pkg, err = rpmlib.OpenPackageFile(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return
+}
+
+// Diff compares two packages. The exit status is 0 if they are same, 1 if
+// they differ and 2 for errors, same as diff.
+func Diff(args []string) int {
+	options := rpmdiff.DefaultOptions
+	output, ignore := "text", ""
+
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm diff [options] <old package> <new package>\n")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&options.Contents, "contents", false, "Show unified diffs of changed text files in the payloads.")
+	flags.IntVar(&options.Context, "context", options.Context, "Lines of context of unified diffs.")
+	flags.StringVar(&ignore, "ignore", "",
+		"Header fields not to compare, separated by commas, such as \"BUILDTIME,BUILDHOST\".")
+	flags.StringVar(&output, "output", output, "Output format, text, json or yaml.")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	if output != "text" && output != "json" && output != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown output format %s\n", output)
+		return 2
+	}
+	if ignore != "" {
+		options.IgnoreFields = strings.Split(ignore, ",")
+	}
+
+	var packages []*rpmlib.PackageFile
+	for _, filename := range flags.Args() {
+		file, pkg, err := openPackage(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 2
+		}
+		defer file.Close()
+		packages = append(packages, pkg)
+	}
+
+	diff, err := rpmdiff.Compare(packages[0], packages[1], options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	if output == "text" {
+		printDiff(diff)
+	} else if err = WriteOutput(output, diff); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	if diff.Empty() {
+		return 0
+	}
+
+	return 1
+}
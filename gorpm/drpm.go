@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/drpm"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+)
+
+func printDelta(delta *drpm.Delta) {
+	fmt.Printf("Version:            %d\n", delta.Version)
+	fmt.Printf("Compression:        %s\n", delta.Compression)
+	fmt.Printf("Source:             %s\n", delta.SourceNEVR)
+	fmt.Printf("Target:             %s\n", delta.TargetNEVRA())
+	fmt.Printf("Sequence:           %s\n", delta.SequenceID())
+	if delta.TargetSize != 0 {
+		fmt.Printf("Target size:        %d\n", delta.TargetSize)
+	}
+	fmt.Printf("Target MD5:         %x\n", delta.TargetMD5)
+	fmt.Printf("Target compression: %s", drpm.CompressionName(delta.TargetCompression))
+	if level := delta.TargetCompression >> 8 & 0xff; level != 0 {
+		fmt.Printf(" (level %d)", level)
+	}
+	fmt.Printf("\n")
+	fmt.Printf("Payload size:       %d\n", delta.PayloadLength())
+	fmt.Printf("Instructions:       %d copies from the old package, %d bytes of new data\n",
+		len(delta.Out), len(delta.InData))
+}
+
+// applyDelta rebuilds the target package from the old package file, and removes
+// the output when the result is not verified
+func applyDelta(delta *drpm.Delta, oldFilename string, output string) (err error) {
+	file, err := os.Open(oldFilename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	old, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return fmt.Errorf("%s: %s", oldFilename, err)
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return
+	}
+
+	err = delta.Apply(old, out)
+	close_err := out.Close()
+	if err == nil {
+		err = close_err
+	}
+	if err != nil {
+		os.Remove(output)
+	}
+
+	return
+}
+
+// Drpm shows delta rpms, or rebuilds the target package of a delta rpm, same as applydeltarpm -r
+func Drpm(args []string) int {
+	oldFilename, output := "", ""
+
+	flags := flag.NewFlagSet("drpm", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm drpm <delta rpm>...\n")
+		fmt.Fprintf(os.Stderr, "       gorpm drpm -old <old package> -o <new package> <delta rpm>\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&oldFilename, "old", "", "Rebuild the new package from the old package file.")
+	flags.StringVar(&output, "o", "", "The new package file to write with -old.")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || (oldFilename == "") != (output == "") || (oldFilename != "" && flags.NArg() != 1) {
+		flags.Usage()
+		return 1
+	}
+
+	status := 0
+	for i, filename := range flags.Args() {
+		delta, err := drpm.Open(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			status = 1
+			continue
+		}
+
+		if oldFilename != "" {
+			err = applyDelta(delta, oldFilename, output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				return 1
+			}
+			fmt.Printf("Wrote: %s\n", output)
+			continue
+		}
+
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		if flags.NArg() > 1 {
+			fmt.Printf("%s:\n", filename)
+		}
+		printDelta(delta)
+	}
+
+	return status
+}
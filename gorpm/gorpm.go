@@ -1,10 +1,19 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmdb"
 	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 func PrintPackageInformation(file *os.File) (err error) {
@@ -14,61 +23,163 @@ func PrintPackageInformation(file *os.File) (err error) {
 		return
 	}
 
-	fmt.Printf("Name:       %s\n", pkg.Header.Name())
-	fmt.Printf("Version:    %s\n", pkg.Header.Version())
-	fmt.Printf("Release:    %s\n", pkg.Header.Release())
-	fmt.Printf("Group:      %s\n", pkg.Header.Group())
-	fmt.Printf("Size:       %d\n", pkg.Header.Size())
-	fmt.Printf("Licence:    %s\n", pkg.Header.Licence())
+	printInformation(pkg.Header)
+
+	return
+}
+
+func printInformation(header *rpmlib.Header) {
+	fmt.Printf("Name:       %s\n", header.Name())
+	fmt.Printf("Version:    %s\n", header.Version())
+	fmt.Printf("Release:    %s\n", header.Release())
+	fmt.Printf("Group:      %s\n", header.Group())
+	fmt.Printf("Size:       %d\n", header.Size())
+	fmt.Printf("Licence:    %s\n", header.Licence())
 
-	date, err := pkg.Header.BuildDate()
+	date, err := header.BuildDate()
 	if err == nil {
 		fmt.Printf("BuildDate:  %s\n", date.String())
 	} else {
 		fmt.Printf("BuildDate:  Unknown\n")
 	}
-	srpm, err := pkg.Header.SourceRpm()
-	if err == nil {
-		fmt.Printf("Source RPM: %s\n", srpm)
+	// Source packages are not built from a source package, same as rpm
+	srpm, err := header.SourceRpm()
+	if err != nil {
+		srpm = "(none)"
+	}
+	fmt.Printf("Source RPM: %s\n", srpm)
+
+	fmt.Printf("Summary:    %s\n", header.Summary())
+	fmt.Printf("Description:\n %s\n", header.Description())
+}
+
+// printSources prints the spec file, sources, patches and build dependencies of a source package
+func printSources(header *rpmlib.Header) (err error) {
+	if !header.IsSource() {
+		return fmt.Errorf("%s is not a source package", header.Name())
 	}
 
-	fmt.Printf("Summary:    %s\n", pkg.Header.Summary())
-	fmt.Printf("Description:\n %s\n", pkg.Header.Description())
+	if spec, spec_err := header.SpecFile(); spec_err == nil {
+		fmt.Printf("Spec: %s\n", strings.TrimPrefix(spec, "/"))
+	}
+
+	sources, err := header.Sources()
+	if err != nil {
+		return
+	}
+	patches, err := header.Patches()
+	if err != nil {
+		return
+	}
+	nosource, err := header.NoSource()
+	if err != nil {
+		return
+	}
+	nopatch, err := header.NoPatch()
+	if err != nil {
+		return
+	}
+	buildRequires, err := header.BuildRequires()
+	if err != nil {
+		return
+	}
+
+	for _, source := range sources {
+		fmt.Printf("Source: %s\n", source)
+	}
+	for _, patch := range patches {
+		fmt.Printf("Patch: %s\n", patch)
+	}
+	for _, number := range nosource {
+		fmt.Printf("NoSource: %d\n", number)
+	}
+	for _, number := range nopatch {
+		fmt.Printf("NoPatch: %d\n", number)
+	}
+	for _, dep := range buildRequires {
+		fmt.Printf("BuildRequires: %s\n", dep)
+	}
 
 	return
 }
 
-func PrintPackagedFiles(file *os.File) (err error) {
-	pkg, err := rpmlib.ReadPackageFile(file)
+func PrintPackageSources(file *os.File) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
 	if err != nil {
 		return
 	}
 
-	file_list, err := pkg.Header.Files()
+	return printSources(pkg.Header)
+}
+
+// PrintSpecFile writes the spec file of a source package to stdout
+func PrintSpecFile(file *os.File) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
 	if err != nil {
 		return
 	}
 
-	for _, f := range file_list {
-		fmt.Println(f.Path)
+	if !pkg.IsSource() {
+		return fmt.Errorf("%s is not a source package", file.Name())
+	}
+
+	_, data, err := pkg.ReadSpec()
+	if err != nil {
+		return
+	}
+
+	_, err = os.Stdout.Write(data)
+
+	return
+}
+
+// ExtractSources writes the spec file, sources and patches of a source package to dir
+func ExtractSources(file *os.File, dir string) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	written, err := pkg.ExtractSources(dir)
+	for _, filename := range written {
+		fmt.Println(filename)
 	}
 
 	return
 }
 
+func PrintPackagedFiles(file *os.File) (err error) {
+	pkg, err := rpmlib.ReadPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	return printFiles(pkg.Header, rpmlib.RPMFILE_NONE)
+}
+
 func PrintPackageFileOf(file *os.File, filetype int32) (err error) {
 	pkg, err := rpmlib.ReadPackageFile(file)
 	if err != nil {
 		return
 	}
 
-	file_list, err := pkg.Header.Files()
+	return printFiles(pkg.Header, filetype)
+}
+
+// printFiles prints files which have the flag of filetype, or all files for RPMFILE_NONE
+func printFiles(header *rpmlib.Header, filetype int32) (err error) {
+	if !header.Section.HasStore(rpmlib.RPMTAG_BASENAMES) && !header.Section.HasStore(rpmlib.RPMTAG_OLDFILENAMES) {
+		fmt.Println("(contains no files)")
+		return
+	}
+
+	file_list, err := header.Files()
 	if err != nil {
 		return
 	}
 
 	for _, f := range file_list {
-		if f.Flag&filetype != 0 {
+		if filetype == rpmlib.RPMFILE_NONE || f.Flag&filetype != 0 {
 			fmt.Println(f.Path)
 		}
 	}
@@ -82,7 +193,11 @@ func PrintPackageChangelog(file *os.File) (err error) {
 		return
 	}
 
-	changelogs, err := pkg.Header.Changelog()
+	return printChangelog(pkg.Header)
+}
+
+func printChangelog(header *rpmlib.Header) (err error) {
+	changelogs, err := header.Changelog()
 	if err != nil {
 		return
 	}
@@ -101,6 +216,11 @@ func VerifyPackage(file *os.File) (err error) {
 		return
 	}
 
+	// Tags of unexpected types are warnings, since rpm also reads them
+	for _, tag_err := range append(pkg.Signature.TagTypeErrors(), pkg.Header.TagTypeErrors()...) {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", tag_err)
+	}
+
 	results, err := pkg.Verify()
 
 	if err != nil {
@@ -161,6 +281,304 @@ func VerifyPackage(file *os.File) (err error) {
 	return
 }
 
+func CatPackagedFile(file *os.File, path string, useCache bool) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	if useCache {
+		err = pkg.CachePayloadIndex(file.Name() + ".cpioidx")
+		if err != nil {
+			return
+		}
+	}
+
+	f, err := pkg.Open(path)
+	if err != nil {
+		return
+	}
+
+	_, err = f.Write(os.Stdout)
+
+	return
+}
+
+func PrintQueryFormat(file *os.File, qf *rpmlib.QueryFormat) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	output, err := qf.Format(pkg.Header)
+	if err != nil {
+		return
+	}
+
+	fmt.Print(output)
+
+	return
+}
+
+func PrintQueryTags() {
+	var names []string
+	for _, info := range rpmlib.HeaderTags {
+		names = append(names, info.Name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
+
+// readEVR takes the version of a package file, or parses the argument as a version string
+func readEVR(arg string) (evr rpmlib.EVR, err error) {
+	if !strings.HasSuffix(arg, ".rpm") {
+		return rpmlib.ParseEVR(arg)
+	}
+
+	file, err := os.Open(arg)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	return pkg.Header.EVR(), nil
+}
+
+// CompareVersions prints the order of two versions and returns
+// the exit status same as rpmdev-vercmp, 0 if same, 11 if a is newer and 12 if b is newer.
+func CompareVersions(a string, b string) (status int, err error) {
+	evr_a, err := readEVR(a)
+	if err != nil {
+		return
+	}
+
+	evr_b, err := readEVR(b)
+	if err != nil {
+		return
+	}
+
+	switch evr_a.Compare(evr_b) {
+	case 1:
+		fmt.Printf("%s > %s\n", evr_a, evr_b)
+		status = 11
+	case -1:
+		fmt.Printf("%s < %s\n", evr_a, evr_b)
+		status = 12
+	default:
+		fmt.Printf("%s == %s\n", evr_a, evr_b)
+	}
+
+	return
+}
+
+// PrintNEVRA prints NEVRA of the package, and warns if the file name is not canonical
+func PrintNEVRA(file *os.File) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	fmt.Println(pkg.NEVRA())
+
+	canonical := pkg.CanonicalFilename()
+	if filepath.Base(file.Name()) != canonical {
+		fmt.Fprintf(os.Stderr, "%s: file name shall be %s\n", file.Name(), canonical)
+	}
+
+	return
+}
+
+func dumpSection(w io.Writer, title string, section *rpmlib.Section, lookup func(int32) (rpmlib.TagInfo, bool)) {
+	indexes := section.Indexes()
+
+	fmt.Fprintf(w, "%s: version %d, %d indexes, %d bytes of data\n",
+		title, section.Version(), len(indexes), len(section.Store()))
+
+	for i, index := range indexes {
+		name := "(unknown)"
+		if info, found := lookup(index.Tag); found {
+			name = info.Name
+		}
+
+		fmt.Fprintf(w, "[%3d] tag=%d %s type=%s offset=%d count=%d\n",
+			i, index.Tag, name, rpmlib.TypeName(index.Type), index.Offset, index.Count)
+
+		values, err := section.IndexValues(index)
+		if err != nil {
+			fmt.Fprintf(w, "      error: %s\n", err)
+			continue
+		}
+
+		for j, value := range values {
+			switch v := value.(type) {
+			case []byte:
+				for _, line := range strings.SplitAfter(hex.Dump(v), "\n") {
+					if line != "" {
+						fmt.Fprintf(w, "      %s", line)
+					}
+				}
+			case string:
+				fmt.Fprintf(w, "      [%d] %q\n", j, v)
+			// Integers are signed, and hex is of the bits stored
+			case int16:
+				fmt.Fprintf(w, "      [%d] %d (0x%x)\n", j, v, uint16(v))
+			case int32:
+				fmt.Fprintf(w, "      [%d] %d (0x%x)\n", j, v, uint32(v))
+			case int64:
+				fmt.Fprintf(w, "      [%d] %d (0x%x)\n", j, v, uint64(v))
+			default:
+				fmt.Fprintf(w, "      [%d] %d (0x%x)\n", j, v, v)
+			}
+		}
+	}
+}
+
+func DumpHeader(file *os.File) (err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if pkg == nil {
+		return
+	}
+
+	dumpPackage(os.Stdout, pkg)
+
+	return
+}
+
+func dumpPackage(w io.Writer, pkg *rpmlib.PackageFile) {
+	if pkg.Signature != nil {
+		dumpSection(w, "Signature", &pkg.Signature.Section, rpmlib.SignatureTagByNumber)
+	}
+
+	if pkg.Header != nil {
+		dumpSection(w, "Header", &pkg.Header.Section, rpmlib.TagByNumber)
+	}
+}
+
+func BuildPackageInfo(file *os.File, option *Option) (info *rpmlib.PackageInfo, err error) {
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	if !option.VerificationMode {
+		return buildHeaderInfo(pkg.Header, option)
+	}
+
+	info = rpmlib.NewPackageInfo(pkg.Header)
+
+	err = pkg.LoadPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := pkg.Verify()
+	if err != nil {
+		return nil, err
+	}
+	info.SetVerifyResults(results)
+
+	return
+}
+
+// buildHeaderInfo builds information of modes which need only the header
+func buildHeaderInfo(header *rpmlib.Header, option *Option) (info *rpmlib.PackageInfo, err error) {
+	info = rpmlib.NewPackageInfo(header)
+
+	if option.ShowInfoMode {
+		err = info.SetDependencies(header)
+	} else if option.ShowFileMode {
+		err = info.SetFiles(header, rpmlib.RPMFILE_NONE)
+	} else if option.ShowConfigFileMode {
+		err = info.SetFiles(header, rpmlib.RPMFILE_CONFIG)
+	} else if option.ShowDocFileMode {
+		err = info.SetFiles(header, rpmlib.RPMFILE_DOC)
+	} else if option.ShowChangelogMode {
+		err = info.SetChangelog(header)
+	} else {
+		err = fmt.Errorf("Output format %s is not supported for this mode", option.Output)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// nvra returns name-version-release.arch same as rpm -q, the arch is omitted if not present
+func nvra(header *rpmlib.Header) string {
+	s := header.Name() + "-" + header.Version() + "-" + header.Release()
+	if arch, err := header.Section.GetString(rpmlib.RPMTAG_ARCH); err == nil {
+		s += "." + arch
+	}
+
+	return s
+}
+
+// queryHeader shows the header in modes which need only the header, for packages
+// without the file such as installed packages. NEVRA is shown without any mode.
+func queryHeader(header *rpmlib.Header, option *Option, qf *rpmlib.QueryFormat) (info *rpmlib.PackageInfo, err error) {
+	if option.Output != "text" {
+		return buildHeaderInfo(header, option)
+	}
+
+	if option.ShowInfoMode {
+		printInformation(header)
+	} else if option.ShowFileMode {
+		err = printFiles(header, rpmlib.RPMFILE_NONE)
+	} else if option.ShowConfigFileMode {
+		err = printFiles(header, rpmlib.RPMFILE_CONFIG)
+	} else if option.ShowDocFileMode {
+		err = printFiles(header, rpmlib.RPMFILE_DOC)
+	} else if option.ShowChangelogMode {
+		err = printChangelog(header)
+	} else if qf != nil {
+		var output string
+		output, err = qf.Format(header)
+		fmt.Print(output)
+	} else if option.NEVRAMode {
+		fmt.Println(header.NEVRA())
+	} else if option.SourcesMode {
+		err = printSources(header)
+	} else if option.VerificationMode || option.CatFile != "" || option.SpecFileMode || option.ExtractSources != "" {
+		err = fmt.Errorf("Payload of the package is not available")
+	} else {
+		fmt.Println(header.NEVRA())
+	}
+
+	return
+}
+
+// WriteOutput writes the value, such as package infos, diffs or problems of lint, as json or yaml
+func WriteOutput(format string, value interface{}) (err error) {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		err = encoder.Encode(value)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		err = encoder.Encode(value)
+		if err == nil {
+			err = encoder.Close()
+		}
+	default:
+		err = fmt.Errorf("Unknown output format %s", format)
+	}
+
+	return
+}
+
 type Option struct {
 	ShowInfoMode       bool
 	ShowFileMode       bool
@@ -168,40 +586,270 @@ type Option struct {
 	ShowDocFileMode    bool
 	ShowChangelogMode  bool
 	VerificationMode   bool
+	CatFile            string
+	UseIndexCache      bool
+	QueryFormat        string
+	QueryTagsMode      bool
+	DumpHeaderMode     bool
+	CompareMode        bool
+	NEVRAMode          bool
+	Output             string
+	Repo               string
+	QueryAllMode       bool
+	DBPath             string
+	QueryMode          bool
+	QueryInfoMode      bool
+	QueryListMode      bool
+	QueryFileMode      bool
+	WhatProvidesMode   bool
+	WhatRequiresMode   bool
+	VerifyAllMode      bool
+	SourcesMode        bool
+	SpecFileMode       bool
+	ExtractSources     string
+	Root               string
+	NoConfig           bool
+	NetShared          string
 	//	CheckSignatureMode bool
 }
 
-func addOption(option *Option) {
-	flag.BoolVar(&option.ShowInfoMode, "i", false, "Show package inforamtion.")
-	flag.BoolVar(&option.ShowFileMode, "l", false, "Show files included package.")
-	flag.BoolVar(&option.ShowConfigFileMode, "c", false, "Show config files included package.")
-	flag.BoolVar(&option.ShowDocFileMode, "d", false, "Show doc files included package.")
-	flag.BoolVar(&option.ShowChangelogMode, "changelog", false, "Show changelog.")
-	flag.BoolVar(&option.VerificationMode, "V", false,
-		"Verify file's size, checksum, permission and type. user and group are not verified.")
+func addOption(flags *flag.FlagSet, option *Option) {
+	flags.BoolVar(&option.ShowInfoMode, "i", false, "Show package inforamtion.")
+	flags.BoolVar(&option.ShowFileMode, "l", false, "Show files included package.")
+	flags.BoolVar(&option.ShowConfigFileMode, "c", false, "Show config files included package.")
+	flags.BoolVar(&option.ShowDocFileMode, "d", false, "Show doc files included package.")
+	flags.BoolVar(&option.ShowChangelogMode, "changelog", false, "Show changelog.")
+	flags.BoolVar(&option.VerificationMode, "V", false,
+		"Verify file's size, checksum, permission and type. user and group are not verified.\n"+
+			"Tags of unexpected data types are warned.")
+	flags.StringVar(&option.CatFile, "cat", "", "Write the content of the file in package to stdout.")
+	flags.BoolVar(&option.UseIndexCache, "index-cache", false,
+		"Save the payload index to <package>.cpioidx and reuse it with -cat.")
+	flags.StringVar(&option.QueryFormat, "qf", "",
+		"Show package information in the query format, same as rpm.")
+	flags.StringVar(&option.QueryFormat, "queryformat", "", "Same as -qf.")
+	flags.BoolVar(&option.QueryTagsMode, "querytags", false, "Show tag names which can be used in query format.")
+	flags.BoolVar(&option.DumpHeaderMode, "dump-header", false,
+		"Dump all tags in signature and header, also for broken package.")
+	flags.BoolVar(&option.CompareMode, "compare", false,
+		"Compare two versions or packages given as arguments, such as 1:2.0-1 or foo-2.0-1.x86_64.rpm.")
+	flags.BoolVar(&option.NEVRAMode, "nevra", false,
+		"Show name-epoch:version-release.arch of package, and warn if the file name is not canonical.")
+	flags.StringVar(&option.Output, "output", "text",
+		"Output format, text, json or yaml. json and yaml are supported with -i, -l, -c, -d, -changelog and -V.")
+	flags.StringVar(&option.Repo, "repo", "",
+		"Query packages in repository metadata of the directory instead of package files. Arguments are package names.")
+	flags.BoolVar(&option.QueryAllMode, "qa", false,
+		"Query all installed packages in the rpm database, with -i, -l, -c, -d, -changelog or -qf.")
+	flags.StringVar(&option.DBPath, "dbpath", rpmdb.DefaultDBPath, "Directory of the rpm database.")
+	flags.BoolVar(&option.SourcesMode, "sources", false,
+		"Show the spec file, sources, patches and build dependencies of a source package.")
+	flags.BoolVar(&option.SpecFileMode, "specfile", false, "Write the spec file of a source package to stdout.")
+	flags.StringVar(&option.ExtractSources, "extract-sources", "",
+		"Write the spec file, sources and patches of a source package to the directory.")
+	flags.BoolVar(&option.VerifyAllMode, "Va", false,
+		"Verify files of all installed packages on disk, same as rpm -Va. Output is grouped by package.")
+	flags.StringVar(&option.Root, "root", "/",
+		"Root directory of installed packages, such as a mounted image. The database is in the root.")
+	flags.BoolVar(&option.NoConfig, "noconfig", false, "Do not verify %config files with -Va.")
+	flags.StringVar(&option.NetShared, "netshared", "",
+		"Paths separated by colons, under which files are not verified with -Va, same as %_netsharedpath.")
+	flags.BoolVar(&option.QueryMode, "q", false,
+		"Query installed packages by names given as arguments, with -i, -l, -c, -d, -changelog or -qf.")
+	flags.BoolVar(&option.QueryInfoMode, "qi", false, "Same as -q -i.")
+	flags.BoolVar(&option.QueryListMode, "ql", false, "Same as -q -l.")
+	flags.BoolVar(&option.QueryFileMode, "f", false,
+		"With -q, query installed packages which own files given as arguments, same as rpm -qf. "+
+			"Relative paths are from the current directory.")
+	flags.BoolVar(&option.WhatProvidesMode, "whatprovides", false,
+		"Query installed packages which provide capabilities given as arguments, such as \"libfoo.so.2()(64bit)\".")
+	flags.BoolVar(&option.WhatRequiresMode, "whatrequires", false,
+		"Query installed packages which require capabilities given as arguments.")
 	//flag.BoolVar(&option.CheckSignatureMode, "checksig", false, "Check all digests and signatures"
 }
 
+// queryMode returns how installed packages are queried by arguments, or "" to read package files.
+// -q -f queries owners of files same as rpm -qf, since -qf is the query format.
+func queryMode(option *Option) (queryBy string, err error) {
+	if option.QueryInfoMode || option.QueryListMode {
+		option.QueryMode = true
+		option.ShowInfoMode = option.ShowInfoMode || option.QueryInfoMode
+		option.ShowFileMode = option.ShowFileMode || option.QueryListMode
+	}
+	if option.QueryFileMode && !option.QueryMode {
+		return "", fmt.Errorf("-f is used with -q to query owners of files")
+	}
+
+	if option.QueryFileMode {
+		queryBy = QueryByFile
+	} else if option.WhatProvidesMode {
+		queryBy = QueryByProvides
+	} else if option.WhatRequiresMode {
+		queryBy = QueryByRequires
+	} else if option.QueryMode {
+		queryBy = QueryByName
+	}
+
+	return
+}
+
+// Subcommands, such as "gorpm repoclosure <dir>". They parse their own arguments
+// and return the exit status.
+var commands = map[string]func(args []string) int{
+	"repoclosure": RepoClosure,
+	"createrepo":  CreateRepo,
+	"spec":        Spec,
+	"drpm":        Drpm,
+	"diff":        Diff,
+	"lint":        Lint,
+	"solve":       Solve,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, found := commands[os.Args[1]]; found {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	var option Option
 
-	addOption(&option)
+	addOption(flag.CommandLine, &option)
 
 	flag.Parse()
 
-	if flag.NArg() < 1 {
+	if option.QueryTagsMode {
+		PrintQueryTags()
+		os.Exit(0)
+	}
+
+	if option.CompareMode {
+		if flag.NArg() != 2 {
+			fmt.Fprintf(os.Stderr, "Two versions are required to compare\n")
+			os.Exit(1)
+		}
+
+		status, err := CompareVersions(flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		os.Exit(status)
+	}
+
+	if option.Output != "text" && option.Output != "json" && option.Output != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown output format %s\n", option.Output)
+		os.Exit(1)
+	}
+
+	// Installed packages are queried by arguments
+	queryArgs := flag.Args()
+	queryBy, err := queryMode(&option)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	if len(queryArgs) < 1 && queryBy != "" {
+		fmt.Fprintf(os.Stderr, "Argument to query not specified\n")
+		os.Exit(1)
+	}
+	// -V with -qa is the same as -Va
+	if option.QueryAllMode && option.VerificationMode {
+		option.VerifyAllMode = true
+	}
+	if option.Root != "/" && option.Root != "" {
+		option.DBPath = filepath.Join(option.Root, option.DBPath)
+	}
+
+	if flag.NArg() < 1 && option.Repo == "" && !option.QueryAllMode && queryBy == "" && !option.VerifyAllMode {
 		fmt.Fprintf(os.Stderr, "Package file not specified\n")
 		os.Exit(1)
 	}
 
-	for _, filename := range flag.Args() {
+	var qf *rpmlib.QueryFormat
+	if option.QueryFormat != "" {
+		var err error
+		qf, err = rpmlib.ParseQueryFormat(option.QueryFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var infos []*rpmlib.PackageInfo
+	status := 0
+
+	// Arguments are package names with -repo
+	filenames := flag.Args()
+	if option.Repo != "" {
+		var err error
+		infos, err = QueryRepo(option.Repo, filenames, &option, qf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		filenames = nil
+	}
+	if option.VerifyAllMode {
+		var err error
+		var failed bool
+		infos, failed, err = VerifyInstalled(option.DBPath, &option)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		if failed {
+			status = 1
+		}
+		filenames = nil
+	} else if option.QueryAllMode {
+		var err error
+		infos, err = QueryInstalled(option.DBPath, &option, qf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		filenames = nil
+	}
+	if queryBy != "" {
+		var err error
+		var missing int
+		infos, missing, err = QueryInstalledBy(option.DBPath, queryBy, queryArgs, &option, qf)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+		if missing > 0 {
+			status = 1
+		}
+		filenames = nil
+	}
+
+	for _, filename := range filenames {
+		if rpmlib.IsURL(filename) {
+			info, err := QueryRemotePackage(filename, &option, qf)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+			} else if info != nil {
+				infos = append(infos, info)
+			}
+			continue
+		}
+
 		file, err := os.Open(filename)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 			continue
 		}
 
-		if option.ShowInfoMode {
+		if option.Output != "text" {
+			var info *rpmlib.PackageInfo
+			info, err = BuildPackageInfo(file, &option)
+			if err == nil {
+				infos = append(infos, info)
+			}
+		} else if option.ShowInfoMode {
 			err = PrintPackageInformation(file)
 		} else if option.ShowFileMode {
 			err = PrintPackagedFiles(file)
@@ -213,6 +861,20 @@ func main() {
 			err = PrintPackageChangelog(file)
 		} else if option.VerificationMode {
 			err = VerifyPackage(file)
+		} else if option.NEVRAMode {
+			err = PrintNEVRA(file)
+		} else if option.DumpHeaderMode {
+			err = DumpHeader(file)
+		} else if qf != nil {
+			err = PrintQueryFormat(file, qf)
+		} else if option.CatFile != "" {
+			err = CatPackagedFile(file, option.CatFile, option.UseIndexCache)
+		} else if option.SourcesMode {
+			err = PrintPackageSources(file)
+		} else if option.SpecFileMode {
+			err = PrintSpecFile(file)
+		} else if option.ExtractSources != "" {
+			err = ExtractSources(file, option.ExtractSources)
 		}
 
 		if err != nil {
@@ -222,5 +884,16 @@ func main() {
 		file.Close()
 	}
 
-	os.Exit(0)
+	if option.Output != "text" {
+		if infos == nil {
+			infos = []*rpmlib.PackageInfo{}
+		}
+		err := WriteOutput(option.Output, infos)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	os.Exit(status)
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+)
+
+// parseOption parses arguments same as the command line of gorpm
+func parseOption(t *testing.T, args ...string) (option *Option, flags *flag.FlagSet) {
+	option = new(Option)
+	flags = flag.NewFlagSet("gorpm", flag.ContinueOnError)
+	addOption(flags, option)
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestQueryMode(t *testing.T) {
+	tests := []struct {
+		args        []string
+		queryBy     string
+		queryFormat string
+	}{
+		{[]string{"-q", "-f", "/usr/bin/foo"}, QueryByFile, ""},
+		{[]string{"-qi", "-f", "/usr/bin/foo"}, QueryByFile, ""},
+		// -qf is the query format, not the owner of the file
+		{[]string{"-q", "-qf", "%{NAME}\n", "foo"}, QueryByName, "%{NAME}\n"},
+		{[]string{"-qf", "/usr/bin/foo", "foo.rpm"}, "", "/usr/bin/foo"},
+		{[]string{"-queryformat", "%{NAME}\n", "-q", "-f", "/usr/bin/foo"}, QueryByFile, "%{NAME}\n"},
+		{[]string{"-whatprovides", "libfoo.so.1()(64bit)"}, QueryByProvides, ""},
+		{[]string{"-whatrequires", "bar"}, QueryByRequires, ""},
+		{[]string{"foo.rpm"}, "", ""},
+	}
+
+	for _, test := range tests {
+		option, _ := parseOption(t, test.args...)
+		queryBy, err := queryMode(option)
+		if err != nil {
+			t.Errorf("%v: %s", test.args, err)
+			continue
+		}
+		if queryBy != test.queryBy || option.QueryFormat != test.queryFormat {
+			t.Errorf("%v: queryMode() = %q with the format %q, want %q with %q",
+				test.args, queryBy, option.QueryFormat, test.queryBy, test.queryFormat)
+		}
+	}
+
+	option, _ := parseOption(t, "-f", "/usr/bin/foo")
+	if _, err := queryMode(option); err == nil {
+		t.Errorf("queryMode() of -f without -q succeeded")
+	}
+}
+
+func TestQueryInstalledByFile(t *testing.T) {
+	option, flags := parseOption(t, "-q", "-f", "-i", "-output", "json",
+		"/usr/bin/foo", "/usr/bin/../share/bar/README", "/usr/share/foo/README")
+	queryBy, err := queryMode(option)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos, missing, err := QueryInstalledBy("../rpmdb/testdata/sqlite", queryBy, flags.Args(), option, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	if strings.Join(names, " ") != "foo bar" || missing != 1 {
+		t.Errorf("QueryInstalledBy() = %v, %d missing", names, missing)
+	}
+}
+
+// Integers of the dump are signed, with the hex of bits stored
+func TestDumpPackage(t *testing.T) {
+	su := rpmtest.File{Path: "/usr/bin/su", Mode: rpmlib.S_IFREG | 04755, Data: "su", Time: -1}
+	pkg := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1", su))
+
+	var output bytes.Buffer
+	dumpPackage(&output, pkg)
+	lines := strings.Split(output.String(), "\n")
+
+	// Each expected line is followed by the next one
+	expected := [][]string{
+		{"Signature: version 1, 6 indexes, 148 bytes of data",
+			"[  0] tag=62 HEADERSIGNATURES type=BIN offset=132 count=16",
+			"      00000000  00 00 00 3e 00 00 00 07  ff ff ff a0 00 00 00 10  |...>............|"},
+		{"[  3] tag=1000 SIZE type=INT32 offset=108 count=1"},
+		{"Header: version 1, 37 indexes, 405 bytes of data"},
+		{"[  2] tag=1000 NAME type=STRING offset=2 count=1", `      [0] "foo"`},
+		{"[  7] tag=1006 BUILDTIME type=INT32 offset=48 count=1", "      [0] 1600000000 (0x5f5e1000)"},
+		{"[ 14] tag=1030 FILEMODES type=INT16 offset=92 count=1", "      [0] -30227 (0x89ed)"},
+		{"[ 16] tag=1034 FILEMTIMES type=INT32 offset=96 count=1", "      [0] -1 (0xffffffff)"},
+		{"[ 23] tag=1045 FILEVERIFYFLAGS type=INT32 offset=200 count=1", "      [0] -1 (0xffffffff)"},
+		{"[ 29] tag=1118 DIRNAMES type=STRING_ARRAY offset=223 count=1", `      [0] "/usr/bin/"`},
+	}
+
+	for _, expectedLines := range expected {
+		found := false
+		for i := range lines {
+			if i+len(expectedLines) <= len(lines) && strings.Join(lines[i:i+len(expectedLines)], "\n") == strings.Join(expectedLines, "\n") {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Dump has no lines %q:\n%s", expectedLines, output.String())
+		}
+	}
+	if strings.Contains(output.String(), "error") {
+		t.Errorf("Dump has errors:\n%s", output.String())
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/lint"
+	"os"
+)
+
+// lintFile checks the package file, which is open while checks read the payload
+func lintFile(linter *lint.Linter, filename string) (problems []lint.Problem, err error) {
+	file, pkg, err := openPackage(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	target, err := lint.NewPackage(pkg, filename)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return linter.Lint(target), nil
+}
+
+// Lint checks packages, same as rpmlint. The exit status is 1 if there are errors.
+func Lint(args []string) int {
+	output, filterFile := "text", ""
+	list := false
+
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm lint [options] <package>...\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&filterFile, "filter", "",
+		"TOML or YAML file to disable checks, change severities and ignore problems.")
+	flags.BoolVar(&list, "list", false, "Show checks.")
+	flags.StringVar(&output, "output", output, "Output format, text, json or yaml.")
+	flags.Parse(args)
+
+	if list {
+		for _, check := range lint.Checks() {
+			fmt.Printf("%-20s %-8s %s\n", check.Name(), check.Severity(), check.Description())
+		}
+		return 0
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+	if output != "text" && output != "json" && output != "yaml" {
+		fmt.Fprintf(os.Stderr, "Unknown output format %s\n", output)
+		return 1
+	}
+
+	var filter *lint.Filter
+	if filterFile != "" {
+		var err error
+		filter, err = lint.LoadFilter(filterFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+	}
+	linter := lint.NewLinter(filter)
+
+	status := 0
+	var problems []lint.Problem
+	for _, filename := range flags.Args() {
+		found, err := lintFile(linter, filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			status = 1
+			continue
+		}
+
+		for _, problem := range found {
+			problems = append(problems, problem)
+			if problem.Severity == lint.SeverityError {
+				status = 1
+			}
+		}
+	}
+
+	if output == "text" {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	} else {
+		if problems == nil {
+			problems = []lint.Problem{}
+		}
+		if err := WriteOutput(output, problems); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+)
+
+// QueryRemotePackage shows a package at http or https URL, reading only its header
+// by range requests.
+func QueryRemotePackage(url string, option *Option, qf *rpmlib.QueryFormat) (info *rpmlib.PackageInfo, err error) {
+	pkg, err := rpmlib.FetchPackageHeader(url)
+	if pkg == nil {
+		return
+	}
+
+	if option.DumpHeaderMode {
+		dumpPackage(os.Stdout, pkg)
+		return nil, err
+	}
+	if err != nil {
+		return
+	}
+
+	info, err = queryHeader(pkg.Header, option, qf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", url, err)
+	}
+
+	return
+}
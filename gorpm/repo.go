@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/repodata"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path/filepath"
+)
+
+// isRepository checks the directory has repository metadata
+func isRepository(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, repodata.RepoDataDir, "repomd.xml"))
+
+	return err == nil
+}
+
+// readRepoHeaders builds headers of packages from repository metadata
+func readRepoHeaders(dir string) (headers []*rpmlib.Header, err error) {
+	packages, err := repodata.ReadRepo(dir, repodata.DefaultReadOptions)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range packages {
+		headers = append(headers, pkg.Header())
+	}
+
+	return
+}
+
+// QueryRepo shows packages in repository metadata same as package files.
+// Packages are selected by name or NEVRA, or all packages if no names given.
+func QueryRepo(dir string, names []string, option *Option, qf *rpmlib.QueryFormat) (infos []*rpmlib.PackageInfo, err error) {
+	headers, err := readRepoHeaders(dir)
+	if err != nil {
+		return
+	}
+
+	for _, header := range headers {
+		if !matchPackage(header, names) {
+			continue
+		}
+
+		info, query_err := queryHeader(header, option, qf)
+		if query_err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", header.NEVRA(), query_err)
+		} else if info != nil {
+			infos = append(infos, info)
+		}
+	}
+
+	return
+}
+
+func matchPackage(header *rpmlib.Header, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+
+	for _, name := range names {
+		if name == header.Name() || name == header.NEVRA().String() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// walkPackages calls fn with headers of all packages under the directory
+func walkPackages(dir string, fn func(path string, header *rpmlib.Header)) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".rpm") {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		pkg, err := rpmlib.OpenPackageFile(file)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+
+		fn(path, pkg.Header)
+
+		return nil
+	})
+}
+
+// readHeaders reads headers of all packages under the directory
+func readHeaders(dir string) (headers []*rpmlib.Header, err error) {
+	err = walkPackages(dir, func(path string, header *rpmlib.Header) {
+		headers = append(headers, header)
+	})
+
+	return
+}
+
+// RepoClosure reports dependencies which cannot be resolved in the directory,
+// and returns 1 as exit status if any.
+func RepoClosure(args []string) int {
+	flags := flag.NewFlagSet("repoclosure", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm repoclosure <directory>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	// Repository metadata are used if any, instead of reading all packages
+	var headers []*rpmlib.Header
+	var err error
+	if isRepository(flags.Arg(0)) {
+		headers, err = readRepoHeaders(flags.Arg(0))
+	} else {
+		headers, err = readHeaders(flags.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	unresolved, err := rpmlib.CheckClosure(headers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	var last *rpmlib.Header
+	for _, u := range unresolved {
+		if u.Header != last {
+			fmt.Printf("package: %s\n", u.Header.NEVRA())
+			fmt.Printf("  unresolved deps:\n")
+			last = u.Header
+		}
+		if u.Err != nil {
+			fmt.Printf("    %s (%s)\n", u.Require, u.Err)
+		} else {
+			fmt.Printf("    %s\n", u.Require)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmdb"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path/filepath"
+)
+
+// readInstalled reads headers of all installed packages in the database
+func readInstalled(dbpath string) (packages []*rpmdb.Package, err error) {
+	db, err := rpmdb.Open(dbpath)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	return db.Packages()
+}
+
+// QueryInstalled shows all installed packages, same as rpm -qa
+func QueryInstalled(dbpath string, option *Option, qf *rpmlib.QueryFormat) (infos []*rpmlib.PackageInfo, err error) {
+	packages, err := readInstalled(dbpath)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range packages {
+		info, query_err := queryHeader(pkg.Header, option, qf)
+		if query_err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", nvra(pkg.Header), query_err)
+		} else if info != nil {
+			infos = append(infos, info)
+		}
+	}
+
+	return
+}
+
+// Queries of installed packages by arguments, with the message when nothing is found
+const (
+	QueryByName     = "name"
+	QueryByFile     = "file"
+	QueryByProvides = "whatprovides"
+	QueryByRequires = "whatrequires"
+)
+
+var notFoundMessages = map[string]string{
+	QueryByName:     "package %s is not installed",
+	QueryByFile:     "file %s is not owned by any package",
+	QueryByProvides: "no package provides %s",
+	QueryByRequires: "no package requires %s",
+}
+
+// QueryInstalledBy shows installed packages found by each argument, same as rpm -q,
+// -qf, --whatprovides and --whatrequires. missing is the number of arguments which
+// found no package.
+func QueryInstalledBy(dbpath string, by string, args []string, option *Option, qf *rpmlib.QueryFormat) (infos []*rpmlib.PackageInfo, missing int, err error) {
+	packages, err := readInstalled(dbpath)
+	if err != nil {
+		return
+	}
+	index := rpmdb.NewIndex(packages)
+
+	for _, arg := range args {
+		var found []*rpmdb.Package
+		var find_err error
+		switch by {
+		case QueryByName:
+			found = index.ByName(arg)
+		case QueryByFile:
+			if !filepath.IsAbs(arg) {
+				arg, find_err = filepath.Abs(arg)
+			}
+			found = index.WhatOwns(arg)
+		case QueryByProvides:
+			found, find_err = index.WhatProvides(arg)
+		case QueryByRequires:
+			found, find_err = index.WhatRequires(arg)
+		}
+
+		if find_err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", find_err)
+			missing++
+			continue
+		}
+		if len(found) == 0 {
+			fmt.Fprintf(os.Stderr, notFoundMessages[by]+"\n", arg)
+			missing++
+			continue
+		}
+
+		for _, pkg := range found {
+			info, query_err := queryHeader(pkg.Header, option, qf)
+			if query_err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", nvra(pkg.Header), query_err)
+			} else if info != nil {
+				infos = append(infos, info)
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/repodata"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"github.com/pombredanne/gorpm-1/solver"
+	"github.com/pombredanne/gorpm-1/specfile"
+	"os"
+	"path/filepath"
+)
+
+// Solve prints packages in the directory to install for requests,
+// such as "gorpm solve <dir> bash 'libfoo >= 2.0'", and returns 1 as exit status if impossible.
+func Solve(args []string) int {
+	arch := specfile.HostArch()
+	locations := false
+
+	flags := flag.NewFlagSet("solve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm solve [options] <directory> <request>...\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&arch, "arch", arch, "Architecture of the machine to install, or empty to allow any architecture.")
+	flags.BoolVar(&locations, "l", false, "Print paths to package files instead of NEVRA.")
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 1
+	}
+
+	// Repository metadata are used if any, same as repoclosure
+	dir := flags.Arg(0)
+	pool := solver.NewPool(arch)
+	var err error
+	if isRepository(dir) {
+		var packages []*repodata.Package
+		packages, err = repodata.ReadRepo(dir, repodata.DefaultReadOptions)
+		for _, pkg := range packages {
+			if _, err = pool.Add(pkg.Header(), filepath.Join(dir, pkg.Location)); err != nil {
+				break
+			}
+		}
+	} else {
+		err = walkPackages(dir, func(path string, header *rpmlib.Header) {
+			if _, add_err := pool.Add(header, path); add_err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, add_err)
+			}
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	install, err := pool.Solve(flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	for _, pkg := range install {
+		if locations {
+			fmt.Println(pkg.Location)
+		} else {
+			fmt.Println(pkg)
+		}
+	}
+
+	return 0
+}
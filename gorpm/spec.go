@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"github.com/pombredanne/gorpm-1/specfile"
+	"os"
+	"strings"
+)
+
+// readSpec parses a spec file, or the spec file in a source package
+func readSpec(filename string, macros *specfile.Macros) (spec *specfile.Spec, err error) {
+	if !strings.HasSuffix(filename, ".rpm") {
+		return specfile.ParseFile(filename, macros)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	name, data, err := pkg.ReadSpec()
+	if err != nil {
+		return
+	}
+
+	spec, err = specfile.Parse(bytes.NewReader(data), macros)
+	if err != nil {
+		err = fmt.Errorf("%s: %s: %s", filename, name, err)
+	}
+
+	return
+}
+
+// Spec queries packages of spec files, same as rpmspec
+func Spec(args []string) int {
+	var macroFlags specfile.MacroFlags
+	query, parse, srpm := false, false, false
+	queryFormat := ""
+	target := specfile.HostArch()
+
+	flags := flag.NewFlagSet("spec", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpm spec [options] <spec file or source package>...\n")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&query, "query", false, "Query binary packages which the spec file builds.")
+	flags.BoolVar(&query, "q", false, "Same as -query.")
+	flags.BoolVar(&srpm, "srpm", false, "Query the source package instead of binary packages.")
+	flags.BoolVar(&parse, "parse", false, "Print the spec file after conditionals and macros are processed.")
+	flags.StringVar(&queryFormat, "qf", "", "Query format of packages, such as \"%{NAME} %{SUMMARY}\\n\".")
+	flags.StringVar(&queryFormat, "queryformat", "", "Same as -qf.")
+	flags.StringVar(&target, "target", target, "Target architecture, such as \"x86_64\" or \"aarch64\".")
+	macroFlags.Register(flags)
+	flags.Parse(args)
+
+	if flags.NArg() == 0 || query == parse {
+		flags.Usage()
+		return 1
+	}
+
+	if queryFormat == "" {
+		queryFormat = "%{NAME}-%{VERSION}-%{RELEASE}.%{ARCH}\n"
+	}
+	qf, err := rpmlib.ParseQueryFormat(queryFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		// Each spec file has its own macros, since the spec defines macros
+		macros, err := macroFlags.Macros(target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+
+		spec, err := readSpec(filename, macros)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			status = 1
+			continue
+		}
+
+		if parse {
+			fmt.Println(strings.Join(spec.Expanded, "\n"))
+			continue
+		}
+
+		var headers []*rpmlib.Header
+		if srpm {
+			headers = append(headers, spec.SourceHeader())
+		} else {
+			// Packages without %files are not built, same as rpmbuild
+			for _, pkg := range spec.Packages {
+				if pkg.HasFiles {
+					headers = append(headers, spec.Header(pkg))
+				}
+			}
+		}
+
+		for _, header := range headers {
+			output, err := qf.Format(header)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+				status = 1
+				break
+			}
+			fmt.Print(output)
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+type installedVerifyResult struct {
+	entries []rpmlib.VerifyEntry
+	err     error
+}
+
+// fileAttrChar is the marker of a file in rpm -V output, such as "c" for %config
+func fileAttrChar(flags int32) string {
+	switch {
+	case flags&rpmlib.RPMFILE_CONFIG != 0:
+		return "c"
+	case flags&rpmlib.RPMFILE_DOC != 0:
+		return "d"
+	case flags&rpmlib.RPMFILE_GHOST != 0:
+		return "g"
+	case flags&rpmlib.RPMFILE_LICENSE != 0:
+		return "l"
+	case flags&rpmlib.RPMFILE_PUBKEY != 0:
+		return "P"
+	case flags&rpmlib.RPMFILE_README != 0:
+		return "r"
+	}
+
+	return " "
+}
+
+// VerifyInstalled verifies files of all installed packages under the root, same as rpm -Va.
+// Packages are verified concurrently, and results are shown grouped by package in the order
+// of the database. failed is true if any file failed.
+func VerifyInstalled(dbpath string, option *Option) (infos []*rpmlib.PackageInfo, failed bool, err error) {
+	packages, err := readInstalled(dbpath)
+	if err != nil {
+		return
+	}
+
+	options := rpmlib.VerifyFilesOptions{Root: option.Root, NoConfig: option.NoConfig}
+	if option.NetShared != "" {
+		options.NetSharedPaths = strings.Split(option.NetShared, ":")
+	}
+
+	results := make([]installedVerifyResult, len(packages))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				entries, err := packages[index].Header.VerifyFiles(options)
+				results[index] = installedVerifyResult{entries, err}
+			}
+		}()
+	}
+	for i := range packages {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i, pkg := range packages {
+		result := results[i]
+		if result.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", nvra(pkg.Header), result.err)
+			failed = true
+			continue
+		}
+		if len(result.entries) == 0 {
+			continue
+		}
+		failed = true
+
+		if option.Output != "text" {
+			info := rpmlib.NewPackageInfo(pkg.Header)
+			info.Verify = result.entries
+			infos = append(infos, info)
+			continue
+		}
+
+		fmt.Println(nvra(pkg.Header))
+		for _, entry := range result.entries {
+			if entry.Result == rpmlib.VerifyMissing {
+				fmt.Printf("missing   %s %s\n", fileAttrChar(entry.Flags), entry.Path)
+			} else {
+				fmt.Printf("%s  %s %s\n", entry.Result, fileAttrChar(entry.Flags), entry.Path)
+			}
+		}
+	}
+
+	return
+}
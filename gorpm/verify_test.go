@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+)
+
+func TestFileAttrChar(t *testing.T) {
+	tests := []struct {
+		flags int32
+		char  string
+	}{
+		{rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE, "c"},
+		{rpmlib.RPMFILE_DOC, "d"},
+		{rpmlib.RPMFILE_GHOST, "g"},
+		{rpmlib.RPMFILE_LICENSE, "l"},
+		{rpmlib.RPMFILE_README, "r"},
+		{0, " "},
+	}
+
+	for _, test := range tests {
+		if char := fileAttrChar(test.flags); char != test.char {
+			t.Errorf("fileAttrChar(%#x) = %q, want %q", test.flags, char, test.char)
+		}
+	}
+}
+
+// Packages are verified concurrently, and results are in the order of the database
+func TestVerifyInstalled(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		option := &Option{Root: t.TempDir(), Output: "json"}
+		infos, failed, err := VerifyInstalled("../rpmdb/testdata/sqlite", option)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name)
+			for _, entry := range info.Verify {
+				if entry.Result != rpmlib.VerifyMissing {
+					t.Errorf("%s: %s is %s, want missing", info.Name, entry.Path, entry.Result)
+				}
+			}
+		}
+		if !failed || strings.Join(names, " ") != "foo bar" {
+			t.Fatalf("VerifyInstalled() = %v, %v, want foo and bar", names, failed)
+		}
+	}
+}
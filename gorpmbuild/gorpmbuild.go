@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/specfile"
+	"os"
+	"strconv"
+	"time"
+)
+
+// buildTime is SOURCE_DATE_EPOCH for reproducible builds, or the current time
+func buildTime() (t time.Time, err error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return t, fmt.Errorf("Invalid SOURCE_DATE_EPOCH %s", epoch)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+func main() {
+	var macroFlags specfile.MacroFlags
+	var options specfile.BuildOptions
+	specFile := ""
+	target := specfile.HostArch()
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gorpmbuild -spec <spec file> -buildroot <directory> [options]\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&specFile, "spec", "", "The spec file of packages.")
+	flag.StringVar(&options.BuildRoot, "buildroot", "",
+		"The directory where files are already installed, same as %{buildroot}. %build and %install are not executed.")
+	flag.StringVar(&options.BuildDir, "builddir", ".",
+		"The directory of relative %doc and %license files, and of file lists of %files -f.")
+	flag.StringVar(&options.OutputDir, "outdir", ".", "The directory where packages are written.")
+	flag.StringVar(&target, "target", target, "Target architecture, such as \"x86_64\" or \"aarch64\".")
+	macroFlags.Register(flag.CommandLine)
+	flag.Parse()
+
+	if specFile == "" || options.BuildRoot == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if info, err := os.Stat(options.BuildRoot); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Build root %s is not a directory\n", options.BuildRoot)
+		os.Exit(1)
+	}
+
+	var err error
+	options.BuildTime, err = buildTime()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	options.BuildHost, _ = os.Hostname()
+
+	macros, err := macroFlags.Macros(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	macros.Define("buildroot", options.BuildRoot)
+
+	spec, err := specfile.ParseFile(specFile, macros)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	filenames, err := spec.Build(options)
+	for _, filename := range filenames {
+		fmt.Printf("Wrote: %s\n", filename)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
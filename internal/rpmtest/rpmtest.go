@@ -0,0 +1,509 @@
+package rpmtest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//
+// Package files written for tests of packages which read them.
+// Files are written here, not by the writer of rpmlib, so that readers are
+// tested against packages laid out same as rpmbuild.
+//
+
+type File struct {
+	Path string
+	// Raw st_mode, such as rpmlib.S_IFREG | 0644
+	Mode   uint16
+	Data   string
+	LinkTo string
+	// RPMFILE_* flags
+	Flags int32
+	// Hard linked files have the same inode, of which data is stored only in the last one
+	// same as rpmbuild. Files of inode 0 are not linked.
+	Inode int32
+	Time  int32
+}
+
+// Regular returns a regular file of mode 0644
+func Regular(name string, data string) File {
+	return File{Path: name, Mode: rpmlib.S_IFREG | 0644, Data: data}
+}
+
+// Dir returns a directory of mode 0755
+func Dir(name string) File {
+	return File{Path: name, Mode: rpmlib.S_IFDIR | 0755}
+}
+
+// Symlink returns a symbolic link to the target
+func Symlink(name string, target string) File {
+	return File{Path: name, Mode: rpmlib.S_IFLNK | 0777, LinkTo: target}
+}
+
+type Package struct {
+	Name    string
+	Version string
+	Release string
+	Arch    string
+	// Packages without the source package are source packages
+	SourceRPM string
+	Files     []File
+	// Tags added to the header, after tags of the package
+	Tags func(b *Builder)
+}
+
+// Binary returns a binary package of the files
+func Binary(name string, version string, release string, files ...File) Package {
+	return Package{
+		Name: name, Version: version, Release: release, Arch: "noarch",
+		SourceRPM: name + "-" + version + "-" + release + ".src.rpm",
+		Files:     files,
+	}
+}
+
+// Tags written by Write
+const (
+	tagHeaderSignatures  = 62
+	tagHeaderImmutable   = 63
+	tagHeaderI18nTable   = 100
+	tagName              = 1000
+	tagVersion           = 1001
+	tagRelease           = 1002
+	tagSummary           = 1004
+	tagDescription       = 1005
+	tagBuildTime         = 1006
+	tagSize              = 1009
+	tagLicense           = 1014
+	tagGroup             = 1016
+	tagOS                = 1021
+	tagArch              = 1022
+	tagFileSizes         = 1028
+	tagFileModes         = 1030
+	tagFileRDevs         = 1033
+	tagFileMTimes        = 1034
+	tagFileDigests       = 1035
+	tagFileLinkTos       = 1036
+	tagFileFlags         = 1037
+	tagFileUserName      = 1039
+	tagFileGroupName     = 1040
+	tagSourceRPM         = 1044
+	tagFileVerifyFlags   = 1045
+	tagFileDevices       = 1095
+	tagFileInodes        = 1096
+	tagFileLangs         = 1097
+	tagDirIndexes        = 1116
+	tagBaseNames         = 1117
+	tagDirNames          = 1118
+	tagPayloadFormat     = 1124
+	tagPayloadCompressor = 1125
+	tagPayloadFlags      = 1126
+	tagFileDigestAlgo    = 5011
+	tagPayloadDigest     = 5092
+	tagPayloadDigestAlgo = 5093
+	tagPayloadDigestAlt  = 5097
+
+	sigTagSHA1        = 269
+	sigTagSHA256      = 273
+	sigTagSize        = 1000
+	sigTagMD5         = 1004
+	sigTagPayloadSize = 1007
+
+	// PGPHASHALGO_SHA256
+	digestAlgoSHA256 = 8
+)
+
+// Data types of tags
+const (
+	typeInt16       = 3
+	typeInt32       = 4
+	typeInt64       = 5
+	typeString      = 6
+	typeBinary      = 7
+	typeStringArray = 8
+	typeI18nString  = 9
+)
+
+var typeAlignments = map[int32]int{typeInt16: 2, typeInt32: 4, typeInt64: 8}
+
+type entry struct {
+	tag      int32
+	datatype int32
+	count    int32
+	data     []byte
+}
+
+// Builder builds a header or a signature section. Adding a tag again replaces the value.
+type Builder struct {
+	entries map[int32]entry
+}
+
+func newBuilder() *Builder {
+	return &Builder{entries: make(map[int32]entry)}
+}
+
+func (b *Builder) add(tag int32, datatype int32, count int, data []byte) {
+	b.entries[tag] = entry{tag, datatype, int32(count), data}
+}
+
+func (b *Builder) AddString(tag int32, value string) {
+	b.add(tag, typeString, 1, append([]byte(value), 0))
+}
+
+func (b *Builder) AddI18nString(tag int32, value string) {
+	b.add(tag, typeI18nString, 1, append([]byte(value), 0))
+}
+
+// AddStringArray adds values, nothing is added for no values
+func (b *Builder) AddStringArray(tag int32, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	var data []byte
+	for _, value := range values {
+		data = append(data, value...)
+		data = append(data, 0)
+	}
+
+	b.add(tag, typeStringArray, len(values), data)
+}
+
+func (b *Builder) AddBinary(tag int32, value []byte) {
+	b.add(tag, typeBinary, len(value), value)
+}
+
+func (b *Builder) AddInt16(tag int32, values ...int16) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 2*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(value))
+	}
+
+	b.add(tag, typeInt16, len(values), data)
+}
+
+func (b *Builder) AddInt32(tag int32, values ...int32) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(value))
+	}
+
+	b.add(tag, typeInt32, len(values), data)
+}
+
+func (b *Builder) AddInt64(tag int32, values ...int64) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint64(data[8*i:], uint64(value))
+	}
+
+	b.add(tag, typeInt64, len(values), data)
+}
+
+// bytes returns the section with the region tag, which covers all tags same as rpmbuild.
+// Indexes are sorted by tag after the region, and data are aligned by their types.
+func (b *Builder) bytes(regionTag int32) []byte {
+	var entries []entry
+	for tag, entry := range b.entries {
+		if tag != regionTag {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].tag < entries[j].tag
+	})
+
+	var indexes, store bytes.Buffer
+	writeIndex := func(tag int32, datatype int32, offset int32, count int32) {
+		binary.Write(&indexes, binary.BigEndian, []int32{tag, datatype, offset, count})
+	}
+
+	for _, entry := range entries {
+		if align, found := typeAlignments[entry.datatype]; found {
+			for store.Len()%align != 0 {
+				store.WriteByte(0)
+			}
+		}
+		writeIndex(entry.tag, entry.datatype, int32(store.Len()), entry.count)
+		store.Write(entry.data)
+	}
+
+	// The region is the first index, of which data at the end of the store
+	// is the trailer pointing back to the start of indexes
+	nindex := int32(len(entries)) + 1
+	var region bytes.Buffer
+	binary.Write(&region, binary.BigEndian, []int32{regionTag, typeBinary, int32(store.Len()), 16})
+	binary.Write(&store, binary.BigEndian, []int32{regionTag, typeBinary, -nindex * 16, 16})
+
+	var section bytes.Buffer
+	section.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+	binary.Write(&section, binary.BigEndian, []int32{nindex, int32(store.Len())})
+	section.Write(region.Bytes())
+	section.Write(indexes.Bytes())
+	section.Write(store.Bytes())
+
+	return section.Bytes()
+}
+
+// addFiles adds file lists sorted by paths, same as rpmbuild
+func (b *Builder) addFiles(files []File) {
+	// Sizes of hard links are the size of the data, which is stored once
+	var basenames, dirnames, digests, linktos, users, langs []string
+	var dirindexes, sizes, mtimes, flags, verifies, devices, inodes []int32
+	var modes, rdevs []int16
+	var size int32
+	dirs := make(map[string]int32)
+
+	for i, f := range files {
+		dir, base := path.Split(f.Path)
+		index, found := dirs[dir]
+		if !found {
+			index = int32(len(dirnames))
+			dirs[dir] = index
+			dirnames = append(dirnames, dir)
+		}
+
+		var fileSize int32
+		digest := ""
+		switch f.Mode & rpmlib.S_IFMT {
+		case rpmlib.S_IFREG:
+			fileSize = int32(len(f.Data))
+			sum := sha256.Sum256([]byte(f.Data))
+			digest = hex.EncodeToString(sum[:])
+			size += fileSize
+		case rpmlib.S_IFLNK:
+			fileSize = int32(len(f.LinkTo))
+		case rpmlib.S_IFDIR:
+			fileSize = 4096
+		}
+
+		basenames = append(basenames, base)
+		dirindexes = append(dirindexes, index)
+		sizes = append(sizes, fileSize)
+		modes = append(modes, int16(f.Mode))
+		rdevs = append(rdevs, 0)
+		mtimes = append(mtimes, f.Time)
+		digests = append(digests, digest)
+		linktos = append(linktos, f.LinkTo)
+		flags = append(flags, f.Flags)
+		users = append(users, "root")
+		verifies = append(verifies, -1)
+		devices = append(devices, 1)
+		inodes = append(inodes, int32(i+1))
+		langs = append(langs, "")
+	}
+
+	if len(files) > 0 {
+		b.AddStringArray(tagBaseNames, basenames)
+		b.AddStringArray(tagDirNames, dirnames)
+		b.AddInt32(tagDirIndexes, dirindexes...)
+		b.AddInt32(tagFileSizes, sizes...)
+		b.AddInt16(tagFileModes, modes...)
+		b.AddInt16(tagFileRDevs, rdevs...)
+		b.AddInt32(tagFileMTimes, mtimes...)
+		b.AddStringArray(tagFileDigests, digests)
+		b.AddStringArray(tagFileLinkTos, linktos)
+		b.AddInt32(tagFileFlags, flags...)
+		b.AddStringArray(tagFileUserName, users)
+		b.AddStringArray(tagFileGroupName, users)
+		b.AddInt32(tagFileVerifyFlags, verifies...)
+		b.AddInt32(tagFileDevices, devices...)
+		b.AddInt32(tagFileInodes, inodes...)
+		b.AddStringArray(tagFileLangs, langs)
+		b.AddInt32(tagFileDigestAlgo, digestAlgoSHA256)
+	}
+	b.AddInt32(tagSize, size)
+}
+
+// payload returns the cpio archive of the files in the new ASCII format
+func payload(files []File) []byte {
+	var archive bytes.Buffer
+	writeEntry := func(ino int32, mode uint16, nlink int, mtime int32, name string, data string) {
+		fmt.Fprintf(&archive, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%s\x00",
+			ino, mode, 0, 0, nlink, uint32(mtime), len(data), 0, 0, 0, 0, len(name)+1, 0, name)
+		for archive.Len()%4 != 0 {
+			archive.WriteByte(0)
+		}
+		archive.WriteString(data)
+		for archive.Len()%4 != 0 {
+			archive.WriteByte(0)
+		}
+	}
+
+	count := make(map[int32]int)
+	for _, f := range files {
+		if f.Inode != 0 {
+			count[f.Inode]++
+		}
+	}
+
+	written := make(map[int32]int)
+	for i, f := range files {
+		ino, nlink := int32(i+1), 1
+		data := f.Data
+		switch f.Mode & rpmlib.S_IFMT {
+		case rpmlib.S_IFLNK:
+			data = f.LinkTo
+		case rpmlib.S_IFDIR:
+			nlink = 2
+		}
+		if f.Inode != 0 {
+			ino, nlink = f.Inode, count[f.Inode]
+			written[f.Inode]++
+			if written[f.Inode] < count[f.Inode] {
+				data = ""
+			}
+		}
+
+		// Files of source packages have relative paths, which are stored as they are
+		name := f.Path
+		if strings.HasPrefix(name, "/") {
+			name = "." + name
+		}
+		writeEntry(ino, f.Mode, nlink, f.Time, name, data)
+	}
+	writeEntry(0, 0, 1, 0, "TRAILER!!!", "")
+
+	return archive.Bytes()
+}
+
+// lead returns the lead, which is only checked by rpm
+func lead(pkg Package) []byte {
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	if pkg.SourceRPM == "" {
+		lead[7] = 1
+	}
+
+	// The name is truncated with the terminating NUL
+	name := pkg.Name + "-" + pkg.Version + "-" + pkg.Release
+	if len(name) >= 66 {
+		name = name[:65]
+	}
+	copy(lead[10:], name)
+
+	// Linux and the signature in a header
+	binary.BigEndian.PutUint16(lead[76:], 1)
+	binary.BigEndian.PutUint16(lead[78:], 5)
+
+	return lead
+}
+
+// Write writes the package in the directory, and returns the path
+func Write(t testing.TB, dir string, pkg Package) string {
+	t.Helper()
+
+	b := newBuilder()
+	b.AddStringArray(tagHeaderI18nTable, []string{"C"})
+	b.AddString(tagName, pkg.Name)
+	b.AddString(tagVersion, pkg.Version)
+	b.AddString(tagRelease, pkg.Release)
+	b.AddString(tagArch, pkg.Arch)
+	b.AddString(tagOS, "linux")
+	b.AddI18nString(tagSummary, "Summary of "+pkg.Name)
+	b.AddI18nString(tagDescription, "Description of "+pkg.Name)
+	b.AddString(tagLicense, "MIT")
+	b.AddI18nString(tagGroup, "Unspecified")
+	b.AddInt32(tagBuildTime, 1600000000)
+	if pkg.SourceRPM != "" {
+		b.AddString(tagSourceRPM, pkg.SourceRPM)
+	}
+	b.addFiles(pkg.Files)
+
+	if pkg.Tags != nil {
+		pkg.Tags(b)
+	}
+
+	archive := payload(pkg.Files)
+	var compressed bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	gz.Write(archive)
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	compressedSum := sha256.Sum256(compressed.Bytes())
+	archiveSum := sha256.Sum256(archive)
+
+	b.AddString(tagPayloadFormat, "cpio")
+	b.AddString(tagPayloadCompressor, "gzip")
+	b.AddString(tagPayloadFlags, "9")
+	b.AddStringArray(tagPayloadDigest, []string{hex.EncodeToString(compressedSum[:])})
+	b.AddStringArray(tagPayloadDigestAlt, []string{hex.EncodeToString(archiveSum[:])})
+	b.AddInt32(tagPayloadDigestAlgo, digestAlgoSHA256)
+	header := b.bytes(tagHeaderImmutable)
+
+	// MD5 covers the header and the payload
+	digest := md5.New()
+	digest.Write(header)
+	digest.Write(compressed.Bytes())
+	sha1sum := sha1.Sum(header)
+	sha256sum := sha256.Sum256(header)
+
+	sig := newBuilder()
+	sig.AddString(sigTagSHA1, hex.EncodeToString(sha1sum[:]))
+	sig.AddString(sigTagSHA256, hex.EncodeToString(sha256sum[:]))
+	sig.AddInt32(sigTagSize, int32(len(header)+compressed.Len()))
+	sig.AddBinary(sigTagMD5, digest.Sum(nil))
+	sig.AddInt32(sigTagPayloadSize, int32(len(archive)))
+	signature := sig.bytes(tagHeaderSignatures)
+
+	// The header starts at 8 byte boundary
+	for len(signature)%8 != 0 {
+		signature = append(signature, 0)
+	}
+
+	arch := pkg.Arch
+	if pkg.SourceRPM == "" {
+		arch = "src"
+	}
+	filename := filepath.Join(dir, fmt.Sprintf("%s-%s-%s.%s.rpm", pkg.Name, pkg.Version, pkg.Release, arch))
+	data := bytes.Join([][]byte{lead(pkg), signature, header, compressed.Bytes()}, nil)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+// Open writes the package in a temporary directory and reads it.
+// The file is closed at the end of the test.
+func Open(t testing.TB, pkg Package) *rpmlib.PackageFile {
+	t.Helper()
+
+	file, err := os.Open(Write(t, t.TempDir(), pkg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	read, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return read
+}
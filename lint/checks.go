@@ -0,0 +1,287 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+)
+
+//
+// Built-in checks
+//
+
+// check is a check of a function
+type check struct {
+	name        string
+	description string
+	severity    string
+	run         func(pkg *Package) []Problem
+}
+
+func (c *check) Name() string {
+	return c.name
+}
+
+func (c *check) Description() string {
+	return c.description
+}
+
+func (c *check) Severity() string {
+	return c.severity
+}
+
+func (c *check) Run(pkg *Package) []Problem {
+	return c.run(pkg)
+}
+
+// Groups of rpm in /usr/share/doc/rpm/GROUPS
+var StandardGroups = []string{
+	"Amusements/Games", "Amusements/Graphics",
+	"Applications/Archiving", "Applications/Communications", "Applications/Databases",
+	"Applications/Editors", "Applications/Emulators", "Applications/Engineering", "Applications/File",
+	"Applications/Internet", "Applications/Multimedia", "Applications/Productivity",
+	"Applications/Publishing", "Applications/System", "Applications/Text",
+	"Development/Debug", "Development/Debuggers", "Development/Languages", "Development/Libraries",
+	"Development/System", "Development/Tools",
+	"Documentation",
+	"System Environment/Base", "System Environment/Daemons", "System Environment/Kernel",
+	"System Environment/Libraries", "System Environment/Shells",
+	"User Interface/Desktops", "User Interface/X", "User Interface/X Hardware Support",
+	"Unspecified",
+}
+
+// Directories of the Filesystem Hierarchy Standard, of which directories in /usr and /var are also checked
+var fhsDirectories = map[string][]string{
+	"":    {"bin", "boot", "dev", "etc", "home", "lib", "lib64", "media", "mnt", "opt", "proc", "root", "run", "sbin", "srv", "sys", "tmp", "usr", "var"},
+	"usr": {"bin", "games", "include", "lib", "lib64", "libexec", "local", "sbin", "share", "src"},
+	"var": {"account", "adm", "cache", "crash", "db", "empty", "games", "kerberos", "lib", "local", "lock", "log", "mail", "nis", "opt", "preserve", "run", "spool", "tmp", "yp"},
+}
+
+func init() {
+	for _, c := range []*check{
+		{"no-summary", "The summary is missing or empty.", SeverityError, checkSummary},
+		{"no-description", "The description is missing or empty.", SeverityError, checkDescription},
+		{"non-standard-group", "The group is not a standard group of rpm or of the filter.", SeverityWarning, checkGroup},
+		{"file-in-tmp", "Files are in /tmp or /var/tmp, which are cleaned up.", SeverityError, checkTmp},
+		{"world-writable", "Files are writable by all users.", SeverityError, checkWorldWritable},
+		{"setuid-binary", "Files are setuid or setgid.", SeverityWarning, checkSetuid},
+		{"non-fhs-path", "Files are in directories which are not in the FHS or the filter.", SeverityWarning, checkFHS},
+		{"config-outside-etc", "%config files are not in /etc.", SeverityWarning, checkConfig},
+		{"no-license-file", "The package has no %license files.", SeverityWarning, checkLicense},
+		{"filename-mismatch", "The file name is not name-version-release.arch.rpm of the header.", SeverityWarning, checkFilename},
+		{"future-build-date", "The build date is in the future.", SeverityWarning, checkBuildDate},
+		{"changelog-order", "Changelog entries are not in order from the newest.", SeverityWarning, checkChangelog},
+	} {
+		Register(c)
+	}
+}
+
+func isEmpty(s string) bool {
+	return strings.TrimSpace(strings.Trim(s, "\x00")) == ""
+}
+
+func checkSummary(pkg *Package) []Problem {
+	if isEmpty(pkg.Header.Summary()) {
+		return []Problem{{Message: "Summary is missing or empty"}}
+	}
+
+	return nil
+}
+
+func checkDescription(pkg *Package) []Problem {
+	if isEmpty(pkg.Header.Description()) {
+		return []Problem{{Message: "Description is missing or empty"}}
+	}
+
+	return nil
+}
+
+func checkGroup(pkg *Package) []Problem {
+	group := strings.Trim(pkg.Header.Group(), "\x00")
+
+	for _, groups := range [][]string{StandardGroups, pkg.Filter.Groups} {
+		for _, standard := range groups {
+			if group == standard {
+				return nil
+			}
+		}
+	}
+
+	if group == "" {
+		return []Problem{{Message: "Group is missing"}}
+	}
+
+	return []Problem{{Message: fmt.Sprintf("Group %q is not standard", group)}}
+}
+
+// fileProblems returns problems of files for which the function returns a message
+func fileProblems(pkg *Package, message func(file rpmlib.FileMeta) string) (problems []Problem) {
+	for _, file := range pkg.Files {
+		if text := message(file); text != "" {
+			problems = append(problems, Problem{Path: file.Path, Message: text})
+		}
+	}
+
+	return
+}
+
+func hasPrefix(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+func checkTmp(pkg *Package) []Problem {
+	return fileProblems(pkg, func(file rpmlib.FileMeta) string {
+		for _, tmp := range []string{"/tmp", "/var/tmp"} {
+			if hasPrefix(file.Path, tmp) && file.Path != tmp {
+				return "is in " + tmp
+			}
+		}
+		return ""
+	})
+}
+
+func checkWorldWritable(pkg *Package) []Problem {
+	return fileProblems(pkg, func(file rpmlib.FileMeta) string {
+		mode := uint16(file.Mode)
+
+		// Symbolic links are always writable, and sticky directories such as /tmp are safe
+		if mode&rpmlib.S_IFMT == rpmlib.S_IFLNK || mode&0002 == 0 ||
+			(mode&rpmlib.S_IFMT == rpmlib.S_IFDIR && mode&rpmlib.S_ISVTX != 0) {
+			return ""
+		}
+		return fmt.Sprintf("is world-writable, mode %s", rpmlib.PermsString(uint64(mode)))
+	})
+}
+
+func checkSetuid(pkg *Package) []Problem {
+	return fileProblems(pkg, func(file rpmlib.FileMeta) string {
+		mode := uint16(file.Mode)
+		if mode&rpmlib.S_IFMT != rpmlib.S_IFREG {
+			return ""
+		}
+
+		switch {
+		case mode&rpmlib.S_ISUID != 0:
+			return fmt.Sprintf("is setuid %s", file.User)
+		case mode&rpmlib.S_ISGID != 0:
+			return fmt.Sprintf("is setgid %s", file.Group)
+		}
+		return ""
+	})
+}
+
+// nonFHSDirectory returns the first directory of the path which is not in the FHS, or an empty string
+func nonFHSDirectory(path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	parent := ""
+	for i, part := range parts {
+		// Files are checked only in directories
+		if i == len(parts)-1 && i > 0 {
+			break
+		}
+
+		dirs, found := fhsDirectories[parent]
+		if !found {
+			break
+		}
+		standard := false
+		for _, dir := range dirs {
+			standard = standard || dir == part
+		}
+		if !standard {
+			return "/" + strings.Join(parts[:i+1], "/")
+		}
+		parent = part
+	}
+
+	return ""
+}
+
+func checkFHS(pkg *Package) (problems []Problem) {
+	// Files of source packages are not installed
+	if pkg.Header.IsSource() {
+		return
+	}
+
+	reported := make(map[string]bool)
+
+	for _, file := range pkg.Files {
+		dir := nonFHSDirectory(file.Path)
+		if dir == "" || reported[dir] {
+			continue
+		}
+
+		allowed := false
+		for _, path := range pkg.Filter.Paths {
+			allowed = allowed || hasPrefix(file.Path, path)
+		}
+		if allowed {
+			continue
+		}
+
+		reported[dir] = true
+		problems = append(problems, Problem{Path: dir, Message: "is not in the FHS"})
+	}
+
+	return
+}
+
+func checkConfig(pkg *Package) []Problem {
+	return fileProblems(pkg, func(file rpmlib.FileMeta) string {
+		// Ghost files such as logs are not shipped
+		if file.Flag&rpmlib.RPMFILE_CONFIG == 0 || file.Flag&rpmlib.RPMFILE_GHOST != 0 || hasPrefix(file.Path, "/etc") {
+			return ""
+		}
+		return "is %config but not in /etc"
+	})
+}
+
+func checkLicense(pkg *Package) []Problem {
+	// Source packages have no %license
+	if pkg.Header.IsSource() || len(pkg.Files) == 0 {
+		return nil
+	}
+
+	for _, file := range pkg.Files {
+		if file.Flag&rpmlib.RPMFILE_LICENSE != 0 {
+			return nil
+		}
+	}
+
+	return []Problem{{Message: "Package has no %license files"}}
+}
+
+func checkFilename(pkg *Package) []Problem {
+	expected := pkg.Header.NEVRA().Filename()
+	if pkg.Filename == "" || pkg.Filename == expected {
+		return nil
+	}
+
+	return []Problem{{Message: fmt.Sprintf("File name %s is not %s", pkg.Filename, expected)}}
+}
+
+func checkBuildDate(pkg *Package) []Problem {
+	date, err := pkg.Header.BuildDate()
+	if err != nil || !date.After(pkg.Now) {
+		return nil
+	}
+
+	return []Problem{{Message: fmt.Sprintf("Build date %s is in the future", date.UTC().Format("2006-01-02 15:04:05 UTC"))}}
+}
+
+func checkChangelog(pkg *Package) (problems []Problem) {
+	logs, err := pkg.Header.Changelog()
+	if err != nil {
+		return
+	}
+
+	for i := 1; i < len(logs); i++ {
+		if logs[i].Date.After(logs[i-1].Date) {
+			problems = append(problems, Problem{Message: fmt.Sprintf("Entry of %s by %s is newer than the previous entry of %s",
+				logs[i].Date.UTC().Format("2006-01-02"), logs[i].Name, logs[i-1].Date.UTC().Format("2006-01-02"))})
+		}
+	}
+
+	return
+}
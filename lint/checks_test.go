@@ -0,0 +1,65 @@
+package lint
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"testing"
+)
+
+func TestNonFHSDirectory(t *testing.T) {
+	tests := []struct {
+		path string
+		dir  string
+	}{
+		{"/usr/bin/foo", ""},
+		{"/usr/share/foo/data", ""},
+		{"/etc/foo.conf", ""},
+		{"/var/lib/foo", ""},
+		{"/opt/foo/bin/foo", ""},
+		{"/usr", ""},
+		// Files in / are checked as directories
+		{"/foo", "/foo"},
+		{"/foo/bar", "/foo"},
+		{"/usr/foo/bar", "/usr/foo"},
+		{"/var/foo/bar", "/var/foo"},
+		// Files in /usr and /var are not directories
+		{"/usr/foo", ""},
+		{"/var/foo", ""},
+	}
+
+	for _, test := range tests {
+		if dir := nonFHSDirectory(test.path); dir != test.dir {
+			t.Errorf("nonFHSDirectory(%s) = %q, want %q", test.path, dir, test.dir)
+		}
+	}
+}
+
+func TestCheckWorldWritable(t *testing.T) {
+	tests := []struct {
+		mode     uint16
+		problems int
+	}{
+		{rpmlib.S_IFREG | 0644, 0},
+		{rpmlib.S_IFREG | 0666, 1},
+		{rpmlib.S_IFREG | 0777, 1},
+		{rpmlib.S_IFDIR | 0777, 1},
+		// Sticky directories such as /tmp are safe
+		{rpmlib.S_IFDIR | rpmlib.S_ISVTX | 0777, 0},
+		// Sticky files are not
+		{rpmlib.S_IFREG | rpmlib.S_ISVTX | 0777, 1},
+		// Symbolic links always have mode 0777
+		{rpmlib.S_IFLNK | 0777, 0},
+	}
+
+	for _, test := range tests {
+		pkg := &Package{Files: []rpmlib.FileMeta{{Path: "/usr/share/foo", Mode: int16(test.mode)}}}
+		problems := checkWorldWritable(pkg)
+		if len(problems) != test.problems {
+			t.Errorf("Mode %o: %v", test.mode, problems)
+		}
+		for _, problem := range problems {
+			if problem.Path != "/usr/share/foo" {
+				t.Errorf("Mode %o: path %s", test.mode, problem.Path)
+			}
+		}
+	}
+}
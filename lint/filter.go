@@ -0,0 +1,172 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//
+// Filters of checks in TOML or YAML, such as
+//
+//   disable = ["setuid-binary"]
+//   groups = ["Vendor/Tools"]
+//   [severity]
+//   non-fhs-path = "error"
+//   [[ignore]]
+//   check = "file-in-tmp"
+//   package = "foo-*"
+//   path = "/tmp/foo/*"
+//
+
+type Filter struct {
+	// Names of checks not to run
+	Disable []string `toml:"disable" yaml:"disable"`
+	// Severities of checks instead of their own, "error", "warning" or "info"
+	Severity map[string]string `toml:"severity" yaml:"severity"`
+	// Groups which are standard in addition to groups of rpm
+	Groups []string `toml:"groups" yaml:"groups"`
+	// Paths which are allowed in addition to the FHS, such as "/srv/app"
+	Paths  []string `toml:"paths" yaml:"paths"`
+	Ignore []Ignore `toml:"ignore" yaml:"ignore"`
+}
+
+// Ignore matches problems to ignore. Empty fields match anything, and Package and Path are
+// patterns of path.Match, of which Package matches the name of the package and
+// Path matches the file or a directory of it.
+type Ignore struct {
+	Check   string `toml:"check" yaml:"check"`
+	Package string `toml:"package" yaml:"package"`
+	Path    string `toml:"path" yaml:"path"`
+	// Part of messages
+	Message string `toml:"message" yaml:"message"`
+}
+
+// LoadFilter reads a filter, which is TOML if the file name ends with ".toml" and YAML otherwise
+func LoadFilter(filename string) (filter *Filter, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	filter = new(Filter)
+	if strings.HasSuffix(filename, ".toml") {
+		var meta toml.MetaData
+		meta, err = toml.Decode(string(data), filter)
+		if err == nil && len(meta.Undecoded()) > 0 {
+			err = fmt.Errorf("Unknown key %s", meta.Undecoded()[0])
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(filter)
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err == nil {
+		err = filter.validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filepath.Base(filename), err)
+	}
+
+	return
+}
+
+func (filter *Filter) validate() (err error) {
+	known := func(name string) error {
+		if _, found := registry[name]; !found {
+			return fmt.Errorf("Unknown check %s", name)
+		}
+		return nil
+	}
+
+	for _, name := range filter.Disable {
+		if err = known(name); err != nil {
+			return
+		}
+	}
+	for name, severity := range filter.Severity {
+		if err = known(name); err != nil {
+			return
+		}
+		if severity != SeverityError && severity != SeverityWarning && severity != SeverityInfo {
+			return fmt.Errorf("Invalid severity %s of %s", severity, name)
+		}
+	}
+	for _, ignore := range filter.Ignore {
+		if ignore.Check != "" {
+			if err = known(ignore.Check); err != nil {
+				return
+			}
+		}
+		for _, pattern := range []string{ignore.Package, ignore.Path} {
+			if _, match_err := path.Match(pattern, ""); match_err != nil {
+				return fmt.Errorf("Invalid pattern %s", pattern)
+			}
+		}
+	}
+
+	return
+}
+
+// Enabled checks the check is not disabled
+func (filter *Filter) Enabled(name string) bool {
+	for _, disabled := range filter.Disable {
+		if disabled == name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SeverityOf returns the severity of problems of the check
+func (filter *Filter) SeverityOf(check Check) string {
+	if severity, found := filter.Severity[check.Name()]; found {
+		return severity
+	}
+
+	return check.Severity()
+}
+
+// Ignored checks the problem of the package is ignored
+func (filter *Filter) Ignored(problem Problem, name string) bool {
+	for _, ignore := range filter.Ignore {
+		if ignore.Check != "" && ignore.Check != problem.Check {
+			continue
+		}
+		if matched, _ := path.Match(ignore.Package, name); ignore.Package != "" && !matched {
+			continue
+		}
+		if ignore.Path != "" && !matchPath(ignore.Path, problem.Path) {
+			continue
+		}
+		if !strings.Contains(problem.Message, ignore.Message) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// matchPath checks the pattern matches the path or a directory of it
+func matchPath(pattern string, name string) bool {
+	for ; name != "/" && name != "." && name != ""; name = path.Dir(name) {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package lint
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const tomlFilter = `disable = ["setuid-binary"]
+groups = ["Vendor/Tools"]
+paths = ["/srv/app"]
+
+[severity]
+non-fhs-path = "error"
+
+[[ignore]]
+check = "file-in-tmp"
+package = "foo*"
+path = "/tmp/foo"
+`
+
+const yamlFilter = `disable: [setuid-binary]
+groups: [Vendor/Tools]
+paths: [/srv/app]
+severity:
+  non-fhs-path: error
+ignore:
+  - check: file-in-tmp
+    package: foo*
+    path: /tmp/foo
+`
+
+func writeFilter(t *testing.T, name string, text string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(filename, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+func TestLoadFilter(t *testing.T) {
+	expected := &Filter{
+		Disable:  []string{"setuid-binary"},
+		Severity: map[string]string{"non-fhs-path": SeverityError},
+		Groups:   []string{"Vendor/Tools"},
+		Paths:    []string{"/srv/app"},
+		Ignore:   []Ignore{{Check: "file-in-tmp", Package: "foo*", Path: "/tmp/foo"}},
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"filter.toml", tomlFilter},
+		{"filter.yaml", yamlFilter},
+	}
+
+	for _, test := range tests {
+		filter, err := LoadFilter(writeFilter(t, test.name, test.text))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(filter, expected) {
+			t.Errorf("%s: %+v", test.name, filter)
+		}
+	}
+
+	// An empty YAML file is an empty filter
+	filter, err := LoadFilter(writeFilter(t, "empty.yaml", ""))
+	if err != nil || !reflect.DeepEqual(filter, new(Filter)) {
+		t.Errorf("Empty filter: %+v, %v", filter, err)
+	}
+}
+
+func TestLoadFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"unknown-key.toml", "unknown = 1\n"},
+		{"unknown-key.yaml", "unknown: 1\n"},
+		{"unknown-check.toml", "disable = [\"no-such-check\"]\n"},
+		{"unknown-severity.yaml", "severity:\n  no-such-check: error\n"},
+		{"invalid-severity.yaml", "severity:\n  world-writable: fatal\n"},
+		{"unknown-ignore.yaml", "ignore:\n  - check: no-such-check\n"},
+		{"invalid-pattern.toml", "[[ignore]]\npath = \"/tmp/[\"\n"},
+		{"broken.toml", "disable = [\n"},
+	}
+
+	for _, test := range tests {
+		if _, err := LoadFilter(writeFilter(t, test.name, test.text)); err == nil {
+			t.Errorf("%s: LoadFilter() succeeded", test.name)
+		}
+	}
+
+	if _, err := LoadFilter(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Errorf("LoadFilter() of a missing file succeeded")
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	filter := &Filter{Ignore: []Ignore{
+		{Check: "file-in-tmp", Package: "foo*", Path: "/tmp/foo"},
+		{Message: "is setgid"},
+	}}
+
+	tests := []struct {
+		problem Problem
+		name    string
+		ignored bool
+	}{
+		{Problem{Check: "file-in-tmp", Path: "/tmp/foo"}, "foo", true},
+		// Path matches the file or a directory of it
+		{Problem{Check: "file-in-tmp", Path: "/tmp/foo/bar"}, "foo-devel", true},
+		{Problem{Check: "file-in-tmp", Path: "/tmp/bar"}, "foo", false},
+		{Problem{Check: "file-in-tmp", Path: "/tmp/foo"}, "bar", false},
+		{Problem{Check: "world-writable", Path: "/tmp/foo"}, "foo", false},
+		{Problem{Check: "setuid-binary", Path: "/usr/bin/bar", Message: "is setgid mail"}, "bar", true},
+		{Problem{Check: "setuid-binary", Path: "/usr/bin/bar", Message: "is setuid root"}, "bar", false},
+	}
+
+	for _, test := range tests {
+		if ignored := filter.Ignored(test.problem, test.name); ignored != test.ignored {
+			t.Errorf("Ignored(%+v, %s) = %v", test.problem, test.name, ignored)
+		}
+	}
+}
+
+func TestLinterFilter(t *testing.T) {
+	pkg := rpmtest.Package{
+		Name: "foo", Version: "1.0", Release: "1", Arch: "noarch", SourceRPM: "foo-1.0-1.src.rpm",
+		Files: []rpmtest.File{
+			{Path: "/vendor/app/foo", Mode: rpmlib.S_IFREG | 0644},
+			{Path: "/vendor/other/foo", Mode: rpmlib.S_IFREG | 0644},
+			{Path: "/tmp/foo", Mode: rpmlib.S_IFREG | 0644},
+			{Path: "/tmp/bar", Mode: rpmlib.S_IFREG | 0644},
+			{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | rpmlib.S_ISUID | 0755},
+			{Path: "/usr/share/licenses/foo/LICENSE", Mode: rpmlib.S_IFREG | 0644, Flags: rpmlib.RPMFILE_LICENSE},
+		},
+		Tags: func(b *rpmtest.Builder) {
+			b.AddI18nString(rpmlib.RPMTAG_GROUP, "Vendor/Tools")
+		},
+	}
+	filename := rpmtest.Write(t, t.TempDir(), pkg)
+
+	lintWith := func(filter *Filter) (problems []string) {
+		target, err := NewPackage(rpmtest.Open(t, pkg), filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		linter := NewLinter(filter)
+		linter.Now = time.Unix(1700000000, 0)
+		for _, problem := range linter.Lint(target) {
+			problems = append(problems, problem.Severity+" "+problem.Check+" "+problem.Path)
+		}
+		return
+	}
+
+	tests := []struct {
+		name     string
+		filter   *Filter
+		problems []string
+	}{
+		{"default", nil, []string{
+			"error file-in-tmp /tmp/foo",
+			"error file-in-tmp /tmp/bar",
+			// Directories are reported once
+			"warning non-fhs-path /vendor",
+			"warning non-standard-group ",
+			"warning setuid-binary /usr/bin/foo",
+		}},
+		{"filter", &Filter{
+			Disable:  []string{"setuid-binary"},
+			Severity: map[string]string{"non-fhs-path": SeverityError},
+			Groups:   []string{"Vendor/Tools"},
+			Paths:    []string{"/vendor/app"},
+			Ignore:   []Ignore{{Check: "file-in-tmp", Package: "foo*", Path: "/tmp/foo"}},
+		}, []string{
+			"error file-in-tmp /tmp/bar",
+			"error non-fhs-path /vendor",
+		}},
+	}
+
+	for _, test := range tests {
+		if problems := lintWith(test.filter); !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: %q", test.name, problems)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+//
+// Checks of packages, same as rpmlint
+//
+
+// Severities of problems
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Check finds problems of a package. Checks are registered by Register to be run by linters.
+type Check interface {
+	// Name is the identifier in filters and output, such as "world-writable"
+	Name() string
+	Description() string
+	// Severity of problems unless a filter changes it
+	Severity() string
+	// Run returns problems of the package, of which Check and Severity are set by the linter
+	Run(pkg *Package) []Problem
+}
+
+type Problem struct {
+	Package  string `json:"package" yaml:"package"`
+	Check    string `json:"check" yaml:"check"`
+	Severity string `json:"severity" yaml:"severity"`
+	// The file of the problem, empty for problems of the package
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (problem Problem) String() string {
+	letter := "W"
+	switch problem.Severity {
+	case SeverityError:
+		letter = "E"
+	case SeverityInfo:
+		letter = "I"
+	}
+
+	if problem.Path != "" {
+		return fmt.Sprintf("%s: %s: %s %s %s", problem.Package, letter, problem.Check, problem.Path, problem.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s %s", problem.Package, letter, problem.Check, problem.Message)
+}
+
+// Package is a package file to check with its files
+type Package struct {
+	*rpmlib.PackageFile
+	// Base name of the package file
+	Filename string
+	Files    []rpmlib.FileMeta
+	// Time to check build dates, the current time by default
+	Now    time.Time
+	Filter *Filter
+}
+
+func NewPackage(pkg *rpmlib.PackageFile, filename string) (p *Package, err error) {
+	p = &Package{PackageFile: pkg, Filename: filepath.Base(filename), Now: time.Now(), Filter: new(Filter)}
+
+	// Packages without files have no file lists
+	if pkg.Header.HasStore(rpmlib.RPMTAG_BASENAMES) || pkg.Header.HasStore(rpmlib.RPMTAG_OLDFILENAMES) {
+		p.Files, err = pkg.Header.Files()
+	}
+
+	return
+}
+
+var registry = make(map[string]Check)
+
+// Register adds a check which linters run. Names of checks shall be unique.
+func Register(check Check) {
+	if _, found := registry[check.Name()]; found {
+		panic(fmt.Sprintf("Check %s is already registered", check.Name()))
+	}
+
+	registry[check.Name()] = check
+}
+
+// Checks returns registered checks sorted by names
+func Checks() (checks []Check) {
+	for _, check := range registry {
+		checks = append(checks, check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name() < checks[j].Name() })
+
+	return
+}
+
+// Linter runs registered checks which the filter enables
+type Linter struct {
+	Filter *Filter
+	// Time to check build dates, the current time if zero
+	Now time.Time
+}
+
+func NewLinter(filter *Filter) *Linter {
+	if filter == nil {
+		filter = new(Filter)
+	}
+
+	return &Linter{Filter: filter}
+}
+
+// Lint returns problems of the package which the filter does not ignore
+func (linter *Linter) Lint(pkg *Package) (problems []Problem) {
+	pkg.Filter = linter.Filter
+	if !linter.Now.IsZero() {
+		pkg.Now = linter.Now
+	}
+
+	name := pkg.Header.NEVRA().String()
+	for _, check := range Checks() {
+		if !linter.Filter.Enabled(check.Name()) {
+			continue
+		}
+
+		for _, problem := range check.Run(pkg) {
+			problem.Package = name
+			problem.Check = check.Name()
+			problem.Severity = linter.Filter.SeverityOf(check)
+			if !linter.Filter.Ignored(problem, pkg.Header.Name()) {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	return
+}
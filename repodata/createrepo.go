@@ -0,0 +1,302 @@
+package repodata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	RepoDataDir = "repodata"
+	// Cache of package data in repodata directory, not listed in repomd.xml
+	CacheFile = "gorpm-cache.json.gz"
+)
+
+type Options struct {
+	// Number of latest changelogs in other.xml, or all if negative
+	ChangelogLimit int
+	// Reuse data of packages whose size and mtime are not changed since the last run
+	UseCache bool
+	// Write filelists-ext.xml, which has digests of files
+	FilelistsExt bool
+}
+
+var DefaultOptions = Options{
+	ChangelogLimit: 10,
+	UseCache:       true,
+	FilelistsExt:   true,
+}
+
+// RepoMDData is a data element of repomd.xml
+type RepoMDData struct {
+	Type         string
+	ChecksumType string
+	Checksum     string
+	OpenChecksum string
+	Location     string
+	Timestamp    int64
+	Size         int64
+	OpenSize     int64
+}
+
+type dataWriter struct {
+	datatype string
+	write    func(io.Writer, []*Package) error
+}
+
+type cacheEntry struct {
+	Size    int64
+	MTime   int64
+	Package *Package
+}
+
+// findPackages returns paths of packages in dir, relative to dir
+func findPackages(dir string) (locations []string, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if path != dir && (info.Name() == RepoDataDir || strings.HasPrefix(info.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".rpm") {
+			location, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			locations = append(locations, filepath.ToSlash(location))
+		}
+
+		return nil
+	})
+
+	sort.Strings(locations)
+
+	return
+}
+
+func readCache(path string) (cache map[string]cacheEntry, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return
+	}
+
+	err = json.NewDecoder(reader).Decode(&cache)
+
+	return
+}
+
+func writeCache(path string, cache map[string]cacheEntry) (err error) {
+	var buffer bytes.Buffer
+
+	writer := gzip.NewWriter(&buffer)
+	err = json.NewEncoder(writer).Encode(cache)
+	if err != nil {
+		return
+	}
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	return ioutil.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+func sha256String(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// writeData writes a gzipped metadata file into dir, named with its checksum as createrepo
+func writeData(dir string, datatype string, packages []*Package,
+	write func(io.Writer, []*Package) error) (data RepoMDData, err error) {
+
+	var open bytes.Buffer
+	err = write(&open, packages)
+	if err != nil {
+		return
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = writer.Write(open.Bytes())
+	if err != nil {
+		return
+	}
+	err = writer.Close()
+	if err != nil {
+		return
+	}
+
+	data.Type = datatype
+	data.ChecksumType = "sha256"
+	data.Checksum = sha256String(compressed.Bytes())
+	data.OpenChecksum = sha256String(open.Bytes())
+	data.Size = int64(compressed.Len())
+	data.OpenSize = int64(open.Len())
+	data.Timestamp = time.Now().Unix()
+
+	name := fmt.Sprintf("%s-%s.xml.gz", data.Checksum, strings.Replace(datatype, "_", "-", -1))
+	data.Location = RepoDataDir + "/" + name
+
+	err = ioutil.WriteFile(filepath.Join(dir, name), compressed.Bytes(), 0644)
+
+	return
+}
+
+func WriteRepoMD(w io.Writer, revision int64, datas []RepoMDData) (err error) {
+	x := newXMLWriter(w)
+
+	x.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	x.printf("<repomd xmlns=\"%s\" xmlns:rpm=\"%s\">\n", NamespaceRepo, NamespaceRpm)
+	x.printf("  <revision>%d</revision>\n", revision)
+
+	for _, data := range datas {
+		x.printf("  <data type=\"%s\">\n", data.Type)
+		x.printf("    <checksum type=\"%s\">%s</checksum>\n", data.ChecksumType, data.Checksum)
+		x.printf("    <open-checksum type=\"%s\">%s</open-checksum>\n", data.ChecksumType, data.OpenChecksum)
+		x.printf("    <location href=\"%s\"/>\n", data.Location)
+		x.printf("    <timestamp>%d</timestamp>\n", data.Timestamp)
+		x.printf("    <size>%d</size>\n", data.Size)
+		x.printf("    <open-size>%d</open-size>\n", data.OpenSize)
+		x.printf("  </data>\n")
+	}
+
+	x.printf("</repomd>\n")
+
+	return x.flush()
+}
+
+// CreateRepo writes repository metadata of packages in dir into dir/repodata.
+// The old repodata is replaced after all files are written.
+// reused is the number of packages read from the cache.
+func CreateRepo(dir string, options Options) (packages []*Package, reused int, err error) {
+	locations, err := findPackages(dir)
+	if err != nil {
+		return
+	}
+
+	repodata := filepath.Join(dir, RepoDataDir)
+
+	cache := make(map[string]cacheEntry)
+	if options.UseCache {
+		// The cache is just ignored if it is missing or broken
+		if old, err := readCache(filepath.Join(repodata, CacheFile)); err == nil {
+			cache = old
+		}
+	}
+
+	newCache := make(map[string]cacheEntry)
+	for _, location := range locations {
+		path := filepath.Join(dir, filepath.FromSlash(location))
+
+		var info os.FileInfo
+		info, err = os.Stat(path)
+		if err != nil {
+			return
+		}
+
+		entry, found := cache[location]
+		if !found || entry.Size != info.Size() || entry.MTime != info.ModTime().UnixNano() || entry.Package == nil {
+			entry.Size = info.Size()
+			entry.MTime = info.ModTime().UnixNano()
+			entry.Package, err = NewPackage(path, location, options.ChangelogLimit)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%s: %s", path, err)
+			}
+		} else {
+			reused++
+		}
+
+		newCache[location] = entry
+		packages = append(packages, entry.Package)
+	}
+
+	temporary := filepath.Join(dir, ".repodata.tmp")
+	err = os.RemoveAll(temporary)
+	if err != nil {
+		return
+	}
+	err = os.Mkdir(temporary, 0755)
+	if err != nil {
+		return
+	}
+
+	writers := []dataWriter{
+		{"primary", WritePrimary},
+		{"filelists", WriteFilelists},
+		{"other", WriteOther},
+	}
+	if options.FilelistsExt {
+		writers = append(writers, dataWriter{"filelists_ext", WriteFilelistsExt})
+	}
+
+	var datas []RepoMDData
+	for _, w := range writers {
+		var data RepoMDData
+		data, err = writeData(temporary, w.datatype, packages, w.write)
+		if err != nil {
+			return
+		}
+		datas = append(datas, data)
+	}
+
+	var repomd bytes.Buffer
+	err = WriteRepoMD(&repomd, time.Now().Unix(), datas)
+	if err != nil {
+		return
+	}
+	err = ioutil.WriteFile(filepath.Join(temporary, "repomd.xml"), repomd.Bytes(), 0644)
+	if err != nil {
+		return
+	}
+
+	if options.UseCache {
+		err = writeCache(filepath.Join(temporary, CacheFile), newCache)
+		if err != nil {
+			return
+		}
+	}
+
+	// The old repodata is moved aside, so that it is kept if the new one cannot be moved in
+	old := filepath.Join(dir, ".repodata.old")
+	err = os.RemoveAll(old)
+	if err != nil {
+		return
+	}
+	err = os.Rename(repodata, old)
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+	err = os.Rename(temporary, repodata)
+	if err != nil {
+		os.Rename(old, repodata)
+		return
+	}
+
+	err = os.RemoveAll(old)
+
+	return
+}
@@ -0,0 +1,129 @@
+package repodata
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	var buffer bytes.Buffer
+	x := newXMLWriter(&buffer)
+	x.printf("<a b=\"%s\" c=\"%d\">%s%%</a>\n", "x\"y\nz<&>", 1, "line 1\n\tline \"2\" <&>")
+	if err := x.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "<a b=\"x&quot;y&#10;z&lt;&amp;&gt;\" c=\"1\">line 1\n\tline \"2\" &lt;&amp;&gt;%</a>\n"
+	if buffer.String() != expected {
+		t.Errorf("printf() = %q, want %q", buffer.String(), expected)
+	}
+}
+
+func readGzip(t *testing.T, path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rd, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestCreateRepo(t *testing.T) {
+	dir := t.TempDir()
+
+	description := "First line\nsecond <line> & \"quoted\"\n\n\tindented"
+	foo := rpmtest.Binary("foo", "1.0", "1", rpmtest.Regular("/usr/bin/foo", "foo"))
+	foo.Tags = func(b *rpmtest.Builder) {
+		b.AddI18nString(rpmlib.RPMTAG_DESCRIPTION, description)
+	}
+	rpmtest.Write(t, dir, foo)
+
+	packages, reused, err := CreateRepo(dir, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || reused != 0 {
+		t.Fatalf("CreateRepo() = %d packages, %d reused", len(packages), reused)
+	}
+
+	// Newlines are kept in text, same as createrepo
+	datas, err := readRepoMDFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range datas {
+		if data.Type != "primary" {
+			continue
+		}
+		primary := readGzip(t, filepath.Join(dir, filepath.FromSlash(data.Location)))
+		if !strings.Contains(primary, "<description>First line\nsecond &lt;line&gt; &amp; \"quoted\"\n\n\tindented</description>") {
+			t.Errorf("Description is not escaped as text:\n%s", primary)
+		}
+	}
+
+	read, err := ReadRepo(dir, DefaultReadOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].Description != description {
+		t.Fatalf("ReadRepo() = %+v", read)
+	}
+
+	// The repodata is replaced, without files of the old one
+	old, err := filepath.Glob(filepath.Join(dir, RepoDataDir, "*-primary.xml.gz"))
+	if err != nil || len(old) != 1 {
+		t.Fatalf("Old primary: %v, %v", old, err)
+	}
+	bar := rpmtest.Binary("bar", "1.0", "1", rpmtest.Regular("/usr/bin/bar", "bar"))
+	rpmtest.Write(t, dir, bar)
+
+	packages, reused, err = CreateRepo(dir, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 || reused != 1 {
+		t.Errorf("CreateRepo() again = %d packages, %d reused", len(packages), reused)
+	}
+	if _, err := os.Stat(old[0]); !os.IsNotExist(err) {
+		t.Errorf("Old primary is kept: %v", err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".repodata") {
+			t.Errorf("%s is left", entry.Name())
+		}
+	}
+	if read, err := ReadRepo(dir, DefaultReadOptions); err != nil || len(read) != 2 {
+		t.Errorf("ReadRepo() again = %d packages, %v", len(read), err)
+	}
+}
+
+func readRepoMDFile(dir string) (datas []RepoMDData, err error) {
+	file, err := os.Open(filepath.Join(dir, RepoDataDir, "repomd.xml"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	return ReadRepoMD(file)
+}
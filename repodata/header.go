@@ -0,0 +1,183 @@
+package repodata
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strconv"
+	"strings"
+)
+
+var entryFlags = map[string]int32{
+	FlagLT: rpmlib.RPMSENSE_LESS,
+	FlagGT: rpmlib.RPMSENSE_GREATER,
+	FlagEQ: rpmlib.RPMSENSE_EQUAL,
+	FlagLE: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL,
+	FlagGE: rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL,
+}
+
+// Dependency converts the entry back to a dependency, the epoch "0" is omitted
+func (entry Entry) Dependency() (dep rpmlib.Dependency) {
+	dep.Name = entry.Name
+	dep.Flags = entryFlags[entry.Flags]
+	if entry.Pre {
+		dep.Flags |= rpmlib.RPMSENSE_PREREQ
+	}
+
+	if dep.Flags&rpmlib.RPMSENSE_SENSEMASK == 0 {
+		return
+	}
+
+	dep.Version = entry.Version
+	if entry.Epoch != "" && entry.Epoch != "0" {
+		dep.Version = entry.Epoch + ":" + dep.Version
+	}
+	if entry.Release != "" {
+		dep.Version += "-" + entry.Release
+	}
+
+	return
+}
+
+func addDependencies(b *rpmlib.SectionBuilder, nameTag, flagsTag, versionTag int32, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	var names, versions []string
+	var flags []int32
+	for _, entry := range entries {
+		dep := entry.Dependency()
+		names = append(names, dep.Name)
+		flags = append(flags, dep.Flags)
+		versions = append(versions, dep.Version)
+	}
+
+	b.AddStringArray(nameTag, names)
+	b.AddInt32(flagsTag, flags...)
+	b.AddStringArray(versionTag, versions)
+}
+
+// addFiles adds file lists. Metadata have only paths and types, so sizes and
+// times are zero, and modes have only the file type with default permissions.
+func addFiles(b *rpmlib.SectionBuilder, pkg *Package) {
+	if len(pkg.Files) == 0 {
+		return
+	}
+
+	var basenames, dirnames, digests, empty []string
+	var dirindexes, flags, zeros []int32
+	var modes, rdevs []int16
+	dirs := make(map[string]int32)
+
+	for _, file := range pkg.Files {
+		slash := strings.LastIndexByte(file.Path, '/')
+		dir, base := file.Path[:slash+1], file.Path[slash+1:]
+
+		index, found := dirs[dir]
+		if !found {
+			index = int32(len(dirnames))
+			dirs[dir] = index
+			dirnames = append(dirnames, dir)
+		}
+
+		var flag int32
+		mode := uint16(rpmlib.S_IFREG | 0644)
+		switch file.Type {
+		case FileTypeDir:
+			mode = rpmlib.S_IFDIR | 0755
+		case FileTypeGhost:
+			flag = rpmlib.RPMFILE_GHOST
+		}
+
+		basenames = append(basenames, base)
+		dirindexes = append(dirindexes, index)
+		flags = append(flags, flag)
+		modes = append(modes, int16(mode))
+		digests = append(digests, file.Digest)
+		empty = append(empty, "")
+		zeros = append(zeros, 0)
+		rdevs = append(rdevs, 0)
+	}
+
+	b.AddStringArray(rpmlib.RPMTAG_BASENAMES, basenames)
+	b.AddStringArray(rpmlib.RPMTAG_DIRNAMES, dirnames)
+	b.AddInt32(rpmlib.RPMTAG_DIRINDEXES, dirindexes...)
+	b.AddInt32(rpmlib.RPMTAG_FILEFLAGS, flags...)
+	b.AddInt16(rpmlib.RPMTAG_FILEMODES, modes...)
+	b.AddStringArray(rpmlib.RPMTAG_FILEDIGESTS, digests)
+	b.AddStringArray(rpmlib.RPMTAG_FILELINKTOS, empty)
+	b.AddInt32(rpmlib.RPMTAG_FILESIZES, zeros...)
+	b.AddInt32(rpmlib.RPMTAG_FILEMTIMES, zeros...)
+	b.AddInt32(rpmlib.RPMTAG_FILEDEVICES, zeros...)
+	b.AddInt16(rpmlib.RPMTAG_FILERDEVS, rdevs...)
+
+	for algo, name := range digestAlgorithms {
+		if name == pkg.FileDigestType {
+			b.AddInt32(rpmlib.RPMTAG_FILEDIGESTALGO, algo)
+		}
+	}
+}
+
+// Header builds a header from the metadata, so packages in a repository can be
+// used same as package files. Tags not in the metadata, such as scripts, are missing.
+func (pkg *Package) Header() *rpmlib.Header {
+	b := rpmlib.NewSectionBuilder()
+
+	b.AddStringArray(rpmlib.RPMTAG_HEADERI18NTABLE, []string{"C"})
+	b.AddString(rpmlib.RPMTAG_NAME, pkg.Name)
+	b.AddString(rpmlib.RPMTAG_VERSION, pkg.Version)
+	b.AddString(rpmlib.RPMTAG_RELEASE, pkg.Release)
+	if epoch, err := strconv.Atoi(pkg.Epoch); err == nil && epoch != 0 {
+		b.AddInt32(rpmlib.RPMTAG_EPOCH, int32(epoch))
+	}
+	b.AddString(rpmlib.RPMTAG_ARCH, pkg.Arch)
+	b.AddString(rpmlib.RPMTAG_OS, "linux")
+
+	b.AddI18nString(rpmlib.RPMTAG_SUMMARY, pkg.Summary)
+	b.AddI18nString(rpmlib.RPMTAG_DESCRIPTION, pkg.Description)
+	b.AddI18nString(rpmlib.RPMTAG_GROUP, pkg.Group)
+	b.AddString(rpmlib.RPMTAG_LICENSE, pkg.License)
+	b.AddString(rpmlib.RPMTAG_PACKAGER, pkg.Packager)
+	b.AddString(rpmlib.RPMTAG_URL, pkg.URL)
+	b.AddString(rpmlib.RPMTAG_VENDOR, pkg.Vendor)
+	b.AddString(rpmlib.RPMTAG_BUILDHOST, pkg.BuildHost)
+	b.AddInt32(rpmlib.RPMTAG_BUILDTIME, int32(pkg.BuildTime))
+
+	// Source packages have no SOURCERPM
+	if pkg.SourceRpm != "" {
+		b.AddString(rpmlib.RPMTAG_SOURCERPM, pkg.SourceRpm)
+	}
+
+	if pkg.InstalledSize > 0x7fffffff {
+		b.AddInt64(rpmlib.RPMTAG_LONGSIZE, pkg.InstalledSize)
+	} else {
+		b.AddInt32(rpmlib.RPMTAG_SIZE, int32(pkg.InstalledSize))
+	}
+
+	addDependencies(b, rpmlib.RPMTAG_PROVIDENAME, rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMTAG_PROVIDEVERSION, pkg.Provides)
+	addDependencies(b, rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION, pkg.Requires)
+	addDependencies(b, rpmlib.RPMTAG_CONFLICTNAME, rpmlib.RPMTAG_CONFLICTFLAGS, rpmlib.RPMTAG_CONFLICTVERSION, pkg.Conflicts)
+	addDependencies(b, rpmlib.RPMTAG_OBSOLETENAME, rpmlib.RPMTAG_OBSOLETEFLAGS, rpmlib.RPMTAG_OBSOLETEVERSION, pkg.Obsoletes)
+	addDependencies(b, rpmlib.RPMTAG_RECOMMENDNAME, rpmlib.RPMTAG_RECOMMENDFLAGS, rpmlib.RPMTAG_RECOMMENDVERSION, pkg.Recommends)
+	addDependencies(b, rpmlib.RPMTAG_SUGGESTNAME, rpmlib.RPMTAG_SUGGESTFLAGS, rpmlib.RPMTAG_SUGGESTVERSION, pkg.Suggests)
+	addDependencies(b, rpmlib.RPMTAG_SUPPLEMENTNAME, rpmlib.RPMTAG_SUPPLEMENTFLAGS, rpmlib.RPMTAG_SUPPLEMENTVERSION, pkg.Supplements)
+	addDependencies(b, rpmlib.RPMTAG_ENHANCENAME, rpmlib.RPMTAG_ENHANCEFLAGS, rpmlib.RPMTAG_ENHANCEVERSION, pkg.Enhances)
+
+	addFiles(b, pkg)
+
+	// Changelogs are stored from the latest one in headers
+	if len(pkg.Changelogs) > 0 {
+		var names, texts []string
+		var times []int32
+		for i := len(pkg.Changelogs) - 1; i >= 0; i-- {
+			log := pkg.Changelogs[i]
+			names = append(names, log.Author)
+			texts = append(texts, log.Text)
+			times = append(times, int32(log.Date))
+		}
+		b.AddInt32(rpmlib.RPMTAG_CHANGELOGTIME, times...)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGNAME, names)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGTEXT, texts)
+	}
+
+	return b.Header()
+}
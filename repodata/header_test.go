@@ -0,0 +1,198 @@
+package repodata
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"reflect"
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		dep   rpmlib.Dependency
+		entry Entry
+		// The dependency converted back, which has no epoch "0"
+		back string
+	}{
+		{rpmlib.Dependency{Name: "foo"}, Entry{Name: "foo"}, "foo"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_LESS, Version: "1.0"},
+			Entry{Name: "foo", Flags: FlagLT, Epoch: "0", Version: "1.0"}, "foo < 1.0"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_GREATER, Version: "1.0-1"},
+			Entry{Name: "foo", Flags: FlagGT, Epoch: "0", Version: "1.0", Release: "1"}, "foo > 1.0-1"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_EQUAL, Version: "2:1.0-1"},
+			Entry{Name: "foo", Flags: FlagEQ, Epoch: "2", Version: "1.0", Release: "1"}, "foo = 2:1.0-1"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL, Version: "0:1.0"},
+			Entry{Name: "foo", Flags: FlagLE, Epoch: "0", Version: "1.0"}, "foo <= 1.0"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL | rpmlib.RPMSENSE_SCRIPT_POST, Version: "1.0"},
+			Entry{Name: "foo", Flags: FlagGE, Epoch: "0", Version: "1.0", Pre: true}, "foo >= 1.0"},
+		// Flags without a version, and a version without flags
+		{rpmlib.Dependency{Name: "/bin/sh", Flags: rpmlib.RPMSENSE_SCRIPT_PRE}, Entry{Name: "/bin/sh", Pre: true}, "/bin/sh"},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_EQUAL}, Entry{Name: "foo"}, "foo"},
+		{rpmlib.Dependency{Name: "foo", Version: "1.0"}, Entry{Name: "foo"}, "foo"},
+	}
+
+	for _, test := range tests {
+		entry := NewEntry(test.dep)
+		if entry != test.entry {
+			t.Errorf("NewEntry(%+v) = %+v, want %+v", test.dep, entry, test.entry)
+		}
+
+		dep := entry.Dependency()
+		if dep.String() != test.back {
+			t.Errorf("Dependency() of %+v = %s, want %s", entry, dep, test.back)
+		}
+		if (dep.Flags&rpmlib.RPMSENSE_PREREQ != 0) != test.entry.Pre {
+			t.Errorf("Dependency() of %+v = %#x", entry, dep.Flags)
+		}
+	}
+}
+
+// addTestDependencies adds versioned dependencies of each kind to the header of foo
+func addTestDependencies(b *rpmtest.Builder) {
+	add := func(nameTag, flagsTag, versionTag int32, deps ...rpmlib.Dependency) {
+		var names, versions []string
+		var flags []int32
+		for _, dep := range deps {
+			names = append(names, dep.Name)
+			flags = append(flags, dep.Flags)
+			versions = append(versions, dep.Version)
+		}
+		b.AddStringArray(nameTag, names)
+		b.AddInt32(flagsTag, flags...)
+		b.AddStringArray(versionTag, versions)
+	}
+
+	add(rpmlib.RPMTAG_PROVIDENAME, rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMTAG_PROVIDEVERSION,
+		rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_EQUAL, Version: "1:1.2-3"},
+		rpmlib.Dependency{Name: "libfoo.so.1()(64bit)"})
+	add(rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION,
+		rpmlib.Dependency{Name: "bar", Flags: rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL, Version: "2.0"},
+		rpmlib.Dependency{Name: "/bin/sh", Flags: rpmlib.RPMSENSE_SCRIPT_PRE},
+		rpmlib.Dependency{Name: "rpmlib(PayloadFilesHavePrefix)",
+			Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL | rpmlib.RPMSENSE_RPMLIB, Version: "4.0-1"})
+	add(rpmlib.RPMTAG_CONFLICTNAME, rpmlib.RPMTAG_CONFLICTFLAGS, rpmlib.RPMTAG_CONFLICTVERSION,
+		rpmlib.Dependency{Name: "baz", Flags: rpmlib.RPMSENSE_LESS, Version: "1.0-1"})
+	add(rpmlib.RPMTAG_OBSOLETENAME, rpmlib.RPMTAG_OBSOLETEFLAGS, rpmlib.RPMTAG_OBSOLETEVERSION,
+		rpmlib.Dependency{Name: "foo-old", Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL, Version: "1:1.0"})
+	add(rpmlib.RPMTAG_RECOMMENDNAME, rpmlib.RPMTAG_RECOMMENDFLAGS, rpmlib.RPMTAG_RECOMMENDVERSION,
+		rpmlib.Dependency{Name: "qux", Flags: rpmlib.RPMSENSE_GREATER, Version: "3"})
+}
+
+// dependencyStrings returns dependencies other than rpmlib() as strings
+func dependencyStrings(deps []rpmlib.Dependency) (strings []string) {
+	for _, dep := range deps {
+		if !dep.IsRpmlib() {
+			strings = append(strings, dep.String())
+		}
+	}
+
+	return
+}
+
+// Header of a package in the repository has the same tags as the package file,
+// except tags which are not in the metadata
+func TestHeader(t *testing.T) {
+	dir := t.TempDir()
+
+	ghost := rpmtest.File{Path: "/var/log/foo.log", Mode: rpmlib.S_IFREG | 0644, Flags: rpmlib.RPMFILE_GHOST}
+	foo := rpmtest.Binary("foo", "1.2", "3", rpmtest.Dir("/etc/foo.d"), rpmtest.Regular("/usr/bin/foo", "foo"), ghost)
+	foo.Arch = "x86_64"
+	foo.Tags = func(b *rpmtest.Builder) {
+		b.AddInt32(rpmlib.RPMTAG_EPOCH, 1)
+		b.AddString(rpmlib.RPMTAG_URL, "https://example.com/foo")
+		addTestDependencies(b)
+		b.AddInt32(rpmlib.RPMTAG_CHANGELOGTIME, 1600000000, 1500000000)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGNAME, []string{"Foo <foo@example.com> - 1.2-3", "Foo <foo@example.com> - 1.1-1"})
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGTEXT, []string{"- Update", "- Initial"})
+	}
+	source := rpmtest.Open(t, foo).Header
+
+	rpmtest.Write(t, dir, foo)
+	if _, _, err := CreateRepo(dir, DefaultOptions); err != nil {
+		t.Fatal(err)
+	}
+	packages, err := ReadRepo(dir, DefaultReadOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 {
+		t.Fatalf("ReadRepo() = %d packages", len(packages))
+	}
+	header := packages[0].Header()
+
+	if header.NEVRA() != source.NEVRA() || header.EVR() != source.EVR() {
+		t.Errorf("NEVRA() = %s, want %s", header.NEVRA(), source.NEVRA())
+	}
+	for _, tag := range []int32{rpmlib.RPMTAG_SUMMARY, rpmlib.RPMTAG_DESCRIPTION, rpmlib.RPMTAG_LICENSE,
+		rpmlib.RPMTAG_URL, rpmlib.RPMTAG_SOURCERPM, rpmlib.RPMTAG_GROUP} {
+		value, err := header.Section.GetString(tag)
+		expected, _ := source.Section.GetString(tag)
+		if err != nil || value != expected {
+			t.Errorf("Tag %d = %q, %v, want %q", tag, value, err, expected)
+		}
+	}
+	if header.Size() != source.Size() {
+		t.Errorf("Size() = %d, want %d", header.Size(), source.Size())
+	}
+	buildTime, err := header.BuildDate()
+	expectedTime, _ := source.BuildDate()
+	if err != nil || !buildTime.Equal(expectedTime) {
+		t.Errorf("BuildDate() = %s, %v, want %s", buildTime, err, expectedTime)
+	}
+
+	kinds := []struct {
+		name string
+		deps func(header *rpmlib.Header) ([]rpmlib.Dependency, error)
+	}{
+		{"provides", (*rpmlib.Header).Provides},
+		{"requires", (*rpmlib.Header).Requires},
+		{"conflicts", (*rpmlib.Header).Conflicts},
+		{"obsoletes", (*rpmlib.Header).Obsoletes},
+		{"recommends", (*rpmlib.Header).Recommends},
+	}
+	for _, kind := range kinds {
+		deps, err := kind.deps(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := kind.deps(source)
+		if !reflect.DeepEqual(dependencyStrings(deps), dependencyStrings(expected)) {
+			t.Errorf("%s = %v, want %v", kind.name, deps, expected)
+		}
+	}
+	requires, _ := header.Requires()
+	for _, require := range requires {
+		if require.Name == "/bin/sh" && require.Flags&rpmlib.RPMSENSE_PREREQ == 0 {
+			t.Errorf("/bin/sh is not required before scripts: %#x", require.Flags)
+		}
+	}
+
+	files, err := header.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceFiles, _ := source.Files()
+	if len(files) != len(sourceFiles) {
+		t.Fatalf("Files() = %d files, want %d", len(files), len(sourceFiles))
+	}
+	for i, file := range files {
+		expected := sourceFiles[i]
+		// Metadata have no digests of ghost files
+		if expected.Flag&rpmlib.RPMFILE_GHOST != 0 {
+			expected.MD5 = ""
+		}
+		if file.Path != expected.Path || file.Flag != expected.Flag || file.MD5 != expected.MD5 ||
+			uint16(file.Mode)&rpmlib.S_IFMT != uint16(expected.Mode)&rpmlib.S_IFMT {
+			t.Errorf("File %s: flags %#x, mode %o, digest %s, want %+v", file.Path, file.Flag, uint16(file.Mode), file.MD5, expected)
+		}
+	}
+	if algo, err := header.Section.GetInt32(rpmlib.RPMTAG_FILEDIGESTALGO); err != nil || algo != rpmlib.PGPHASHALGO_SHA256 {
+		t.Errorf("File digest algorithm %d, %v", algo, err)
+	}
+
+	changelogs, err := header.Changelog()
+	expectedChangelogs, _ := source.Changelog()
+	if err != nil || !reflect.DeepEqual(changelogs, expectedChangelogs) {
+		t.Errorf("Changelog() = %+v, %v, want %+v", changelogs, err, expectedChangelogs)
+	}
+}
@@ -0,0 +1,362 @@
+package repodata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Dependency flags in repository metadata
+const (
+	FlagLT = "LT"
+	FlagGT = "GT"
+	FlagEQ = "EQ"
+	FlagLE = "LE"
+	FlagGE = "GE"
+)
+
+// Requirements needed before install scripts, marked as pre="1"
+const preRequireFlags = rpmlib.RPMSENSE_PREREQ | rpmlib.RPMSENSE_SCRIPT_PRE | rpmlib.RPMSENSE_SCRIPT_POST |
+	rpmlib.RPMSENSE_SCRIPT_PREUN | rpmlib.RPMSENSE_SCRIPT_POSTUN
+
+// Entry is a dependency as rpm:entry element
+type Entry struct {
+	Name    string
+	Flags   string
+	Epoch   string
+	Version string
+	Release string
+	Pre     bool
+}
+
+// File types of file element, regular files have no type
+const (
+	FileTypeDir   = "dir"
+	FileTypeGhost = "ghost"
+)
+
+type File struct {
+	Path   string
+	Type   string
+	Digest string
+}
+
+type Changelog struct {
+	Author string
+	Date   int64
+	Text   string
+}
+
+// Package is a package in repository metadata, which has all data of
+// primary.xml, filelists.xml and other.xml
+type Package struct {
+	Name    string
+	Arch    string
+	Epoch   string
+	Version string
+	Release string
+
+	ChecksumType string
+	Checksum     string
+
+	Summary     string
+	Description string
+	Packager    string
+	URL         string
+
+	FileTime  int64
+	BuildTime int64
+
+	PackageSize   int64
+	InstalledSize int64
+	ArchiveSize   int64
+
+	Location string
+
+	License     string
+	Vendor      string
+	Group       string
+	BuildHost   string
+	SourceRpm   string
+	HeaderStart int64
+	HeaderEnd   int64
+
+	Provides    []Entry
+	Requires    []Entry
+	Conflicts   []Entry
+	Obsoletes   []Entry
+	Recommends  []Entry
+	Suggests    []Entry
+	Supplements []Entry
+	Enhances    []Entry
+
+	FileDigestType string
+	Files          []File
+	Changelogs     []Changelog
+}
+
+// Digest algorithms of files, as RPMTAG_FILEDIGESTALGO
+var digestAlgorithms = map[int32]string{
+	1:  "md5",
+	2:  "sha1",
+	8:  "sha256",
+	9:  "sha384",
+	10: "sha512",
+	11: "sha224",
+}
+
+func flagsString(flags int32) string {
+	switch flags & rpmlib.RPMSENSE_SENSEMASK {
+	case rpmlib.RPMSENSE_LESS:
+		return FlagLT
+	case rpmlib.RPMSENSE_GREATER:
+		return FlagGT
+	case rpmlib.RPMSENSE_EQUAL:
+		return FlagEQ
+	case rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL:
+		return FlagLE
+	case rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL:
+		return FlagGE
+	}
+
+	return ""
+}
+
+// NewEntry converts a dependency, the epoch is "0" if it has a version but no epoch
+func NewEntry(dep rpmlib.Dependency) (entry Entry) {
+	entry.Name = dep.Name
+	entry.Pre = dep.Flags&preRequireFlags != 0
+
+	entry.Flags = flagsString(dep.Flags)
+	if entry.Flags == "" || dep.Version == "" {
+		entry.Flags = ""
+		return
+	}
+
+	evr, err := rpmlib.ParseEVR(dep.Version)
+	if err != nil {
+		entry.Version = dep.Version
+		return
+	}
+
+	entry.Epoch = strconv.Itoa(int(evr.Epoch))
+	entry.Version = evr.Version
+	entry.Release = evr.Release
+
+	return
+}
+
+// newEntries converts dependencies, taking the results of Header.Requires or others
+func newEntries(deps []rpmlib.Dependency, err error) ([]Entry, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, dep := range deps {
+		entries = append(entries, NewEntry(dep))
+	}
+
+	return entries, nil
+}
+
+// fileChecksum computes sha256 checksum of whole file
+func fileChecksum(file *os.File) (checksum string, err error) {
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// NewPackage reads the package file at path. location is the path relative to the repository.
+// changelogLimit is the number of latest changelogs to keep, or all if negative.
+func NewPackage(path string, location string, changelogLimit int) (pkg *Package, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	rpm, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+	header := rpm.Header
+
+	pkg = new(Package)
+	pkg.Location = location
+	pkg.PackageSize = info.Size()
+	pkg.FileTime = info.ModTime().Unix()
+	pkg.HeaderStart, pkg.HeaderEnd = rpm.HeaderRange()
+
+	nevra := rpm.NEVRA()
+	pkg.Name = nevra.Name
+	pkg.Arch = nevra.Arch
+	pkg.Epoch = strconv.Itoa(int(nevra.EVR.Epoch))
+	pkg.Version = nevra.EVR.Version
+	pkg.Release = nevra.EVR.Release
+
+	pkg.ChecksumType = "sha256"
+	pkg.Checksum, err = fileChecksum(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.Summary = header.Summary()
+	pkg.Description = header.Description()
+	pkg.License = header.License()
+	pkg.Group = header.Group()
+	pkg.Packager, _ = header.Section.GetString(rpmlib.RPMTAG_PACKAGER)
+	pkg.URL, _ = header.Section.GetString(rpmlib.RPMTAG_URL)
+	pkg.Vendor, _ = header.Section.GetString(rpmlib.RPMTAG_VENDOR)
+	pkg.BuildHost, _ = header.Section.GetString(rpmlib.RPMTAG_BUILDHOST)
+	pkg.SourceRpm, _ = header.SourceRpm()
+
+	if buildtime, err := header.Section.GetInt32(rpmlib.RPMTAG_BUILDTIME); err == nil {
+		pkg.BuildTime = int64(uint32(buildtime))
+	}
+
+	pkg.InstalledSize = int64(uint32(header.Size()))
+	if size, err := header.Section.GetInt64(rpmlib.RPMTAG_LONGSIZE); err == nil {
+		pkg.InstalledSize = size
+	}
+
+	if size, err := rpm.Signature.PayloadSize(); err == nil {
+		pkg.ArchiveSize = int64(uint32(size))
+	} else if size, err := rpm.Signature.Section.GetInt64(rpmlib.RPMSIGTAG_LONGARCHIVESIZE); err == nil {
+		pkg.ArchiveSize = size
+	}
+
+	err = pkg.setDependencies(header)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pkg.setFiles(header)
+	if err != nil {
+		return nil, err
+	}
+
+	err = pkg.setChangelogs(header, changelogLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (pkg *Package) setDependencies(header *rpmlib.Header) (err error) {
+	pkg.Provides, err = newEntries(header.Provides())
+	if err != nil {
+		return
+	}
+
+	// rpmlib() requirements and duplicates are omitted, same as createrepo
+	requires, err := header.Requires()
+	if err != nil {
+		return
+	}
+	found := make(map[Entry]bool)
+	for _, require := range requires {
+		entry := NewEntry(require)
+		if require.IsRpmlib() || found[entry] {
+			continue
+		}
+		found[entry] = true
+		pkg.Requires = append(pkg.Requires, entry)
+	}
+
+	pkg.Conflicts, err = newEntries(header.Conflicts())
+	if err != nil {
+		return
+	}
+	pkg.Obsoletes, err = newEntries(header.Obsoletes())
+	if err != nil {
+		return
+	}
+	pkg.Recommends, err = newEntries(header.Recommends())
+	if err != nil {
+		return
+	}
+	pkg.Suggests, err = newEntries(header.Suggests())
+	if err != nil {
+		return
+	}
+	pkg.Supplements, err = newEntries(header.Supplements())
+	if err != nil {
+		return
+	}
+	pkg.Enhances, err = newEntries(header.Enhances())
+
+	return
+}
+
+func (pkg *Package) setFiles(header *rpmlib.Header) (err error) {
+	// Packages without files are valid
+	if !header.Section.HasStore(rpmlib.RPMTAG_BASENAMES) && !header.Section.HasStore(rpmlib.RPMTAG_OLDFILENAMES) {
+		return
+	}
+
+	files, err := header.Files()
+	if err != nil {
+		return
+	}
+
+	pkg.FileDigestType = "md5"
+	if algo, err := header.Section.GetInt32(rpmlib.RPMTAG_FILEDIGESTALGO); err == nil {
+		pkg.FileDigestType = digestAlgorithms[algo]
+	}
+
+	for _, f := range files {
+		file := File{Path: f.Path, Digest: f.MD5}
+
+		if f.Flag&rpmlib.RPMFILE_GHOST != 0 {
+			file.Type = FileTypeGhost
+		} else if uint16(f.Mode)&rpmlib.S_IFMT == rpmlib.S_IFDIR {
+			file.Type = FileTypeDir
+		}
+
+		pkg.Files = append(pkg.Files, file)
+	}
+
+	return
+}
+
+func (pkg *Package) setChangelogs(header *rpmlib.Header, limit int) (err error) {
+	if !header.Section.HasStore(rpmlib.RPMTAG_CHANGELOGNAME) {
+		return
+	}
+
+	changelogs, err := header.Changelog()
+	if err != nil {
+		return
+	}
+
+	// Changelogs are stored from the latest one
+	if limit >= 0 && len(changelogs) > limit {
+		changelogs = changelogs[:limit]
+	}
+
+	for i := len(changelogs) - 1; i >= 0; i-- {
+		log := changelogs[i]
+		pkg.Changelogs = append(pkg.Changelogs, Changelog{log.Name, log.Date.Unix(), log.Text})
+	}
+
+	return
+}
@@ -0,0 +1,715 @@
+package repodata
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pombredanne/gorpm-1/sqlite"
+	"github.com/xi2/xz"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ReadOptions struct {
+	// Read filelists.xml for all files, otherwise only files listed in primary.xml are read
+	Filelists bool
+	// Read other.xml for changelogs
+	Other bool
+	// Verify checksums of metadata files listed in repomd.xml
+	VerifyChecksum bool
+}
+
+var DefaultReadOptions = ReadOptions{
+	Filelists:      true,
+	Other:          true,
+	VerifyChecksum: true,
+}
+
+type xmlChecksum struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlRepoMD struct {
+	Data []struct {
+		Type         string      `xml:"type,attr"`
+		Checksum     xmlChecksum `xml:"checksum"`
+		OpenChecksum xmlChecksum `xml:"open-checksum"`
+		Location     struct {
+			Href string `xml:"href,attr"`
+		} `xml:"location"`
+		Timestamp int64 `xml:"timestamp"`
+		Size      int64 `xml:"size"`
+		OpenSize  int64 `xml:"open-size"`
+	} `xml:"data"`
+}
+
+type xmlVersion struct {
+	Epoch   string `xml:"epoch,attr"`
+	Version string `xml:"ver,attr"`
+	Release string `xml:"rel,attr"`
+}
+
+type xmlEntry struct {
+	Name string `xml:"name,attr"`
+	xmlVersion
+	Flags string `xml:"flags,attr"`
+	Pre   string `xml:"pre,attr"`
+}
+
+type xmlFile struct {
+	Type string `xml:"type,attr"`
+	Hash string `xml:"hash,attr"`
+	Path string `xml:",chardata"`
+}
+
+// Elements are matched with local names, namespaces such as rpm: are ignored
+type xmlPrimaryPackage struct {
+	Name     string      `xml:"name"`
+	Arch     string      `xml:"arch"`
+	Version  xmlVersion  `xml:"version"`
+	Checksum xmlChecksum `xml:"checksum"`
+
+	Summary     string `xml:"summary"`
+	Description string `xml:"description"`
+	Packager    string `xml:"packager"`
+	URL         string `xml:"url"`
+
+	Time struct {
+		File  int64 `xml:"file,attr"`
+		Build int64 `xml:"build,attr"`
+	} `xml:"time"`
+	Size struct {
+		Package   int64 `xml:"package,attr"`
+		Installed int64 `xml:"installed,attr"`
+		Archive   int64 `xml:"archive,attr"`
+	} `xml:"size"`
+	Location struct {
+		Href string `xml:"href,attr"`
+	} `xml:"location"`
+
+	Format struct {
+		License     string `xml:"license"`
+		Vendor      string `xml:"vendor"`
+		Group       string `xml:"group"`
+		BuildHost   string `xml:"buildhost"`
+		SourceRpm   string `xml:"sourcerpm"`
+		HeaderRange struct {
+			Start int64 `xml:"start,attr"`
+			End   int64 `xml:"end,attr"`
+		} `xml:"header-range"`
+
+		Provides    []xmlEntry `xml:"provides>entry"`
+		Requires    []xmlEntry `xml:"requires>entry"`
+		Conflicts   []xmlEntry `xml:"conflicts>entry"`
+		Obsoletes   []xmlEntry `xml:"obsoletes>entry"`
+		Recommends  []xmlEntry `xml:"recommends>entry"`
+		Suggests    []xmlEntry `xml:"suggests>entry"`
+		Supplements []xmlEntry `xml:"supplements>entry"`
+		Enhances    []xmlEntry `xml:"enhances>entry"`
+
+		Files []xmlFile `xml:"file"`
+	} `xml:"format"`
+}
+
+type xmlFilelistsPackage struct {
+	PkgID    string      `xml:"pkgid,attr"`
+	Checksum xmlChecksum `xml:"checksum"`
+	Files    []xmlFile   `xml:"file"`
+}
+
+type xmlOtherPackage struct {
+	PkgID      string `xml:"pkgid,attr"`
+	Changelogs []struct {
+		Author string `xml:"author,attr"`
+		Date   int64  `xml:"date,attr"`
+		Text   string `xml:",chardata"`
+	} `xml:"changelog"`
+}
+
+func ReadRepoMD(r io.Reader) (datas []RepoMDData, err error) {
+	var repomd xmlRepoMD
+	err = xml.NewDecoder(r).Decode(&repomd)
+	if err != nil {
+		return
+	}
+
+	for _, d := range repomd.Data {
+		datas = append(datas, RepoMDData{
+			Type:         d.Type,
+			ChecksumType: d.Checksum.Type,
+			Checksum:     strings.TrimSpace(d.Checksum.Value),
+			OpenChecksum: strings.TrimSpace(d.OpenChecksum.Value),
+			Location:     d.Location.Href,
+			Timestamp:    d.Timestamp,
+			Size:         d.Size,
+			OpenSize:     d.OpenSize,
+		})
+	}
+
+	return
+}
+
+func newHash(checksumType string) (h hash.Hash, err error) {
+	switch checksumType {
+	case "md5":
+		h = md5.New()
+	case "sha", "sha1":
+		h = sha1.New()
+	case "sha224":
+		h = sha256.New224()
+	case "sha256":
+		h = sha256.New()
+	case "sha384":
+		h = sha512.New384()
+	case "sha512":
+		h = sha512.New()
+	default:
+		err = fmt.Errorf("Unknown checksum type %s", checksumType)
+	}
+
+	return
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+// openData opens a metadata file, decompressing it by the extension,
+// gz, xz, zst or bz2. The checksum of the file is verified if verify is true.
+func openData(dir string, data RepoMDData, verify bool) (r io.ReadCloser, err error) {
+	path := filepath.Join(dir, filepath.FromSlash(data.Location))
+
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+
+	if verify && data.Checksum != "" {
+		var h hash.Hash
+		h, err = newHash(data.ChecksumType)
+		if err == nil {
+			_, err = io.Copy(h, file)
+		}
+		if err == nil && hex.EncodeToString(h.Sum(nil)) != data.Checksum {
+			err = fmt.Errorf("%s: Checksum mismatch", data.Location)
+		}
+		if err == nil {
+			_, err = file.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			file.Close()
+			return
+		}
+	}
+
+	var decompressed io.Reader
+	closeFile := file.Close
+
+	switch filepath.Ext(path) {
+	case ".gz":
+		decompressed, err = gzip.NewReader(file)
+	case ".xz":
+		decompressed, err = xz.NewReader(file, 0)
+	case ".zst":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(file)
+		if err == nil {
+			decompressed = decoder
+			closeFile = func() error {
+				decoder.Close()
+				return file.Close()
+			}
+		}
+	case ".bz2":
+		decompressed = bzip2.NewReader(file)
+	default:
+		decompressed = file
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %s", data.Location, err)
+	}
+
+	return &readCloser{decompressed, closeFile}, nil
+}
+
+// decodePackages calls fn for each package element, decoded into v
+func decodePackages(r io.Reader, v interface{}, fn func() error) (err error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		var token xml.Token
+		token, err = decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "package" {
+			continue
+		}
+
+		err = decoder.DecodeElement(v, &start)
+		if err != nil {
+			return
+		}
+
+		err = fn()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (e xmlEntry) entry() Entry {
+	return Entry{
+		Name:    e.Name,
+		Flags:   e.Flags,
+		Epoch:   e.Epoch,
+		Version: e.Version,
+		Release: e.Release,
+		Pre:     e.Pre == "1" || e.Pre == "true",
+	}
+}
+
+func xmlEntries(elements []xmlEntry) (entries []Entry) {
+	for _, e := range elements {
+		entries = append(entries, e.entry())
+	}
+
+	return
+}
+
+func xmlFiles(elements []xmlFile) (files []File) {
+	for _, f := range elements {
+		files = append(files, File{Path: f.Path, Type: f.Type, Digest: f.Hash})
+	}
+
+	return
+}
+
+func readPrimary(r io.Reader) (packages []*Package, err error) {
+	var p xmlPrimaryPackage
+
+	err = decodePackages(r, &p, func() error {
+		pkg := &Package{
+			Name:          p.Name,
+			Arch:          p.Arch,
+			Epoch:         p.Version.Epoch,
+			Version:       p.Version.Version,
+			Release:       p.Version.Release,
+			ChecksumType:  p.Checksum.Type,
+			Checksum:      strings.TrimSpace(p.Checksum.Value),
+			Summary:       p.Summary,
+			Description:   p.Description,
+			Packager:      p.Packager,
+			URL:           p.URL,
+			FileTime:      p.Time.File,
+			BuildTime:     p.Time.Build,
+			PackageSize:   p.Size.Package,
+			InstalledSize: p.Size.Installed,
+			ArchiveSize:   p.Size.Archive,
+			Location:      p.Location.Href,
+			License:       p.Format.License,
+			Vendor:        p.Format.Vendor,
+			Group:         p.Format.Group,
+			BuildHost:     p.Format.BuildHost,
+			SourceRpm:     p.Format.SourceRpm,
+			HeaderStart:   p.Format.HeaderRange.Start,
+			HeaderEnd:     p.Format.HeaderRange.End,
+			Provides:      xmlEntries(p.Format.Provides),
+			Requires:      xmlEntries(p.Format.Requires),
+			Conflicts:     xmlEntries(p.Format.Conflicts),
+			Obsoletes:     xmlEntries(p.Format.Obsoletes),
+			Recommends:    xmlEntries(p.Format.Recommends),
+			Suggests:      xmlEntries(p.Format.Suggests),
+			Supplements:   xmlEntries(p.Format.Supplements),
+			Enhances:      xmlEntries(p.Format.Enhances),
+			Files:         xmlFiles(p.Format.Files),
+		}
+		packages = append(packages, pkg)
+
+		// Elements are appended to slices of the reused struct
+		p = xmlPrimaryPackage{}
+
+		return nil
+	})
+
+	return
+}
+
+func readFilelists(r io.Reader, byID map[string]*Package) (err error) {
+	var p xmlFilelistsPackage
+
+	return decodePackages(r, &p, func() error {
+		if pkg, found := byID[p.PkgID]; found {
+			pkg.Files = xmlFiles(p.Files)
+			if p.Checksum.Type != "" {
+				pkg.FileDigestType = p.Checksum.Type
+			}
+		}
+		p = xmlFilelistsPackage{}
+
+		return nil
+	})
+}
+
+func readOther(r io.Reader, byID map[string]*Package) (err error) {
+	var p xmlOtherPackage
+
+	return decodePackages(r, &p, func() error {
+		if pkg, found := byID[p.PkgID]; found {
+			pkg.Changelogs = nil
+			for _, log := range p.Changelogs {
+				pkg.Changelogs = append(pkg.Changelogs, Changelog{log.Author, log.Date, log.Text})
+			}
+		}
+		p = xmlOtherPackage{}
+
+		return nil
+	})
+}
+
+//
+// sqlite variants of metadata, primary_db, filelists_db and other_db
+//
+
+// sqliteRow gets values of a row by column names
+type sqliteRow struct {
+	table  *sqlite.Table
+	values []interface{}
+}
+
+func (row sqliteRow) value(column string) interface{} {
+	i := row.table.ColumnIndex(column)
+	if i < 0 || i >= len(row.values) {
+		return nil
+	}
+
+	return row.values[i]
+}
+
+func (row sqliteRow) String(column string) string {
+	switch v := row.value(column).(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+
+	return ""
+}
+
+func (row sqliteRow) Int(column string) int64 {
+	switch v := row.value(column).(type) {
+	case int64:
+		return v
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+
+	return 0
+}
+
+func openDatabase(dir string, data RepoMDData, verify bool) (db *sqlite.Database, err error) {
+	r, err := openData(dir, data, verify)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return
+	}
+
+	return sqlite.New(content)
+}
+
+// eachRow calls fn for rows of the table. Missing tables are ignored
+// as old metadata may not have tables such as recommends.
+func eachRow(db *sqlite.Database, name string, fn func(row sqliteRow) error) (err error) {
+	table, err := db.Table(name)
+	if err != nil {
+		return nil
+	}
+
+	return table.Rows(func(rowid int64, values []interface{}) error {
+		return fn(sqliteRow{table, values})
+	})
+}
+
+// packageKeys maps pkgKey of packages table to the packages read by pkgId
+func packageKeys(db *sqlite.Database, byID map[string]*Package) (byKey map[int64]*Package, err error) {
+	byKey = make(map[int64]*Package)
+	err = eachRow(db, "packages", func(row sqliteRow) error {
+		if pkg, found := byID[row.String("pkgId")]; found {
+			byKey[row.Int("pkgKey")] = pkg
+		}
+		return nil
+	})
+
+	return
+}
+
+func readPrimaryDB(db *sqlite.Database) (packages []*Package, err error) {
+	byKey := make(map[int64]*Package)
+
+	err = eachRow(db, "packages", func(row sqliteRow) error {
+		pkg := &Package{
+			Name:          row.String("name"),
+			Arch:          row.String("arch"),
+			Epoch:         row.String("epoch"),
+			Version:       row.String("version"),
+			Release:       row.String("release"),
+			ChecksumType:  row.String("checksum_type"),
+			Checksum:      row.String("pkgId"),
+			Summary:       row.String("summary"),
+			Description:   row.String("description"),
+			Packager:      row.String("rpm_packager"),
+			URL:           row.String("url"),
+			FileTime:      row.Int("time_file"),
+			BuildTime:     row.Int("time_build"),
+			PackageSize:   row.Int("size_package"),
+			InstalledSize: row.Int("size_installed"),
+			ArchiveSize:   row.Int("size_archive"),
+			Location:      row.String("location_href"),
+			License:       row.String("rpm_license"),
+			Vendor:        row.String("rpm_vendor"),
+			Group:         row.String("rpm_group"),
+			BuildHost:     row.String("rpm_buildhost"),
+			SourceRpm:     row.String("rpm_sourcerpm"),
+			HeaderStart:   row.Int("rpm_header_start"),
+			HeaderEnd:     row.Int("rpm_header_end"),
+		}
+		byKey[row.Int("pkgKey")] = pkg
+		packages = append(packages, pkg)
+
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	tables := []struct {
+		name    string
+		entries func(*Package) *[]Entry
+	}{
+		{"provides", func(pkg *Package) *[]Entry { return &pkg.Provides }},
+		{"requires", func(pkg *Package) *[]Entry { return &pkg.Requires }},
+		{"conflicts", func(pkg *Package) *[]Entry { return &pkg.Conflicts }},
+		{"obsoletes", func(pkg *Package) *[]Entry { return &pkg.Obsoletes }},
+		{"recommends", func(pkg *Package) *[]Entry { return &pkg.Recommends }},
+		{"suggests", func(pkg *Package) *[]Entry { return &pkg.Suggests }},
+		{"supplements", func(pkg *Package) *[]Entry { return &pkg.Supplements }},
+		{"enhances", func(pkg *Package) *[]Entry { return &pkg.Enhances }},
+	}
+
+	for _, t := range tables {
+		entries := t.entries
+		err = eachRow(db, t.name, func(row sqliteRow) error {
+			pkg, found := byKey[row.Int("pkgKey")]
+			if !found {
+				return nil
+			}
+
+			pre := row.String("pre")
+			*entries(pkg) = append(*entries(pkg), Entry{
+				Name:    row.String("name"),
+				Flags:   row.String("flags"),
+				Epoch:   row.String("epoch"),
+				Version: row.String("version"),
+				Release: row.String("release"),
+				Pre:     pre == "1" || strings.EqualFold(pre, "TRUE"),
+			})
+
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	err = eachRow(db, "files", func(row sqliteRow) error {
+		if pkg, found := byKey[row.Int("pkgKey")]; found {
+			pkg.Files = append(pkg.Files, File{Path: row.String("name"), Type: fileType(row.String("type"))})
+		}
+		return nil
+	})
+
+	return
+}
+
+// fileType converts file types in sqlite metadata, "file" or "f" is a regular file
+func fileType(t string) string {
+	switch t {
+	case "dir", "d":
+		return FileTypeDir
+	case "ghost", "g":
+		return FileTypeGhost
+	}
+
+	return ""
+}
+
+func readFilelistsDB(db *sqlite.Database, byID map[string]*Package) (err error) {
+	byKey, err := packageKeys(db, byID)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range byKey {
+		pkg.Files = nil
+	}
+
+	// File names in a directory are joined by "/", and types are a character for each file
+	return eachRow(db, "filelist", func(row sqliteRow) error {
+		pkg, found := byKey[row.Int("pkgKey")]
+		if !found {
+			return nil
+		}
+
+		dirname := row.String("dirname")
+		types := row.String("filetypes")
+		for i, name := range strings.Split(row.String("filenames"), "/") {
+			file := File{Path: strings.TrimSuffix(dirname, "/") + "/" + name}
+			if i < len(types) {
+				file.Type = fileType(types[i : i+1])
+			}
+			pkg.Files = append(pkg.Files, file)
+		}
+
+		return nil
+	})
+}
+
+func readOtherDB(db *sqlite.Database, byID map[string]*Package) (err error) {
+	byKey, err := packageKeys(db, byID)
+	if err != nil {
+		return
+	}
+
+	return eachRow(db, "changelog", func(row sqliteRow) error {
+		if pkg, found := byKey[row.Int("pkgKey")]; found {
+			pkg.Changelogs = append(pkg.Changelogs,
+				Changelog{row.String("author"), row.Int("date"), row.String("changelog")})
+		}
+		return nil
+	})
+}
+
+// ReadRepo reads metadata of the repository at dir, which has repodata/repomd.xml.
+// XML metadata are preferred, and sqlite metadata are used if no XML is listed.
+func ReadRepo(dir string, options ReadOptions) (packages []*Package, err error) {
+	repomd, err := os.Open(filepath.Join(dir, RepoDataDir, "repomd.xml"))
+	if err != nil {
+		return
+	}
+	datas, err := ReadRepoMD(repomd)
+	repomd.Close()
+	if err != nil {
+		return nil, fmt.Errorf("repomd.xml: %s", err)
+	}
+
+	byType := make(map[string]RepoMDData)
+	for _, data := range datas {
+		byType[data.Type] = data
+	}
+
+	// withData reads the first type found in repomd.xml, only primary data is required
+	withData := func(types []string, readXML func(io.Reader) error, readDB func(*sqlite.Database) error) error {
+		for _, t := range types {
+			data, found := byType[t]
+			if !found {
+				continue
+			}
+
+			if strings.HasSuffix(t, "_db") {
+				db, err := openDatabase(dir, data, options.VerifyChecksum)
+				if err != nil {
+					return err
+				}
+				return readDB(db)
+			}
+
+			r, err := openData(dir, data, options.VerifyChecksum)
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+
+			err = readXML(r)
+			if err != nil {
+				return fmt.Errorf("%s: %s", data.Location, err)
+			}
+			return nil
+		}
+
+		if types[0] != "primary" {
+			return nil
+		}
+		return fmt.Errorf("None of %s found in repomd.xml", strings.Join(types, ", "))
+	}
+
+	err = withData([]string{"primary", "primary_db"},
+		func(r io.Reader) (err error) {
+			packages, err = readPrimary(r)
+			return
+		},
+		func(db *sqlite.Database) (err error) {
+			packages, err = readPrimaryDB(db)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	byID := make(map[string]*Package)
+	for _, pkg := range packages {
+		byID[pkg.Checksum] = pkg
+	}
+
+	if options.Filelists {
+		err = withData([]string{"filelists_ext", "filelists", "filelists_db"},
+			func(r io.Reader) error { return readFilelists(r, byID) },
+			func(db *sqlite.Database) error { return readFilelistsDB(db, byID) })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.Other {
+		err = withData([]string{"other", "other_db"},
+			func(r io.Reader) error { return readOther(r, byID) },
+			func(db *sqlite.Database) error { return readOtherDB(db, byID) })
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
@@ -0,0 +1,131 @@
+package repodata
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The repository of testdata/sqlite has only sqlite metadata, generated by testdata/generate.py
+const sqliteRepo = "testdata/sqlite"
+
+func TestReadRepoSQLite(t *testing.T) {
+	packages, err := ReadRepo(sqliteRepo, DefaultReadOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 2 {
+		t.Fatalf("ReadRepo() = %d packages", len(packages))
+	}
+
+	foo, bar := packages[0], packages[1]
+	if foo.Name != "foo" || foo.Epoch != "1" || foo.Version != "1.2" || foo.Release != "3" || foo.Arch != "x86_64" ||
+		foo.ChecksumType != "sha256" || foo.Checksum != strings.Repeat("a", 64) || foo.Location != "foo-1.2-3.x86_64.rpm" ||
+		foo.Summary != "Foo" || foo.URL != "https://example.com/foo" || foo.BuildTime != 1600000000 ||
+		foo.PackageSize != 10003 || foo.InstalledSize != 3 || foo.ArchiveSize != 515 ||
+		foo.SourceRpm != "foo-1.2-3.src.rpm" || foo.HeaderStart != 4504 || foo.HeaderEnd != 5000 {
+		t.Errorf("foo = %+v", foo)
+	}
+	if bar.Name != "bar" || bar.Epoch != "0" || bar.Location != "Packages/bar-2.0-1.noarch.rpm" {
+		t.Errorf("bar = %+v", bar)
+	}
+
+	// Values of pre are "TRUE" and "1" of createrepo_c versions
+	requires := []Entry{
+		{Name: "bar", Flags: FlagGE, Epoch: "0", Version: "2.0"},
+		{Name: "/bin/sh", Pre: true},
+	}
+	if !reflect.DeepEqual(foo.Requires, requires) {
+		t.Errorf("Requires of foo = %+v, want %+v", foo.Requires, requires)
+	}
+	requires = []Entry{{Name: "foo", Flags: FlagLT, Epoch: "2", Version: "1.0", Release: "1", Pre: true}}
+	if !reflect.DeepEqual(bar.Requires, requires) {
+		t.Errorf("Requires of bar = %+v, want %+v", bar.Requires, requires)
+	}
+	if len(foo.Provides) != 2 || len(foo.Conflicts) != 1 || len(foo.Obsoletes) != 1 || len(bar.Recommends) != 1 ||
+		foo.Enhances != nil {
+		t.Errorf("Dependencies of foo = %+v", foo)
+	}
+
+	files := []File{
+		{Path: "/etc/foo.d", Type: FileTypeDir},
+		{Path: "/usr/bin/foo"},
+		{Path: "/var/log/foo.log", Type: FileTypeGhost},
+		{Path: "/var/log/foo.old", Type: FileTypeGhost},
+	}
+	if !reflect.DeepEqual(foo.Files, files) {
+		t.Errorf("Files of foo = %+v, want %+v", foo.Files, files)
+	}
+	files = []File{{Path: "/usr/share/bar/README"}, {Path: "/usr/share/bar/doc", Type: FileTypeDir}}
+	if !reflect.DeepEqual(bar.Files, files) {
+		t.Errorf("Files of bar = %+v, want %+v", bar.Files, files)
+	}
+
+	// Packages of other.sqlite are matched by pkgId, not pkgKey
+	changelogs := []Changelog{
+		{"Foo <foo@example.com> - 1.2-3", 1600000000, "- Update"},
+		{"Foo <foo@example.com> - 1.1-1", 1500000000, "- Initial"},
+	}
+	if !reflect.DeepEqual(foo.Changelogs, changelogs) || bar.Changelogs != nil {
+		t.Errorf("Changelogs = %+v, %+v", foo.Changelogs, bar.Changelogs)
+	}
+
+	header := foo.Header()
+	deps, err := header.Requires()
+	if err != nil || len(deps) != 2 || deps[0].String() != "bar >= 2.0" || deps[1].Flags&rpmlib.RPMSENSE_PREREQ == 0 {
+		t.Errorf("Requires() = %v, %v", deps, err)
+	}
+	if header.NEVRA().String() != "foo-1:1.2-3.x86_64" {
+		t.Errorf("NEVRA() = %s", header.NEVRA())
+	}
+}
+
+func TestReadRepoSQLiteOptions(t *testing.T) {
+	packages, err := ReadRepo(sqliteRepo, ReadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of primary only
+	files := []File{{Path: "/usr/bin/foo"}, {Path: "/etc/foo.d", Type: FileTypeDir}}
+	if len(packages) != 2 || !reflect.DeepEqual(packages[0].Files, files) || packages[1].Files != nil ||
+		packages[0].Changelogs != nil {
+		t.Errorf("ReadRepo() = %+v", packages)
+	}
+}
+
+func TestReadRepoSQLiteChecksum(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, RepoDataDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	names, err := filepath.Glob(filepath.Join(sqliteRepo, RepoDataDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(name)
+		if err == nil && strings.HasPrefix(filepath.Base(name), "other.") {
+			data[len(data)-1] ^= 0xff
+		}
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, RepoDataDir, filepath.Base(name)), data, 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := ReadRepo(dir, DefaultReadOptions); err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Errorf("ReadRepo() = %v, want a checksum mismatch", err)
+	}
+	options := DefaultReadOptions
+	options.Other = false
+	if packages, err := ReadRepo(dir, options); err != nil || len(packages) != 2 {
+		t.Errorf("ReadRepo() without other = %d packages, %v", len(packages), err)
+	}
+}
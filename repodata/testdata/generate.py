@@ -0,0 +1,145 @@
+# Generates the repository of sqlite metadata with foo-1:1.2-3.x86_64 and bar-2.0-1.noarch,
+# same as createrepo_c --database --no-database-xml. Metadata are compressed differently
+# to read each compression, and there are no tables of weak dependencies but recommends.
+import bz2
+import gzip
+import hashlib
+import lzma
+import os
+import sqlite3
+
+os.chdir(os.path.dirname(os.path.abspath(__file__)))
+
+repodata = "sqlite/repodata"
+os.makedirs(repodata, exist_ok=True)
+for name in os.listdir(repodata):
+    os.remove(os.path.join(repodata, name))
+
+FOO = "a" * 64
+BAR = "b" * 64
+TIME = 1600000000
+
+packages = [
+    # pkgKey, pkgId, name, arch, epoch, version, release, summary, sourcerpm, size, location
+    (1, FOO, "foo", "x86_64", "1", "1.2", "3", "Foo", "foo-1.2-3.src.rpm", 3, "foo-1.2-3.x86_64.rpm"),
+    (2, BAR, "bar", "noarch", "0", "2.0", "1", "Bar", "bar-2.0-1.src.rpm", 6, "Packages/bar-2.0-1.noarch.rpm"),
+]
+
+dependencies = {
+    # pkgKey, name, flags, epoch, version, release, pre
+    "provides": [
+        (1, "foo", "EQ", "1", "1.2", "3", None),
+        (1, "libfoo.so.1()(64bit)", None, None, None, None, None),
+        (2, "bar", "EQ", "0", "2.0", "1", None),
+    ],
+    "requires": [
+        (1, "bar", "GE", "0", "2.0", None, "FALSE"),
+        (1, "/bin/sh", None, None, None, None, "TRUE"),
+        (2, "foo", "LT", "2", "1.0", "1", "1"),
+    ],
+    "conflicts": [(1, "baz", "LT", "0", "1.0", "1", None)],
+    "obsoletes": [(1, "foo-old", "LE", "1", "1.0", None, None)],
+    "recommends": [(2, "qux", None, None, None, None, None)],
+}
+
+# Only files in bin directories and /etc are in primary
+primary_files = [(1, "/usr/bin/foo", "file"), (1, "/etc/foo.d", "dir")]
+
+filelists = [
+    # pkgKey, dirname, filenames, filetypes
+    (1, "/etc", "foo.d", "d"),
+    (1, "/usr/bin", "foo", "f"),
+    (1, "/var/log", "foo.log/foo.old", "gg"),
+    (2, "/usr/share/bar", "README/doc", "fd"),
+]
+
+changelogs = [
+    # pkgKey, author, date, changelog
+    (1, "Foo <foo@example.com> - 1.2-3", TIME, "- Update"),
+    (1, "Foo <foo@example.com> - 1.1-1", TIME - 100000000, "- Initial"),
+]
+
+
+def database(path, schema, rows):
+    if os.path.exists(path):
+        os.remove(path)
+    db = sqlite3.connect(path)
+    db.execute("CREATE TABLE db_info (dbversion INTEGER, checksum TEXT)")
+    db.execute("INSERT INTO db_info VALUES (10, '')")
+    for statement in schema:
+        db.execute(statement)
+    for table, values in rows:
+        for row in values:
+            db.execute("INSERT INTO %s VALUES (%s)" % (table, ",".join("?" * len(row))), row)
+    db.commit()
+    db.execute("VACUUM")
+    db.close()
+    data = open(path, "rb").read()
+    os.remove(path)
+    return data
+
+
+dependency_columns = "(name TEXT, flags TEXT, epoch TEXT, version TEXT, release TEXT, pkgKey INTEGER%s)"
+primary = database("primary.sqlite", [
+    "CREATE TABLE packages (pkgKey INTEGER PRIMARY KEY, pkgId TEXT, name TEXT, arch TEXT, version TEXT, "
+    "epoch TEXT, release TEXT, summary TEXT, description TEXT, url TEXT, time_file INTEGER, time_build INTEGER, "
+    "rpm_license TEXT, rpm_vendor TEXT, rpm_group TEXT, rpm_buildhost TEXT, rpm_sourcerpm TEXT, "
+    "rpm_header_start INTEGER, rpm_header_end INTEGER, rpm_packager TEXT, size_package INTEGER, "
+    "size_installed INTEGER, size_archive INTEGER, location_href TEXT, location_base TEXT, checksum_type TEXT)",
+    "CREATE TABLE files (name TEXT, type TEXT, pkgKey INTEGER)",
+] + ["CREATE TABLE %s %s" % (table, dependency_columns % (", pre BOOLEAN DEFAULT FALSE" if table == "requires" else ""))
+     for table in dependencies], [
+    ("packages", [(key, pkgId, name, arch, version, epoch, release, summary, summary + ".", "https://example.com/" + name,
+                   TIME, TIME, "MIT", "", "Unspecified", "build.example.com", source, 4504, 5000, "",
+                   10000 + size, size, size + 512, location, None, "sha256")
+                  for key, pkgId, name, arch, epoch, version, release, summary, source, size, location in packages]),
+    ("files", [(name, kind, key) for key, name, kind in primary_files]),
+] + [(table, [(name, flags, epoch, version, release, key) + ((pre,) if table == "requires" else ())
+              for key, name, flags, epoch, version, release, pre in rows])
+     for table, rows in dependencies.items()])
+
+filelists_db = database("filelists.sqlite", [
+    "CREATE TABLE packages (pkgKey INTEGER PRIMARY KEY, pkgId TEXT)",
+    "CREATE TABLE filelist (pkgKey INTEGER, dirname TEXT, filenames TEXT, filetypes TEXT)",
+], [
+    ("packages", [(key, pkgId) for key, pkgId, *_ in packages]),
+    ("filelist", filelists),
+])
+
+# pkgKey of other differs from primary
+other_db = database("other.sqlite", [
+    "CREATE TABLE packages (pkgKey INTEGER PRIMARY KEY, pkgId TEXT)",
+    "CREATE TABLE changelog (pkgKey INTEGER, author TEXT, date INTEGER, changelog TEXT)",
+], [
+    ("packages", [(10, FOO), (20, BAR)]),
+    ("changelog", [(key * 10, author, date, text) for key, author, date, text in changelogs]),
+])
+
+datas = [
+    ("primary_db", primary, "primary.sqlite.bz2", bz2.compress),
+    ("filelists_db", filelists_db, "filelists.sqlite.xz", lzma.compress),
+    ("other_db", other_db, "other.sqlite.gz", lambda data: gzip.compress(data, mtime=0)),
+]
+
+repomd = ['<?xml version="1.0" encoding="UTF-8"?>',
+          '<repomd xmlns="http://linux.duke.edu/metadata/repo" xmlns:rpm="http://linux.duke.edu/metadata/rpm">',
+          '  <revision>%d</revision>' % TIME]
+for datatype, data, location, compress in datas:
+    compressed = compress(data)
+    with open(os.path.join(repodata, location), "wb") as f:
+        f.write(compressed)
+    repomd += [
+        '  <data type="%s">' % datatype,
+        '    <checksum type="sha256">%s</checksum>' % hashlib.sha256(compressed).hexdigest(),
+        '    <open-checksum type="sha256">%s</open-checksum>' % hashlib.sha256(data).hexdigest(),
+        '    <location href="repodata/%s"/>' % location,
+        '    <timestamp>%d</timestamp>' % TIME,
+        '    <size>%d</size>' % len(compressed),
+        '    <open-size>%d</open-size>' % len(data),
+        '    <database_version>10</database_version>',
+        '  </data>',
+    ]
+repomd.append('</repomd>')
+
+with open(os.path.join(repodata, "repomd.xml"), "w") as f:
+    f.write("\n".join(repomd) + "\n")
@@ -0,0 +1,231 @@
+package repodata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+const (
+	NamespaceCommon       = "http://linux.duke.edu/metadata/common"
+	NamespaceRpm          = "http://linux.duke.edu/metadata/rpm"
+	NamespaceFilelists    = "http://linux.duke.edu/metadata/filelists"
+	NamespaceFilelistsExt = "http://linux.duke.edu/metadata/filelists-ext"
+	NamespaceOther        = "http://linux.duke.edu/metadata/other"
+	NamespaceRepo         = "http://linux.duke.edu/metadata/repo"
+)
+
+// Files listed also in primary.xml, same as createrepo
+var primaryFilePattern = regexp.MustCompile(`^(.*bin/.*|/etc/.*|/usr/lib/sendmail)$`)
+
+// xmlWriter writes XML, keeping the first error
+type xmlWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newXMLWriter(w io.Writer) *xmlWriter {
+	return &xmlWriter{w: bufio.NewWriter(w)}
+}
+
+// Text is escaped same as createrepo, keeping newlines and tabs as they are.
+// Attributes are also escaped quotes and whitespaces, which are normalized by XML parsers.
+var (
+	textEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;",
+		"\n", "&#10;", "\r", "&#13;", "\t", "&#9;")
+)
+
+// inAttributes checks each verb of format is in an attribute value, as `name="%s"`
+func inAttributes(format string) (attributes []bool) {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 < len(format) && format[i+1] == '%' {
+			i++
+			continue
+		}
+		attributes = append(attributes, i > 0 && format[i-1] == '"')
+	}
+
+	return
+}
+
+// printf writes format, where %s of args are escaped
+func (x *xmlWriter) printf(format string, args ...interface{}) {
+	if x.err != nil {
+		return
+	}
+
+	attributes := inAttributes(format)
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			if i < len(attributes) && attributes[i] {
+				args[i] = attributeEscaper.Replace(s)
+			} else {
+				args[i] = textEscaper.Replace(s)
+			}
+		}
+	}
+
+	_, x.err = fmt.Fprintf(x.w, format, args...)
+}
+
+func (x *xmlWriter) flush() error {
+	if x.err != nil {
+		return x.err
+	}
+
+	return x.w.Flush()
+}
+
+func (x *xmlWriter) version(pkg *Package) {
+	x.printf("  <version epoch=\"%s\" ver=\"%s\" rel=\"%s\"/>\n", pkg.Epoch, pkg.Version, pkg.Release)
+}
+
+func (x *xmlWriter) entries(name string, entries []Entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	x.printf("    <rpm:%s>\n", name)
+	for _, e := range entries {
+		x.printf("      <rpm:entry name=\"%s\"", e.Name)
+		if e.Flags != "" {
+			x.printf(" flags=\"%s\" epoch=\"%s\" ver=\"%s\"", e.Flags, e.Epoch, e.Version)
+			if e.Release != "" {
+				x.printf(" rel=\"%s\"", e.Release)
+			}
+		}
+		if e.Pre {
+			x.printf(" pre=\"1\"")
+		}
+		x.printf("/>\n")
+	}
+	x.printf("    </rpm:%s>\n", name)
+}
+
+func (x *xmlWriter) file(indent string, file File, withDigest bool) {
+	x.printf("%s<file", indent)
+	if file.Type != "" {
+		x.printf(" type=\"%s\"", file.Type)
+	}
+	if withDigest && file.Type == "" && file.Digest != "" {
+		x.printf(" hash=\"%s\"", file.Digest)
+	}
+	x.printf(">%s</file>\n", file.Path)
+}
+
+func WritePrimary(w io.Writer, packages []*Package) (err error) {
+	x := newXMLWriter(w)
+
+	x.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	x.printf("<metadata xmlns=\"%s\" xmlns:rpm=\"%s\" packages=\"%d\">\n",
+		NamespaceCommon, NamespaceRpm, len(packages))
+
+	for _, pkg := range packages {
+		x.printf("<package type=\"rpm\">\n")
+		x.printf("  <name>%s</name>\n", pkg.Name)
+		x.printf("  <arch>%s</arch>\n", pkg.Arch)
+		x.version(pkg)
+		x.printf("  <checksum type=\"%s\" pkgid=\"YES\">%s</checksum>\n", pkg.ChecksumType, pkg.Checksum)
+		x.printf("  <summary>%s</summary>\n", pkg.Summary)
+		x.printf("  <description>%s</description>\n", pkg.Description)
+		x.printf("  <packager>%s</packager>\n", pkg.Packager)
+		x.printf("  <url>%s</url>\n", pkg.URL)
+		x.printf("  <time file=\"%d\" build=\"%d\"/>\n", pkg.FileTime, pkg.BuildTime)
+		x.printf("  <size package=\"%d\" installed=\"%d\" archive=\"%d\"/>\n",
+			pkg.PackageSize, pkg.InstalledSize, pkg.ArchiveSize)
+		x.printf("  <location href=\"%s\"/>\n", pkg.Location)
+
+		x.printf("  <format>\n")
+		x.printf("    <rpm:license>%s</rpm:license>\n", pkg.License)
+		x.printf("    <rpm:vendor>%s</rpm:vendor>\n", pkg.Vendor)
+		x.printf("    <rpm:group>%s</rpm:group>\n", pkg.Group)
+		x.printf("    <rpm:buildhost>%s</rpm:buildhost>\n", pkg.BuildHost)
+		x.printf("    <rpm:sourcerpm>%s</rpm:sourcerpm>\n", pkg.SourceRpm)
+		x.printf("    <rpm:header-range start=\"%d\" end=\"%d\"/>\n", pkg.HeaderStart, pkg.HeaderEnd)
+		x.entries("provides", pkg.Provides)
+		x.entries("requires", pkg.Requires)
+		x.entries("conflicts", pkg.Conflicts)
+		x.entries("obsoletes", pkg.Obsoletes)
+		x.entries("suggests", pkg.Suggests)
+		x.entries("enhances", pkg.Enhances)
+		x.entries("recommends", pkg.Recommends)
+		x.entries("supplements", pkg.Supplements)
+		for _, file := range pkg.Files {
+			if primaryFilePattern.MatchString(file.Path) {
+				x.file("    ", file, false)
+			}
+		}
+		x.printf("  </format>\n")
+		x.printf("</package>\n")
+	}
+
+	x.printf("</metadata>\n")
+
+	return x.flush()
+}
+
+func writeFilelists(w io.Writer, packages []*Package, ext bool) (err error) {
+	x := newXMLWriter(w)
+
+	x.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	if ext {
+		x.printf("<filelists-ext xmlns=\"%s\" packages=\"%d\">\n", NamespaceFilelistsExt, len(packages))
+	} else {
+		x.printf("<filelists xmlns=\"%s\" packages=\"%d\">\n", NamespaceFilelists, len(packages))
+	}
+
+	for _, pkg := range packages {
+		x.printf("<package pkgid=\"%s\" name=\"%s\" arch=\"%s\">\n", pkg.Checksum, pkg.Name, pkg.Arch)
+		x.version(pkg)
+		if ext && pkg.FileDigestType != "" {
+			x.printf("  <checksum type=\"%s\"/>\n", pkg.FileDigestType)
+		}
+		for _, file := range pkg.Files {
+			x.file("  ", file, ext)
+		}
+		x.printf("</package>\n")
+	}
+
+	if ext {
+		x.printf("</filelists-ext>\n")
+	} else {
+		x.printf("</filelists>\n")
+	}
+
+	return x.flush()
+}
+
+func WriteFilelists(w io.Writer, packages []*Package) (err error) {
+	return writeFilelists(w, packages, false)
+}
+
+// WriteFilelistsExt writes filelists-ext.xml, which has also digests of files
+func WriteFilelistsExt(w io.Writer, packages []*Package) (err error) {
+	return writeFilelists(w, packages, true)
+}
+
+func WriteOther(w io.Writer, packages []*Package) (err error) {
+	x := newXMLWriter(w)
+
+	x.printf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	x.printf("<otherdata xmlns=\"%s\" packages=\"%d\">\n", NamespaceOther, len(packages))
+
+	for _, pkg := range packages {
+		x.printf("<package pkgid=\"%s\" name=\"%s\" arch=\"%s\">\n", pkg.Checksum, pkg.Name, pkg.Arch)
+		x.version(pkg)
+		for _, log := range pkg.Changelogs {
+			x.printf("  <changelog author=\"%s\" date=\"%d\">%s</changelog>\n", log.Author, log.Date, log.Text)
+		}
+		x.printf("</package>\n")
+	}
+
+	x.printf("</otherdata>\n")
+
+	return x.flush()
+}
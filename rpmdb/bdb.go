@@ -0,0 +1,198 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+)
+
+// Berkeley DB hash database, used as /var/lib/rpm/Packages until rpm 4.16
+const (
+	bdbHashMagic = 0x061561
+
+	bdbPageHeaderSize = 26
+
+	// Page types
+	bdbPageHashUnsorted = 2
+	bdbPageOverflow     = 7
+	bdbPageHashMeta     = 8
+	bdbPageHash         = 13
+
+	// Item types on hash pages
+	bdbItemKeyData = 1
+	bdbItemOffPage = 3
+)
+
+// bdbBackend reads the hash pages of Packages. Keys are instance numbers and
+// data are header blobs, stored on the hash page or on chained overflow pages.
+type bdbBackend struct {
+	data     []byte
+	order    binary.ByteOrder
+	pageSize uint32
+	lastPage uint32
+}
+
+func openBDB(path string) (backend Backend, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	return newBDB(data)
+}
+
+func newBDB(data []byte) (b *bdbBackend, err error) {
+	if len(data) < 72 {
+		return nil, fmt.Errorf("Too short for a Berkeley DB file")
+	}
+
+	// Byte order of the database is the one of the host which created it
+	b = &bdbBackend{data: data}
+	switch {
+	case binary.LittleEndian.Uint32(data[12:16]) == bdbHashMagic:
+		b.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data[12:16]) == bdbHashMagic:
+		b.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("Not a Berkeley DB hash file")
+	}
+
+	if data[25] != bdbPageHashMeta {
+		return nil, fmt.Errorf("Metadata page type %d is invalid", data[25])
+	}
+	if data[24] != 0 {
+		return nil, fmt.Errorf("Encrypted database is not supported")
+	}
+
+	b.pageSize = b.order.Uint32(data[20:24])
+	b.lastPage = b.order.Uint32(data[32:36])
+	if b.pageSize < 512 || b.pageSize > 65536 {
+		return nil, fmt.Errorf("Page size %d is invalid", b.pageSize)
+	}
+
+	return
+}
+
+func (b *bdbBackend) page(pgno uint32) (page []byte, err error) {
+	start := int64(pgno) * int64(b.pageSize)
+	if pgno > b.lastPage || start+int64(b.pageSize) > int64(len(b.data)) {
+		return nil, fmt.Errorf("Page %d is out of the file", pgno)
+	}
+
+	return b.data[start : start+int64(b.pageSize)], nil
+}
+
+// overflow reads an item stored on the chain of overflow pages from pgno
+func (b *bdbBackend) overflow(pgno uint32, length uint32) (data []byte, err error) {
+	data = make([]byte, 0, length)
+	visited := make(map[uint32]bool)
+
+	for pgno != 0 && uint32(len(data)) < length {
+		if visited[pgno] {
+			return nil, fmt.Errorf("Overflow pages loop at page %d", pgno)
+		}
+		visited[pgno] = true
+
+		page, page_err := b.page(pgno)
+		if page_err != nil {
+			return nil, page_err
+		}
+		if page[25] != bdbPageOverflow {
+			return nil, fmt.Errorf("Page %d is not an overflow page", pgno)
+		}
+
+		// The high free offset is the used length on overflow pages
+		used := uint32(b.order.Uint16(page[22:24]))
+		if bdbPageHeaderSize+used > b.pageSize {
+			return nil, fmt.Errorf("Overflow page %d is broken", pgno)
+		}
+
+		data = append(data, page[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+		pgno = b.order.Uint32(page[16:20])
+	}
+
+	if uint32(len(data)) != length {
+		return nil, fmt.Errorf("Overflow item has %d bytes, expected %d", len(data), length)
+	}
+
+	return
+}
+
+// items returns key and data pairs on a hash page. Items are stored from
+// the end of the page, so each item ends at the start of the previous one.
+func (b *bdbBackend) items(pgno uint32, page []byte, fn func(key, data []byte) error) (err error) {
+	entries := int(b.order.Uint16(page[20:22]))
+	if bdbPageHeaderSize+entries*2 > len(page) {
+		return fmt.Errorf("Hash page %d has too many entries", pgno)
+	}
+
+	var values [2][]byte
+	end := len(page)
+	for i := 0; i < entries; i++ {
+		offset := int(b.order.Uint16(page[bdbPageHeaderSize+i*2:]))
+		if offset < bdbPageHeaderSize+entries*2 || offset >= end {
+			return fmt.Errorf("Item %d on hash page %d is broken", i, pgno)
+		}
+		item := page[offset:end]
+		end = offset
+
+		switch item[0] {
+		case bdbItemKeyData:
+			values[i%2] = item[1:]
+		case bdbItemOffPage:
+			if len(item) < 12 {
+				return fmt.Errorf("Item %d on hash page %d is broken", i, pgno)
+			}
+			values[i%2], err = b.overflow(b.order.Uint32(item[4:8]), b.order.Uint32(item[8:12]))
+			if err != nil {
+				return
+			}
+		default:
+			return fmt.Errorf("Item type %d on hash page %d is not supported", item[0], pgno)
+		}
+
+		if i%2 == 1 {
+			err = fn(values[0], values[1])
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+func (b *bdbBackend) Blobs(fn func(instance uint32, blob []byte) error) (err error) {
+	for pgno := uint32(1); pgno <= b.lastPage; pgno++ {
+		page, page_err := b.page(pgno)
+		if page_err != nil {
+			return page_err
+		}
+		if page[25] != bdbPageHash && page[25] != bdbPageHashUnsorted {
+			continue
+		}
+
+		err = b.items(pgno, page, func(key, data []byte) error {
+			if len(key) != 4 {
+				return nil
+			}
+
+			// The instance 0 holds the last instance number, not a header
+			instance := b.order.Uint32(key)
+			if instance == 0 {
+				return nil
+			}
+
+			return fn(instance, data)
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (b *bdbBackend) Close() error {
+	return nil
+}
@@ -0,0 +1,185 @@
+package rpmdb
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"path"
+	"strings"
+)
+
+type fileRef struct {
+	pkg     *Package
+	dirname string
+}
+
+type requirer struct {
+	pkg     *Package
+	require rpmlib.Dependency
+	// Simple dependencies of the require, which are terms of a rich dependency
+	terms []rpmlib.Dependency
+}
+
+// Index finds installed packages by name, owned file, provide or require,
+// same as the Name, Basenames, Providename and Requirename indexes of rpm
+type Index struct {
+	Packages  []*Package
+	names     map[string][]*Package
+	basenames map[string][]fileRef
+	provides  *rpmlib.ProvidesIndex
+	requires  map[string][]requirer
+	headers   map[*rpmlib.Header]*Package
+}
+
+// NewIndex builds the index of packages. A package with broken
+// file or dependency tags is only found by its name.
+func NewIndex(packages []*Package) (index *Index) {
+	index = &Index{
+		Packages:  packages,
+		names:     make(map[string][]*Package),
+		basenames: make(map[string][]fileRef),
+		provides:  rpmlib.NewProvidesIndex(),
+		requires:  make(map[string][]requirer),
+		headers:   make(map[*rpmlib.Header]*Package),
+	}
+
+	for _, pkg := range packages {
+		index.add(pkg)
+	}
+
+	return
+}
+
+func (index *Index) add(pkg *Package) {
+	header := pkg.Header
+	index.headers[header] = pkg
+	index.names[header.Name()] = append(index.names[header.Name()], pkg)
+
+	index.provides.Add(header)
+
+	requires, _ := header.Requires()
+	for _, require := range requires {
+		rich, err := require.Rich()
+		if err != nil {
+			continue
+		}
+
+		terms := rich.Dependencies()
+		for _, term := range terms {
+			index.requires[term.Name] = append(index.requires[term.Name], requirer{pkg, require, terms})
+		}
+	}
+
+	basenames, err := header.Section.GetStringArray(rpmlib.RPMTAG_BASENAMES)
+	if err != nil {
+		return
+	}
+	dirnames, err := header.Section.GetStringArray(rpmlib.RPMTAG_DIRNAMES)
+	if err != nil {
+		return
+	}
+	dirindexes, err := header.Section.GetInt32Array(rpmlib.RPMTAG_DIRINDEXES)
+	if err != nil || len(dirindexes) != len(basenames) {
+		return
+	}
+
+	for i, basename := range basenames {
+		if dirindexes[i] < 0 || int(dirindexes[i]) >= len(dirnames) {
+			continue
+		}
+		index.basenames[basename] = append(index.basenames[basename], fileRef{pkg, dirnames[dirindexes[i]]})
+	}
+}
+
+// ByName returns packages of the name, NVR, NVRA or NEVRA, such as "bash" or "bash-5.2-1.fc40.x86_64"
+func (index *Index) ByName(name string) (packages []*Package) {
+	if packages = index.names[name]; packages != nil {
+		return
+	}
+
+	for _, pkg := range index.Packages {
+		header := pkg.Header
+		nvr := header.Name() + "-" + header.Version() + "-" + header.Release()
+		nevra := header.NEVRA()
+		if name == nvr || name == nvr+"."+nevra.Arch || name == nevra.String() {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return
+}
+
+// WhatOwns returns packages which have the file of the absolute path
+func (index *Index) WhatOwns(filename string) (packages []*Package) {
+	filename = path.Clean(filename)
+	dirname, basename := path.Split(filename)
+
+	found := make(map[*Package]bool)
+	for _, ref := range index.basenames[basename] {
+		if found[ref.pkg] || path.Clean(ref.dirname) != path.Clean(dirname) {
+			continue
+		}
+
+		found[ref.pkg] = true
+		packages = append(packages, ref.pkg)
+	}
+
+	return
+}
+
+// WhatProvides returns packages which provide the capability, such as "libfoo.so.2()(64bit)"
+// or "foo >= 1.0". Files of packages are also provided, as rpm does.
+func (index *Index) WhatProvides(capability string) (packages []*Package, err error) {
+	dep, err := parseCapability(capability)
+	if err != nil {
+		return
+	}
+
+	for _, header := range index.provides.WhatProvides(dep) {
+		packages = append(packages, index.headers[header])
+	}
+
+	return
+}
+
+// WhatRequires returns packages which require the capability.
+// Terms of rich dependencies are also matched.
+func (index *Index) WhatRequires(capability string) (packages []*Package, err error) {
+	dep, err := parseCapability(capability)
+	if err != nil {
+		return
+	}
+
+	found := make(map[*Package]bool)
+	for _, r := range index.requires[dep.Name] {
+		if found[r.pkg] {
+			continue
+		}
+
+		// Terms are simple dependencies, which are matched without errors
+		match := false
+		for _, term := range r.terms {
+			satisfied, _ := rpmlib.Satisfies(dep, term)
+			match = match || satisfied
+		}
+
+		if match {
+			found[r.pkg] = true
+			packages = append(packages, r.pkg)
+		}
+	}
+
+	return
+}
+
+func parseCapability(capability string) (dep rpmlib.Dependency, err error) {
+	capability = strings.TrimSpace(capability)
+	if strings.HasPrefix(capability, "/") {
+		return rpmlib.Dependency{Name: path.Clean(capability)}, nil
+	}
+
+	rich, err := rpmlib.ParseRichDependency(capability)
+	if err != nil {
+		return
+	}
+
+	return rich.Dependency, nil
+}
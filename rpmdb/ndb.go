@@ -0,0 +1,138 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"io/ioutil"
+	"sort"
+)
+
+// ndb format of rpm, used as Packages.db by SUSE. All numbers are little endian.
+const (
+	ndbMagic     = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	ndbSlotMagic = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	ndbBlobMagic = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	ndbTailMagic = 'B' | 'l'<<8 | 'b'<<16 | 'E'<<24
+
+	ndbHeaderSize = 32
+	ndbSlotSize   = 16
+	ndbPageSize   = 4096
+	ndbBlockSize  = 16
+	ndbBlobSize   = 16
+	ndbTailSize   = 12
+)
+
+type ndbSlot struct {
+	pkgidx uint32
+	blkoff uint32
+	blkcnt uint32
+}
+
+// ndbBackend reads Packages.db, which has slot pages at the start and
+// blobs of headers with checksums in blocks after them
+type ndbBackend struct {
+	data  []byte
+	slots []ndbSlot
+}
+
+func openNDB(path string) (backend Backend, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	return newNDB(data)
+}
+
+func newNDB(data []byte) (b *ndbBackend, err error) {
+	if len(data) < ndbHeaderSize {
+		return nil, fmt.Errorf("Too short for a ndb file")
+	}
+	if binary.LittleEndian.Uint32(data[0:4]) != ndbMagic {
+		return nil, fmt.Errorf("Not a ndb file")
+	}
+	if version := binary.LittleEndian.Uint32(data[4:8]); version != 0 {
+		return nil, fmt.Errorf("ndb version %d is not supported", version)
+	}
+
+	npages := binary.LittleEndian.Uint32(data[12:16])
+	if uint64(npages)*ndbPageSize > uint64(len(data)) {
+		return nil, fmt.Errorf("Slot pages are out of the file")
+	}
+
+	b = &ndbBackend{data: data}
+	for offset := ndbHeaderSize; offset < int(npages)*ndbPageSize; offset += ndbSlotSize {
+		slot := data[offset : offset+ndbSlotSize]
+		if binary.LittleEndian.Uint32(slot[0:4]) != ndbSlotMagic {
+			return nil, fmt.Errorf("Slot at %d is broken", offset)
+		}
+
+		pkgidx := binary.LittleEndian.Uint32(slot[4:8])
+		if pkgidx == 0 {
+			continue
+		}
+
+		b.slots = append(b.slots, ndbSlot{
+			pkgidx: pkgidx,
+			blkoff: binary.LittleEndian.Uint32(slot[8:12]),
+			blkcnt: binary.LittleEndian.Uint32(slot[12:16]),
+		})
+	}
+
+	sort.Slice(b.slots, func(i, j int) bool { return b.slots[i].pkgidx < b.slots[j].pkgidx })
+
+	return
+}
+
+// blob reads a blob: its head, the header blob, padding to blocks and its tail
+func (b *ndbBackend) blob(slot ndbSlot) (blob []byte, err error) {
+	start := uint64(slot.blkoff) * ndbBlockSize
+	end := start + uint64(slot.blkcnt)*ndbBlockSize
+	if end > uint64(len(b.data)) || end-start < ndbBlobSize+ndbTailSize {
+		return nil, fmt.Errorf("Blob is out of the file")
+	}
+	data := b.data[start:end]
+
+	if binary.LittleEndian.Uint32(data[0:4]) != ndbBlobMagic {
+		return nil, fmt.Errorf("Blob has no magic")
+	}
+	if binary.LittleEndian.Uint32(data[4:8]) != slot.pkgidx {
+		return nil, fmt.Errorf("Blob is for instance %d", binary.LittleEndian.Uint32(data[4:8]))
+	}
+
+	length := uint64(binary.LittleEndian.Uint32(data[12:16]))
+	if (ndbBlobSize+length+ndbTailSize+ndbBlockSize-1)/ndbBlockSize != uint64(slot.blkcnt) {
+		return nil, fmt.Errorf("Blob length %d does not match its blocks", length)
+	}
+
+	tail := data[len(data)-ndbTailSize:]
+	if binary.LittleEndian.Uint32(tail[8:12]) != ndbTailMagic || uint64(binary.LittleEndian.Uint32(tail[4:8])) != length {
+		return nil, fmt.Errorf("Blob tail is broken")
+	}
+	if binary.LittleEndian.Uint32(tail[0:4]) != adler32.Checksum(data[:len(data)-ndbTailSize]) {
+		return nil, fmt.Errorf("Blob checksum mismatch")
+	}
+
+	return data[ndbBlobSize : ndbBlobSize+length], nil
+}
+
+func (b *ndbBackend) Blobs(fn func(instance uint32, blob []byte) error) (err error) {
+	for _, slot := range b.slots {
+		blob, blob_err := b.blob(slot)
+		if blob_err != nil {
+			return fmt.Errorf("Instance %d: %s", slot.pkgidx, blob_err)
+		}
+
+		err = fn(slot.pkgidx, blob)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func (b *ndbBackend) Close() error {
+	return nil
+}
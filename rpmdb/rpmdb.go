@@ -0,0 +1,87 @@
+package rpmdb
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDBPath is the database directory of rpm, relative to the root
+const DefaultDBPath = "/var/lib/rpm"
+
+// Backend reads header blobs of installed packages from a database file
+type Backend interface {
+	// Blobs calls fn for each header blob with its instance number
+	Blobs(fn func(instance uint32, blob []byte) error) error
+	Close() error
+}
+
+type backendType struct {
+	name string
+	file string
+	open func(path string) (Backend, error)
+}
+
+// Database files in dbpath, tried in this order
+var backendTypes = []backendType{
+	{"sqlite", "rpmdb.sqlite", openSQLite},
+	{"ndb", "Packages.db", openNDB},
+	{"bdb", "Packages", openBDB},
+}
+
+// Package is an installed package
+type Package struct {
+	Instance uint32
+	Header   *rpmlib.Header
+}
+
+type DB struct {
+	Path    string
+	Type    string
+	backend Backend
+}
+
+// Open opens the database in dbpath, such as /var/lib/rpm.
+// The format is detected by the database file found in dbpath.
+func Open(dbpath string) (db *DB, err error) {
+	for _, t := range backendTypes {
+		path := filepath.Join(dbpath, t.file)
+		if _, stat_err := os.Stat(path); stat_err != nil {
+			continue
+		}
+
+		var backend Backend
+		backend, err = t.open(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+
+		return &DB{Path: path, Type: t.name, backend: backend}, nil
+	}
+
+	return nil, fmt.Errorf("No rpm database found in %s", dbpath)
+}
+
+func (db *DB) Close() error {
+	return db.backend.Close()
+}
+
+// Packages reads headers of all installed packages in the order of the database
+func (db *DB) Packages() (packages []*Package, err error) {
+	err = db.backend.Blobs(func(instance uint32, blob []byte) error {
+		header, err := rpmlib.ScanHeaderBlob(blob)
+		if err != nil {
+			return fmt.Errorf("Header of instance %d: %s", instance, err)
+		}
+
+		packages = append(packages, &Package{instance, header})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", db.Path, err)
+	}
+
+	return
+}
@@ -0,0 +1,216 @@
+package rpmdb
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Databases in testdata are generated by testdata/generate.py
+
+// openTestDB reads packages of the database in dbpath, which has foo and bar
+func openTestDB(t *testing.T, dbpath string, dbtype string) (packages []*Package) {
+	db, err := Open(dbpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if db.Type != dbtype {
+		t.Errorf("Type = %s, want %s", db.Type, dbtype)
+	}
+
+	packages, err = db.Packages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nevras []string
+	for _, pkg := range packages {
+		nevras = append(nevras, pkg.Header.NEVRA().String())
+	}
+	if strings.Join(nevras, " ") != "foo-1.0-1.x86_64 bar-2.0-1.noarch" {
+		t.Errorf("Packages() = %v", nevras)
+	}
+
+	return
+}
+
+func TestSQLite(t *testing.T) {
+	packages := openTestDB(t, "testdata/sqlite", "sqlite")
+
+	// The removed instance is skipped
+	if packages[0].Instance != 1 || packages[1].Instance != 3 {
+		t.Errorf("Instances %d and %d", packages[0].Instance, packages[1].Instance)
+	}
+	// The header of bar overflows pages
+	if description := packages[1].Header.Description(); len(description) != 6000 {
+		t.Errorf("Description of %d bytes", len(description))
+	}
+}
+
+func TestOpenMissing(t *testing.T) {
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Errorf("Open() of an empty directory succeeded")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex(openTestDB(t, "testdata/sqlite", "sqlite"))
+
+	names := func(packages []*Package) string {
+		var names []string
+		for _, pkg := range packages {
+			names = append(names, pkg.Header.Name())
+		}
+		return strings.Join(names, " ")
+	}
+
+	for name, expected := range map[string]string{
+		"foo": "foo", "foo-1.0-1": "foo", "foo-1.0-1.x86_64": "foo", "bar-2.0-1.noarch": "bar",
+		"baz": "", "foo-1.0-2": "",
+	} {
+		if found := names(index.ByName(name)); found != expected {
+			t.Errorf("ByName(%s) = %q, want %q", name, found, expected)
+		}
+	}
+
+	for filename, expected := range map[string]string{
+		"/usr/bin/foo": "foo", "/usr/bin/../bin/foo": "foo", "/usr/share/bar/README": "bar",
+		"/usr/share/foo/README": "", "/etc": "",
+	} {
+		if found := names(index.WhatOwns(filename)); found != expected {
+			t.Errorf("WhatOwns(%s) = %q, want %q", filename, found, expected)
+		}
+	}
+
+	for capability, expected := range map[string]string{
+		"libfoo.so.1()(64bit)": "foo",
+		"foo > 1.0":            "",
+		"bar >= 2.0":           "bar",
+		// A release-less range matches any release
+		"bar < 2.0-2":   "bar",
+		"bar > 2.0":     "",
+		"/etc/foo.conf": "foo",
+		"/usr/bin/bar":  "",
+	} {
+		found, err := index.WhatProvides(capability)
+		if err != nil || names(found) != expected {
+			t.Errorf("WhatProvides(%s) = %q, %v, want %q", capability, names(found), err, expected)
+		}
+	}
+
+	for capability, expected := range map[string]string{
+		"bar":         "foo",
+		"bar = 2.0-1": "foo",
+		"bar = 1.0":   "",
+		"/bin/sh":     "foo",
+		"foo":         "",
+	} {
+		found, err := index.WhatRequires(capability)
+		if err != nil || names(found) != expected {
+			t.Errorf("WhatRequires(%s) = %q, %v, want %q", capability, names(found), err, expected)
+		}
+	}
+
+	if _, err := index.WhatProvides("(foo or"); err == nil {
+		t.Errorf("WhatProvides() of a broken capability succeeded")
+	}
+}
+
+func TestBDB(t *testing.T) {
+	for _, dbpath := range []string{"testdata/bdb", "testdata/bdb-be"} {
+		packages := openTestDB(t, dbpath, "bdb")
+
+		// The header of bar is on overflow pages
+		if packages[0].Instance != 1 || packages[1].Instance != 3 || len(packages[1].Header.Description()) != 6000 {
+			t.Errorf("%s: instances %d and %d", dbpath, packages[0].Instance, packages[1].Instance)
+		}
+	}
+}
+
+func TestBDBBroken(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bdb/Packages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pageSize = 4096
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+		err    string
+	}{
+		{"not bdb", func(data []byte) []byte { data[12] = 0; return data }, "Not a Berkeley DB hash file"},
+		{"encrypted", func(data []byte) []byte { data[24] = 1; return data }, "Encrypted"},
+		{"truncated", func(data []byte) []byte { return data[:3*pageSize] }, "Page 3 is out of the file"},
+		// The second overflow page links to the first one
+		{"overflow loop", func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[3*pageSize+16:], 2)
+			binary.LittleEndian.PutUint16(data[3*pageSize+22:], 1)
+			return data
+		}, "Overflow pages loop at page 2"},
+		{"not overflow", func(data []byte) []byte { data[2*pageSize+25] = 13; return data }, "Page 2 is not an overflow page"},
+		{"short overflow", func(data []byte) []byte {
+			binary.LittleEndian.PutUint16(data[3*pageSize+22:], 10)
+			return data
+		}, "Overflow item has"},
+		{"broken item", func(data []byte) []byte {
+			binary.LittleEndian.PutUint16(data[pageSize+26:], 10)
+			return data
+		}, "Item 0 on hash page 1 is broken"},
+	}
+
+	for _, test := range tests {
+		b, err := newBDB(test.modify(append([]byte{}, data...)))
+		if err == nil {
+			err = b.Blobs(func(uint32, []byte) error { return nil })
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestNDB(t *testing.T) {
+	packages := openTestDB(t, "testdata/ndb", "ndb")
+
+	if packages[0].Instance != 1 || packages[1].Instance != 3 || len(packages[1].Header.Description()) != 6000 {
+		t.Errorf("Instances %d and %d", packages[0].Instance, packages[1].Instance)
+	}
+}
+
+func TestNDBBroken(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/ndb/Packages.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The blob of foo is at the first block after the slot page
+	const blob = 4096
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+		err    string
+	}{
+		{"not ndb", func(data []byte) []byte { data[0] = 0; return data }, "Not a ndb file"},
+		{"version", func(data []byte) []byte { data[4] = 1; return data }, "ndb version 1"},
+		{"slot magic", func(data []byte) []byte { data[32] = 0; return data }, "Slot at 32 is broken"},
+		{"truncated", func(data []byte) []byte { return data[:blob+100] }, "Blob is out of the file"},
+		{"checksum mismatch", func(data []byte) []byte { data[blob+100] ^= 0xff; return data }, "Blob checksum mismatch"},
+		{"blob magic", func(data []byte) []byte { data[blob] = 0; return data }, "Blob has no magic"},
+		{"instance", func(data []byte) []byte { data[blob+4] = 2; return data }, "Blob is for instance 2"},
+		{"length", func(data []byte) []byte { data[blob+12] ^= 0x80; return data }, "does not match its blocks"},
+	}
+
+	for _, test := range tests {
+		b, err := newNDB(test.modify(append([]byte{}, data...)))
+		if err == nil {
+			err = b.Blobs(func(uint32, []byte) error { return nil })
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
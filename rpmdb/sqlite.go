@@ -0,0 +1,48 @@
+package rpmdb
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/sqlite"
+)
+
+// sqliteBackend reads rpmdb.sqlite of rpm 4.16 or later,
+// which has headers in the table "Packages" (hnum INTEGER PRIMARY KEY, blob BLOB)
+type sqliteBackend struct {
+	db *sqlite.Database
+}
+
+func openSQLite(path string) (backend Backend, err error) {
+	db, err := sqlite.Open(path)
+	if err != nil {
+		return
+	}
+
+	return &sqliteBackend{db}, nil
+}
+
+func (b *sqliteBackend) Blobs(fn func(instance uint32, blob []byte) error) (err error) {
+	table, err := b.db.Table("Packages")
+	if err != nil {
+		return
+	}
+
+	hnum := table.ColumnIndex("hnum")
+	column := table.ColumnIndex("blob")
+	if hnum < 0 || column < 0 {
+		return fmt.Errorf("Table Packages has no hnum or blob")
+	}
+
+	return table.Rows(func(rowid int64, values []interface{}) error {
+		instance, _ := values[hnum].(int64)
+		blob, ok := values[column].([]byte)
+		if !ok {
+			return fmt.Errorf("Blob of instance %d is not a blob", instance)
+		}
+
+		return fn(uint32(instance), blob)
+	})
+}
+
+func (b *sqliteBackend) Close() error {
+	return nil
+}
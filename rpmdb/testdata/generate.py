@@ -0,0 +1,108 @@
+# Generates the test databases from header blobs of foo-1.0-1.x86_64 and bar-2.0-1.noarch,
+# which are headers of packages without the magic of the header section
+import os
+import sqlite3
+
+os.chdir(os.path.dirname(os.path.abspath(__file__)))
+
+blobs = [open(name, "rb").read() for name in ["foo.blob", "bar.blob"]]
+
+# rpmdb.sqlite of rpm 4.16, where the instance 2 is removed
+path = "sqlite/rpmdb.sqlite"
+if os.path.exists(path):
+    os.remove(path)
+db = sqlite3.connect(path)
+db.execute("CREATE TABLE IF NOT EXISTS 'Packages' (hnum INTEGER PRIMARY KEY AUTOINCREMENT,blob BLOB NOT NULL)")
+db.execute("CREATE TABLE IF NOT EXISTS 'Name' (key 'TEXT' NOT NULL,hnum INTEGER NOT NULL,idx INTEGER NOT NULL,"
+           "FOREIGN KEY (hnum) REFERENCES 'Packages'(hnum))")
+db.execute("INSERT INTO Packages VALUES (1, ?)", (blobs[0],))
+db.execute("INSERT INTO Packages VALUES (2, ?)", (blobs[0],))
+db.execute("INSERT INTO Packages VALUES (3, ?)", (blobs[1],))
+db.execute("DELETE FROM Packages WHERE hnum = 2")
+db.execute("INSERT INTO Name VALUES ('foo', 1, 0), ('bar', 3, 0)")
+db.commit()
+db.close()
+
+import struct
+import zlib
+
+
+def bdb(order):
+    """Packages of Berkeley DB hash with 4096 bytes pages: the metadata page,
+    a hash page with foo and the last instance number, and overflow pages of bar"""
+    page_size = 4096
+    pages = [bytearray(page_size) for _ in range(4)]
+
+    def page_header(page, pgno, next_pgno, entries, hf_offset, page_type):
+        struct.pack_into(order + "QIIIHHBB", page, 0, 0, pgno, 0, next_pgno, entries, hf_offset, 0, page_type)
+
+    # Metadata: magic, version, page size, encryption, type, flags, free list and the last page
+    meta = pages[0]
+    struct.pack_into(order + "QIIIIBBBxII", meta, 0, 0, 0, 0x061561, 9, page_size, 0, 8, 0, 0, 3)
+
+    # Items of the hash page are stored from the end of the page, key and data in turn
+    items = [
+        b"\x01" + struct.pack(order + "I", 0), b"\x01" + struct.pack(order + "I", 3),
+        b"\x01" + struct.pack(order + "I", 1), b"\x01" + blobs[0],
+        b"\x01" + struct.pack(order + "I", 3), b"\x03\x00\x00\x00" + struct.pack(order + "II", 2, len(blobs[1])),
+    ]
+    hash_page = pages[1]
+    end = page_size
+    for i, item in enumerate(items):
+        end -= len(item)
+        hash_page[end:end + len(item)] = item
+        struct.pack_into(order + "H", hash_page, 26 + i * 2, end)
+    page_header(hash_page, 1, 0, len(items), end, 13)
+
+    # Overflow pages have the used length as the high free offset
+    capacity = page_size - 26
+    for i, pgno in enumerate([2, 3]):
+        chunk = blobs[1][i * capacity:(i + 1) * capacity]
+        pages[pgno][26:26 + len(chunk)] = chunk
+        page_header(pages[pgno], pgno, 3 if pgno == 2 else 0, 1, len(chunk), 7)
+
+    return b"".join(pages)
+
+
+for directory, order in [("bdb", "<"), ("bdb-be", ">")]:
+    os.makedirs(directory, exist_ok=True)
+    with open(os.path.join(directory, "Packages"), "wb") as f:
+        f.write(bdb(order))
+
+
+def ndb():
+    """Packages.db of ndb with a slot page of foo, an empty slot and bar,
+    and blobs with adler32 checksums in 16 bytes blocks after it"""
+    page_size, block_size = 4096, 16
+    data = bytearray(page_size)
+    struct.pack_into("<4sIII", data, 0, b"RpmP", 0, 1, 1)
+
+    slots = []
+    for pkgidx, blob in [(1, blobs[0]), (0, None), (3, blobs[1])]:
+        if blob is None:
+            slots.append(struct.pack("<4sIII", b"Slot", 0, 0, 0))
+            continue
+
+        blkoff = len(data) // block_size
+        body = struct.pack("<4sIII", b"BlbS", pkgidx, 1, len(blob)) + blob
+        padding = -(len(body) + 12) % block_size
+        body += b"\x00" * padding
+        body += struct.pack("<II4s", zlib.adler32(body), len(blob), b"BlbE")
+        data += body
+        slots.append(struct.pack("<4sIII", b"Slot", pkgidx, blkoff, len(body) // block_size))
+
+    offset = 32
+    for slot in slots:
+        data[offset:offset + 16] = slot
+        offset += 16
+    # Unused slots fill the rest of the slot page
+    while offset < page_size:
+        data[offset:offset + 16] = struct.pack("<4sIII", b"Slot", 0, 0, 0)
+        offset += 16
+
+    return bytes(data)
+
+
+os.makedirs("ndb", exist_ok=True)
+with open("ndb/Packages.db", "wb") as f:
+    f.write(ndb())
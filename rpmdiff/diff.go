@@ -0,0 +1,425 @@
+package rpmdiff
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"sort"
+	"strings"
+)
+
+//
+// Differences between two packages, such as an old and a new version of a vendor package
+//
+
+// Header fields to compare, formatted by query formats
+var Fields = []string{
+	"NAME", "EPOCH", "VERSION", "RELEASE", "ARCH", "SUMMARY", "DESCRIPTION", "LICENSE", "GROUP",
+	"URL", "VENDOR", "PACKAGER", "DISTRIBUTION", "BUILDHOST", "BUILDTIME", "SOURCERPM", "SIZE",
+	"PREIN", "PREINPROG", "POSTIN", "POSTINPROG", "PREUN", "PREUNPROG", "POSTUN", "POSTUNPROG",
+	"PRETRANS", "PRETRANSPROG", "POSTTRANS", "POSTTRANSPROG",
+}
+
+// Query formats of fields which are not strings
+var fieldFormats = map[string]string{
+	"BUILDTIME":     "%{BUILDTIME:date}",
+	"PREINPROG":     "[%{PREINPROG} ]",
+	"POSTINPROG":    "[%{POSTINPROG} ]",
+	"PREUNPROG":     "[%{PREUNPROG} ]",
+	"POSTUNPROG":    "[%{POSTUNPROG} ]",
+	"PRETRANSPROG":  "[%{PRETRANSPROG} ]",
+	"POSTTRANSPROG": "[%{POSTTRANSPROG} ]",
+}
+
+// Kinds of dependencies to compare
+var dependencyKinds = []struct {
+	name string
+	get  func(header *rpmlib.Header) ([]rpmlib.Dependency, error)
+}{
+	{"PROVIDES", (*rpmlib.Header).Provides},
+	{"REQUIRES", (*rpmlib.Header).Requires},
+	{"CONFLICTS", (*rpmlib.Header).Conflicts},
+	{"OBSOLETES", (*rpmlib.Header).Obsoletes},
+	{"RECOMMENDS", (*rpmlib.Header).Recommends},
+	{"SUGGESTS", (*rpmlib.Header).Suggests},
+	{"SUPPLEMENTS", (*rpmlib.Header).Supplements},
+	{"ENHANCES", (*rpmlib.Header).Enhances},
+}
+
+// Kinds of changes of files
+const (
+	FileAdded   = "added"
+	FileRemoved = "removed"
+	FileChanged = "changed"
+)
+
+// Attributes of files which are compared, with letters of text output
+var fileAttributes = []struct {
+	name   string
+	letter byte
+	differ func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool
+}{
+	{"size", 'S', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.Size != b.Size }},
+	{"mode", 'M', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.Mode != b.Mode }},
+	{"digest", '5', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.Digest != b.Digest }},
+	{"link", 'L', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.LinkTo != b.LinkTo }},
+	{"user", 'U', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.User != b.User }},
+	{"group", 'G', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.Group != b.Group }},
+	{"flags", 'F', func(a *rpmlib.FileEntry, b *rpmlib.FileEntry) bool { return a.Flags != b.Flags }},
+}
+
+type FieldChange struct {
+	Field string `json:"field" yaml:"field"`
+	Old   string `json:"old" yaml:"old"`
+	New   string `json:"new" yaml:"new"`
+}
+
+// DependencyChange is an added dependency without Old, a removed one without New,
+// or a changed one which has the same name
+type DependencyChange struct {
+	Kind string             `json:"kind" yaml:"kind"`
+	Old  *rpmlib.Dependency `json:"old,omitempty" yaml:"old,omitempty"`
+	New  *rpmlib.Dependency `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+type FileChange struct {
+	Path   string `json:"path" yaml:"path"`
+	Change string `json:"change" yaml:"change"`
+	// Attributes which differ, such as "size" and "digest"
+	Attributes []string          `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Old        *rpmlib.FileEntry `json:"old,omitempty" yaml:"old,omitempty"`
+	New        *rpmlib.FileEntry `json:"new,omitempty" yaml:"new,omitempty"`
+	// Unified diff of a text file, with Options.Contents
+	Diff string `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+type PackageDiff struct {
+	Old          string             `json:"old" yaml:"old"`
+	New          string             `json:"new" yaml:"new"`
+	Fields       []FieldChange      `json:"fields" yaml:"fields"`
+	Dependencies []DependencyChange `json:"dependencies" yaml:"dependencies"`
+	Files        []FileChange       `json:"files" yaml:"files"`
+}
+
+type Options struct {
+	// Unified diffs of changed text files from the payloads
+	Contents bool
+	// Lines of context of unified diffs
+	Context int
+	// Header fields not to compare, such as "BUILDTIME"
+	IgnoreFields []string
+}
+
+var DefaultOptions = Options{Context: 3}
+
+// Empty checks the packages have no differences
+func (diff *PackageDiff) Empty() bool {
+	return len(diff.Fields) == 0 && len(diff.Dependencies) == 0 && len(diff.Files) == 0
+}
+
+// Compare returns differences from the old package to the new package.
+// Payloads are read only for unified diffs.
+func Compare(old *rpmlib.PackageFile, new *rpmlib.PackageFile, options Options) (diff *PackageDiff, err error) {
+	diff = &PackageDiff{
+		Old:          old.Header.NEVRA().String(),
+		New:          new.Header.NEVRA().String(),
+		Fields:       []FieldChange{},
+		Dependencies: []DependencyChange{},
+		Files:        []FileChange{},
+	}
+
+	diff.Fields, err = compareFields(old.Header, new.Header, options.IgnoreFields)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.Dependencies, err = compareDependencies(old.Header, new.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.Files, err = compareFiles(old.Header, new.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Contents {
+		err = diffContents(old, new, diff.Files, options.Context)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+func compareFields(old *rpmlib.Header, new *rpmlib.Header, ignore []string) (changes []FieldChange, err error) {
+	changes = []FieldChange{}
+
+	for _, field := range Fields {
+		ignored := false
+		for _, name := range ignore {
+			ignored = ignored || strings.EqualFold(name, field)
+		}
+		if ignored {
+			continue
+		}
+
+		format, found := fieldFormats[field]
+		if !found {
+			format = "%{" + field + "}"
+		}
+		qf, parse_err := rpmlib.ParseQueryFormat(format)
+		if parse_err != nil {
+			return nil, parse_err
+		}
+
+		oldValue, format_err := qf.Format(old)
+		if format_err != nil {
+			return nil, format_err
+		}
+		newValue, format_err := qf.Format(new)
+		if format_err != nil {
+			return nil, format_err
+		}
+
+		if oldValue != newValue {
+			changes = append(changes, FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	return
+}
+
+func compareDependencies(old *rpmlib.Header, new *rpmlib.Header) (changes []DependencyChange, err error) {
+	changes = []DependencyChange{}
+
+	for _, kind := range dependencyKinds {
+		oldDeps, get_err := kind.get(old)
+		if get_err != nil {
+			return nil, get_err
+		}
+		newDeps, get_err := kind.get(new)
+		if get_err != nil {
+			return nil, get_err
+		}
+
+		// Same dependencies in both are not changes
+		removed, added := subtract(oldDeps, newDeps), subtract(newDeps, oldDeps)
+
+		// A dependency is changed if only one with the name is removed and added
+		count := func(deps []rpmlib.Dependency, name string) (n int) {
+			for _, dep := range deps {
+				if dep.Name == name {
+					n++
+				}
+			}
+			return
+		}
+
+		for i := range removed {
+			change := DependencyChange{Kind: kind.name, Old: &removed[i]}
+			if count(removed, removed[i].Name) == 1 && count(added, removed[i].Name) == 1 {
+				for j := range added {
+					if added[j].Name == removed[i].Name {
+						change.New = &added[j]
+					}
+				}
+			}
+			changes = append(changes, change)
+		}
+		for i := range added {
+			if count(removed, added[i].Name) == 1 && count(added, added[i].Name) == 1 {
+				continue
+			}
+			changes = append(changes, DependencyChange{Kind: kind.name, New: &added[i]})
+		}
+	}
+
+	return
+}
+
+// subtract returns dependencies of a which are not in b
+func subtract(a []rpmlib.Dependency, b []rpmlib.Dependency) (deps []rpmlib.Dependency) {
+	for _, dep := range a {
+		found := false
+		for _, other := range b {
+			found = found || dep == other
+		}
+		if !found {
+			deps = append(deps, dep)
+		}
+	}
+
+	return
+}
+
+func compareFiles(old *rpmlib.Header, new *rpmlib.Header) (changes []FileChange, err error) {
+	changes = []FileChange{}
+
+	oldFiles, err := fileEntries(old)
+	if err != nil {
+		return
+	}
+	newFiles, err := fileEntries(new)
+	if err != nil {
+		return
+	}
+
+	var paths []string
+	for path := range oldFiles {
+		paths = append(paths, path)
+	}
+	for path := range newFiles {
+		if _, found := oldFiles[path]; !found {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		oldFile, newFile := oldFiles[path], newFiles[path]
+
+		switch {
+		case newFile == nil:
+			changes = append(changes, FileChange{Path: path, Change: FileRemoved, Old: oldFile})
+		case oldFile == nil:
+			changes = append(changes, FileChange{Path: path, Change: FileAdded, New: newFile})
+		default:
+			var attributes []string
+			for _, attribute := range fileAttributes {
+				if attribute.differ(oldFile, newFile) {
+					attributes = append(attributes, attribute.name)
+				}
+			}
+			if len(attributes) > 0 {
+				changes = append(changes, FileChange{
+					Path: path, Change: FileChanged, Attributes: attributes, Old: oldFile, New: newFile})
+			}
+		}
+	}
+
+	return
+}
+
+func fileEntries(header *rpmlib.Header) (entries map[string]*rpmlib.FileEntry, err error) {
+	entries = make(map[string]*rpmlib.FileEntry)
+
+	// Packages without files have no file lists
+	if !header.HasStore(rpmlib.RPMTAG_BASENAMES) && !header.HasStore(rpmlib.RPMTAG_OLDFILENAMES) {
+		return
+	}
+
+	files, err := header.Files()
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		entry := rpmlib.NewFileEntry(file)
+		entries[file.Path] = &entry
+	}
+
+	return
+}
+
+// readContents reads data of files at paths from the payload of the package
+func readContents(pkg *rpmlib.PackageFile, paths map[string]bool) (contents map[string][]byte, err error) {
+	contents = make(map[string][]byte)
+
+	err = pkg.LoadPayload()
+	if err != nil {
+		return
+	}
+
+	// Data of hard links is only in the last entry of them, so paths of
+	// earlier entries are resolved by the inode
+	linked := make(map[uint64][]string)
+
+	reader := cpio.NewCPIOReader(pkg.Payload.Cpio())
+	for {
+		file, read_err := reader.GetFile()
+		if read_err == io.EOF {
+			break
+		}
+		if read_err != nil {
+			return nil, read_err
+		}
+
+		path := "/" + strings.TrimPrefix(strings.TrimPrefix(file.Name, "."), "/")
+		ino := file.Metadata.Ino
+		if file.Metadata.Nlink > 1 && file.Metadata.Filesize == 0 {
+			linked[ino] = append(linked[ino], path)
+			continue
+		}
+
+		for _, p := range append(linked[ino], path) {
+			if paths[p] {
+				contents[p] = file.Bytes()
+			}
+		}
+		delete(linked, ino)
+	}
+
+	// Hard links which are all empty
+	for _, names := range linked {
+		for _, p := range names {
+			if paths[p] {
+				contents[p] = []byte{}
+			}
+		}
+	}
+
+	return
+}
+
+// diffContents sets unified diffs of changed text files, of which the digest is changed
+func diffContents(old *rpmlib.PackageFile, new *rpmlib.PackageFile, changes []FileChange, context int) (err error) {
+	paths := make(map[string]bool)
+	for _, change := range changes {
+		if change.Change == FileChanged && change.Old.Digest != change.New.Digest &&
+			change.Old.Mode&rpmlib.S_IFMT == rpmlib.S_IFREG && change.New.Mode&rpmlib.S_IFMT == rpmlib.S_IFREG {
+			paths[change.Path] = true
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	oldContents, err := readContents(old, paths)
+	if err != nil {
+		return fmt.Errorf("%s: %s", old.Header.Name(), err)
+	}
+	newContents, err := readContents(new, paths)
+	if err != nil {
+		return fmt.Errorf("%s: %s", new.Header.Name(), err)
+	}
+
+	for i, change := range changes {
+		oldData, oldFound := oldContents[change.Path]
+		newData, newFound := newContents[change.Path]
+		if !oldFound || !newFound || !IsText(oldData) || !IsText(newData) {
+			continue
+		}
+
+		changes[i].Diff = UnifiedDiff("a"+change.Path, "b"+change.Path, string(oldData), string(newData), context)
+	}
+
+	return
+}
+
+// Letters returns changed attributes of a changed file by letters, such as "S.5...."
+func (change *FileChange) Letters() string {
+	letters := make([]byte, len(fileAttributes))
+	for i, attribute := range fileAttributes {
+		letters[i] = '.'
+		for _, name := range change.Attributes {
+			if name == attribute.name {
+				letters[i] = attribute.letter
+			}
+		}
+	}
+
+	return string(letters)
+}
@@ -0,0 +1,192 @@
+package rpmdiff_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmdiff"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+)
+
+func testPackages(t *testing.T) (old *rpmlib.PackageFile, new *rpmlib.PackageFile) {
+	old = rpmtest.Open(t, rpmtest.Package{
+		Name: "foo", Version: "1.0", Release: "1", Arch: "x86_64", SourceRPM: "foo-1.0-1.src.rpm",
+		Files: []rpmtest.File{
+			{Path: "/etc/foo.conf", Mode: rpmlib.S_IFREG | 0644, Data: "a = 1\nb = 2\n"},
+			// Hard links, of which data is in the last entry
+			{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | 0755, Data: "#!/bin/sh\necho 1\n", Inode: 10},
+			{Path: "/usr/bin/foo-alias", Mode: rpmlib.S_IFREG | 0755, Data: "#!/bin/sh\necho 1\n", Inode: 10},
+			{Path: "/usr/share/foo/old", Mode: rpmlib.S_IFREG | 0644, Data: "old"},
+			{Path: "/usr/share/foo/logo.png", Mode: rpmlib.S_IFREG | 0644, Data: "\x89PNG\x00\x01"},
+		},
+		Tags: func(b *rpmtest.Builder) {
+			b.AddStringArray(rpmlib.RPMTAG_REQUIRENAME, []string{"bar", "baz"})
+			b.AddInt32(rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMSENSE_GREATER|rpmlib.RPMSENSE_EQUAL, 0)
+			b.AddStringArray(rpmlib.RPMTAG_REQUIREVERSION, []string{"1.0", ""})
+		},
+	})
+
+	new = rpmtest.Open(t, rpmtest.Package{
+		Name: "foo", Version: "1.1", Release: "1", Arch: "x86_64", SourceRPM: "foo-1.1-1.src.rpm",
+		Files: []rpmtest.File{
+			{Path: "/etc/foo.conf", Mode: rpmlib.S_IFREG | 0600, Data: "a = 1\nb = 3\n"},
+			{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | 0755, Data: "#!/bin/sh\necho 2\n", Inode: 10},
+			{Path: "/usr/bin/foo-alias", Mode: rpmlib.S_IFREG | 0755, Data: "#!/bin/sh\necho 2\n", Inode: 10},
+			{Path: "/usr/share/foo/new", Mode: rpmlib.S_IFREG | 0644, Data: "new"},
+			{Path: "/usr/share/foo/logo.png", Mode: rpmlib.S_IFREG | 0644, Data: "\x89PNG\x00\x02"},
+		},
+		Tags: func(b *rpmtest.Builder) {
+			b.AddStringArray(rpmlib.RPMTAG_REQUIRENAME, []string{"bar", "qux"})
+			b.AddInt32(rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMSENSE_GREATER|rpmlib.RPMSENSE_EQUAL, 0)
+			b.AddStringArray(rpmlib.RPMTAG_REQUIREVERSION, []string{"2.0", ""})
+		},
+	})
+
+	return
+}
+
+func TestCompare(t *testing.T) {
+	old, new := testPackages(t)
+
+	options := rpmdiff.DefaultOptions
+	options.IgnoreFields = []string{"sourcerpm"}
+	diff, err := rpmdiff.Compare(old, new, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff.Empty() || diff.Old != "foo-1.0-1.x86_64" || diff.New != "foo-1.1-1.x86_64" {
+		t.Errorf("Compare() = %s, %s", diff.Old, diff.New)
+	}
+
+	var fields []string
+	for _, field := range diff.Fields {
+		fields = append(fields, field.Field+" "+field.Old+" -> "+field.New)
+	}
+	if strings.Join(fields, ", ") != "VERSION 1.0 -> 1.1" {
+		t.Errorf("Fields = %v", fields)
+	}
+
+	var deps []string
+	for _, dep := range diff.Dependencies {
+		s := dep.Kind
+		if dep.Old != nil {
+			s += " -" + dep.Old.String()
+		}
+		if dep.New != nil {
+			s += " +" + dep.New.String()
+		}
+		deps = append(deps, s)
+	}
+	if strings.Join(deps, ", ") != "REQUIRES -bar >= 1.0 +bar >= 2.0, REQUIRES -baz, REQUIRES +qux" {
+		t.Errorf("Dependencies = %v", deps)
+	}
+
+	tests := []struct {
+		path    string
+		change  string
+		letters string
+	}{
+		{"/etc/foo.conf", rpmdiff.FileChanged, ".M5...."},
+		{"/usr/bin/foo", rpmdiff.FileChanged, "..5...."},
+		{"/usr/bin/foo-alias", rpmdiff.FileChanged, "..5...."},
+		{"/usr/share/foo/logo.png", rpmdiff.FileChanged, "..5...."},
+		{"/usr/share/foo/new", rpmdiff.FileAdded, ""},
+		{"/usr/share/foo/old", rpmdiff.FileRemoved, ""},
+	}
+	if len(diff.Files) != len(tests) {
+		t.Fatalf("Files = %+v", diff.Files)
+	}
+	for i, test := range tests {
+		file := diff.Files[i]
+		if file.Path != test.path || file.Change != test.change {
+			t.Errorf("Files[%d] = %s %s, want %s %s", i, file.Change, file.Path, test.change, test.path)
+		}
+		if test.letters != "" && file.Letters() != test.letters {
+			t.Errorf("%s: Letters() = %s, want %s", file.Path, file.Letters(), test.letters)
+		}
+		if file.Diff != "" {
+			t.Errorf("%s has a diff without Contents", file.Path)
+		}
+	}
+}
+
+func TestCompareContents(t *testing.T) {
+	old, new := testPackages(t)
+
+	options := rpmdiff.DefaultOptions
+	options.Contents = true
+	diff, err := rpmdiff.Compare(old, new, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs := make(map[string]string)
+	for _, file := range diff.Files {
+		diffs[file.Path] = file.Diff
+	}
+
+	// Both hard links have the data of the last entry
+	tests := map[string]string{
+		"/etc/foo.conf":           "--- a/etc/foo.conf\n+++ b/etc/foo.conf\n@@ -1,2 +1,2 @@\n a = 1\n-b = 2\n+b = 3\n",
+		"/usr/bin/foo":            "--- a/usr/bin/foo\n+++ b/usr/bin/foo\n@@ -1,2 +1,2 @@\n #!/bin/sh\n-echo 1\n+echo 2\n",
+		"/usr/bin/foo-alias":      "--- a/usr/bin/foo-alias\n+++ b/usr/bin/foo-alias\n@@ -1,2 +1,2 @@\n #!/bin/sh\n-echo 1\n+echo 2\n",
+		"/usr/share/foo/logo.png": "",
+	}
+	for path, expected := range tests {
+		if diffs[path] != expected {
+			t.Errorf("Diff of %s = %q, want %q", path, diffs[path], expected)
+		}
+	}
+}
+
+func TestCompareSame(t *testing.T) {
+	old, _ := testPackages(t)
+
+	diff, err := rpmdiff.Compare(old, old, rpmdiff.DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Empty() {
+		t.Errorf("Compare() of the same package = %+v", diff)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		old      string
+		new      string
+		context  int
+		expected string
+	}{
+		{"a\n", "a\n", 3, ""},
+		{"", "a\n", 3, "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"a\nb", "a\nc", 3, "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\nX\n3\n4\n5\n6\n7\nY\n9\n", 1,
+			"--- a\n+++ b\n@@ -1,3 +1,3 @@\n 1\n-2\n+X\n 3\n@@ -7,3 +7,3 @@\n 7\n-8\n+Y\n 9\n"},
+	}
+
+	for _, test := range tests {
+		if diff := rpmdiff.UnifiedDiff("a", "b", test.old, test.new, test.context); diff != test.expected {
+			t.Errorf("UnifiedDiff(%q, %q) = %q, want %q", test.old, test.new, diff, test.expected)
+		}
+	}
+}
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		data []byte
+		text bool
+	}{
+		{[]byte("plain text\n"), true},
+		{[]byte("caf\xc3\xa9\n"), true},
+		{[]byte("\x89PNG\x00"), false},
+		{[]byte("\xff\xfe"), false},
+	}
+
+	for _, test := range tests {
+		if rpmdiff.IsText(test.data) != test.text {
+			t.Errorf("IsText(%q) = %v", test.data, !test.text)
+		}
+	}
+}
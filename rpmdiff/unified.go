@@ -0,0 +1,208 @@
+package rpmdiff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+//
+// Unified diffs of text files
+//
+
+// Files of which the common prefix and suffix removed have more lines are
+// compared as if all the lines are replaced, to bound the time and the memory
+const maxDiffCells = 4 * 1024 * 1024
+
+// IsText checks data is UTF-8 without NUL bytes, same as diff which compares text files
+func IsText(data []byte) bool {
+	for _, b := range data {
+		if b == 0 {
+			return false
+		}
+	}
+
+	return utf8.Valid(data)
+}
+
+// splitLines splits text into lines with the newline at the end
+func splitLines(text string) (lines []string) {
+	for text != "" {
+		end := strings.IndexByte(text, '\n')
+		if end < 0 {
+			return append(lines, text)
+		}
+		lines = append(lines, text[:end+1])
+		text = text[end+1:]
+	}
+
+	return
+}
+
+// Operations of lines in an edit script
+const (
+	opEqual  = ' '
+	opDelete = '-'
+	opInsert = '+'
+)
+
+type edit struct {
+	op   byte
+	line string
+}
+
+// editScript returns operations to change a into b, of the longest common subsequence
+func editScript(a []string, b []string) (edits []edit) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{opEqual, line})
+	}
+
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(middleA)+1)*(len(middleB)+1) > maxDiffCells {
+		for _, line := range middleA {
+			edits = append(edits, edit{opDelete, line})
+		}
+		for _, line := range middleB {
+			edits = append(edits, edit{opInsert, line})
+		}
+	} else {
+		edits = append(edits, lcsScript(middleA, middleB)...)
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{opEqual, line})
+	}
+
+	return
+}
+
+func lcsScript(a []string, b []string) (edits []edit) {
+	// lengths[i][j] is the length of the common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	lengths := make([]int, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+				lengths[i*width+j] = lengths[(i+1)*width+j]
+			default:
+				lengths[i*width+j] = lengths[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{opEqual, a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lengths[(i+1)*width+j] >= lengths[i*width+j+1]):
+			edits = append(edits, edit{opDelete, a[i]})
+			i++
+		default:
+			edits = append(edits, edit{opInsert, b[j]})
+			j++
+		}
+	}
+
+	return
+}
+
+// hunkRange formats the start and the length of a hunk, same as diff -u
+func hunkRange(start int, length int) string {
+	if length == 0 {
+		start--
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// UnifiedDiff returns the difference of texts in the unified format with lines of context,
+// or an empty string if the texts are same
+func UnifiedDiff(oldName string, newName string, oldText string, newText string, context int) string {
+	edits := editScript(splitLines(oldText), splitLines(newText))
+
+	var builder strings.Builder
+	for start := 0; start < len(edits); {
+		// The next change, and the end of the hunk where changes are not apart
+		// more than twice of the context
+		for start < len(edits) && edits[start].op == opEqual {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		end := start
+		for equal := 0; end < len(edits) && equal <= 2*context; end++ {
+			if edits[end].op == opEqual {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		for end > start && edits[end-1].op == opEqual {
+			end--
+		}
+
+		first := start - context
+		if first < 0 {
+			first = 0
+		}
+		last := end + context
+		if last > len(edits) {
+			last = len(edits)
+		}
+
+		// Line numbers of the hunk in both texts
+		oldStart, newStart := 0, 0
+		for _, e := range edits[:first] {
+			if e.op != opInsert {
+				oldStart++
+			}
+			if e.op != opDelete {
+				newStart++
+			}
+		}
+		oldLength, newLength := 0, 0
+		for _, e := range edits[first:last] {
+			if e.op != opInsert {
+				oldLength++
+			}
+			if e.op != opDelete {
+				newLength++
+			}
+		}
+
+		if builder.Len() == 0 {
+			fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLength), hunkRange(newStart, newLength))
+		for _, e := range edits[first:last] {
+			builder.WriteByte(e.op)
+			builder.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				builder.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = end
+	}
+
+	return builder.String()
+}
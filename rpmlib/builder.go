@@ -0,0 +1,145 @@
+package rpmlib
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+type builderEntry struct {
+	tag      int32
+	datatype int32
+	count    int32
+	data     []byte
+}
+
+// SectionBuilder builds a signature or header section from tag values.
+// Adding a tag again replaces the value.
+type SectionBuilder struct {
+	entries map[int32]builderEntry
+}
+
+func NewSectionBuilder() *SectionBuilder {
+	return &SectionBuilder{entries: make(map[int32]builderEntry)}
+}
+
+func (b *SectionBuilder) add(tag int32, datatype int32, count int, data []byte) {
+	b.entries[tag] = builderEntry{tag, datatype, int32(count), data}
+}
+
+func (b *SectionBuilder) Has(tag int32) bool {
+	_, found := b.entries[tag]
+
+	return found
+}
+
+func (b *SectionBuilder) Remove(tag int32) {
+	delete(b.entries, tag)
+}
+
+func (b *SectionBuilder) AddString(tag int32, value string) {
+	b.add(tag, String, 1, append([]byte(value), 0))
+}
+
+func (b *SectionBuilder) AddI18nString(tag int32, value string) {
+	b.add(tag, I18nString, 1, append([]byte(value), 0))
+}
+
+// AddStringArray adds values, nothing is added for no values
+func (b *SectionBuilder) AddStringArray(tag int32, values []string) {
+	if len(values) == 0 {
+		return
+	}
+
+	var data []byte
+	for _, value := range values {
+		data = append(data, value...)
+		data = append(data, 0)
+	}
+
+	b.add(tag, StringArray, len(values), data)
+}
+
+func (b *SectionBuilder) AddBinary(tag int32, value []byte) {
+	b.add(tag, Binary, len(value), value)
+}
+
+func (b *SectionBuilder) AddInt16(tag int32, values ...int16) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 2*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(value))
+	}
+
+	b.add(tag, Int16, len(values), data)
+}
+
+func (b *SectionBuilder) AddInt32(tag int32, values ...int32) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 4*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(value))
+	}
+
+	b.add(tag, Int32, len(values), data)
+}
+
+func (b *SectionBuilder) AddInt64(tag int32, values ...int64) {
+	if len(values) == 0 {
+		return
+	}
+
+	data := make([]byte, 8*len(values))
+	for i, value := range values {
+		binary.BigEndian.PutUint64(data[8*i:], uint64(value))
+	}
+
+	b.add(tag, Int64, len(values), data)
+}
+
+// Section builds the section. Indexes are sorted by tag, and data are aligned by their types.
+func (b *SectionBuilder) Section() (section *Section) {
+	var entries []builderEntry
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].tag < entries[j].tag
+	})
+
+	section = new(Section)
+	section.magic = append([]byte{}, SectionHeaderMagic...)
+	section.header = new(SectionHeader)
+	section.header.version = 1
+
+	for _, entry := range entries {
+		if align, found := typeSizes[entry.datatype]; found {
+			for int64(len(section.store))%align != 0 {
+				section.store = append(section.store, 0)
+			}
+		}
+
+		section.header.indexes = append(section.header.indexes, SectionHeaderIndex{
+			Tag:    entry.tag,
+			Type:   entry.datatype,
+			Offset: int32(len(section.store)),
+			Count:  entry.count,
+		})
+		section.store = append(section.store, entry.data...)
+	}
+
+	section.header.nindex = int32(len(section.header.indexes))
+	section.header.hsize = int32(len(section.store))
+
+	return
+}
+
+// Header builds a header section
+func (b *SectionBuilder) Header() *Header {
+	return &Header{*b.Section()}
+}
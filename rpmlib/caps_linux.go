@@ -0,0 +1,26 @@
+package rpmlib
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// verifyCaps checks the file has capabilities if and only if the header has them.
+// The capabilities themselves are not compared.
+func verifyCaps(diskPath string, fileCaps string) error {
+	_, err := syscall.Getxattr(diskPath, "security.capability", nil)
+	hasCaps := err == nil
+	if err != nil && err != syscall.ENODATA {
+		// File systems without extended attributes have no capabilities
+		if fileCaps == "" {
+			return nil
+		}
+		return errNotChecked{err}
+	}
+
+	if hasCaps != (fileCaps != "") {
+		return fmt.Errorf("Capabilities %q are different", fileCaps)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package rpmlib
+
+// Capabilities are verified only on Linux
+func verifyCaps(diskPath string, fileCaps string) error {
+	return nil
+}
@@ -0,0 +1,197 @@
+package rpmlib
+
+import (
+	"fmt"
+)
+
+type Dependency struct {
+	Name    string `json:"name" yaml:"name"`
+	Flags   int32  `json:"flags" yaml:"flags"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// String formats the dependency like "name >= version"
+func (dep Dependency) String() string {
+	sense := DepFlagsString(uint64(uint32(dep.Flags)))
+	if sense == "" || dep.Version == "" {
+		return dep.Name
+	}
+
+	return dep.Name + " " + sense + " " + dep.Version
+}
+
+func (header *Header) dependencies(nameTag, flagsTag, versionTag int32) (deps []Dependency, err error) {
+	if !header.Section.HasStore(nameTag) {
+		return
+	}
+
+	names, err := header.Section.GetStringArray(nameTag)
+	if err != nil {
+		return
+	}
+
+	// Flags and versions may be omitted for old packages
+	var flags []int32
+	if header.Section.HasStore(flagsTag) {
+		flags, err = header.Section.GetInt32Array(flagsTag)
+		if err != nil {
+			return
+		}
+	}
+
+	var versions []string
+	if header.Section.HasStore(versionTag) {
+		versions, err = header.Section.GetStringArray(versionTag)
+		if err != nil {
+			return
+		}
+	}
+
+	if (flags != nil && len(flags) != len(names)) || (versions != nil && len(versions) != len(names)) {
+		return nil, fmt.Errorf("Number of dependency's name, flags and version are different for tag %d", nameTag)
+	}
+
+	for i, name := range names {
+		dep := Dependency{Name: name}
+		if flags != nil {
+			dep.Flags = flags[i]
+		}
+		if versions != nil {
+			dep.Version = versions[i]
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return
+}
+
+func (header *Header) Requires() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_REQUIRENAME, RPMTAG_REQUIREFLAGS, RPMTAG_REQUIREVERSION)
+}
+
+func (header *Header) Provides() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_PROVIDENAME, RPMTAG_PROVIDEFLAGS, RPMTAG_PROVIDEVERSION)
+}
+
+func (header *Header) Conflicts() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_CONFLICTNAME, RPMTAG_CONFLICTFLAGS, RPMTAG_CONFLICTVERSION)
+}
+
+func (header *Header) Obsoletes() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_OBSOLETENAME, RPMTAG_OBSOLETEFLAGS, RPMTAG_OBSOLETEVERSION)
+}
+
+func (header *Header) Recommends() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_RECOMMENDNAME, RPMTAG_RECOMMENDFLAGS, RPMTAG_RECOMMENDVERSION)
+}
+
+func (header *Header) Suggests() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_SUGGESTNAME, RPMTAG_SUGGESTFLAGS, RPMTAG_SUGGESTVERSION)
+}
+
+func (header *Header) Supplements() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_SUPPLEMENTNAME, RPMTAG_SUPPLEMENTFLAGS, RPMTAG_SUPPLEMENTVERSION)
+}
+
+func (header *Header) Enhances() (deps []Dependency, err error) {
+	return header.dependencies(RPMTAG_ENHANCENAME, RPMTAG_ENHANCEFLAGS, RPMTAG_ENHANCEVERSION)
+}
+
+// IsRich checks the dependency is a rich (boolean) dependency, such as "(foo or bar)"
+func (dep Dependency) IsRich() bool {
+	return len(dep.Name) > 0 && dep.Name[0] == '('
+}
+
+// Satisfies checks provide satisfies require, with the same rules as rpmdsCompare of rpm.
+// The ranges of them shall overlap, where a missing epoch is treated as 0
+// and releases are compared only if both of them have a release.
+// A rich require is satisfied if it is true when provide is the only one provided,
+// and err is the error of parsing it.
+func Satisfies(provide Dependency, require Dependency) (satisfied bool, err error) {
+	return satisfies([]Dependency{provide}, require, false)
+}
+
+// SatisfiesPromoteEpoch is the same as Satisfies, except for the epoch promotion.
+// If either provide or require has no epoch, epochs are not compared,
+// same as old rpm and libsolv.
+func SatisfiesPromoteEpoch(provide Dependency, require Dependency) (satisfied bool, err error) {
+	return satisfies([]Dependency{provide}, require, true)
+}
+
+// SatisfiesAny checks any of provides satisfies require.
+// For a rich require, each term of the expression is checked against all of provides.
+func SatisfiesAny(provides []Dependency, require Dependency) (satisfied bool, err error) {
+	return satisfies(provides, require, false)
+}
+
+func satisfies(provides []Dependency, require Dependency, promote bool) (satisfied bool, err error) {
+	rich, err := require.Rich()
+	if err != nil {
+		return
+	}
+
+	return rich.Eval(providesMatch(provides, promote)), nil
+}
+
+// providesMatch returns the match function of RichDependency.Eval,
+// which checks any of provides overlaps a simple dependency
+func providesMatch(provides []Dependency, promote bool) func(Dependency) bool {
+	return func(require Dependency) bool {
+		for _, provide := range provides {
+			if overlaps(provide, require, promote) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// overlaps checks the ranges of two dependencies of the same name overlap
+func overlaps(a Dependency, b Dependency, promote bool) bool {
+	if a.Name != b.Name {
+		return false
+	}
+
+	// Either of them has no range, always overlap
+	if a.Flags&RPMSENSE_SENSEMASK == 0 || b.Flags&RPMSENSE_SENSEMASK == 0 {
+		return true
+	}
+	if a.Version == "" || b.Version == "" {
+		return true
+	}
+
+	evr_a, err := ParseEVR(a.Version)
+	if err != nil {
+		return false
+	}
+	evr_b, err := ParseEVR(b.Version)
+	if err != nil {
+		return false
+	}
+
+	if promote && (!evr_a.HasEpoch || !evr_b.HasEpoch) {
+		evr_a.Epoch = evr_b.Epoch
+	}
+
+	// Releases are compared only if both of them are specified. Otherwise, the side
+	// without release matches any release if it has EQUAL, as "foo = 1.0" satisfies "foo > 1.0-1".
+	sense := evr_a.compareVersion(evr_b)
+	if sense == 0 && evr_a.Release != "" && evr_b.Release != "" {
+		sense = Compare(evr_a.Release, evr_b.Release)
+	} else if sense == 0 && ((evr_a.Release != "" && b.Flags&RPMSENSE_EQUAL != 0) ||
+		(evr_b.Release != "" && a.Flags&RPMSENSE_EQUAL != 0)) {
+		return true
+	}
+
+	switch {
+	case sense < 0:
+		return a.Flags&RPMSENSE_GREATER != 0 || b.Flags&RPMSENSE_LESS != 0
+	case sense > 0:
+		return a.Flags&RPMSENSE_LESS != 0 || b.Flags&RPMSENSE_GREATER != 0
+	}
+
+	return (a.Flags&RPMSENSE_EQUAL != 0 && b.Flags&RPMSENSE_EQUAL != 0) ||
+		(a.Flags&RPMSENSE_LESS != 0 && b.Flags&RPMSENSE_LESS != 0) ||
+		(a.Flags&RPMSENSE_GREATER != 0 && b.Flags&RPMSENSE_GREATER != 0)
+}
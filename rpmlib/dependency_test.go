@@ -0,0 +1,148 @@
+package rpmlib
+
+import (
+	"testing"
+)
+
+func parseTestDependency(t *testing.T, s string) Dependency {
+	rich, err := ParseRichDependency(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rich.Op != RichNone {
+		return Dependency{Name: s}
+	}
+
+	return rich.Dependency
+}
+
+// Cases of overlapping ranges of rpmdsCompare of rpm
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		provide   string
+		require   string
+		satisfied bool
+	}{
+		// Names differ
+		{"foo", "bar", false},
+		// No range always overlaps
+		{"foo", "foo >= 1.0", true},
+		{"foo = 1.0", "foo", true},
+		{"foo = 1.0", "foo = 1.0", true},
+		{"foo = 1.0", "foo >= 1.0", true},
+		{"foo = 1.0", "foo <= 1.0", true},
+		{"foo = 1.0", "foo > 1.0", false},
+		{"foo = 1.0", "foo < 1.0", false},
+		{"foo = 1.1", "foo > 1.0", true},
+		{"foo = 0.9", "foo > 1.0", false},
+		// Ranges which do not contain each other
+		{"foo >= 1.0", "foo < 2.0", true},
+		{"foo <= 1.0", "foo >= 2.0", false},
+		{"foo < 1.0", "foo < 0.5", true},
+		{"foo > 2.0", "foo > 1.0", true},
+		{"foo < 1.0", "foo > 1.0", false},
+		{"foo >= 1.0", "foo <= 1.0", true},
+		// Releases are compared if both have a release
+		{"foo = 1.0-1", "foo = 1.0-1", true},
+		{"foo = 1.0-2", "foo = 1.0-1", false},
+		{"foo = 1.0-2", "foo > 1.0-1", true},
+		{"foo = 1.0-2", "foo < 1.0-1", false},
+		// The side without release and with EQUAL matches any release
+		{"foo = 1.0", "foo > 1.0-1", true},
+		{"foo = 1.0", "foo < 1.0-1", true},
+		{"foo = 1.0", "foo = 1.0-1", true},
+		{"foo = 1.0-1", "foo = 1.0", true},
+		{"foo = 1.0-1", "foo >= 1.0", true},
+		{"foo = 1.0-1", "foo <= 1.0", true},
+		{"foo >= 1.0", "foo < 1.0-1", true},
+		{"foo = 1.0-1", "foo > 1.0", false},
+		{"foo = 1.0-1", "foo < 1.0", false},
+		{"foo > 1.0", "foo = 1.0-1", false},
+		{"foo = 1.1", "foo < 1.0-1", false},
+		// Missing epochs are 0
+		{"foo = 0:1.0", "foo = 1.0", true},
+		{"foo = 1:1.0", "foo >= 2.0", true},
+		{"foo = 1.0", "foo >= 1:0.5", false},
+		{"foo = 1:1.0", "foo = 1.0", false},
+		// Pre-releases
+		{"foo = 1.0~rc1", "foo >= 1.0", false},
+		{"foo = 1.0^git1", "foo > 1.0", true},
+		// Rich dependencies are evaluated against the provide
+		{"foo = 1.0", "(foo >= 1.0 or bar)", true},
+		{"foo = 1.0", "(foo >= 1.0 and bar)", false},
+		{"foo = 1.0", "(bar if baz)", true},
+		{"foo = 1.0", "(foo without bar)", true},
+		{"foo = 1.0", "(bar unless foo else foo)", true},
+	}
+
+	for _, test := range tests {
+		provide, require := parseTestDependency(t, test.provide), parseTestDependency(t, test.require)
+
+		satisfied, err := Satisfies(provide, require)
+		if err != nil || satisfied != test.satisfied {
+			t.Errorf("Satisfies(%s, %s) = %v, %v, want %v", test.provide, test.require, satisfied, err, test.satisfied)
+		}
+
+		// Overlapping is symmetric for simple dependencies
+		if !require.IsRich() {
+			if satisfied, _ := Satisfies(require, provide); satisfied != test.satisfied {
+				t.Errorf("Satisfies(%s, %s) = %v, want %v", test.require, test.provide, satisfied, test.satisfied)
+			}
+		}
+	}
+}
+
+func TestSatisfiesPromoteEpoch(t *testing.T) {
+	tests := []struct {
+		provide   string
+		require   string
+		satisfied bool
+	}{
+		{"foo = 1:1.0", "foo = 1.0", true},
+		{"foo = 1.0", "foo = 1:1.0", true},
+		{"foo = 1:1.0", "foo = 2:1.0", false},
+		{"foo = 1:1.0", "foo >= 2.0", false},
+	}
+
+	for _, test := range tests {
+		satisfied, err := SatisfiesPromoteEpoch(parseTestDependency(t, test.provide), parseTestDependency(t, test.require))
+		if err != nil || satisfied != test.satisfied {
+			t.Errorf("SatisfiesPromoteEpoch(%s, %s) = %v, %v, want %v", test.provide, test.require, satisfied, err, test.satisfied)
+		}
+	}
+}
+
+func TestSatisfiesAny(t *testing.T) {
+	provides := []Dependency{
+		parseTestDependency(t, "foo = 1.0-1"),
+		parseTestDependency(t, "bar = 2.0-1"),
+	}
+
+	tests := []struct {
+		require   string
+		satisfied bool
+	}{
+		{"foo", true},
+		{"baz", false},
+		{"(foo and bar >= 2.0)", true},
+		{"(foo and baz)", false},
+		{"(foo with bar)", true},
+		{"(foo without bar)", false},
+		{"(baz if qux)", true},
+		{"(baz if foo else bar)", false},
+	}
+
+	for _, test := range tests {
+		satisfied, err := SatisfiesAny(provides, Dependency{Name: test.require})
+		if err != nil || satisfied != test.satisfied {
+			t.Errorf("SatisfiesAny(%s) = %v, %v, want %v", test.require, satisfied, err, test.satisfied)
+		}
+	}
+
+	// Errors of rich dependencies are not mistaken for unsatisfied
+	for _, require := range []string{"(foo or", "(foo or bar and baz)", "(foo >=)"} {
+		if _, err := SatisfiesAny(provides, Dependency{Name: require}); err == nil {
+			t.Errorf("SatisfiesAny(%s) has no error", require)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package rpmlib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EVR is a version of package, consists of epoch, version and release.
+// HasEpoch is false if the epoch is not specified, which is treated as 0 in comparison.
+// Empty Release means the release is not specified, which is older than any release
+// in comparison, but matches any release in dependencies of Satisfies.
+type EVR struct {
+	Epoch    int32
+	HasEpoch bool
+	Version  string
+	Release  string
+}
+
+// ParseEVR parses a version string in the form of "[epoch:]version[-release]"
+// in the same way as rpm
+func ParseEVR(s string) (evr EVR, err error) {
+	version := s
+
+	digits := 0
+	for digits < len(s) && isDigit(s[digits]) {
+		digits++
+	}
+
+	if digits < len(s) && s[digits] == ':' {
+		if digits > 0 {
+			epoch, err := strconv.ParseUint(s[:digits], 10, 31)
+			if err != nil {
+				return evr, fmt.Errorf("Invalid epoch in %s", s)
+			}
+			evr.Epoch = int32(epoch)
+		}
+		evr.HasEpoch = true
+		version = s[digits+1:]
+	}
+
+	if i := strings.LastIndexByte(version, '-'); i >= 0 {
+		evr.Release = version[i+1:]
+		version = version[:i]
+	}
+	evr.Version = version
+
+	return
+}
+
+func (evr EVR) String() (s string) {
+	s = evr.Version
+	if evr.HasEpoch {
+		s = fmt.Sprintf("%d:%s", evr.Epoch, s)
+	}
+	if evr.Release != "" {
+		s += "-" + evr.Release
+	}
+
+	return
+}
+
+// Compare returns -1, 0 or 1 when evr is older than, same as or newer than other.
+// Epochs, versions and releases are compared by rpmvercmp, same as ordering of packages
+// by rpm, so a missing release is older than any release as "1.0" < "1.0-1".
+func (evr EVR) Compare(other EVR) int {
+	if rc := evr.compareVersion(other); rc != 0 {
+		return rc
+	}
+
+	return Compare(evr.Release, other.Release)
+}
+
+// compareVersion compares epochs and versions without releases
+func (evr EVR) compareVersion(other EVR) int {
+	if evr.Epoch < other.Epoch {
+		return -1
+	} else if evr.Epoch > other.Epoch {
+		return 1
+	}
+
+	return Compare(evr.Version, other.Version)
+}
+
+// CompareEVR parses and compares two version strings by EVR.Compare
+func CompareEVR(a string, b string) (rc int, err error) {
+	evr_a, err := ParseEVR(a)
+	if err != nil {
+		return
+	}
+
+	evr_b, err := ParseEVR(b)
+	if err != nil {
+		return
+	}
+
+	return evr_a.Compare(evr_b), nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// Compare compares two version or release strings with the same semantics as rpmvercmp.
+// It returns -1, 0 or 1 when a is older than, same as or newer than b.
+//
+// Strings are split into segments of digits or letters, and other characters are separators.
+// Numeric segments are compared as numbers and are newer than alphabetic segments.
+// '~' sorts before anything, even the end of string, as "1.0~rc1" < "1.0".
+// '^' sorts after the end of string but before anything else, as "1.0" < "1.0^git1" < "1.0.1".
+func Compare(a string, b string) int {
+	if a == b {
+		return 0
+	}
+
+	one, two := 0, 0
+	for one < len(a) || two < len(b) {
+		for one < len(a) && !isDigit(a[one]) && !isAlpha(a[one]) && a[one] != '~' && a[one] != '^' {
+			one++
+		}
+		for two < len(b) && !isDigit(b[two]) && !isAlpha(b[two]) && b[two] != '~' && b[two] != '^' {
+			two++
+		}
+
+		// tilde separator, sorts before everything else
+		tilde_a := one < len(a) && a[one] == '~'
+		tilde_b := two < len(b) && b[two] == '~'
+		if tilde_a || tilde_b {
+			if !tilde_a {
+				return 1
+			}
+			if !tilde_b {
+				return -1
+			}
+			one++
+			two++
+			continue
+		}
+
+		// caret separator, sorts after the end of string but before everything else
+		caret_a := one < len(a) && a[one] == '^'
+		caret_b := two < len(b) && b[two] == '^'
+		if caret_a || caret_b {
+			if one >= len(a) {
+				return -1
+			}
+			if two >= len(b) {
+				return 1
+			}
+			if !caret_a {
+				return 1
+			}
+			if !caret_b {
+				return -1
+			}
+			one++
+			two++
+			continue
+		}
+
+		if one >= len(a) || two >= len(b) {
+			break
+		}
+
+		// take the same type of segment from both strings
+		end_a, end_b := one, two
+		isnum := isDigit(a[one])
+		if isnum {
+			for end_a < len(a) && isDigit(a[end_a]) {
+				end_a++
+			}
+			for end_b < len(b) && isDigit(b[end_b]) {
+				end_b++
+			}
+		} else {
+			for end_a < len(a) && isAlpha(a[end_a]) {
+				end_a++
+			}
+			for end_b < len(b) && isAlpha(b[end_b]) {
+				end_b++
+			}
+		}
+
+		// segments of different types, numeric one is newer
+		if end_b == two {
+			if isnum {
+				return 1
+			}
+			return -1
+		}
+
+		seg_a, seg_b := a[one:end_a], b[two:end_b]
+		if isnum {
+			seg_a = strings.TrimLeft(seg_a, "0")
+			seg_b = strings.TrimLeft(seg_b, "0")
+
+			if len(seg_a) > len(seg_b) {
+				return 1
+			} else if len(seg_a) < len(seg_b) {
+				return -1
+			}
+		}
+
+		if rc := strings.Compare(seg_a, seg_b); rc != 0 {
+			return rc
+		}
+
+		one, two = end_a, end_b
+	}
+
+	if one >= len(a) && two >= len(b) {
+		return 0
+	}
+	if one >= len(a) {
+		return -1
+	}
+
+	return 1
+}
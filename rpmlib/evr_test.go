@@ -0,0 +1,176 @@
+package rpmlib
+
+import (
+	"testing"
+)
+
+// Cases of rpmvercmp.at of rpm
+var rpmvercmpTests = []struct {
+	a  string
+	b  string
+	rc int
+}{
+	{"1.0", "1.0", 0},
+	{"1.0", "2.0", -1},
+	{"2.0", "1.0", 1},
+	{"2.0.1", "2.0.1", 0},
+	{"2.0", "2.0.1", -1},
+	{"2.0.1", "2.0", 1},
+	{"2.0.1a", "2.0.1a", 0},
+	{"2.0.1a", "2.0.1", 1},
+	{"2.0.1", "2.0.1a", -1},
+	{"5.5p1", "5.5p1", 0},
+	{"5.5p1", "5.5p2", -1},
+	{"5.5p2", "5.5p1", 1},
+	{"5.5p10", "5.5p10", 0},
+	{"5.5p1", "5.5p10", -1},
+	{"5.5p10", "5.5p1", 1},
+	{"10xyz", "10.1xyz", -1},
+	{"10.1xyz", "10xyz", 1},
+	{"xyz10", "xyz10", 0},
+	{"xyz10", "xyz10.1", -1},
+	{"xyz10.1", "xyz10", 1},
+	{"xyz.4", "xyz.4", 0},
+	{"xyz.4", "8", -1},
+	{"8", "xyz.4", 1},
+	{"xyz.4", "2", -1},
+	{"2", "xyz.4", 1},
+	{"5.5p2", "5.6p1", -1},
+	{"5.6p1", "5.5p2", 1},
+	{"5.6p1", "6.5p1", -1},
+	{"6.5p1", "5.6p1", 1},
+	{"6.0.rc1", "6.0", 1},
+	{"6.0", "6.0.rc1", -1},
+	{"10b2", "10a1", 1},
+	{"10a2", "10b2", -1},
+	{"1.0aa", "1.0aa", 0},
+	{"1.0a", "1.0aa", -1},
+	{"1.0aa", "1.0a", 1},
+	{"10.0001", "10.0001", 0},
+	{"10.0001", "10.1", 0},
+	{"10.1", "10.0001", 0},
+	{"10.0001", "10.0039", -1},
+	{"10.0039", "10.0001", 1},
+	{"4.999.9", "5.0", -1},
+	{"5.0", "4.999.9", 1},
+	{"20101121", "20101121", 0},
+	{"20101121", "20101122", -1},
+	{"20101122", "20101121", 1},
+	{"2_0", "2_0", 0},
+	{"2.0", "2_0", 0},
+	{"2_0", "2.0", 0},
+	{"a", "a", 0},
+	{"a+", "a+", 0},
+	{"a+", "a_", 0},
+	{"a_", "a+", 0},
+	{"+a", "+a", 0},
+	{"+a", "_a", 0},
+	{"_a", "+a", 0},
+	{"+_", "+_", 0},
+	{"_+", "+_", 0},
+	{"_+", "_+", 0},
+	{"+", "_", 0},
+	{"_", "+", 0},
+	{"1.0~rc1", "1.0~rc1", 0},
+	{"1.0~rc1", "1.0", -1},
+	{"1.0", "1.0~rc1", 1},
+	{"1.0~rc1", "1.0~rc2", -1},
+	{"1.0~rc2", "1.0~rc1", 1},
+	{"1.0~rc1~git123", "1.0~rc1~git123", 0},
+	{"1.0~rc1~git123", "1.0~rc1", -1},
+	{"1.0~rc1", "1.0~rc1~git123", 1},
+	{"1.0^", "1.0^", 0},
+	{"1.0^", "1.0", 1},
+	{"1.0", "1.0^", -1},
+	{"1.0^git1", "1.0^git1", 0},
+	{"1.0^git1", "1.0", 1},
+	{"1.0", "1.0^git1", -1},
+	{"1.0^git1", "1.0^git2", -1},
+	{"1.0^git2", "1.0^git1", 1},
+	{"1.0^git1", "1.01", -1},
+	{"1.01", "1.0^git1", 1},
+	{"1.0^20160101", "1.0^20160101", 0},
+	{"1.0^20160101", "1.0.1", -1},
+	{"1.0.1", "1.0^20160101", 1},
+	{"1.0^20160101^git1", "1.0^20160101^git1", 0},
+	{"1.0^20160102", "1.0^20160101^git1", 1},
+	{"1.0^20160101^git1", "1.0^20160102", -1},
+	{"1.0~rc1^git1", "1.0~rc1^git1", 0},
+	{"1.0~rc1^git1", "1.0~rc1", 1},
+	{"1.0~rc1", "1.0~rc1^git1", -1},
+	{"1.0^git1~pre", "1.0^git1~pre", 0},
+	{"1.0^git1", "1.0^git1~pre", 1},
+	{"1.0^git1~pre", "1.0^git1", -1},
+	{"1b.fc17", "1b.fc17", 0},
+	{"1b.fc17", "1.fc17", -1},
+	{"1.fc17", "1b.fc17", 1},
+	{"1g.fc17", "1g.fc17", 0},
+	{"1g.fc17", "1.fc17", 1},
+	{"1.fc17", "1g.fc17", -1},
+}
+
+func TestCompare(t *testing.T) {
+	for _, test := range rpmvercmpTests {
+		if rc := Compare(test.a, test.b); rc != test.rc {
+			t.Errorf("Compare(%s, %s) = %d, want %d", test.a, test.b, rc, test.rc)
+		}
+	}
+}
+
+func TestParseEVR(t *testing.T) {
+	tests := []struct {
+		s   string
+		evr EVR
+	}{
+		{"1.0", EVR{Version: "1.0"}},
+		{"1.0-1.fc35", EVR{Version: "1.0", Release: "1.fc35"}},
+		{"2:1.0-1", EVR{Epoch: 2, HasEpoch: true, Version: "1.0", Release: "1"}},
+		{"0:1.0", EVR{HasEpoch: true, Version: "1.0"}},
+		{":1.0", EVR{HasEpoch: true, Version: "1.0"}},
+		{"1.0-rc-1", EVR{Version: "1.0-rc", Release: "1"}},
+	}
+
+	for _, test := range tests {
+		evr, err := ParseEVR(test.s)
+		if err != nil || evr != test.evr {
+			t.Errorf("ParseEVR(%s) = %+v, %v, want %+v", test.s, evr, err, test.evr)
+		}
+		if evr.String() != test.s && test.s != ":1.0" {
+			t.Errorf("String() of %s = %s", test.s, evr.String())
+		}
+	}
+
+	if _, err := ParseEVR("99999999999:1.0"); err == nil {
+		t.Errorf("Epoch out of range is accepted")
+	}
+}
+
+func TestEVRCompare(t *testing.T) {
+	tests := []struct {
+		a  string
+		b  string
+		rc int
+	}{
+		{"1.0-1", "1.0-1", 0},
+		{"1.0-1", "1.0-2", -1},
+		{"1.0-10", "1.0-9", 1},
+		// The version is compared before the release
+		{"1.1-1", "1.0-9", 1},
+		// A missing release is older than any release
+		{"1.0", "1.0-1", -1},
+		{"1.0-1", "1.0", 1},
+		{"1.0", "1.0", 0},
+		{"1.0~rc1-1", "1.0-1", -1},
+		// A missing epoch is 0
+		{"0:1.0-1", "1.0-1", 0},
+		{"1:1.0-1", "2.0-1", 1},
+		{"1.0-1", "1:0.1-1", -1},
+	}
+
+	for _, test := range tests {
+		rc, err := CompareEVR(test.a, test.b)
+		if err != nil || rc != test.rc {
+			t.Errorf("CompareEVR(%s, %s) = %d, %v, want %d", test.a, test.b, rc, err, test.rc)
+		}
+	}
+}
@@ -8,15 +8,38 @@ import (
 	"os"
 )
 
+// ErrNoPayload is returned for the payload of a package read only the header, such as a remote package
+var ErrNoPayload = fmt.Errorf("Payload is not available, only the header was read")
+
 type PackageFile struct {
 	Lead      *Lead
 	Signature *Signature
 	Header    *Header
 	Payload   *Payload
+
+	file          *os.File
+	headerOffset  int64
+	payloadOffset int64
+	payloadIndex  *PayloadIndex
 }
 
 func ReadPackageFile(file *os.File) (pkg *PackageFile, err error) {
+	pkg, err = OpenPackageFile(file)
+	if err != nil {
+		return
+	}
+
+	err = pkg.LoadPayload()
+
+	return
+}
+
+// OpenPackageFile reads lead, signature and header of a package.
+// Payload is not read until LoadPayload or Open is called,
+// so the file shall be kept open while the package is used.
+func OpenPackageFile(file *os.File) (pkg *PackageFile, err error) {
 	pkg = new(PackageFile)
+	pkg.file = file
 
 	pkg.Lead, err = ScanLead(file)
 	if err != nil {
@@ -29,10 +52,9 @@ func ReadPackageFile(file *os.File) (pkg *PackageFile, err error) {
 		return
 	}
 
-	// Data store size is always 8 byte boundary
-	boundary := pkg.Signature.header.hsize % 8
-	if boundary != 0 {
-		file.Seek(int64(boundary), os.SEEK_CUR)
+	pkg.headerOffset, err = file.Seek(signaturePadding(int64(pkg.Signature.header.hsize)), os.SEEK_CUR)
+	if err != nil {
+		return
 	}
 
 	pkg.Header, err = ScanHeader(file)
@@ -41,13 +63,46 @@ func ReadPackageFile(file *os.File) (pkg *PackageFile, err error) {
 		return
 	}
 
-	compressor := pkg.Header.PayloadCompressor()
-	pkg.Payload, err = ScanPayload(file, compressor)
+	pkg.payloadOffset, err = file.Seek(0, os.SEEK_CUR)
 
+	return
+}
+
+// signaturePadding returns the size of padding after a signature of the size, by which
+// the header starts at 8 byte boundary. Intros and indexes are multiples of 8 bytes,
+// so the size of the data store is enough.
+func signaturePadding(size int64) int64 {
+	return (8 - size%8) % 8
+}
+
+// PayloadOffset returns the position of compressed payload in the package file
+func (pkg *PackageFile) PayloadOffset() int64 {
+	return pkg.payloadOffset
+}
+
+// HeaderRange returns the start and end position of header in the package file,
+// same as rpm:header-range of repository metadata
+func (pkg *PackageFile) HeaderRange() (start int64, end int64) {
+	return pkg.headerOffset, pkg.payloadOffset
+}
+
+func (pkg *PackageFile) LoadPayload() (err error) {
+	if pkg.Payload != nil {
+		return
+	}
+
+	if pkg.file == nil {
+		return ErrNoPayload
+	}
+
+	_, err = pkg.file.Seek(pkg.payloadOffset, os.SEEK_SET)
 	if err != nil {
 		return
 	}
 
+	compressor := pkg.Header.PayloadCompressor()
+	pkg.Payload, err = ScanPayload(pkg.file, compressor)
+
 	return
 }
 
@@ -110,7 +165,6 @@ func (pkg *PackageFile) Verify() (results []VerifyResult, err error) {
 
 		if f_h.Mode != int16(archives[i].Metadata.Mode) {
 			result.Mode = fmt.Errorf("M: h=%x != a=%x", f_h.Mode, archives[i].Metadata.Mode)
-			fmt.Println(result.Mode)
 		}
 
 		if len(f_h.MD5) > 0 {
@@ -0,0 +1,91 @@
+package rpmlib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignaturePadding(t *testing.T) {
+	tests := []struct {
+		size    int64
+		padding int64
+	}{
+		{0, 0}, {1, 7}, {3, 5}, {4, 4}, {5, 3}, {7, 1}, {8, 0}, {1004, 4}, {1005, 3},
+	}
+
+	for _, test := range tests {
+		if padding := signaturePadding(test.size); padding != test.padding {
+			t.Errorf("signaturePadding(%d) = %d, want %d", test.size, padding, test.padding)
+		}
+	}
+}
+
+// testHeaderBuilder returns a builder of a header which has required tags
+func testHeaderBuilder() *SectionBuilder {
+	b := NewSectionBuilder()
+	b.AddStringArray(RPMTAG_HEADERI18NTABLE, []string{"C"})
+	for tag, value := range map[int32]string{
+		RPMTAG_NAME: "foo", RPMTAG_VERSION: "1.0", RPMTAG_RELEASE: "1", RPMTAG_LICENSE: "MIT",
+		RPMTAG_OS: "linux", RPMTAG_ARCH: "noarch", RPMTAG_SOURCERPM: "foo-1.0-1.src.rpm",
+		RPMTAG_PAYLOADFORMAT: "cpio", RPMTAG_PAYLOADCOMPRESSOR: "gzip", RPMTAG_PAYLOADFLAGS: "9",
+	} {
+		b.AddString(tag, value)
+	}
+	for _, tag := range []int32{RPMTAG_SUMMARY, RPMTAG_DESCRIPTION, RPMTAG_GROUP} {
+		b.AddI18nString(tag, "foo")
+	}
+	b.AddInt32(RPMTAG_SIZE, 0)
+
+	return b
+}
+
+// Headers start at the next 8 byte boundary of signatures of any size
+func TestOpenPackageFileSignaturePadding(t *testing.T) {
+	dir := t.TempDir()
+	header := testHeaderBuilder()
+	headerBytes := header.RegionSection(RPMTAG_HEADERIMMUTABLE).Bytes()
+
+	for length := 0; length < 8; length++ {
+		sig := NewSectionBuilder()
+		sig.AddBinary(RPMSIGTAG_MD5, make([]byte, 16))
+		sig.AddInt32(RPMSIGTAG_SIZE, int32(len(headerBytes)))
+		sig.AddString(RPMSIGTAG_SHA1, strings.Repeat("0", length))
+		sigBytes := sig.RegionSection(RPMSIGTAG_HEADERSIGNATURES).Bytes()
+		for len(sigBytes)%8 != 0 {
+			sigBytes = append(sigBytes, 0)
+		}
+
+		var data bytes.Buffer
+		if err := writeLead(&data, header.Header()); err != nil {
+			t.Fatal(err)
+		}
+		data.Write(sigBytes)
+		data.Write(headerBytes)
+
+		filename := filepath.Join(dir, "foo.rpm")
+		if err := ioutil.WriteFile(filename, data.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pkg, err := OpenPackageFile(file)
+		file.Close()
+		if err != nil {
+			t.Errorf("Signature of %d bytes store: %s", length, err)
+			continue
+		}
+		if start, end := pkg.HeaderRange(); start != LeadSize+int64(len(sigBytes)) || end != int64(data.Len()) {
+			t.Errorf("Signature of %d bytes store: header range %d-%d", length, start, end)
+		}
+		if pkg.Header.Name() != "foo" {
+			t.Errorf("Signature of %d bytes store: name %q", length, pkg.Header.Name())
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package rpmlib
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Maximum number of symbolic links followed while resolving a path
+const maxSymlinkFollow = 40
+
+// PackageFS is a read-only view of the files in a package.
+// Directory entries are built from the file list of the header,
+// and file contents are served from the cpio payload.
+type PackageFS struct {
+	entries map[string]*fsEntry
+}
+
+var (
+	_ fs.FS         = (*PackageFS)(nil)
+	_ fs.ReadDirFS  = (*PackageFS)(nil)
+	_ fs.StatFS     = (*PackageFS)(nil)
+	_ fs.ReadFileFS = (*PackageFS)(nil)
+)
+
+type fsEntry struct {
+	name     string
+	meta     *FileMeta
+	mode     fs.FileMode
+	size     int64
+	mtime    time.Time
+	data     []byte
+	children []string
+}
+
+func (pkg *PackageFile) FS() (fsys *PackageFS, err error) {
+	fsys = new(PackageFS)
+	fsys.entries = make(map[string]*fsEntry)
+	fsys.entries["."] = &fsEntry{name: ".", mode: fs.ModeDir | 0755}
+
+	files, err := pkg.Header.Files()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range files {
+		meta := &files[i]
+
+		name := fsName(meta.Path)
+		if name == "." {
+			continue
+		}
+
+		entry := &fsEntry{
+			name:  path.Base(name),
+			meta:  meta,
+			mode:  meta.FileMode(),
+			mtime: time.Unix(int64(meta.Time), 0),
+		}
+		if entry.mode.IsRegular() {
+			entry.size = int64(meta.Size)
+		} else if entry.mode&fs.ModeSymlink != 0 {
+			entry.size = int64(len(meta.LinkTo))
+		}
+
+		fsys.entries[name] = entry
+		fsys.addParents(name)
+	}
+
+	err = fsys.loadPayload(pkg.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range fsys.entries {
+		sort.Strings(entry.children)
+	}
+
+	return
+}
+
+// fsName converts an absolute package path to a fs.FS path
+func fsName(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	name = path.Clean("/" + name)
+
+	if name == "/" {
+		return "."
+	}
+	return name[1:]
+}
+
+func (fsys *PackageFS) addParents(name string) {
+	for name != "." {
+		parent := path.Dir(name)
+
+		entry, found := fsys.entries[parent]
+		if !found {
+			// Directories which the package does not own
+			entry = &fsEntry{name: path.Base(parent), mode: fs.ModeDir | 0755}
+			fsys.entries[parent] = entry
+		}
+
+		base := path.Base(name)
+		for _, child := range entry.children {
+			if child == base {
+				return
+			}
+		}
+		entry.children = append(entry.children, base)
+
+		name = parent
+	}
+}
+
+func (fsys *PackageFS) loadPayload(payload *Payload) (err error) {
+	if payload == nil {
+		return fmt.Errorf("Payload is not loaded")
+	}
+
+	reader := cpio.NewCPIOReader(payload.Cpio())
+
+	// Hard linked files share one data, which is stored in the last entry of them
+	linked := make(map[uint64][]*fsEntry)
+	linkedData := make(map[uint64][]byte)
+
+	for {
+		file, read_err := reader.GetFile()
+		if read_err == io.EOF {
+			break
+		}
+
+		if read_err != nil {
+			return read_err
+		}
+
+		entry, found := fsys.entries[fsName(file.Name)]
+		if !found || !entry.mode.IsRegular() {
+			continue
+		}
+
+		entry.data = file.Bytes()
+
+		if file.Metadata.Nlink > 1 {
+			linked[file.Metadata.Ino] = append(linked[file.Metadata.Ino], entry)
+			if len(entry.data) > 0 {
+				linkedData[file.Metadata.Ino] = entry.data
+			}
+		}
+	}
+
+	for ino, entries := range linked {
+		for _, entry := range entries {
+			entry.data = linkedData[ino]
+		}
+	}
+
+	return
+}
+
+// lookup finds the entry of name, following symbolic links when follow is true
+func (fsys *PackageFS) lookup(op string, name string, follow bool) (entry *fsEntry, resolved string, err error) {
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	resolved = name
+	for i := 0; i < maxSymlinkFollow; i++ {
+		var found bool
+
+		entry, found = fsys.entries[resolved]
+		if !found {
+			return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+
+		if !follow || entry.mode&fs.ModeSymlink == 0 {
+			return
+		}
+
+		target := entry.meta.LinkTo
+		if !path.IsAbs(target) {
+			target = path.Join("/", path.Dir(resolved), target)
+		}
+		resolved = fsName(target)
+	}
+
+	return nil, "", &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("Too many levels of symbolic links")}
+}
+
+func (fsys *PackageFS) Open(name string) (file fs.File, err error) {
+	entry, resolved, err := fsys.lookup("open", name, true)
+	if err != nil {
+		return
+	}
+
+	if entry.mode.IsDir() {
+		return &fsDir{fsys: fsys, entry: entry, path: resolved}, nil
+	}
+
+	return &fsFile{entry: entry, reader: bytes.NewReader(entry.data)}, nil
+}
+
+func (fsys *PackageFS) Stat(name string) (info fs.FileInfo, err error) {
+	entry, _, err := fsys.lookup("stat", name, true)
+	if err != nil {
+		return
+	}
+
+	return &fsFileInfo{entry}, nil
+}
+
+// Lstat returns the FileInfo of name without following a symbolic link
+func (fsys *PackageFS) Lstat(name string) (info fs.FileInfo, err error) {
+	entry, _, err := fsys.lookup("lstat", name, false)
+	if err != nil {
+		return
+	}
+
+	return &fsFileInfo{entry}, nil
+}
+
+func (fsys *PackageFS) ReadFile(name string) (data []byte, err error) {
+	entry, _, err := fsys.lookup("readfile", name, true)
+	if err != nil {
+		return
+	}
+
+	if entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fmt.Errorf("Is a directory")}
+	}
+
+	data = make([]byte, len(entry.data))
+	copy(data, entry.data)
+
+	return
+}
+
+func (fsys *PackageFS) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	entry, resolved, err := fsys.lookup("readdir", name, true)
+	if err != nil {
+		return
+	}
+
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("Not a directory")}
+	}
+
+	return fsys.dirEntries(entry, resolved), nil
+}
+
+func (fsys *PackageFS) dirEntries(entry *fsEntry, name string) (entries []fs.DirEntry) {
+	entries = make([]fs.DirEntry, 0, len(entry.children))
+
+	for _, child := range entry.children {
+		entries = append(entries, &fsFileInfo{fsys.entries[path.Join(name, child)]})
+	}
+
+	return
+}
+
+// fsFileInfo implements both fs.FileInfo and fs.DirEntry
+type fsFileInfo struct {
+	entry *fsEntry
+}
+
+func (info *fsFileInfo) Name() string               { return info.entry.name }
+func (info *fsFileInfo) Size() int64                { return info.entry.size }
+func (info *fsFileInfo) Mode() fs.FileMode          { return info.entry.mode }
+func (info *fsFileInfo) ModTime() time.Time         { return info.entry.mtime }
+func (info *fsFileInfo) IsDir() bool                { return info.entry.mode.IsDir() }
+func (info *fsFileInfo) Type() fs.FileMode          { return info.entry.mode.Type() }
+func (info *fsFileInfo) Info() (fs.FileInfo, error) { return info, nil }
+
+// Sys returns *FileMeta, or nil for directories which are not owned by the package
+func (info *fsFileInfo) Sys() interface{} {
+	if info.entry.meta == nil {
+		return nil
+	}
+	return info.entry.meta
+}
+
+type fsFile struct {
+	entry  *fsEntry
+	reader *bytes.Reader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{f.entry}, nil
+}
+
+func (f *fsFile) Read(b []byte) (int, error) {
+	return f.reader.Read(b)
+}
+
+func (f *fsFile) ReadAt(b []byte, offset int64) (int, error) {
+	return f.reader.ReadAt(b, offset)
+}
+
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *fsFile) Close() error {
+	return nil
+}
+
+type fsDir struct {
+	fsys    *PackageFS
+	entry   *fsEntry
+	path    string
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) {
+	return &fsFileInfo{d.entry}, nil
+}
+
+func (d *fsDir) Read(b []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: fmt.Errorf("Is a directory")}
+}
+
+func (d *fsDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	if d.entries == nil {
+		d.entries = d.fsys.dirEntries(d.entry, d.path)
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+
+	return rest[:n], nil
+}
+
+func (d *fsDir) Close() error {
+	return nil
+}
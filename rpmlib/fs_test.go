@@ -0,0 +1,112 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func openFS(t *testing.T, files ...rpmtest.File) *rpmlib.PackageFS {
+	pkg := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1", files...))
+	if err := pkg.LoadPayload(); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := pkg.FS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fsys
+}
+
+func TestFS(t *testing.T) {
+	fsys := openFS(t,
+		rpmtest.Dir("/etc/foo"),
+		rpmtest.Regular("/etc/foo/foo.conf", "key = value\n"),
+		rpmtest.Regular("/usr/bin/foo", "#!/bin/sh\n"),
+		rpmtest.Symlink("/usr/bin/bar", "foo"),
+		rpmtest.Regular("/usr/share/doc/foo/README", ""),
+	)
+
+	err := fstest.TestFS(fsys, "etc/foo/foo.conf", "usr/bin/foo", "usr/bin/bar", "usr/share/doc/foo/README")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSReadFile(t *testing.T) {
+	fsys := openFS(t,
+		rpmtest.Regular("/usr/bin/foo", "foo"),
+		rpmtest.Symlink("/usr/bin/bar", "foo"),
+		rpmtest.Symlink("/usr/bin/abs", "/usr/bin/foo"),
+		rpmtest.Symlink("/usr/bin/loop", "loop"),
+		rpmtest.File{Path: "/usr/lib/a", Mode: rpmlib.S_IFREG | 0644, Data: "linked", Inode: 100},
+		rpmtest.File{Path: "/usr/lib/b", Mode: rpmlib.S_IFREG | 0644, Data: "linked", Inode: 100},
+	)
+
+	tests := []struct {
+		name string
+		data string
+		err  bool
+	}{
+		{"usr/bin/foo", "foo", false},
+		{"usr/bin/bar", "foo", false},
+		{"usr/bin/abs", "foo", false},
+		{"usr/bin/loop", "", true},
+		{"usr/bin/missing", "", true},
+		{"usr/bin", "", true},
+		{"/usr/bin/foo", "", true},
+		// Data of hard links is stored only in the last entry
+		{"usr/lib/a", "linked", false},
+		{"usr/lib/b", "linked", false},
+	}
+
+	for _, test := range tests {
+		data, err := fs.ReadFile(fsys, test.name)
+		if (err != nil) != test.err {
+			t.Errorf("ReadFile(%s): error %v", test.name, err)
+			continue
+		}
+		if string(data) != test.data {
+			t.Errorf("ReadFile(%s) = %q, want %q", test.name, data, test.data)
+		}
+	}
+}
+
+func TestFSStat(t *testing.T) {
+	fsys := openFS(t,
+		rpmtest.File{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | 04755, Data: "foo"},
+		rpmtest.Symlink("/usr/bin/bar", "foo"),
+	)
+
+	info, err := fsys.Stat("usr/bin/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode() != fs.ModeSetuid|0755 || info.Size() != 3 {
+		t.Errorf("Stat: mode %s size %d", info.Mode(), info.Size())
+	}
+	if meta, ok := info.Sys().(*rpmlib.FileMeta); !ok || meta.Path != "/usr/bin/foo" {
+		t.Errorf("Stat: Sys() = %v", info.Sys())
+	}
+
+	info, err = fsys.Lstat("usr/bin/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Lstat: mode %s is not a symbolic link", info.Mode())
+	}
+
+	// Directories which the package does not own are synthesized
+	info, err = fsys.Stat("usr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.IsDir() || info.Sys() != nil {
+		t.Errorf("Stat(usr): mode %s, Sys() = %v", info.Mode(), info.Sys())
+	}
+}
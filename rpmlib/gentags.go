@@ -0,0 +1,135 @@
+//go:build ignore
+// +build ignore
+
+// gentags generates tagtable.go from tags.list
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var typeNames = map[string]string{
+	"NULL":         "Null",
+	"CHAR":         "Char",
+	"INT8":         "Int8",
+	"INT16":        "Int16",
+	"INT32":        "Int32",
+	"INT64":        "Int64",
+	"STRING":       "String",
+	"BIN":          "Binary",
+	"STRING_ARRAY": "StringArray",
+	"I18NSTRING":   "I18nString",
+}
+
+type tag struct {
+	name      string
+	number    int
+	datatype  string
+	array     bool
+	extension bool
+}
+
+func readList(path string) (sections map[string][]tag, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	sections = make(map[string][]tag)
+	section := ""
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
+			section = text[1 : len(text)-1]
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) < 3 || section == "" {
+			return nil, fmt.Errorf("%s:%d: invalid line", path, line)
+		}
+
+		var t tag
+		t.name = fields[0]
+		t.number, err = strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+
+		t.datatype = typeNames[fields[2]]
+		if t.datatype == "" {
+			return nil, fmt.Errorf("%s:%d: unknown type %s", path, line, fields[2])
+		}
+
+		for _, flag := range fields[3:] {
+			switch flag {
+			case "array":
+				t.array = true
+			case "ext":
+				t.extension = true
+			default:
+				return nil, fmt.Errorf("%s:%d: unknown flag %s", path, line, flag)
+			}
+		}
+
+		sections[section] = append(sections[section], t)
+	}
+
+	err = scanner.Err()
+
+	return
+}
+
+func writeSection(buffer *bytes.Buffer, prefix string, table string, tags []tag) {
+	fmt.Fprintf(buffer, "const (\n")
+	for _, t := range tags {
+		fmt.Fprintf(buffer, "\t%s%s = %d\n", prefix, t.name, t.number)
+	}
+	fmt.Fprintf(buffer, ")\n\n")
+
+	fmt.Fprintf(buffer, "var %s []TagInfo = []TagInfo{\n", table)
+	for _, t := range tags {
+		fmt.Fprintf(buffer, "\t{%s%s, %q, %s, %t, %t},\n", prefix, t.name, t.name, t.datatype, t.array, t.extension)
+	}
+	fmt.Fprintf(buffer, "}\n\n")
+}
+
+func main() {
+	sections, err := readList("tags.list")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "// Code generated by gentags.go from tags.list; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buffer, "package rpmlib\n\n")
+	writeSection(&buffer, "RPMTAG_", "HeaderTags", sections["header"])
+	writeSection(&buffer, "RPMSIGTAG_", "SignatureTags", sections["signature"])
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+
+	err = ioutil.WriteFile("tagtable.go", source, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
@@ -4,62 +4,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
 
-const (
-	// Header private
-	RPMTAG_HEADERSIGNATURES = 62
-	RPMTAG_HEADERIMMUTABLE  = 63
-	RPMTAG_HEADER18NTABLE   = 100
-
-	RPMTAG_NAME              = 1000
-	RPMTAG_VERSION           = 1001
-	RPMTAG_RELEASE           = 1002
-	RPMTAG_SUMMARY           = 1004
-	RPMTAG_DESCRIPTION       = 1005
-	RPMTAG_BUILDTIME         = 1006
-	RPMTAG_BUILDHOST         = 1007
-	RPMTAG_SIZE              = 1009
-	RPMTAG_DISTRIBUTION      = 1010
-	RPMTAG_VERNDOR           = 1011
-	RPMTAG_LICENCE           = 1014
-	RPMTAG_PACKAGER          = 1015
-	RPMTAG_GROUP             = 1016
-	RPMTAG_URL               = 1020
-	RPMTAG_OS                = 1021
-	RPMTAG_ARCH              = 1022
-	RPMTAG_OLDFILENAMES      = 1027
-	RPMTAG_FILESIZES         = 1028
-	RPMTAG_FILEMODES         = 1030
-	RPMTAG_FILERDEVS         = 1033
-	RPMTAG_FILEMTIMES        = 1034
-	RPMTAG_FILEMD5S          = 1035
-	RPMTAG_FILEDIGESTS       = 1035
-	RPMTAG_FILELINKTOS       = 1036
-	RPMTAG_FILEFLAGS         = 1037
-	RPMTAG_FILEUSERNAME      = 1039
-	RPMTAG_FILEGROUPNAME     = 1040
-	RPMTAG_SOURCERPM         = 1044
-	RPMTAG_ARCHIVESIZE       = 1046
-	RPMTAG_RPMVERSION        = 1064
-	RPMTAG_CHANGELOGTIME     = 1080
-	RPMTAG_CHANGELOGNAME     = 1081
-	RPMTAG_CHANGELOGTEXT     = 1082
-	RPMTAG_COOKIE            = 1094
-	RPMTAG_FILEDEVICES       = 1095
-	RPMTAG_FILEINODES        = 1096
-	RPMTAG_FILELANGS         = 1097
-	RPMTAG_DIRINDEXES        = 1116
-	RPMTAG_BASENAMES         = 1117
-	RPMTAG_DIRNAMES          = 1118
-	RPMTAG_DISTURL           = 1123
-	RPMTAG_PAYLOADFORMAT     = 1124
-	RPMTAG_PAYLOADCOMPRESSOR = 1125
-	RPMTAG_PAYLOAD_FLAGS     = 1126
-)
-
 const (
 	RPMFILE_NONE      = 0
 	RPMFILE_CONFIG    = 1
@@ -74,23 +23,46 @@ const (
 	RPMFILE_UNPATCHED = 1 << 9
 	RPMFILE_PUBKEY    = 1 << 10
 	RPMFILE_POLICY    = 1 << 11
+	RPMFILE_ARTIFACT  = 1 << 12
+)
+
+const (
+	RPMSENSE_ANY     = 0
+	RPMSENSE_LESS    = 1 << 1
+	RPMSENSE_GREATER = 1 << 2
+	RPMSENSE_EQUAL   = 1 << 3
+
+	RPMSENSE_SENSEMASK = 15
+
+	RPMSENSE_POSTTRANS     = 1 << 5
+	RPMSENSE_PREREQ        = 1 << 6
+	RPMSENSE_PRETRANS      = 1 << 7
+	RPMSENSE_INTERP        = 1 << 8
+	RPMSENSE_SCRIPT_PRE    = 1 << 9
+	RPMSENSE_SCRIPT_POST   = 1 << 10
+	RPMSENSE_SCRIPT_PREUN  = 1 << 11
+	RPMSENSE_SCRIPT_POSTUN = 1 << 12
+	RPMSENSE_SCRIPT_VERIFY = 1 << 14
+	RPMSENSE_MISSINGOK     = 1 << 19
+	RPMSENSE_META          = 1 << 22
+	RPMSENSE_RPMLIB        = 1 << 24
 )
 
 var HeaderRequiredField []int32 = []int32{
-	RPMTAG_HEADER18NTABLE,
+	RPMTAG_HEADERI18NTABLE,
 	RPMTAG_NAME,
 	RPMTAG_VERSION,
 	RPMTAG_RELEASE,
 	RPMTAG_SUMMARY,
 	RPMTAG_DESCRIPTION,
 	RPMTAG_SIZE,
-	RPMTAG_LICENCE,
+	RPMTAG_LICENSE,
 	RPMTAG_GROUP,
 	RPMTAG_OS,
 	RPMTAG_ARCH,
 	RPMTAG_PAYLOADFORMAT,
 	RPMTAG_PAYLOADCOMPRESSOR,
-	RPMTAG_PAYLOAD_FLAGS,
+	RPMTAG_PAYLOADFLAGS,
 }
 
 type FileMeta struct {
@@ -110,24 +82,100 @@ type FileMeta struct {
 	Lang    string
 }
 
+// File type bits of st_mode, as stored in RPMTAG_FILEMODES
+const (
+	S_IFMT   = 0170000
+	S_IFSOCK = 0140000
+	S_IFLNK  = 0120000
+	S_IFREG  = 0100000
+	S_IFBLK  = 0060000
+	S_IFDIR  = 0040000
+	S_IFCHR  = 0020000
+	S_IFIFO  = 0010000
+	S_ISUID  = 04000
+	S_ISGID  = 02000
+	S_ISVTX  = 01000
+)
+
+// FileMode converts the raw st_mode value to os.FileMode
+func (meta *FileMeta) FileMode() (mode os.FileMode) {
+	raw := uint16(meta.Mode)
+
+	mode = os.FileMode(raw & 0777)
+
+	switch raw & S_IFMT {
+	case S_IFSOCK:
+		mode |= os.ModeSocket
+	case S_IFLNK:
+		mode |= os.ModeSymlink
+	case S_IFBLK:
+		mode |= os.ModeDevice
+	case S_IFDIR:
+		mode |= os.ModeDir
+	case S_IFCHR:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case S_IFIFO:
+		mode |= os.ModeNamedPipe
+	}
+
+	if raw&S_ISUID != 0 {
+		mode |= os.ModeSetuid
+	}
+	if raw&S_ISGID != 0 {
+		mode |= os.ModeSetgid
+	}
+	if raw&S_ISVTX != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return
+}
+
 type Header struct {
 	Section
 }
 
-func ScanHeader(file *os.File) (header *Header, err error) {
+func ScanHeader(file io.Reader) (header *Header, err error) {
 	if err != nil {
 		return
 	}
 
+	// A section which is read but invalid is returned with the error
 	section, err := scanSection(file)
-	if err != nil {
+	if section == nil {
+		return
+	}
+
+	return newHeader(section, err, HeaderRequiredField)
+}
+
+// Installed headers have no payload tags, and gpg-pubkey headers have no arch
+var InstalledHeaderRequiredField []int32 = []int32{
+	RPMTAG_NAME,
+	RPMTAG_VERSION,
+	RPMTAG_RELEASE,
+}
+
+// ScanHeaderBlob reads a header blob stored in rpmdb, which has no magic, version
+// and reserved bytes before the number of indexes
+func ScanHeaderBlob(blob []byte) (header *Header, err error) {
+	section, err := scanSectionBlob(blob)
+	if section == nil {
 		return
 	}
 
+	return newHeader(section, err, InstalledHeaderRequiredField)
+}
+
+func newHeader(section *Section, scan_err error, required []int32) (header *Header, err error) {
 	header = new(Header)
 	header.Section = *section
 
-	for _, tag := range HeaderRequiredField {
+	if scan_err != nil {
+		return header, scan_err
+	}
+
+	for _, tag := range required {
 		if !header.Section.HasStore(tag) {
 			err = fmt.Errorf("Cannot find required field tag=%d", tag)
 			break
@@ -137,6 +185,12 @@ func ScanHeader(file *os.File) (header *Header, err error) {
 	return
 }
 
+// TagTypeErrors returns errors of known tags which have other data types than expected.
+// Headers of such tags are read, same as rpm.
+func (header *Header) TagTypeErrors() []error {
+	return header.Section.tagTypeErrors(HeaderTags)
+}
+
 //
 // Required Fields
 // These field shall present and already checked above
@@ -206,7 +260,7 @@ func (header *Header) Files() (meta_list []FileMeta, err error) {
 		return
 	}
 
-	md5_list, err := header.Section.GetStringArray(RPMTAG_FILEMD5S)
+	md5_list, err := header.Section.GetStringArray(RPMTAG_FILEDIGESTS)
 	if err != nil {
 		return
 	}
@@ -245,6 +299,34 @@ func (header *Header) Files() (meta_list []FileMeta, err error) {
 		return
 	}
 
+	// Optional attributes
+	var user_list, group_list, lang_list []string
+	var inode_list []int32
+	if header.Section.HasStore(RPMTAG_FILEUSERNAME) {
+		user_list, err = header.Section.GetStringArray(RPMTAG_FILEUSERNAME)
+		if err != nil {
+			return
+		}
+	}
+	if header.Section.HasStore(RPMTAG_FILEGROUPNAME) {
+		group_list, err = header.Section.GetStringArray(RPMTAG_FILEGROUPNAME)
+		if err != nil {
+			return
+		}
+	}
+	if header.Section.HasStore(RPMTAG_FILELANGS) {
+		lang_list, err = header.Section.GetStringArray(RPMTAG_FILELANGS)
+		if err != nil {
+			return
+		}
+	}
+	if header.Section.HasStore(RPMTAG_FILEINODES) {
+		inode_list, err = header.Section.GetInt32Array(RPMTAG_FILEINODES)
+		if err != nil {
+			return
+		}
+	}
+
 	for i, name := range filenames {
 		var meta FileMeta
 		meta.Path = name
@@ -256,6 +338,18 @@ func (header *Header) Files() (meta_list []FileMeta, err error) {
 		meta.LinkTo = linkto_list[i]
 		meta.Time = mtime_list[i]
 		meta.Mode = mode_list[i]
+		if i < len(user_list) {
+			meta.User = user_list[i]
+		}
+		if i < len(group_list) {
+			meta.Group = group_list[i]
+		}
+		if i < len(lang_list) {
+			meta.Lang = lang_list[i]
+		}
+		if i < len(inode_list) {
+			meta.Inode = inode_list[i]
+		}
 
 		meta_list = append(meta_list, meta)
 	}
@@ -316,14 +410,19 @@ func (header *Header) Description() (description string) {
 	return
 }
 
-func (header *Header) Licence() (licence string) {
-	store, _, _ := header.Section.GetStore(RPMTAG_LICENCE)
+func (header *Header) License() (license string) {
+	store, _, _ := header.Section.GetStore(RPMTAG_LICENSE)
 
-	licence = string(store)
+	license = string(store)
 
 	return
 }
 
+// Deprecated: use License
+func (header *Header) Licence() (licence string) {
+	return header.License()
+}
+
 //
 // Optional or Informational field
 //
@@ -331,6 +430,22 @@ func (header *Header) SourceRpm() (name string, err error) {
 	return header.Section.GetString(RPMTAG_SOURCERPM)
 }
 
+func (header *Header) Epoch() (epoch int32, err error) {
+	return header.Section.GetInt32(RPMTAG_EPOCH)
+}
+
+func (header *Header) EVR() (evr EVR) {
+	epoch, err := header.Epoch()
+	if err == nil {
+		evr.Epoch = epoch
+		evr.HasEpoch = true
+	}
+	evr.Version = header.Version()
+	evr.Release = header.Release()
+
+	return
+}
+
 func (header *Header) BuildDate() (buildtime time.Time, err error) {
 
 	t, err := header.Section.GetInt32(RPMTAG_BUILDTIME)
@@ -383,7 +498,7 @@ func (header *Header) Changelog() (logs []Changelog, err error) {
 }
 
 type Changelog struct {
-	Name string
-	Text string
-	Date time.Time
+	Name string    `json:"name" yaml:"name"`
+	Text string    `json:"text" yaml:"text"`
+	Date time.Time `json:"date" yaml:"date"`
 }
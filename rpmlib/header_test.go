@@ -0,0 +1,33 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"testing"
+)
+
+// Headers which have tags of unexpected types are read, and the tags are reported
+func TestTagTypeErrors(t *testing.T) {
+	pkg := rpmtest.Binary("foo", "1.0", "1")
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddInt16(rpmlib.RPMTAG_BUILDTIME, 1)
+	}
+
+	header := rpmtest.Open(t, pkg).Header
+	if header.Name() != "foo" {
+		t.Errorf("Name() = %q", header.Name())
+	}
+
+	errs := header.TagTypeErrors()
+	if len(errs) != 1 || errs[0].Error() != "Tag BUILDTIME(1006) has data type INT16, but INT32 is expected" {
+		t.Errorf("TagTypeErrors() = %v", errs)
+	}
+
+	valid := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1"))
+	if errs := valid.Header.TagTypeErrors(); len(errs) != 0 {
+		t.Errorf("TagTypeErrors() of a valid header = %v", errs)
+	}
+	if errs := valid.Signature.TagTypeErrors(); len(errs) != 0 {
+		t.Errorf("TagTypeErrors() of a valid signature = %v", errs)
+	}
+}
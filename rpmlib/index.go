@@ -0,0 +1,260 @@
+package rpmlib
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// PayloadIndex records where each cpio entry is in the uncompressed payload.
+// It can be saved as a sidecar file of the package to skip building it again.
+type PayloadIndex struct {
+	// Digest of the header, to check the index belongs to the package
+	Digest     string
+	Compressor string
+	Entries    []PayloadIndexEntry
+	// Blocks of multi-block xz payload. Empty for other payloads
+	XZStreamHeader []byte    `json:",omitempty"`
+	XZBlocks       []XZBlock `json:",omitempty"`
+}
+
+type PayloadIndexEntry struct {
+	Name string
+	// Offset of the file data in the uncompressed payload
+	Offset   int64
+	Metadata cpio.Meta
+}
+
+func (pkg *PackageFile) headerDigest() string {
+	sum := sha256.Sum256(pkg.Header.Section.store)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func (pkg *PackageFile) payloadSize() (size int64, err error) {
+	if pkg.file == nil {
+		return 0, ErrNoPayload
+	}
+
+	stat, err := pkg.file.Stat()
+	if err != nil {
+		return
+	}
+
+	return stat.Size() - pkg.payloadOffset, nil
+}
+
+// BuildPayloadIndex reads through the payload once, and records the offset of every entry.
+// File data are decompressed but not kept in memory.
+func (pkg *PackageFile) BuildPayloadIndex() (index *PayloadIndex, err error) {
+	index = new(PayloadIndex)
+	index.Digest = pkg.headerDigest()
+	index.Compressor = pkg.Header.PayloadCompressor()
+
+	if pkg.Payload == nil && index.Compressor == "xz" {
+		size, err := pkg.payloadSize()
+		if err != nil {
+			return nil, err
+		}
+
+		ra := io.NewSectionReader(pkg.file, pkg.payloadOffset, size)
+		header, blocks, err := readXZBlocks(ra, size)
+		if err == nil && len(blocks) > 1 {
+			index.XZStreamHeader = header
+			index.XZBlocks = blocks
+		}
+	}
+
+	rd, err := pkg.payloadReader(index, 0)
+	if err != nil {
+		return
+	}
+	defer rd.Close()
+
+	reader := cpio.NewCPIOStreamReader(rd)
+	for {
+		file, read_err := reader.Next()
+		if read_err == io.EOF {
+			break
+		}
+
+		if read_err != nil {
+			return nil, read_err
+		}
+
+		index.Entries = append(index.Entries, PayloadIndexEntry{file.Name, file.Offset, *file.Metadata})
+	}
+
+	return
+}
+
+// payloadReader returns uncompressed payload from offset, which the caller closes
+func (pkg *PackageFile) payloadReader(index *PayloadIndex, offset int64) (rd io.ReadCloser, err error) {
+	if pkg.Payload != nil {
+		cpio := pkg.Payload.Cpio()
+		if offset > int64(len(cpio)) {
+			return nil, io.EOF
+		}
+		return ioutil.NopCloser(bytes.NewReader(cpio[offset:])), nil
+	}
+
+	if pkg.file == nil {
+		return nil, ErrNoPayload
+	}
+
+	if len(index.XZBlocks) > 0 {
+		size, err := pkg.payloadSize()
+		if err != nil {
+			return nil, err
+		}
+
+		ra := io.NewSectionReader(pkg.file, pkg.payloadOffset, size)
+		reader, err := newXZSeekReader(ra, index.XZStreamHeader, index.XZBlocks, offset)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(reader), nil
+	}
+
+	_, err = pkg.file.Seek(pkg.payloadOffset, os.SEEK_SET)
+	if err != nil {
+		return
+	}
+
+	rd, err = getDecompressor(index.Compressor, pkg.file)
+	if err != nil {
+		return
+	}
+
+	_, err = io.CopyN(ioutil.Discard, rd, offset)
+	if err != nil {
+		rd.Close()
+		return nil, err
+	}
+
+	return
+}
+
+func (pkg *PackageFile) PayloadIndex() (index *PayloadIndex, err error) {
+	if pkg.payloadIndex == nil {
+		pkg.payloadIndex, err = pkg.BuildPayloadIndex()
+	}
+
+	return pkg.payloadIndex, err
+}
+
+func (pkg *PackageFile) SetPayloadIndex(index *PayloadIndex) (err error) {
+	if index.Digest != pkg.headerDigest() {
+		return fmt.Errorf("Payload index does not belong to the package")
+	}
+
+	pkg.payloadIndex = index
+
+	return
+}
+
+// CachePayloadIndex loads the payload index from the sidecar file at path.
+// If the file does not exist or is stale, the index is built and saved to path.
+func (pkg *PackageFile) CachePayloadIndex(path string) (err error) {
+	cache, err := os.Open(path)
+	if err == nil {
+		index, read_err := ReadPayloadIndex(cache)
+		cache.Close()
+
+		if read_err == nil && pkg.SetPayloadIndex(index) == nil {
+			return
+		}
+	}
+
+	index, err := pkg.BuildPayloadIndex()
+	if err != nil {
+		return
+	}
+	pkg.payloadIndex = index
+
+	cache, err = os.Create(path)
+	if err != nil {
+		return
+	}
+
+	err = index.Write(cache)
+	if close_err := cache.Close(); err == nil {
+		err = close_err
+	}
+
+	return
+}
+
+func ReadPayloadIndex(rd io.Reader) (index *PayloadIndex, err error) {
+	index = new(PayloadIndex)
+	err = json.NewDecoder(rd).Decode(index)
+
+	return
+}
+
+func (index *PayloadIndex) Write(w io.Writer) (err error) {
+	return json.NewEncoder(w).Encode(index)
+}
+
+func (index *PayloadIndex) Lookup(name string) (entry *PayloadIndexEntry, found bool) {
+	name = fsName(name)
+
+	for i := range index.Entries {
+		if fsName(index.Entries[i].Name) == name {
+			return &index.Entries[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Open reads a file in the payload.
+// Only the data up to the end of the file is decompressed,
+// and with multi-block xz payload, only blocks containing the file.
+func (pkg *PackageFile) Open(name string) (file *cpio.File, err error) {
+	index, err := pkg.PayloadIndex()
+	if err != nil {
+		return
+	}
+
+	entry, found := index.Lookup(name)
+	if !found {
+		return nil, fmt.Errorf("%s is not found in the payload", name)
+	}
+
+	// Data of hard linked files is stored in the last entry of them
+	data_entry := entry
+	if entry.Metadata.Nlink > 1 && entry.Metadata.Filesize == 0 {
+		for i := range index.Entries {
+			e := &index.Entries[i]
+			if e.Metadata.Ino == entry.Metadata.Ino && e.Metadata.Filesize > 0 {
+				data_entry = e
+			}
+		}
+	}
+
+	rd, err := pkg.payloadReader(index, data_entry.Offset)
+	if err != nil {
+		return
+	}
+	defer rd.Close()
+
+	data := make([]byte, data_entry.Metadata.Filesize)
+	_, err = io.ReadFull(rd, data)
+	if err != nil {
+		return
+	}
+
+	meta := entry.Metadata
+	meta.Filesize = data_entry.Metadata.Filesize
+	file = cpio.NewFile(&meta, entry.Name, data)
+	file.Offset = data_entry.Offset
+
+	return
+}
@@ -0,0 +1,107 @@
+package rpmlib_test
+
+import (
+	"bytes"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func indexTestPackage() rpmtest.Package {
+	return rpmtest.Binary("foo", "1.0", "1",
+		rpmtest.Dir("/etc/foo"),
+		rpmtest.Regular("/etc/foo/a.conf", "a = 1\n"),
+		rpmtest.Regular("/etc/foo/b.conf", string(bytes.Repeat([]byte("b"), 70000))),
+		rpmtest.File{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | 0755, Data: "linked", Inode: 100},
+		rpmtest.File{Path: "/usr/bin/foo2", Mode: rpmlib.S_IFREG | 0755, Data: "linked", Inode: 100},
+		rpmtest.Regular("/usr/share/foo/z", "z"),
+	)
+}
+
+func TestPackageFileOpen(t *testing.T) {
+	pkg := rpmtest.Open(t, indexTestPackage())
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"/etc/foo/a.conf", "a = 1\n"},
+		{"./etc/foo/b.conf", string(bytes.Repeat([]byte("b"), 70000))},
+		{"usr/share/foo/z", "z"},
+		// Data of hard links is stored only in the last entry
+		{"/usr/bin/foo", "linked"},
+		{"/usr/bin/foo2", "linked"},
+	}
+
+	for _, test := range tests {
+		file, err := pkg.Open(test.name)
+		if err != nil {
+			t.Errorf("Open(%s): %s", test.name, err)
+			continue
+		}
+		if string(file.Bytes()) != test.data {
+			t.Errorf("Open(%s) = %q, want %q", test.name, file.Bytes(), test.data)
+		}
+	}
+
+	if _, err := pkg.Open("/etc/foo/missing"); err == nil {
+		t.Errorf("Open of a missing file succeeded")
+	}
+}
+
+func TestCachePayloadIndex(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "foo.index")
+
+	pkg := rpmtest.Open(t, indexTestPackage())
+	if err := pkg.CachePayloadIndex(cache); err != nil {
+		t.Fatal(err)
+	}
+	built, _ := pkg.PayloadIndex()
+
+	// The cache is read again, and offsets are kept
+	pkg = rpmtest.Open(t, indexTestPackage())
+	if err := pkg.CachePayloadIndex(cache); err != nil {
+		t.Fatal(err)
+	}
+	index, _ := pkg.PayloadIndex()
+	if len(index.Entries) != len(built.Entries) {
+		t.Fatalf("Cached index has %d entries, want %d", len(index.Entries), len(built.Entries))
+	}
+	for i := range index.Entries {
+		if index.Entries[i] != built.Entries[i] {
+			t.Errorf("Cached entry %d = %+v, want %+v", i, index.Entries[i], built.Entries[i])
+		}
+	}
+	if file, err := pkg.Open("/usr/share/foo/z"); err != nil || string(file.Bytes()) != "z" {
+		t.Errorf("Open by the cached index: %v", err)
+	}
+
+	// A cache of another package is stale, and is built again
+	other := rpmtest.Binary("bar", "1.0", "1", rpmtest.Regular("/usr/share/bar/z", "bar"))
+	pkg = rpmtest.Open(t, other)
+	if err := pkg.SetPayloadIndex(index); err == nil {
+		t.Errorf("Index of another package is accepted")
+	}
+	if err := pkg.CachePayloadIndex(cache); err != nil {
+		t.Fatal(err)
+	}
+	if file, err := pkg.Open("/usr/share/bar/z"); err != nil || string(file.Bytes()) != "bar" {
+		t.Errorf("Open after the stale cache: %v", err)
+	}
+
+	f, err := os.Open(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved, err := rpmlib.ReadPayloadIndex(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := saved.Lookup("/usr/share/bar/z"); !found {
+		t.Errorf("Stale cache is not replaced")
+	}
+}
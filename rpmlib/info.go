@@ -0,0 +1,206 @@
+package rpmlib
+
+import (
+	"time"
+)
+
+//
+// Serializable model of a package for JSON and YAML output.
+// Field names of these structures shall be kept stable.
+//
+
+type PackageInfo struct {
+	Name        string     `json:"name" yaml:"name"`
+	Epoch       *int32     `json:"epoch,omitempty" yaml:"epoch,omitempty"`
+	Version     string     `json:"version" yaml:"version"`
+	Release     string     `json:"release" yaml:"release"`
+	Arch        string     `json:"arch" yaml:"arch"`
+	Group       string     `json:"group,omitempty" yaml:"group,omitempty"`
+	Size        int64      `json:"size" yaml:"size"`
+	License     string     `json:"license,omitempty" yaml:"license,omitempty"`
+	BuildTime   *time.Time `json:"buildtime,omitempty" yaml:"buildtime,omitempty"`
+	BuildHost   string     `json:"buildhost,omitempty" yaml:"buildhost,omitempty"`
+	SourceRpm   string     `json:"sourcerpm,omitempty" yaml:"sourcerpm,omitempty"`
+	Vendor      string     `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Packager    string     `json:"packager,omitempty" yaml:"packager,omitempty"`
+	URL         string     `json:"url,omitempty" yaml:"url,omitempty"`
+	Summary     string     `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	// Sources and patches of a source package
+	Sources []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Patches []string `json:"patches,omitempty" yaml:"patches,omitempty"`
+
+	Requires  []Dependency `json:"requires,omitempty" yaml:"requires,omitempty"`
+	Provides  []Dependency `json:"provides,omitempty" yaml:"provides,omitempty"`
+	Conflicts []Dependency `json:"conflicts,omitempty" yaml:"conflicts,omitempty"`
+	Obsoletes []Dependency `json:"obsoletes,omitempty" yaml:"obsoletes,omitempty"`
+
+	Files     []FileEntry   `json:"files,omitempty" yaml:"files,omitempty"`
+	Changelog []Changelog   `json:"changelog,omitempty" yaml:"changelog,omitempty"`
+	Verify    []VerifyEntry `json:"verify,omitempty" yaml:"verify,omitempty"`
+}
+
+type FileEntry struct {
+	Path   string    `json:"path" yaml:"path"`
+	Size   int64     `json:"size" yaml:"size"`
+	Mode   uint16    `json:"mode" yaml:"mode"`
+	Perms  string    `json:"perms" yaml:"perms"`
+	MTime  time.Time `json:"mtime" yaml:"mtime"`
+	Digest string    `json:"digest,omitempty" yaml:"digest,omitempty"`
+	LinkTo string    `json:"linkto,omitempty" yaml:"linkto,omitempty"`
+	Flags  int32     `json:"flags" yaml:"flags"`
+	// Flags by letters of rpm, such as "cn" for %config(noreplace)
+	FlagNames string `json:"flagnames,omitempty" yaml:"flagnames,omitempty"`
+	User      string `json:"user,omitempty" yaml:"user,omitempty"`
+	Group     string `json:"group,omitempty" yaml:"group,omitempty"`
+}
+
+// Kinds of verification failure
+const (
+	VerifySize   = "size"
+	VerifyMode   = "mode"
+	VerifyDigest = "digest"
+	VerifyMTime  = "mtime"
+)
+
+type VerifyProblem struct {
+	Kind    string `json:"kind" yaml:"kind"`
+	Message string `json:"message" yaml:"message"`
+}
+
+type VerifyEntry struct {
+	Path  string `json:"path" yaml:"path"`
+	Flags int32  `json:"flags" yaml:"flags"`
+	// Result in the same format as "rpm -V", such as "S.5."
+	Result   string          `json:"result" yaml:"result"`
+	Problems []VerifyProblem `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+func NewPackageInfo(header *Header) (info *PackageInfo) {
+	info = new(PackageInfo)
+
+	info.Name = header.Name()
+	info.Version = header.Version()
+	info.Release = header.Release()
+	info.Group = header.Group()
+	info.Size = int64(uint32(header.Size()))
+	info.License = header.License()
+	info.Summary = header.Summary()
+	info.Description = header.Description()
+
+	if epoch, err := header.Section.GetInt32(RPMTAG_EPOCH); err == nil {
+		info.Epoch = &epoch
+	}
+	if buildtime, err := header.BuildDate(); err == nil {
+		info.BuildTime = &buildtime
+	}
+
+	info.Arch, _ = header.Section.GetString(RPMTAG_ARCH)
+	info.BuildHost, _ = header.Section.GetString(RPMTAG_BUILDHOST)
+	info.SourceRpm, _ = header.SourceRpm()
+	info.Vendor, _ = header.Section.GetString(RPMTAG_VENDOR)
+	info.Packager, _ = header.Section.GetString(RPMTAG_PACKAGER)
+	info.URL, _ = header.Section.GetString(RPMTAG_URL)
+	info.Sources, _ = header.Sources()
+	info.Patches, _ = header.Patches()
+
+	return
+}
+
+func (info *PackageInfo) SetDependencies(header *Header) (err error) {
+	info.Requires, err = header.Requires()
+	if err != nil {
+		return
+	}
+
+	info.Provides, err = header.Provides()
+	if err != nil {
+		return
+	}
+
+	info.Conflicts, err = header.Conflicts()
+	if err != nil {
+		return
+	}
+
+	info.Obsoletes, err = header.Obsoletes()
+
+	return
+}
+
+// SetFiles sets files which have any of flags. All files are set if flags is RPMFILE_NONE
+func (info *PackageInfo) SetFiles(header *Header, flags int32) (err error) {
+	files, err := header.Files()
+	if err != nil {
+		return
+	}
+
+	info.Files = []FileEntry{}
+	for _, f := range files {
+		if flags != RPMFILE_NONE && f.Flag&flags == 0 {
+			continue
+		}
+
+		info.Files = append(info.Files, NewFileEntry(f))
+	}
+
+	return
+}
+
+func NewFileEntry(meta FileMeta) (entry FileEntry) {
+	entry.Path = meta.Path
+	entry.Size = int64(uint32(meta.Size))
+	entry.Mode = uint16(meta.Mode)
+	entry.Perms = PermsString(uint64(entry.Mode))
+	entry.MTime = time.Unix(int64(uint32(meta.Time)), 0)
+	entry.Digest = meta.MD5
+	entry.LinkTo = meta.LinkTo
+	entry.Flags = meta.Flag
+	entry.FlagNames = FileFlagsString(uint64(uint32(meta.Flag)))
+	entry.User = meta.User
+	entry.Group = meta.Group
+
+	return
+}
+
+func (info *PackageInfo) SetChangelog(header *Header) (err error) {
+	info.Changelog, err = header.Changelog()
+
+	return
+}
+
+func (info *PackageInfo) SetVerifyResults(results []VerifyResult) {
+	info.Verify = []VerifyEntry{}
+
+	for _, r := range results {
+		info.Verify = append(info.Verify, NewVerifyEntry(r))
+	}
+}
+
+func NewVerifyEntry(result VerifyResult) (entry VerifyEntry) {
+	entry.Path = result.Path
+	entry.Flags = result.FileType
+
+	checks := []struct {
+		kind   string
+		letter byte
+		err    error
+	}{
+		{VerifySize, 'S', result.Size},
+		{VerifyMode, 'M', result.Mode},
+		{VerifyDigest, '5', result.Checksum},
+		{VerifyMTime, 'T', result.MTime},
+	}
+
+	for _, c := range checks {
+		if c.err == nil {
+			entry.Result += "."
+			continue
+		}
+
+		entry.Result += string(c.letter)
+		entry.Problems = append(entry.Problems, VerifyProblem{c.kind, c.err.Error()})
+	}
+
+	return
+}
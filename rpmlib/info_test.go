@@ -0,0 +1,203 @@
+package rpmlib_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Update golden files in testdata")
+
+// infoPackage is the fixture of package infos, with files of each type and flags
+func infoPackage() rpmtest.Package {
+	conf := rpmtest.Regular("/etc/foo.conf", "option=1\n")
+	conf.Flags = rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE
+	doc := rpmtest.Regular("/usr/share/doc/foo/README", "Read me\n")
+	doc.Flags = rpmlib.RPMFILE_DOC
+	ghost := rpmtest.File{Path: "/var/log/foo.log", Mode: rpmlib.S_IFREG | 0640, Flags: rpmlib.RPMFILE_GHOST}
+
+	pkg := rpmtest.Binary("foo", "1.2", "3", conf, rpmtest.Regular("/usr/bin/foo", "#!/bin/sh\n"),
+		rpmtest.Symlink("/usr/lib/libfoo.so", "libfoo.so.1"), rpmtest.Dir("/usr/share/doc/foo"), doc, ghost)
+	for i := range pkg.Files {
+		pkg.Files[i].Time = 1600000000
+	}
+	pkg.Arch = "x86_64"
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddInt32(rpmlib.RPMTAG_EPOCH, 1)
+		b.AddString(rpmlib.RPMTAG_BUILDHOST, "build.example.com")
+		b.AddString(rpmlib.RPMTAG_VENDOR, "Example")
+		b.AddString(rpmlib.RPMTAG_PACKAGER, "Foo <foo@example.com>")
+		b.AddString(rpmlib.RPMTAG_URL, "https://example.com/foo")
+		b.AddStringArray(rpmlib.RPMTAG_REQUIRENAME, []string{"/bin/sh", "bar"})
+		b.AddInt32(rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMSENSE_SCRIPT_PRE, rpmlib.RPMSENSE_GREATER|rpmlib.RPMSENSE_EQUAL)
+		b.AddStringArray(rpmlib.RPMTAG_REQUIREVERSION, []string{"", "2.0"})
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDENAME, []string{"foo"})
+		b.AddInt32(rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMSENSE_EQUAL)
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDEVERSION, []string{"1:1.2-3"})
+		b.AddStringArray(rpmlib.RPMTAG_OBSOLETENAME, []string{"foo-old"})
+		b.AddInt32(rpmlib.RPMTAG_OBSOLETEFLAGS, rpmlib.RPMSENSE_LESS)
+		b.AddStringArray(rpmlib.RPMTAG_OBSOLETEVERSION, []string{"1.0"})
+		b.AddInt32(rpmlib.RPMTAG_CHANGELOGTIME, 1600000000)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGNAME, []string{"Foo <foo@example.com> - 1.2-3"})
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGTEXT, []string{"- Update to 1.2"})
+	}
+
+	return pkg
+}
+
+// inUTC converts times of the info to UTC, so golden files do not depend on the time zone
+func inUTC(info *rpmlib.PackageInfo) {
+	if info.BuildTime != nil {
+		buildTime := info.BuildTime.UTC()
+		info.BuildTime = &buildTime
+	}
+	for i := range info.Files {
+		info.Files[i].MTime = info.Files[i].MTime.UTC()
+	}
+	for i := range info.Changelog {
+		info.Changelog[i].Date = info.Changelog[i].Date.UTC()
+	}
+}
+
+// checkGolden compares the output with the file in testdata, which is written with -update
+func checkGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(golden, output, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Errorf("Output differs from %s:\n%s", golden, output)
+	}
+}
+
+// Outputs are encoded same as WriteOutput of gorpm
+func TestPackageInfoGolden(t *testing.T) {
+	header := rpmtest.Open(t, infoPackage()).Header
+
+	info := rpmlib.NewPackageInfo(header)
+	if err := info.SetDependencies(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := info.SetFiles(header, rpmlib.RPMFILE_NONE); err != nil {
+		t.Fatal(err)
+	}
+	if err := info.SetChangelog(header); err != nil {
+		t.Fatal(err)
+	}
+	info.SetVerifyResults([]rpmlib.VerifyResult{
+		{Path: "/usr/bin/foo"},
+		{Path: "/etc/foo.conf", FileType: rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE,
+			Size: errors.New("Size 9 differs from 12"), Checksum: errors.New("Digest differs")},
+		{Path: "/usr/lib/libfoo.so", Mode: errors.New("Mode 0120777 differs from 0100644"),
+			MTime: errors.New("Modification time differs")},
+	})
+	inUTC(info)
+
+	var output bytes.Buffer
+	encoder := json.NewEncoder(&output)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(info); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "info.json", output.Bytes())
+
+	output.Reset()
+	yamlEncoder := yaml.NewEncoder(&output)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(info); err != nil {
+		t.Fatal(err)
+	}
+	if err := yamlEncoder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "info.yaml", output.Bytes())
+}
+
+func TestSetFiles(t *testing.T) {
+	header := rpmtest.Open(t, infoPackage()).Header
+	info := rpmlib.NewPackageInfo(header)
+
+	tests := []struct {
+		flags int32
+		paths []string
+	}{
+		{rpmlib.RPMFILE_CONFIG, []string{"/etc/foo.conf"}},
+		{rpmlib.RPMFILE_DOC | rpmlib.RPMFILE_GHOST, []string{"/usr/share/doc/foo/README", "/var/log/foo.log"}},
+		{rpmlib.RPMFILE_LICENSE, nil},
+	}
+
+	for _, test := range tests {
+		if err := info.SetFiles(header, test.flags); err != nil {
+			t.Fatal(err)
+		}
+		// Files are an empty list, not null, if none has the flags
+		if info.Files == nil || len(info.Files) != len(test.paths) {
+			t.Errorf("SetFiles(%#x) = %+v, want %v", test.flags, info.Files, test.paths)
+			continue
+		}
+		for i, file := range info.Files {
+			if file.Path != test.paths[i] || file.Flags&test.flags == 0 {
+				t.Errorf("SetFiles(%#x) = %+v, want %v", test.flags, info.Files, test.paths)
+			}
+		}
+	}
+}
+
+func TestNewFileEntry(t *testing.T) {
+	meta := rpmlib.FileMeta{Path: "/usr/bin/su", Size: -1, Mode: int16(rpmlib.S_IFREG | 04755 - 0x10000),
+		Time: -1, Flag: rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE, User: "root", Group: "wheel"}
+
+	// Sizes and times are unsigned in headers
+	entry := rpmlib.NewFileEntry(meta)
+	if entry.Size != 1<<32-1 || entry.Mode != rpmlib.S_IFREG|04755 || entry.Perms != "-rwsr-xr-x" ||
+		entry.MTime.Unix() != 1<<32-1 || entry.FlagNames != "cn" || entry.User != "root" || entry.Group != "wheel" {
+		t.Errorf("NewFileEntry() = %+v", entry)
+	}
+}
+
+func TestNewVerifyEntry(t *testing.T) {
+	tests := []struct {
+		result rpmlib.VerifyResult
+		entry  string
+		kinds  []string
+	}{
+		{rpmlib.VerifyResult{Path: "/usr/bin/foo"}, "....", nil},
+		{rpmlib.VerifyResult{Path: "/usr/bin/foo", Size: errors.New("size")}, "S...", []string{rpmlib.VerifySize}},
+		{rpmlib.VerifyResult{Path: "/usr/bin/foo", Mode: errors.New("mode"), MTime: errors.New("mtime")},
+			".M.T", []string{rpmlib.VerifyMode, rpmlib.VerifyMTime}},
+		{rpmlib.VerifyResult{Path: "/usr/bin/foo", Size: errors.New("size"), Mode: errors.New("mode"),
+			Checksum: errors.New("digest"), MTime: errors.New("mtime")},
+			"SM5T", []string{rpmlib.VerifySize, rpmlib.VerifyMode, rpmlib.VerifyDigest, rpmlib.VerifyMTime}},
+	}
+
+	for _, test := range tests {
+		entry := rpmlib.NewVerifyEntry(test.result)
+		if entry.Path != test.result.Path || entry.Result != test.entry || len(entry.Problems) != len(test.kinds) {
+			t.Errorf("NewVerifyEntry(%+v) = %+v, want %s", test.result, entry, test.entry)
+			continue
+		}
+		for i, problem := range entry.Problems {
+			if problem.Kind != test.kinds[i] || problem.Message != test.kinds[i] {
+				t.Errorf("NewVerifyEntry(%+v) = %+v, want %v", test.result, entry, test.kinds)
+			}
+		}
+	}
+}
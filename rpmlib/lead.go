@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -125,19 +126,15 @@ func (lead *Lead) SignatureType() (typenumber int16) {
 	return
 }
 
-func ScanLead(file *os.File) (rpmlead *Lead, err error) {
+func ScanLead(file io.Reader) (rpmlead *Lead, err error) {
 
 	rpmlead = new(Lead)
 	rpmlead.data = make([]byte, LeadSize)
 
-	nsize, err := file.Read(rpmlead.data)
-
+	// A lead shorter than LeadSize is io.ErrUnexpectedEOF
+	_, err = io.ReadFull(file, rpmlead.data)
 	if err != nil {
-		return
-	}
-
-	if int64(nsize) != LeadSize {
-		return nil, fmt.Errorf("Less data size for RPM Lead")
+		return nil, err
 	}
 
 	err = rpmlead.Validate()
@@ -0,0 +1,130 @@
+package rpmlib
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// NEVRA identifies a package by name, epoch, version, release and architecture.
+// Arch is "src" or "nosrc" for source packages.
+type NEVRA struct {
+	Name string
+	EVR  EVR
+	Arch string
+}
+
+// ParseNEVRA parses a string in the form of "name-[epoch:]version-release.arch".
+// The epoch is also accepted in front of the name, as "epoch:name-version-release.arch".
+func ParseNEVRA(s string) (nevra NEVRA, err error) {
+	rest := s
+
+	epoch := ""
+	if i := strings.IndexByte(rest, ':'); i > 0 && strings.Trim(rest[:i], "0123456789") == "" {
+		epoch = rest[:i+1]
+		rest = rest[i+1:]
+	}
+
+	dot := strings.LastIndexByte(rest, '.')
+	if dot < 0 {
+		return nevra, fmt.Errorf("Architecture not found in %s", s)
+	}
+	nevra.Arch = rest[dot+1:]
+	rest = rest[:dot]
+
+	dash := strings.LastIndexByte(rest, '-')
+	if dash < 0 {
+		return nevra, fmt.Errorf("Release not found in %s", s)
+	}
+	release := rest[dash+1:]
+	rest = rest[:dash]
+
+	dash = strings.LastIndexByte(rest, '-')
+	if dash < 0 {
+		return nevra, fmt.Errorf("Version not found in %s", s)
+	}
+	nevra.Name = rest[:dash]
+	version := rest[dash+1:]
+
+	nevra.EVR, err = ParseEVR(epoch + version + "-" + release)
+	if err != nil {
+		return
+	}
+
+	// Epochs are numbers given once
+	if nevra.Name == "" || nevra.EVR.Version == "" || nevra.EVR.Release == "" || nevra.Arch == "" ||
+		strings.HasPrefix(version, ":") || strings.ContainsRune(nevra.EVR.Version, ':') {
+		return nevra, fmt.Errorf("Invalid NEVRA %s", s)
+	}
+
+	return
+}
+
+// ParsePackageFilename parses a package file name such as "/path/to/name-version-release.arch.rpm"
+func ParsePackageFilename(filename string) (nevra NEVRA, err error) {
+	base := filepath.Base(filename)
+	if !strings.HasSuffix(base, ".rpm") {
+		return nevra, fmt.Errorf("Package file name shall end with .rpm: %s", filename)
+	}
+
+	return ParseNEVRA(strings.TrimSuffix(base, ".rpm"))
+}
+
+func (nevra NEVRA) String() string {
+	return nevra.Name + "-" + nevra.EVR.String() + "." + nevra.Arch
+}
+
+// Filename returns the canonical file name of the package, "name-version-release.arch.rpm".
+// The epoch is not a part of file name, same as rpmbuild.
+func (nevra NEVRA) Filename() string {
+	return fmt.Sprintf("%s-%s-%s.%s.rpm", nevra.Name, nevra.EVR.Version, nevra.EVR.Release, nevra.Arch)
+}
+
+// IsSource checks the header is of a source package.
+// Source packages have no RPMTAG_SOURCERPM, same as rpm.
+func (header *Header) IsSource() bool {
+	return !header.Section.HasStore(RPMTAG_SOURCERPM)
+}
+
+func (header *Header) sourceArch() string {
+	if header.Section.HasStore(RPMTAG_NOSOURCE) || header.Section.HasStore(RPMTAG_NOPATCH) {
+		return "nosrc"
+	}
+
+	return "src"
+}
+
+// NEVRA of the header. It is decided by the header whether a source package or not,
+// use PackageFile.NEVRA to decide it by the lead.
+func (header *Header) NEVRA() (nevra NEVRA) {
+	nevra.Name = header.Name()
+	nevra.EVR = header.EVR()
+
+	if header.IsSource() {
+		nevra.Arch = header.sourceArch()
+	} else {
+		nevra.Arch, _ = header.Section.GetString(RPMTAG_ARCH)
+	}
+
+	return
+}
+
+func (pkg *PackageFile) NEVRA() (nevra NEVRA) {
+	nevra = pkg.Header.NEVRA()
+
+	if pkg.Lead != nil {
+		if pkg.Lead.RpmType() == SourcePackageFileType {
+			nevra.Arch = pkg.Header.sourceArch()
+		} else {
+			nevra.Arch, _ = pkg.Header.Section.GetString(RPMTAG_ARCH)
+		}
+	}
+
+	return
+}
+
+// CanonicalFilename returns the canonical file name of the package,
+// to be compared with the actual file name
+func (pkg *PackageFile) CanonicalFilename() string {
+	return pkg.NEVRA().Filename()
+}
@@ -0,0 +1,135 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"testing"
+)
+
+func TestParseNEVRA(t *testing.T) {
+	tests := []struct {
+		s     string
+		nevra rpmlib.NEVRA
+	}{
+		{"foo-1.0-1.x86_64", rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{Version: "1.0", Release: "1"}, Arch: "x86_64"}},
+		{"1:foo-1-2.x86_64",
+			rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{Epoch: 1, HasEpoch: true, Version: "1", Release: "2"}, Arch: "x86_64"}},
+		{"foo-1:1-2.x86_64",
+			rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{Epoch: 1, HasEpoch: true, Version: "1", Release: "2"}, Arch: "x86_64"}},
+		{"foo-0:1-2.noarch",
+			rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{HasEpoch: true, Version: "1", Release: "2"}, Arch: "noarch"}},
+		{"foo-1.0-1.src", rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{Version: "1.0", Release: "1"}, Arch: "src"}},
+		{"foo-1.0-1.nosrc", rpmlib.NEVRA{Name: "foo", EVR: rpmlib.EVR{Version: "1.0", Release: "1"}, Arch: "nosrc"}},
+		{"python3-foo-bar-2.1.3-4.el9.noarch",
+			rpmlib.NEVRA{Name: "python3-foo-bar", EVR: rpmlib.EVR{Version: "2.1.3", Release: "4.el9"}, Arch: "noarch"}},
+		{"2:foo-bar-1.0-1.fc38.i686",
+			rpmlib.NEVRA{Name: "foo-bar", EVR: rpmlib.EVR{Epoch: 2, HasEpoch: true, Version: "1.0", Release: "1.fc38"}, Arch: "i686"}},
+	}
+
+	for _, test := range tests {
+		nevra, err := rpmlib.ParseNEVRA(test.s)
+		if err != nil {
+			t.Errorf("ParseNEVRA(%q): %s", test.s, err)
+			continue
+		}
+		if nevra != test.nevra {
+			t.Errorf("ParseNEVRA(%q) = %+v, want %+v", test.s, nevra, test.nevra)
+		}
+	}
+}
+
+func TestParseNEVRAErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		".",
+		"-",
+		":",
+		"1:",
+		"foo",
+		"foo.x86_64",
+		"foo-1.x86_64",
+		"foo-1-2",
+		"foo-1-2.",
+		"foo--2.x86_64",
+		"foo-1-.x86_64",
+		"-1-2.x86_64",
+		"1:-1-2.x86_64",
+		"foo-:1-2.x86_64",
+		"foo-a:1-2.x86_64",
+		"1:foo-2:1-2.x86_64",
+		"99999999999:foo-1-2.x86_64",
+	} {
+		if nevra, err := rpmlib.ParseNEVRA(s); err == nil {
+			t.Errorf("ParseNEVRA(%q) = %+v, want an error", s, nevra)
+		}
+	}
+}
+
+func TestParsePackageFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		nevra    string
+	}{
+		{"foo-1.0-1.x86_64.rpm", "foo-1.0-1.x86_64"},
+		{"/path/to/foo-bar-1.0-1.noarch.rpm", "foo-bar-1.0-1.noarch"},
+		{"foo-1.0-1.nosrc.rpm", "foo-1.0-1.nosrc"},
+		// Not a package file
+		{"foo-1.0-1.x86_64", ""},
+		{"foo-1.0-1.x86_64.rpm.bak", ""},
+		{".rpm", ""},
+		{"foo.rpm", ""},
+	}
+
+	for _, test := range tests {
+		nevra, err := rpmlib.ParsePackageFilename(test.filename)
+		switch {
+		case test.nevra == "" && err == nil:
+			t.Errorf("ParsePackageFilename(%q) = %s, want an error", test.filename, nevra)
+		case test.nevra != "" && err != nil:
+			t.Errorf("ParsePackageFilename(%q): %s", test.filename, err)
+		case test.nevra != "" && nevra.String() != test.nevra:
+			t.Errorf("ParsePackageFilename(%q) = %s, want %s", test.filename, nevra, test.nevra)
+		}
+	}
+}
+
+func TestCanonicalFilename(t *testing.T) {
+	epoch := func(b *rpmtest.Builder) { b.AddInt32(rpmlib.RPMTAG_EPOCH, 1) }
+
+	binary := rpmtest.Binary("foo-bar", "1.0", "1")
+	binary.Arch = "x86_64"
+	binary.Tags = epoch
+	source := rpmtest.Binary("foo-bar", "1.0", "1")
+	source.SourceRPM = ""
+	source.Tags = epoch
+	nosource := source
+	nosource.Tags = func(b *rpmtest.Builder) { b.AddInt32(rpmlib.RPMTAG_NOSOURCE, 0) }
+
+	tests := []struct {
+		name     string
+		pkg      rpmtest.Package
+		nevra    string
+		filename string
+	}{
+		// The epoch is not a part of file names
+		{"binary", binary, "foo-bar-1:1.0-1.x86_64", "foo-bar-1.0-1.x86_64.rpm"},
+		{"source", source, "foo-bar-1:1.0-1.src", "foo-bar-1.0-1.src.rpm"},
+		{"nosource", nosource, "foo-bar-1.0-1.nosrc", "foo-bar-1.0-1.nosrc.rpm"},
+	}
+
+	for _, test := range tests {
+		pkg := rpmtest.Open(t, test.pkg)
+		if nevra := pkg.NEVRA().String(); nevra != test.nevra {
+			t.Errorf("%s: NEVRA() = %s, want %s", test.name, nevra, test.nevra)
+		}
+		if filename := pkg.CanonicalFilename(); filename != test.filename {
+			t.Errorf("%s: CanonicalFilename() = %s, want %s", test.name, filename, test.filename)
+		}
+
+		// Without the lead, it is decided by the header
+		pkg.Lead = nil
+		if filename := pkg.CanonicalFilename(); filename != test.filename {
+			t.Errorf("%s: CanonicalFilename() without the lead = %s, want %s", test.name, filename, test.filename)
+		}
+	}
+}
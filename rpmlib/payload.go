@@ -1,11 +1,13 @@
 package rpmlib
 
 import (
+	"compress/bzip2"
 	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"github.com/klauspost/compress/zstd"
 	"github.com/xi2/xz"
 )
 
@@ -22,14 +24,28 @@ func testread(file *os.File) {
 	}
 }
 
-func getDecompressor(name string, file *os.File) (rd io.Reader, err error) {
+// getDecompressor returns the reader of the payload, which has to be closed
+// to release goroutines and memory of the zstd decoder
+func getDecompressor(name string, file *os.File) (rd io.ReadCloser, err error) {
 	switch name {
 	case "xz":
-		rd, err = xz.NewReader(file, 0)
+		var reader *xz.Reader
+		reader, err = xz.NewReader(file, 0)
+		if err == nil {
+			rd = ioutil.NopCloser(reader)
+		}
 		break
 	case "gzip":
 		rd, err = gzip.NewReader(file)
 		break
+	case "bzip2":
+		rd = ioutil.NopCloser(bzip2.NewReader(file))
+	case "zstd":
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(file, zstd.WithDecoderConcurrency(1))
+		if err == nil {
+			rd = decoder.IOReadCloser()
+		}
 	default:
 		err = fmt.Errorf("Unkown compressor name %s", name)
 	}
@@ -43,6 +59,8 @@ func ScanPayload(file *os.File, comparessor string) (payload *Payload, err error
 		return
 	}
 
+	defer decompressor.Close()
+
 	payload = new(Payload)
 	payload.uncompressed, err = ioutil.ReadAll(decompressor)
 
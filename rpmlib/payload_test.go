@@ -0,0 +1,55 @@
+package rpmlib
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// testdata/lines.* are lines "line 0000" to "line 0099" compressed by
+// gzip, bzip2, xz and zstd, which are compressors of payloads
+func TestScanPayload(t *testing.T) {
+	var expected string
+	for i := 0; i < 100; i++ {
+		expected += fmt.Sprintf("line %04d\n", i)
+	}
+
+	tests := []struct {
+		compressor string
+		filename   string
+	}{
+		{"gzip", "testdata/lines.gz"},
+		{"bzip2", "testdata/lines.bz2"},
+		{"xz", "testdata/lines.xz"},
+		{"zstd", "testdata/lines.zst"},
+	}
+
+	for _, test := range tests {
+		file, err := os.Open(test.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		payload, err := ScanPayload(file, test.compressor)
+		file.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.compressor, err)
+			continue
+		}
+		if string(payload.Cpio()) != expected {
+			t.Errorf("%s: %d bytes are decompressed", test.compressor, len(payload.Cpio()))
+		}
+	}
+}
+
+func TestScanPayloadUnknown(t *testing.T) {
+	file, err := os.Open("testdata/lines.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err = ScanPayload(file, "lzma"); err == nil {
+		t.Errorf("ScanPayload() of lzma succeeded")
+	}
+}
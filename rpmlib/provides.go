@@ -0,0 +1,133 @@
+package rpmlib
+
+import (
+	"strings"
+)
+
+type provider struct {
+	header  *Header
+	provide Dependency
+}
+
+// ProvidesIndex finds packages by what they provide,
+// both of provides and files in packages
+type ProvidesIndex struct {
+	providers map[string][]provider
+}
+
+func NewProvidesIndex() (index *ProvidesIndex) {
+	index = new(ProvidesIndex)
+	index.providers = make(map[string][]provider)
+
+	return
+}
+
+// Add adds provides and files of the package to the index
+func (index *ProvidesIndex) Add(header *Header) (err error) {
+	provides, err := header.Provides()
+	if err != nil {
+		return
+	}
+
+	for _, provide := range provides {
+		index.providers[provide.Name] = append(index.providers[provide.Name], provider{header, provide})
+	}
+
+	// Packages without files are valid
+	if !header.Section.HasStore(RPMTAG_BASENAMES) && !header.Section.HasStore(RPMTAG_OLDFILENAMES) {
+		return
+	}
+
+	filenames, err := header.FileNames()
+	if err != nil {
+		return
+	}
+
+	for _, filename := range filenames {
+		index.providers[filename] = append(index.providers[filename], provider{header, Dependency{Name: filename}})
+	}
+
+	return
+}
+
+// WhatProvides returns packages which satisfy the simple dependency.
+// A package is returned only once even if it has multiple matching provides.
+func (index *ProvidesIndex) WhatProvides(require Dependency) (headers []*Header) {
+	found := make(map[*Header]bool)
+
+	for _, p := range index.providers[require.Name] {
+		if found[p.header] || !overlaps(p.provide, require, false) {
+			continue
+		}
+
+		found[p.header] = true
+		headers = append(headers, p.header)
+	}
+
+	return
+}
+
+// Resolves checks the dependency is satisfied by any package in the index.
+// A rich dependency is evaluated with the packages in the index,
+// and err is the error of parsing it.
+func (index *ProvidesIndex) Resolves(require Dependency) (resolved bool, err error) {
+	rich, err := require.Rich()
+	if err != nil {
+		return
+	}
+
+	return rich.Eval(func(dep Dependency) bool {
+		return len(index.WhatProvides(dep)) > 0
+	}), nil
+}
+
+// IsRpmlib checks the dependency is on a feature of rpm, such as "rpmlib(PayloadIsXz)",
+// which is not provided by any package
+func (dep Dependency) IsRpmlib() bool {
+	return dep.Flags&RPMSENSE_RPMLIB != 0 || strings.HasPrefix(dep.Name, "rpmlib(")
+}
+
+// UnresolvedDependency is a requirement of package not satisfied in a repository
+type UnresolvedDependency struct {
+	Header  *Header
+	Require Dependency
+	// Error of parsing a rich dependency, which is not resolved
+	Err error
+}
+
+// CheckClosure finds requirements of binary packages which are not satisfied in packages.
+// Requirements on rpmlib features and ones allowed to be missing are ignored.
+func CheckClosure(headers []*Header) (unresolved []UnresolvedDependency, err error) {
+	index := NewProvidesIndex()
+	for _, header := range headers {
+		err = index.Add(header)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, header := range headers {
+		if header.IsSource() {
+			continue
+		}
+
+		var requires []Dependency
+		requires, err = header.Requires()
+		if err != nil {
+			return
+		}
+
+		for _, require := range requires {
+			if require.IsRpmlib() || require.Flags&RPMSENSE_MISSINGOK != 0 {
+				continue
+			}
+
+			resolved, resolve_err := index.Resolves(require)
+			if !resolved {
+				unresolved = append(unresolved, UnresolvedDependency{header, require, resolve_err})
+			}
+		}
+	}
+
+	return
+}
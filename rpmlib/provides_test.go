@@ -0,0 +1,91 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"testing"
+)
+
+func providesTestHeader(t *testing.T, name string, provides []string, flags []int32, versions []string) *rpmlib.Header {
+	pkg := rpmtest.Binary(name, "1.0", "1", rpmtest.Regular("/usr/share/"+name+"/data", name))
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDENAME, provides)
+		b.AddInt32(rpmlib.RPMTAG_PROVIDEFLAGS, flags...)
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDEVERSION, versions)
+	}
+
+	return rpmtest.Open(t, pkg).Header
+}
+
+func TestProvidesIndex(t *testing.T) {
+	foo := providesTestHeader(t, "foo", []string{"foo", "libfoo"}, []int32{rpmlib.RPMSENSE_EQUAL, rpmlib.RPMSENSE_EQUAL}, []string{"1.0-1", "1.0"})
+	bar := providesTestHeader(t, "bar", []string{"bar"}, []int32{rpmlib.RPMSENSE_EQUAL}, []string{"1.0-1"})
+
+	index := rpmlib.NewProvidesIndex()
+	for _, header := range []*rpmlib.Header{foo, bar} {
+		if err := index.Add(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		require rpmlib.Dependency
+		found   int
+	}{
+		{rpmlib.Dependency{Name: "foo"}, 1},
+		{rpmlib.Dependency{Name: "foo", Flags: rpmlib.RPMSENSE_GREATER, Version: "1.0"}, 0},
+		// A provide without release matches requires of any release
+		{rpmlib.Dependency{Name: "libfoo", Flags: rpmlib.RPMSENSE_GREATER, Version: "1.0-1"}, 1},
+		{rpmlib.Dependency{Name: "libfoo", Flags: rpmlib.RPMSENSE_LESS, Version: "1.0-1"}, 1},
+		{rpmlib.Dependency{Name: "libfoo", Flags: rpmlib.RPMSENSE_GREATER, Version: "1.1"}, 0},
+		// Files are provided
+		{rpmlib.Dependency{Name: "/usr/share/bar/data"}, 1},
+		{rpmlib.Dependency{Name: "/usr/share/baz/data"}, 0},
+	}
+
+	for _, test := range tests {
+		if headers := index.WhatProvides(test.require); len(headers) != test.found {
+			t.Errorf("WhatProvides(%s) found %d packages, want %d", test.require, len(headers), test.found)
+		}
+	}
+
+	for require, resolved := range map[string]bool{
+		"(foo and bar)":           true,
+		"(foo and baz)":           false,
+		"(baz or libfoo > 1.0-1)": true,
+	} {
+		if ok, err := index.Resolves(rpmlib.Dependency{Name: require}); err != nil || ok != resolved {
+			t.Errorf("Resolves(%s) = %v, %v, want %v", require, ok, err, resolved)
+		}
+	}
+	if _, err := index.Resolves(rpmlib.Dependency{Name: "(foo and"}); err == nil {
+		t.Errorf("Broken rich dependency is resolved without errors")
+	}
+}
+
+// Rich dependencies which are not parsed are reported with the errors
+func TestCheckClosure(t *testing.T) {
+	pkg := rpmtest.Binary("foo", "1.0", "1")
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDENAME, []string{"foo"})
+		b.AddInt32(rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMSENSE_EQUAL)
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDEVERSION, []string{"1.0-1"})
+		b.AddStringArray(rpmlib.RPMTAG_REQUIRENAME, []string{"rpmlib(PayloadIsXz)", "foo", "bar", "(foo or", "(foo or baz)"})
+		b.AddInt32(rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMSENSE_RPMLIB|rpmlib.RPMSENSE_LESS|rpmlib.RPMSENSE_EQUAL, 0, 0, 0, 0)
+		b.AddStringArray(rpmlib.RPMTAG_REQUIREVERSION, []string{"5.2-1", "", "", "", ""})
+	}
+
+	unresolved, err := rpmlib.CheckClosure([]*rpmlib.Header{rpmtest.Open(t, pkg).Header})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unresolved) != 2 {
+		t.Fatalf("CheckClosure() = %v", unresolved)
+	}
+	if unresolved[0].Require.Name != "bar" || unresolved[0].Err != nil {
+		t.Errorf("Unresolved %s: %v", unresolved[0].Require.Name, unresolved[0].Err)
+	}
+	if unresolved[1].Require.Name != "(foo or" || unresolved[1].Err == nil {
+		t.Errorf("Unresolved %s: %v", unresolved[1].Require.Name, unresolved[1].Err)
+	}
+}
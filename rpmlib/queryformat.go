@@ -0,0 +1,707 @@
+package rpmlib
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//
+// Query format, compatible with the --queryformat option of rpm.
+//
+//   %{NAME}, %-20{NAME}, %{BUILDTIME:date}  tag with width and formatter
+//   %{#FILENAMES}                           number of elements of tag
+//   %{=NAME}                                first element, also in array
+//   [%{FILENAMES}\n]                        iterate array tags
+//   %|EPOCH?{%{EPOCH}:}:{}|                 conditional on tag presence
+//
+
+type qfToken interface{}
+
+type qfLiteral string
+
+type qfTag struct {
+	info      TagInfo
+	width     string
+	formatter string
+	count     bool
+	first     bool
+}
+
+type qfArray struct {
+	tokens []qfToken
+}
+
+type qfCond struct {
+	info    TagInfo
+	ifTrue  []qfToken
+	ifFalse []qfToken
+}
+
+type QueryFormat struct {
+	tokens []qfToken
+}
+
+var queryFormatters = map[string]func(value interface{}) (string, error){
+	"date":     qfDate,
+	"day":      qfDay,
+	"octal":    qfOctal,
+	"hex":      qfHex,
+	"perms":    qfPerms,
+	"fflags":   qfFileFlags,
+	"depflags": qfDepFlags,
+	"shescape": qfShellEscape,
+	"json":     qfJSON,
+	"string":   qfString,
+}
+
+type qfParser struct {
+	format string
+	pos    int
+	// Arrays are not nested, but conditionals are nested in arrays and the reverse
+	inArray bool
+}
+
+func ParseQueryFormat(format string) (qf *QueryFormat, err error) {
+	parser := &qfParser{format: format}
+
+	qf = new(QueryFormat)
+	qf.tokens, err = parser.parse(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (p *qfParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Query format error at %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// parse reads tokens until end character. end is 0 for the whole format
+func (p *qfParser) parse(end byte) (tokens []qfToken, err error) {
+	var literal []byte
+
+	flush := func() {
+		if len(literal) > 0 {
+			tokens = append(tokens, qfLiteral(literal))
+			literal = nil
+		}
+	}
+
+	for p.pos < len(p.format) {
+		c := p.format[p.pos]
+
+		switch {
+		case c == end:
+			p.pos++
+			flush()
+			return
+
+		case c == '\\':
+			p.pos++
+			if p.pos >= len(p.format) {
+				return nil, p.errorf("escape at end of format")
+			}
+			literal = append(literal, unescapeQueryFormat(p.format[p.pos]))
+			p.pos++
+
+		case c == '%' && p.pos+1 < len(p.format) && p.format[p.pos+1] == '%':
+			literal = append(literal, '%')
+			p.pos += 2
+
+		case c == '%' && p.pos+1 < len(p.format) && p.format[p.pos+1] == '|':
+			flush()
+			p.pos += 2
+			cond, err := p.parseCond()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, cond)
+
+		case c == '%':
+			flush()
+			p.pos++
+			tag, err := p.parseTag()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tag)
+
+		case c == '[':
+			if p.inArray {
+				return nil, p.errorf("nested array is not allowed")
+			}
+			flush()
+			p.pos++
+			array := new(qfArray)
+			p.inArray = true
+			array.tokens, err = p.parse(']')
+			if err != nil {
+				return nil, err
+			}
+			p.inArray = false
+			tokens = append(tokens, array)
+
+		case c == ']' || c == '}':
+			return nil, p.errorf("unexpected '%c'", c)
+
+		default:
+			literal = append(literal, c)
+			p.pos++
+		}
+	}
+
+	if end != 0 {
+		return nil, p.errorf("missing '%c'", end)
+	}
+
+	flush()
+
+	return
+}
+
+func unescapeQueryFormat(c byte) byte {
+	switch c {
+	case 'a':
+		return '\a'
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'v':
+		return '\v'
+	}
+
+	return c
+}
+
+func (p *qfParser) readUntil(chars string) (s string, err error) {
+	start := p.pos
+	for p.pos < len(p.format) && !strings.ContainsRune(chars, rune(p.format[p.pos])) {
+		p.pos++
+	}
+
+	if p.pos >= len(p.format) {
+		return "", p.errorf("missing one of '%s'", chars)
+	}
+
+	return p.format[start:p.pos], nil
+}
+
+func (p *qfParser) lookupTag(name string) (info TagInfo, err error) {
+	info, found := TagByName(name)
+	if !found {
+		return info, p.errorf("unknown tag '%s'", name)
+	}
+
+	return
+}
+
+// parseTag reads "-20{=NAME:format}" after '%'
+func (p *qfParser) parseTag() (tag *qfTag, err error) {
+	tag = new(qfTag)
+
+	tag.width, err = p.readUntil("{")
+	if err != nil {
+		return
+	}
+	if strings.Trim(tag.width, "-0123456789.") != "" {
+		return nil, p.errorf("invalid width '%s'", tag.width)
+	}
+	p.pos++
+
+	if p.pos < len(p.format) {
+		switch p.format[p.pos] {
+		case '#':
+			tag.count = true
+			p.pos++
+		case '=':
+			tag.first = true
+			p.pos++
+		}
+	}
+
+	name, err := p.readUntil(":}")
+	if err != nil {
+		return
+	}
+
+	tag.info, err = p.lookupTag(name)
+	if err != nil {
+		return
+	}
+
+	if p.format[p.pos] == ':' {
+		p.pos++
+		tag.formatter, err = p.readUntil("}")
+		if err != nil {
+			return
+		}
+
+		if _, found := queryFormatters[tag.formatter]; !found {
+			return nil, p.errorf("unknown formatter '%s'", tag.formatter)
+		}
+	}
+	p.pos++
+
+	return
+}
+
+// parseCond reads "TAG?{...}:{...}|" after "%|"
+func (p *qfParser) parseCond() (cond *qfCond, err error) {
+	cond = new(qfCond)
+
+	name, err := p.readUntil("?")
+	if err != nil {
+		return
+	}
+
+	cond.info, err = p.lookupTag(name)
+	if err != nil {
+		return
+	}
+	p.pos++
+
+	if p.pos >= len(p.format) || p.format[p.pos] != '{' {
+		return nil, p.errorf("'{' is expected after '?'")
+	}
+	p.pos++
+
+	cond.ifTrue, err = p.parse('}')
+	if err != nil {
+		return
+	}
+
+	if p.pos < len(p.format) && p.format[p.pos] == ':' {
+		p.pos++
+		if p.pos >= len(p.format) || p.format[p.pos] != '{' {
+			return nil, p.errorf("'{' is expected after ':'")
+		}
+		p.pos++
+
+		cond.ifFalse, err = p.parse('}')
+		if err != nil {
+			return
+		}
+	}
+
+	if p.pos >= len(p.format) || p.format[p.pos] != '|' {
+		return nil, p.errorf("'|' is expected at end of conditional")
+	}
+	p.pos++
+
+	return
+}
+
+func (qf *QueryFormat) Format(header *Header) (output string, err error) {
+	var builder strings.Builder
+
+	err = qf.format(&builder, header, qf.tokens, -1)
+	if err != nil {
+		return
+	}
+
+	return builder.String(), nil
+}
+
+// tagValues returns the values of tag, also for extension tags
+func (header *Header) tagValues(info TagInfo) (values []interface{}, found bool, err error) {
+	switch info.Tag {
+	case RPMTAG_EPOCHNUM:
+		epoch, _ := header.Section.GetInt32(RPMTAG_EPOCH)
+		return []interface{}{uint64(uint32(epoch))}, true, nil
+	case RPMTAG_NVR, RPMTAG_NVRA, RPMTAG_EVR, RPMTAG_NEVR, RPMTAG_NEVRA:
+		return []interface{}{header.nevraString(info.Tag)}, true, nil
+	case RPMTAG_FILENAMES:
+		filenames, err := header.FileNames()
+		if err != nil {
+			return nil, false, nil
+		}
+		for _, name := range filenames {
+			values = append(values, name)
+		}
+		return values, true, nil
+	}
+
+	if !header.Section.HasStore(info.Tag) {
+		return nil, false, nil
+	}
+
+	_, values, err = header.Section.values(info.Tag)
+	if err != nil {
+		return
+	}
+
+	return values, true, nil
+}
+
+func (header *Header) nevraString(tag int32) string {
+	evr := header.EVR().String()
+
+	arch, _ := header.Section.GetString(RPMTAG_ARCH)
+
+	switch tag {
+	case RPMTAG_NVR:
+		return header.Name() + "-" + header.Version() + "-" + header.Release()
+	case RPMTAG_NVRA:
+		return header.Name() + "-" + header.Version() + "-" + header.Release() + "." + arch
+	case RPMTAG_EVR:
+		return evr
+	case RPMTAG_NEVR:
+		return header.Name() + "-" + evr
+	}
+
+	return header.Name() + "-" + evr + "." + arch
+}
+
+func (qf *QueryFormat) format(builder *strings.Builder, header *Header, tokens []qfToken, element int) (err error) {
+	for _, token := range tokens {
+		switch t := token.(type) {
+		case qfLiteral:
+			builder.WriteString(string(t))
+
+		case *qfTag:
+			s, err := qf.formatTag(header, t, element)
+			if err != nil {
+				return err
+			}
+			builder.WriteString(s)
+
+		case *qfCond:
+			_, found, err := header.tagValues(t.info)
+			if err != nil {
+				return err
+			}
+
+			branch := t.ifFalse
+			if found {
+				branch = t.ifTrue
+			}
+
+			err = qf.format(builder, header, branch, element)
+			if err != nil {
+				return err
+			}
+
+		case *qfArray:
+			n, err := qf.arraySize(header, t.tokens)
+			if err != nil {
+				return err
+			}
+
+			for i := 0; i < n; i++ {
+				err = qf.format(builder, header, t.tokens, i)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// arraySize counts iterations of array from tags in it.
+// Single value tags are repeated in each iteration.
+func (qf *QueryFormat) arraySize(header *Header, tokens []qfToken) (size int, err error) {
+	size = 1
+	var sized *TagInfo
+
+	for _, token := range tokens {
+		var n int
+
+		switch t := token.(type) {
+		case *qfTag:
+			if t.first || t.count {
+				continue
+			}
+
+			values, found, err := header.tagValues(t.info)
+			if err != nil {
+				return 0, err
+			}
+			if !found {
+				continue
+			}
+			n = len(values)
+
+			if n > 1 && sized != nil && n != size {
+				return 0, fmt.Errorf("Array iterator used with different sized arrays %s and %s",
+					sized.Name, t.info.Name)
+			}
+			if n > 1 {
+				sized = &t.info
+			}
+
+		case *qfCond:
+			var m int
+			m, err = qf.arraySize(header, append(append([]qfToken{}, t.ifTrue...), t.ifFalse...))
+			if err != nil {
+				return
+			}
+			n = m
+		}
+
+		if n > size {
+			size = n
+		}
+	}
+
+	return
+}
+
+func (qf *QueryFormat) formatTag(header *Header, tag *qfTag, element int) (output string, err error) {
+	values, found, err := header.tagValues(tag.info)
+	if err != nil {
+		return
+	}
+
+	if tag.count {
+		output = fmt.Sprintf("%d", len(values))
+	} else if !found {
+		output = "(none)"
+	} else {
+		if element < 0 || tag.first || len(values) == 1 {
+			element = 0
+		}
+		if element >= len(values) {
+			return "", fmt.Errorf("Array index %d is out of %s", element, tag.info.Name)
+		}
+
+		formatter := qfString
+		if tag.formatter != "" {
+			formatter = queryFormatters[tag.formatter]
+		}
+
+		output, err = formatter(values[element])
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", tag.info.Name, err)
+		}
+	}
+
+	if tag.width != "" {
+		output = fmt.Sprintf("%"+tag.width+"s", output)
+	}
+
+	return
+}
+
+//
+// Formatters
+//
+
+func qfInteger(value interface{}) (n uint64, err error) {
+	n, ok := value.(uint64)
+	if !ok {
+		err = fmt.Errorf("(not a number)")
+	}
+
+	return
+}
+
+func qfString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case uint64:
+		return fmt.Sprintf("%d", v), nil
+	case string:
+		return v, nil
+	case []byte:
+		return hex.EncodeToString(v), nil
+	}
+
+	return "", fmt.Errorf("(unknown type)")
+}
+
+// qfDate formats the time same as strftime %c of the C locale, which rpm uses
+func qfDate(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return time.Unix(int64(n), 0).Format("Mon Jan _2 15:04:05 2006"), nil
+}
+
+// qfDay formats the date same as strftime "%a %b %d %Y"
+func qfDay(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return time.Unix(int64(n), 0).Format("Mon Jan 02 2006"), nil
+}
+
+func qfOctal(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%o", n), nil
+}
+
+func qfHex(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", n), nil
+}
+
+// PermsString formats st_mode like "ls -l", such as "-rwxr-xr-x"
+func PermsString(mode uint64) string {
+	perms := []byte("----------")
+
+	switch mode & S_IFMT {
+	case S_IFDIR:
+		perms[0] = 'd'
+	case S_IFLNK:
+		perms[0] = 'l'
+	case S_IFIFO:
+		perms[0] = 'p'
+	case S_IFSOCK:
+		perms[0] = 's'
+	case S_IFCHR:
+		perms[0] = 'c'
+	case S_IFBLK:
+		perms[0] = 'b'
+	}
+
+	for i, c := range "rwxrwxrwx" {
+		if mode&(1<<uint(8-i)) != 0 {
+			perms[i+1] = byte(c)
+		}
+	}
+
+	special := []struct {
+		bit   uint64
+		pos   int
+		set   byte
+		unset byte
+	}{
+		{S_ISUID, 3, 's', 'S'},
+		{S_ISGID, 6, 's', 'S'},
+		{S_ISVTX, 9, 't', 'T'},
+	}
+	for _, s := range special {
+		if mode&s.bit != 0 {
+			if perms[s.pos] == 'x' {
+				perms[s.pos] = s.set
+			} else {
+				perms[s.pos] = s.unset
+			}
+		}
+	}
+
+	return string(perms)
+}
+
+func qfPerms(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return PermsString(n), nil
+}
+
+// FileFlagsString formats RPMFILE_* flags in the same way as rpm
+func FileFlagsString(flags uint64) string {
+	var builder strings.Builder
+
+	table := []struct {
+		flag uint64
+		c    byte
+	}{
+		{RPMFILE_DOC, 'd'},
+		{RPMFILE_CONFIG, 'c'},
+		{RPMFILE_SPECFILE, 's'},
+		{RPMFILE_MISSINGOK, 'm'},
+		{RPMFILE_NOREPLACE, 'n'},
+		{RPMFILE_GHOST, 'g'},
+		{RPMFILE_LICENSE, 'l'},
+		{RPMFILE_README, 'r'},
+		{RPMFILE_ARTIFACT, 'a'},
+	}
+	for _, t := range table {
+		if flags&t.flag != 0 {
+			builder.WriteByte(t.c)
+		}
+	}
+
+	return builder.String()
+}
+
+func qfFileFlags(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return FileFlagsString(n), nil
+}
+
+// DepFlagsString formats sense flags of dependency, such as ">="
+func DepFlagsString(flags uint64) string {
+	var s string
+
+	if flags&RPMSENSE_LESS != 0 {
+		s += "<"
+	}
+	if flags&RPMSENSE_GREATER != 0 {
+		s += ">"
+	}
+	if flags&RPMSENSE_EQUAL != 0 {
+		s += "="
+	}
+
+	return s
+}
+
+func qfDepFlags(value interface{}) (string, error) {
+	n, err := qfInteger(value)
+	if err != nil {
+		return "", err
+	}
+
+	return DepFlagsString(n), nil
+}
+
+func qfShellEscape(value interface{}) (string, error) {
+	s, err := qfString(value)
+	if err != nil {
+		return "", err
+	}
+
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'", nil
+}
+
+func qfJSON(value interface{}) (string, error) {
+	if n, ok := value.(uint64); ok {
+		return fmt.Sprintf("%d", n), nil
+	}
+
+	s, err := qfString(value)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+
+	err = encoder.Encode(s)
+
+	return strings.TrimSuffix(builder.String(), "\n"), err
+}
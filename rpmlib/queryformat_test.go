@@ -0,0 +1,154 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+	"time"
+)
+
+func queryFormatTestHeader(t *testing.T) *rpmlib.Header {
+	pkg := rpmtest.Binary("foo", "1.0", "2",
+		rpmtest.File{Path: "/etc/foo.conf", Mode: rpmlib.S_IFREG | 0644, Data: "a", Flags: rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE},
+		rpmtest.File{Path: "/usr/bin/foo", Mode: rpmlib.S_IFREG | 04755, Data: "foo"},
+	)
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDENAME, []string{"foo", "bar"})
+		b.AddInt32(rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMSENSE_EQUAL, rpmlib.RPMSENSE_GREATER|rpmlib.RPMSENSE_EQUAL)
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDEVERSION, []string{"1.0-2", "2"})
+		b.AddString(rpmlib.RPMTAG_URL, "it's")
+	}
+
+	return rpmtest.Open(t, pkg).Header
+}
+
+func TestQueryFormat(t *testing.T) {
+	header := queryFormatTestHeader(t)
+
+	tests := []struct {
+		format string
+		output string
+	}{
+		{"%{NAME}-%{VERSION}-%{RELEASE}\n", "foo-1.0-2\n"},
+		{"%{nevra}", "foo-1.0-2.noarch"},
+		{"%-5{NAME}|%5{NAME}", "foo  |  foo"},
+		{"%{EPOCH}", "(none)"},
+		{"%{EPOCHNUM}", "0"},
+		{"100%% \\t\\n", "100% \t\n"},
+		{"%{#FILENAMES} %{=FILENAMES}", "2 /etc/foo.conf"},
+		{"[%{FILENAMES} %{FILEMODES:perms} %{FILEMODES:octal} %{FILEFLAGS:fflags};]",
+			"/etc/foo.conf -rw-r--r-- 100644 cn;/usr/bin/foo -rwsr-xr-x 104755 ;"},
+		{"[%{PROVIDENAME} %{PROVIDEFLAGS:depflags} %{PROVIDEVERSION}\n]", "foo = 1.0-2\nbar >= 2\n"},
+		// Single value tags are repeated in arrays
+		{"[%{NAME}:%{FILENAMES} ]", "foo:/etc/foo.conf foo:/usr/bin/foo "},
+		{"%{FILEMODES:hex}", "81a4"},
+		{"%{URL:shescape} %{URL:json}", `'it'\''s' "it's"`},
+		{"%|EPOCH?{%{EPOCH}:}:{no epoch}|", "no epoch"},
+		{"%|URL?{url}|", "url"},
+		{"%|EPOCH?{epoch}|", ""},
+		// Conditionals in arrays, and arrays in conditionals
+		{"[%|FILENAMES?{%{FILENAMES}}|,]", "/etc/foo.conf,/usr/bin/foo,"},
+		{"%|NAME?{[%{FILENAMES}\\n]}|", "/etc/foo.conf\n/usr/bin/foo\n"},
+		{"%|EPOCH?{}:{[%{PROVIDENAME} ]}|", "foo bar "},
+	}
+
+	for _, test := range tests {
+		qf, err := rpmlib.ParseQueryFormat(test.format)
+		if err != nil {
+			t.Errorf("ParseQueryFormat(%q): %s", test.format, err)
+			continue
+		}
+
+		output, err := qf.Format(header)
+		if err != nil {
+			t.Errorf("Format(%q): %s", test.format, err)
+			continue
+		}
+		if output != test.output {
+			t.Errorf("Format(%q) = %q, want %q", test.format, output, test.output)
+		}
+	}
+}
+
+// Dates are formatted in the local time zone, same as strftime %c of the C locale
+func TestQueryFormatDate(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	pkg := rpmtest.Binary("foo", "1.0", "2")
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddInt32(rpmlib.RPMTAG_CHANGELOGTIME, 1599000000, 946684799)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGNAME, []string{"foo", "bar"})
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGTEXT, []string{"- foo", "- bar"})
+	}
+	header := rpmtest.Open(t, pkg).Header
+
+	tests := []struct {
+		format string
+		output string
+	}{
+		{"%{BUILDTIME:date}", "Sun Sep 13 12:26:40 2020"},
+		{"%{BUILDTIME:day}", "Sun Sep 13 2020"},
+		// Days of one digit are padded by a space for dates, and by zero for days
+		{"[%{CHANGELOGTIME:date}\\n]", "Tue Sep  1 22:40:00 2020\nFri Dec 31 23:59:59 1999\n"},
+		{"[%{CHANGELOGTIME:day}\\n]", "Tue Sep 01 2020\nFri Dec 31 1999\n"},
+	}
+
+	for _, test := range tests {
+		qf, err := rpmlib.ParseQueryFormat(test.format)
+		if err != nil {
+			t.Errorf("ParseQueryFormat(%q): %s", test.format, err)
+			continue
+		}
+
+		output, err := qf.Format(header)
+		if err != nil {
+			t.Errorf("Format(%q): %s", test.format, err)
+			continue
+		}
+		if output != test.output {
+			t.Errorf("Format(%q) = %q, want %q", test.format, output, test.output)
+		}
+	}
+}
+
+func TestQueryFormatErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		err    string
+	}{
+		{"%{NOSUCHTAG}", "unknown tag"},
+		{"%{NAME:nosuchformat}", "unknown formatter"},
+		{"%{NAME", "missing"},
+		{"[%{NAME}", "missing ']'"},
+		{"[[%{NAME}]]", "nested array"},
+		{"[%|NAME?{[%{NAME}]}|]", "nested array"},
+		{"%|NAME?{x}", "'|' is expected"},
+		{"%|NAME{x}|", "missing"},
+		{"%x{NAME}", "invalid width"},
+		{"a]", "unexpected ']'"},
+		{"\\", "escape at end"},
+	}
+
+	for _, test := range tests {
+		_, err := rpmlib.ParseQueryFormat(test.format)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseQueryFormat(%q): error %v, want %q", test.format, err, test.err)
+		}
+	}
+
+	// Arrays of different sizes are not iterated together
+	qf, err := rpmlib.ParseQueryFormat("[%{FILENAMES} %{PROVIDENAME}\n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provides := rpmtest.Binary("foo", "1.0", "1", rpmtest.Regular("/a", ""), rpmtest.Regular("/b", ""), rpmtest.Regular("/c", ""))
+	provides.Tags = func(b *rpmtest.Builder) {
+		b.AddStringArray(rpmlib.RPMTAG_PROVIDENAME, []string{"foo", "bar"})
+	}
+	if _, err := qf.Format(rpmtest.Open(t, provides).Header); err == nil {
+		t.Errorf("Arrays of different sizes are iterated")
+	}
+}
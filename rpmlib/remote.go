@@ -0,0 +1,165 @@
+package rpmlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Size of magic, version, reserved bytes, number of indexes and data size of a section
+const SectionIntroSize = 16
+
+// HTTPRangeReader reads a remote file by HTTP range requests, one request for each ReadAt
+type HTTPRangeReader struct {
+	URL    string
+	Client *http.Client
+	// Number of requests and bytes read, for diagnostics
+	Requests  int
+	BytesRead int64
+}
+
+func NewHTTPRangeReader(url string) *HTTPRangeReader {
+	return &HTTPRangeReader{URL: url, Client: http.DefaultClient}
+}
+
+// IsURL checks the argument is an http or https URL instead of a file path
+func IsURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+func (r *HTTPRangeReader) ReadAt(p []byte, off int64) (n int, err error) {
+	if len(p) == 0 {
+		return
+	}
+
+	request, err := http.NewRequest("GET", r.URL, nil)
+	if err != nil {
+		return
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	response, err := r.Client.Do(request)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	r.Requests++
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server ignores ranges and sends the whole file, which is read until p is filled
+		_, err = io.CopyN(ioutil.Discard, response.Body, off)
+		if err != nil {
+			return 0, io.EOF
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		return 0, io.EOF
+	default:
+		return 0, fmt.Errorf("%s", response.Status)
+	}
+
+	n, err = io.ReadFull(response.Body, p)
+	r.BytesRead += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	return
+}
+
+// sectionSize computes the size of a section from its intro, without padding
+func sectionSize(intro []byte) (size int64, err error) {
+	nindex := int64(int32(binary.BigEndian.Uint32(intro[8:12])))
+	hsize := int64(int32(binary.BigEndian.Uint32(intro[12:16])))
+
+	if nindex < 0 || nindex > SectionMaxIndexes || hsize < 0 || hsize > SectionMaxStoreSize {
+		return 0, fmt.Errorf("Section size is invalid")
+	}
+
+	return SectionIntroSize + nindex*16 + hsize, nil
+}
+
+func readAt(r io.ReaderAt, off int64, size int64) (data []byte, err error) {
+	data = make([]byte, size)
+	_, err = r.ReadAt(data, off)
+	if err == io.EOF {
+		err = fmt.Errorf("Reached EOF before reading a section completed")
+	}
+
+	return
+}
+
+// ReadPackageHeaderAt reads lead, signature and header of a package from r.
+// Only bytes of them are read, with three reads, and the payload is not read.
+// The payload of the returned package is not available.
+func ReadPackageHeaderAt(r io.ReaderAt) (pkg *PackageFile, err error) {
+	pkg = new(PackageFile)
+
+	// Lead and the intro of signature
+	data, err := readAt(r, 0, LeadSize+SectionIntroSize)
+	if err != nil {
+		return
+	}
+
+	pkg.Lead, err = ScanLead(bytes.NewReader(data[:LeadSize]))
+	if err != nil {
+		return
+	}
+
+	signatureSize, err := sectionSize(data[LeadSize:])
+	if err != nil {
+		return
+	}
+
+	// Rest of signature, padding to 8 bytes boundary and the intro of header
+	padding := signaturePadding(signatureSize)
+	rest, err := readAt(r, LeadSize+SectionIntroSize, signatureSize-SectionIntroSize+padding+SectionIntroSize)
+	if err != nil {
+		return
+	}
+	data = append(data, rest...)
+
+	pkg.Signature, err = ScanSignature(bytes.NewReader(data[LeadSize : LeadSize+signatureSize]))
+	if err != nil {
+		return
+	}
+
+	pkg.headerOffset = LeadSize + signatureSize + padding
+	headerIntro := data[pkg.headerOffset:]
+
+	headerSize, err := sectionSize(headerIntro)
+	if err != nil {
+		return
+	}
+
+	rest, err = readAt(r, pkg.headerOffset+SectionIntroSize, headerSize-SectionIntroSize)
+	if err != nil {
+		return
+	}
+
+	pkg.Header, err = ScanHeader(io.MultiReader(bytes.NewReader(headerIntro), bytes.NewReader(rest)))
+	if err != nil {
+		return
+	}
+
+	pkg.payloadOffset = pkg.headerOffset + headerSize
+
+	return
+}
+
+// FetchPackageHeader reads lead, signature and header of a remote package
+// by HTTP range requests, without downloading the payload.
+func FetchPackageHeader(url string) (pkg *PackageFile, err error) {
+	// A package which is read but invalid is returned with the error, same as OpenPackageFile
+	pkg, err = ReadPackageHeaderAt(NewHTTPRangeReader(url))
+	if err != nil {
+		err = fmt.Errorf("%s: %s", url, err)
+	}
+
+	return
+}
@@ -0,0 +1,107 @@
+package rpmlib_test
+
+import (
+	"bytes"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func remoteTestPackage(t *testing.T) []byte {
+	pkg := rpmtest.Binary("foo", "1.0", "1",
+		rpmtest.Regular("/usr/share/foo/data", string(bytes.Repeat([]byte("data"), 10000))),
+	)
+
+	data, err := ioutil.ReadFile(rpmtest.Write(t, t.TempDir(), pkg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestFetchPackageHeader(t *testing.T) {
+	data := remoteTestPackage(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		ranges  bool
+	}{
+		{"ranges", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "foo.rpm", time.Time{}, bytes.NewReader(data))
+		}, true},
+		// The whole file is sent for each request, and bytes before the range are skipped
+		{"no ranges", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data)
+		}, false},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(test.handler)
+
+		reader := rpmlib.NewHTTPRangeReader(server.URL + "/foo.rpm")
+		pkg, err := rpmlib.ReadPackageHeaderAt(reader)
+		server.Close()
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		if pkg.Header.NEVRA().String() != "foo-1.0-1.noarch" {
+			t.Errorf("%s: NEVRA %s", test.name, pkg.Header.NEVRA())
+		}
+		if reader.Requests != 3 {
+			t.Errorf("%s: %d requests", test.name, reader.Requests)
+		}
+		_, end := pkg.HeaderRange()
+		if test.ranges && reader.BytesRead != end {
+			t.Errorf("%s: %d bytes are read, want %d", test.name, reader.BytesRead, end)
+		}
+	}
+}
+
+func TestFetchPackageHeaderErrors(t *testing.T) {
+	data := remoteTestPackage(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"not found", http.NotFound},
+		// The file ends before the header
+		{"short file", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeContent(w, r, "foo.rpm", time.Time{}, bytes.NewReader(data[:200]))
+		}},
+		// The response is cut in the middle of the body
+		{"truncated response", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Write(data[:100])
+		}},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(test.handler)
+		_, err := rpmlib.FetchPackageHeader(server.URL + "/foo.rpm")
+		server.Close()
+		if err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+}
+
+func TestScanLeadShort(t *testing.T) {
+	if _, err := rpmlib.ScanLead(bytes.NewReader(make([]byte, 10))); err != io.ErrUnexpectedEOF {
+		t.Errorf("ScanLead() of 10 bytes: %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := rpmlib.ScanLead(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("ScanLead() of no bytes: %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,335 @@
+package rpmlib
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Rich (boolean) dependencies of rpm 4.13 and later, such as
+// "(foo >= 1.0 if bar)" or "(baz or (qux without quux))"
+//
+
+type RichOp string
+
+const (
+	RichNone    RichOp = ""
+	RichAnd     RichOp = "and"
+	RichOr      RichOp = "or"
+	RichIf      RichOp = "if"
+	RichUnless  RichOp = "unless"
+	RichWith    RichOp = "with"
+	RichWithout RichOp = "without"
+)
+
+var richOperators = map[string]bool{
+	"and": true, "or": true, "if": true, "else": true,
+	"unless": true, "with": true, "without": true,
+}
+
+// RichDependency is a node of parsed rich dependency.
+// Op is RichNone for a simple dependency, which is in Dependency.
+// Operands of "A if B else C" are A and B, and C is Else.
+// "A unless B else C" is the same.
+type RichDependency struct {
+	Op         RichOp
+	Dependency Dependency
+	Operands   []*RichDependency
+	Else       *RichDependency
+}
+
+// ParseRichDependency parses a rich dependency such as "(foo >= 1.0 if bar)".
+// The outermost parentheses may be omitted, as "(foo if bar) or baz",
+// and a simple dependency without parentheses is also parsed.
+func ParseRichDependency(expr string) (rich *RichDependency, err error) {
+	tokens := tokenizeRich(expr)
+
+	p := &richParser{tokens: tokens}
+	rich, err = p.term()
+	if err != nil || p.pos != len(p.tokens) {
+		p = &richParser{tokens: append(append([]string{"("}, tokens...), ")")}
+		rich, err = p.term()
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s in rich dependency %s", err, expr)
+	}
+
+	return
+}
+
+// Rich parses the name of dependency as a rich dependency
+func (dep Dependency) Rich() (rich *RichDependency, err error) {
+	if !dep.IsRich() {
+		return &RichDependency{Dependency: dep}, nil
+	}
+
+	return ParseRichDependency(dep.Name)
+}
+
+// tokenizeRich splits a rich dependency into parentheses, operators and words.
+// Parentheses in a name, such as "rpmlib(Foo)", are kept in the word.
+func tokenizeRich(expr string) (tokens []string) {
+	for i := 0; i < len(expr); {
+		c := expr[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+
+		default:
+			start := i
+			depth := 0
+			for i < len(expr) && !strings.ContainsRune(" \t\n", rune(expr[i])) {
+				if expr[i] == '(' {
+					depth++
+				} else if expr[i] == ')' {
+					if depth == 0 {
+						break
+					}
+					depth--
+				}
+				i++
+			}
+			tokens = append(tokens, expr[start:i])
+		}
+	}
+
+	return
+}
+
+type richParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *richParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *richParser) next() string {
+	token := p.peek()
+	p.pos++
+
+	return token
+}
+
+// group parses "(term op term ...)". Only and, or and with can be chained,
+// and different operators shall not be mixed without parentheses, same as rpm.
+func (p *richParser) group() (rich *RichDependency, err error) {
+	if p.next() != "(" {
+		return nil, fmt.Errorf("'(' is expected")
+	}
+
+	first, err := p.term()
+	if err != nil {
+		return
+	}
+
+	op := p.peek()
+	switch RichOp(op) {
+	case RichAnd, RichOr, RichWith, RichWithout, RichIf, RichUnless:
+		p.next()
+		rich = &RichDependency{Op: RichOp(op), Operands: []*RichDependency{first}}
+
+		var operand *RichDependency
+		operand, err = p.term()
+		if err != nil {
+			return
+		}
+		rich.Operands = append(rich.Operands, operand)
+
+		switch rich.Op {
+		case RichAnd, RichOr, RichWith:
+			for p.peek() == op {
+				p.next()
+				operand, err = p.term()
+				if err != nil {
+					return
+				}
+				rich.Operands = append(rich.Operands, operand)
+			}
+
+		case RichIf, RichUnless:
+			if p.peek() == "else" {
+				p.next()
+				rich.Else, err = p.term()
+				if err != nil {
+					return
+				}
+			}
+		}
+
+	default:
+		// Only a term in parentheses
+		if op == "" {
+			return nil, fmt.Errorf("')' is expected")
+		} else if op != ")" {
+			return nil, fmt.Errorf("Unexpected '%s'", op)
+		}
+		rich = first
+	}
+
+	if token := p.next(); token != ")" {
+		if richOperators[token] {
+			return nil, fmt.Errorf("'%s' shall be in parentheses after '%s'", token, op)
+		}
+		return nil, fmt.Errorf("')' is expected")
+	}
+
+	return
+}
+
+// term parses a group or a simple dependency like "foo >= 1.0"
+func (p *richParser) term() (rich *RichDependency, err error) {
+	token := p.peek()
+	if token == "(" {
+		return p.group()
+	}
+
+	if token == "" || token == ")" || richOperators[token] {
+		return nil, fmt.Errorf("Dependency name is expected")
+	}
+	p.next()
+
+	dep := Dependency{Name: token}
+
+	if op := p.peek(); op != "" && strings.Trim(op, "<>=") == "" {
+		if strings.Contains(op, "<") {
+			dep.Flags |= RPMSENSE_LESS
+		}
+		if strings.Contains(op, ">") {
+			dep.Flags |= RPMSENSE_GREATER
+		}
+		if strings.Contains(op, "=") {
+			dep.Flags |= RPMSENSE_EQUAL
+		}
+		p.next()
+
+		dep.Version = p.next()
+		if dep.Version == "" || dep.Version == "(" || dep.Version == ")" {
+			return nil, fmt.Errorf("Version is expected after %s", token)
+		}
+	}
+
+	return &RichDependency{Dependency: dep}, nil
+}
+
+// Eval evaluates the rich dependency, match is called for each simple dependency.
+// "with" and "without" are evaluated as "and" and "and not", since match cannot tell
+// whether the dependencies are provided by the same package.
+func (rich *RichDependency) Eval(match func(Dependency) bool) bool {
+	switch rich.Op {
+	case RichNone:
+		return match(rich.Dependency)
+
+	case RichAnd, RichWith:
+		for _, operand := range rich.Operands {
+			if !operand.Eval(match) {
+				return false
+			}
+		}
+		return true
+
+	case RichOr:
+		for _, operand := range rich.Operands {
+			if operand.Eval(match) {
+				return true
+			}
+		}
+		return false
+
+	case RichWithout:
+		return rich.Operands[0].Eval(match) && !rich.Operands[1].Eval(match)
+
+	case RichIf, RichUnless:
+		cond := rich.Operands[1].Eval(match)
+		if (rich.Op == RichIf) == cond {
+			return rich.Operands[0].Eval(match)
+		}
+		// Without else, nothing is required
+		if rich.Else == nil {
+			return true
+		}
+		return rich.Else.Eval(match)
+	}
+
+	return false
+}
+
+// EvalProvides evaluates the rich dependency against a set of provides, same as SatisfiesAny
+func (rich *RichDependency) EvalProvides(provides []Dependency) bool {
+	return rich.Eval(providesMatch(provides, false))
+}
+
+// Dependencies returns all simple dependencies in the rich dependency
+func (rich *RichDependency) Dependencies() (deps []Dependency) {
+	if rich.Op == RichNone {
+		return []Dependency{rich.Dependency}
+	}
+
+	for _, operand := range rich.Operands {
+		deps = append(deps, operand.Dependencies()...)
+	}
+	if rich.Else != nil {
+		deps = append(deps, rich.Else.Dependencies()...)
+	}
+
+	return
+}
+
+// String formats the rich dependency in one line, in the same form as rpm
+func (rich *RichDependency) String() string {
+	if rich.Op == RichNone {
+		return rich.Dependency.String()
+	}
+
+	var parts []string
+	for _, operand := range rich.Operands {
+		parts = append(parts, operand.String())
+	}
+
+	s := "(" + strings.Join(parts, " "+string(rich.Op)+" ")
+	if rich.Else != nil {
+		s += " else " + rich.Else.String()
+	}
+
+	return s + ")"
+}
+
+// Pretty formats the rich dependency in multiple lines, indented by the nesting
+func (rich *RichDependency) Pretty() string {
+	var builder strings.Builder
+
+	rich.pretty(&builder, "")
+
+	return builder.String()
+}
+
+func (rich *RichDependency) pretty(builder *strings.Builder, indent string) {
+	if rich.Op == RichNone {
+		builder.WriteString(indent + rich.Dependency.String() + "\n")
+		return
+	}
+
+	builder.WriteString(indent + "(\n")
+	for i, operand := range rich.Operands {
+		if i > 0 {
+			builder.WriteString(indent + "  " + string(rich.Op) + "\n")
+		}
+		operand.pretty(builder, indent+"    ")
+	}
+	if rich.Else != nil {
+		builder.WriteString(indent + "  else\n")
+		rich.Else.pretty(builder, indent+"    ")
+	}
+	builder.WriteString(indent + ")\n")
+}
@@ -0,0 +1,125 @@
+package rpmlib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRichDependency(t *testing.T) {
+	tests := []struct {
+		expr   string
+		output string
+	}{
+		{"foo", "foo"},
+		{"foo >= 1.0-1", "foo >= 1.0-1"},
+		{"(foo)", "foo"},
+		{"(foo >= 1.0 if bar)", "(foo >= 1.0 if bar)"},
+		{"(foo or bar or baz)", "(foo or bar or baz)"},
+		{"(foo and (bar or baz))", "(foo and (bar or baz))"},
+		{"(foo if bar else baz < 2)", "(foo if bar else baz < 2)"},
+		{"(foo unless bar else baz)", "(foo unless bar else baz)"},
+		{"(foo with bar)", "(foo with bar)"},
+		{"(foo without bar)", "(foo without bar)"},
+		// Outermost parentheses are optional
+		{"foo if bar", "(foo if bar)"},
+		{"(foo if bar) or baz", "((foo if bar) or baz)"},
+		// Parentheses in names are kept
+		{"(rpmlib(RichDependencies) and perl(Foo::Bar) > 1)", "(rpmlib(RichDependencies) and perl(Foo::Bar) > 1)"},
+		{"  (  foo\tand\nbar  )  ", "(foo and bar)"},
+	}
+
+	for _, test := range tests {
+		rich, err := ParseRichDependency(test.expr)
+		if err != nil {
+			t.Errorf("ParseRichDependency(%q): %s", test.expr, err)
+			continue
+		}
+		if rich.String() != test.output {
+			t.Errorf("ParseRichDependency(%q) = %s, want %s", test.expr, rich.String(), test.output)
+		}
+	}
+}
+
+func TestParseRichDependencyErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"", "Dependency name is expected"},
+		{"()", "Dependency name is expected"},
+		{"(foo or", "Dependency name is expected"},
+		{"(foo and bar", "')' is expected"},
+		{"(foo or bar and baz)", "'and' shall be in parentheses after 'or'"},
+		{"(foo if bar if baz)", "'if' shall be in parentheses after 'if'"},
+		{"(foo and bar else baz)", "'else' shall be in parentheses after 'and'"},
+		{"(foo >=)", "Version is expected after foo"},
+		{"(foo bar)", "Unexpected 'bar'"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRichDependency(test.expr)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ParseRichDependency(%q): error %v, want %q", test.expr, err, test.err)
+		}
+	}
+}
+
+func TestRichDependencyEval(t *testing.T) {
+	provided := map[string]bool{"a": true, "b": true}
+	match := func(dep Dependency) bool {
+		return provided[dep.Name]
+	}
+
+	tests := []struct {
+		expr string
+		eval bool
+	}{
+		{"(a and b)", true},
+		{"(a and c)", false},
+		{"(c or b)", true},
+		{"(c or d)", false},
+		{"(a with b)", true},
+		{"(a without b)", false},
+		{"(a without c)", true},
+		// Without else, a false condition requires nothing
+		{"(c if d)", true},
+		{"(c if a)", false},
+		{"(c if d else b)", true},
+		{"(c if d else d)", false},
+		{"(c unless a)", true},
+		{"(c unless d)", false},
+		{"(a unless d else c)", true},
+		{"(c unless a else b)", true},
+		{"((a or c) and (b if d))", true},
+	}
+
+	for _, test := range tests {
+		rich, err := ParseRichDependency(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eval := rich.Eval(match); eval != test.eval {
+			t.Errorf("Eval(%s) = %v, want %v", test.expr, eval, test.eval)
+		}
+	}
+}
+
+func TestRichDependencyDependencies(t *testing.T) {
+	rich, err := ParseRichDependency("(a >= 1 if (b or c) else d)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, dep := range rich.Dependencies() {
+		names = append(names, dep.String())
+	}
+	if strings.Join(names, ",") != "a >= 1,b,c,d" {
+		t.Errorf("Dependencies() = %v", names)
+	}
+
+	expected := "(\n    a >= 1\n  if\n    (\n        b\n      or\n        c\n    )\n  else\n    d\n)\n"
+	if pretty := rich.Pretty(); pretty != expected {
+		t.Errorf("Pretty() = %q, want %q", pretty, expected)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"os"
 )
 
 const (
@@ -24,6 +23,8 @@ const (
 const (
 	SectionHeaderMagicSize    = 3
 	SectionHeaderReservedSize = 4
+	SectionMaxIndexes         = 0x0000ffff
+	SectionMaxStoreSize       = 0x0fffffff
 )
 var SectionHeaderMagic []byte = []byte{0x8e, 0xad, 0xe8}
 
@@ -48,25 +49,47 @@ type Section struct {
 	store  []byte
 }
 
-func readSectionHeader(file *os.File) (header *SectionHeader, err error) {
+func readSectionHeader(file io.Reader) (header *SectionHeader, err error) {
 	header = new(SectionHeader)
 	err = binary.Read(file, binary.BigEndian, &header.version)
 	if err != nil {
 		return
 	}
 
-	file.Seek(SectionHeaderReservedSize, os.SEEK_CUR)
+	reserved := make([]byte, SectionHeaderReservedSize)
+	_, err = io.ReadFull(file, reserved)
+	if err != nil {
+		return
+	}
+
+	err = readSectionIndexes(file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
 
-	binary.Read(file, binary.BigEndian, &header.nindex)
+// readSectionIndexes reads the number of indexes, the data size and indexes
+func readSectionIndexes(file io.Reader, header *SectionHeader) (err error) {
+	err = binary.Read(file, binary.BigEndian, &header.nindex)
 	if err != nil {
 		return
 	}
 
-	binary.Read(file, binary.BigEndian, &header.hsize)
+	err = binary.Read(file, binary.BigEndian, &header.hsize)
 	if err != nil {
 		return
 	}
 
+	// Same limits as rpm, not to allocate huge memory for a broken file
+	if header.nindex < 0 || header.nindex > SectionMaxIndexes {
+		return fmt.Errorf("Number of section indexes %d is invalid", header.nindex)
+	}
+	if header.hsize < 0 || header.hsize > SectionMaxStoreSize {
+		return fmt.Errorf("Section data store size %d is invalid", header.hsize)
+	}
+
 	header.indexes = make([]SectionHeaderIndex, header.nindex)
 
 	for i, _ := range header.indexes {
@@ -94,30 +117,57 @@ func readSectionHeader(file *os.File) (header *SectionHeader, err error) {
 	return
 }
 
-func scanSection(file *os.File) (section *Section, err error) {
+func scanSection(file io.Reader) (section *Section, err error) {
 	section = new(Section)
 	section.magic = make([]byte, SectionHeaderMagicSize)
 
-	_, err = file.Read(section.magic)
+	_, err = io.ReadFull(file, section.magic)
 	if err != nil {
-		if err == io.EOF {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			err = fmt.Errorf("Reached EOF before reading a section completed")
 		}
-		return
+		return nil, err
 	}
 
 	section.header, err = readSectionHeader(file)
 	if err != nil {
-		if err == io.EOF {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			err = fmt.Errorf("Reached EOF before reading a section completed")
 		}
-		return
+		return nil, err
 	}
 
-	section.store = make([]byte, section.header.hsize)
-	_, err = file.Read(section.store)
+	err = section.readStore(file)
 	if err != nil {
-		return
+		return nil, err
+	}
+
+	err = section.validate()
+
+	return
+}
+
+// scanSectionBlob reads a section without magic, version and reserved bytes,
+// which starts with the number of indexes, such as a header blob in rpmdb
+func scanSectionBlob(blob []byte) (section *Section, err error) {
+	section = new(Section)
+	section.magic = append([]byte{}, SectionHeaderMagic...)
+	section.header = new(SectionHeader)
+	section.header.version = 1
+
+	file := bytes.NewReader(blob)
+
+	err = readSectionIndexes(file, section.header)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Reached EOF before reading a section completed")
+		}
+		return nil, err
+	}
+
+	err = section.readStore(file)
+	if err != nil {
+		return nil, err
 	}
 
 	err = section.validate()
@@ -125,6 +175,19 @@ func scanSection(file *os.File) (section *Section, err error) {
 	return
 }
 
+// readStore reads the data store following indexes
+func (section *Section) readStore(file io.Reader) (err error) {
+	section.store = make([]byte, section.header.hsize)
+	_, err = io.ReadFull(file, section.store)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Reached EOF before reading a section completed")
+		}
+	}
+
+	return
+}
+
 func (section *Section) validate() (err error) {
 
 	// Check magic numbers
@@ -147,8 +210,75 @@ func (section *Section) validate() (err error) {
 		}
 	}
 
-	// TODO
-	// Calculate offset and count to check overrun
+	for _, index := range section.header.indexes {
+		err = section.checkOverrun(index)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+var typeSizes = map[int32]int64{
+	Char:   1,
+	Int8:   1,
+	Int16:  2,
+	Int32:  4,
+	Int64:  8,
+	Binary: 1,
+}
+
+// checkOverrun checks the data of index is in the data store
+func (section *Section) checkOverrun(index SectionHeaderIndex) (err error) {
+	hsize := int64(len(section.store))
+	offset := int64(index.Offset)
+	count := int64(index.Count)
+
+	if offset < 0 || offset > hsize || count < 0 {
+		return fmt.Errorf("Tag %d has invalid offset %d or count %d", index.Tag, offset, count)
+	}
+
+	switch index.Type {
+	case Char, Int8, Int16, Int32, Int64, Binary:
+		if offset+count*typeSizes[index.Type] > hsize {
+			return fmt.Errorf("Data of tag %d overruns the data store", index.Tag)
+		}
+	case String, StringArray, I18nString:
+		if index.Type == String && count != 1 {
+			return fmt.Errorf("Tag %d has invalid count %d for string", index.Tag, count)
+		}
+
+		rest := section.store[offset:]
+		for i := int64(0); i < count; i++ {
+			end := bytes.IndexByte(rest, 0)
+			if end < 0 {
+				return fmt.Errorf("Data of tag %d overruns the data store", index.Tag)
+			}
+			rest = rest[end+1:]
+		}
+	case Null:
+	default:
+		return fmt.Errorf("Tag %d has unknown data type %d", index.Tag, index.Type)
+	}
+
+	return
+}
+
+// tagTypeErrors checks the data types of known tags. rpm reads tags of other types,
+// such as INT16 instead of INT32 of old packages, so they are not errors of reading.
+func (section *Section) tagTypeErrors(tags []TagInfo) (errs []error) {
+	for _, index := range section.header.indexes {
+		info, found := findTagByNumber(tags, index.Tag)
+		if !found {
+			continue
+		}
+
+		if !info.compatibleType(index.Type) {
+			errs = append(errs, fmt.Errorf("Tag %s(%d) has data type %s, but %s is expected",
+				info.Name, info.Tag, TypeName(index.Type), TypeName(info.Type)))
+		}
+	}
 
 	return
 }
@@ -231,6 +361,19 @@ func (section *Section) GetInt32Array(tag int32) (value_list []int32, err error)
 	
 }
 
+func (section *Section) GetInt64(tag int32) (value int64, err error) {
+	store, _, err := section.GetStore(tag)
+
+	if err != nil {
+		return
+	}
+
+	buffer := bytes.NewBuffer(store)
+	err = binary.Read(buffer, binary.BigEndian, &value)
+
+	return
+}
+
 func (section *Section) GetString(tag int32) (value string, err error) {
 	store, _, err := section.GetStore(tag)
 
@@ -266,24 +409,30 @@ func (section *Section) GetStringArray(tag int32) (value_list []string, err erro
 	return
 }
 
-func (section *Section) GetStore(tag int32) (store []byte, size int32, err error) {
-	found := false
-
-	var offset, count, datatype int32
+func (section *Section) findIndex(tag int32) (index SectionHeaderIndex, found bool) {
 	for _, index := range section.header.indexes {
-		for index.Tag == tag {
-			found = true
-			offset = index.Offset
-			count = index.Count
-			datatype = index.Type
-			break
+		if index.Tag == tag {
+			return index, true
 		}
 	}
 
+	return
+}
+
+func (section *Section) GetStore(tag int32) (store []byte, size int32, err error) {
+	index, found := section.findIndex(tag)
 	if !found {
 		return nil, -1, fmt.Errorf("Cannot find store for tag %d", tag)
 	}
 
+	return section.storeOf(index)
+}
+
+func (section *Section) storeOf(index SectionHeaderIndex) (store []byte, size int32, err error) {
+	offset := index.Offset
+	count := index.Count
+	datatype := index.Type
+
 	switch datatype {
 	case Null:
 		err = fmt.Errorf("Null field founded.")
@@ -301,8 +450,8 @@ func (section *Section) GetStore(tag int32) (store []byte, size int32, err error
 		store = section.store[offset : offset+(count*size)]
 		break
 	case Int64:
-		size = 0
-		err = fmt.Errorf("Unsupported type Int64 data type found.")
+		size = 8
+		store = section.store[offset : offset+(count*size)]
 		break
 	case String:
 		size = 0
@@ -340,7 +489,6 @@ func (section *Section) GetStore(tag int32) (store []byte, size int32, err error
 		}
 		store = section.store[offset : offset+size]
 		break
-		break
 	default:
 		err = fmt.Errorf("Unknwon data type %x", datatype)
 		break
@@ -348,3 +496,91 @@ func (section *Section) GetStore(tag int32) (store []byte, size int32, err error
 
 	return
 }
+
+// values decodes the data of tag by its type.
+// Integers are returned as uint64 as rpm formats them, strings as string and binary as []byte
+func (section *Section) values(tag int32) (datatype int32, values []interface{}, err error) {
+	index, found := section.findIndex(tag)
+	if !found {
+		return Null, nil, fmt.Errorf("Cannot find store for tag %d", tag)
+	}
+
+	values, err = section.IndexValues(index)
+
+	for i, value := range values {
+		switch v := value.(type) {
+		case uint8:
+			values[i] = uint64(v)
+		case int16:
+			values[i] = uint64(uint16(v))
+		case int32:
+			values[i] = uint64(uint32(v))
+		case int64:
+			values[i] = uint64(v)
+		}
+	}
+
+	return index.Type, values, err
+}
+
+// IndexValues decodes the data of index by its type. Char and Int8 are returned
+// as uint8, Int16, Int32 and Int64 as signed values same as GetInt32 and others,
+// strings as string and binary as []byte
+func (section *Section) IndexValues(index SectionHeaderIndex) (values []interface{}, err error) {
+	err = section.checkOverrun(index)
+	if err != nil {
+		return
+	}
+
+	store, size, err := section.storeOf(index)
+	if err != nil {
+		return
+	}
+
+	switch index.Type {
+	case Char, Int8, Int16, Int32, Int64:
+		for i := int32(0); i+size <= int32(len(store)); i += size {
+			var value uint64
+			for _, b := range store[i : i+size] {
+				value = value<<8 | uint64(b)
+			}
+
+			switch index.Type {
+			case Int16:
+				values = append(values, int16(value))
+			case Int32:
+				values = append(values, int32(value))
+			case Int64:
+				values = append(values, int64(value))
+			default:
+				values = append(values, uint8(value))
+			}
+		}
+	case String, I18nString:
+		values = append(values, string(store))
+	case StringArray:
+		for _, s := range bytes.SplitAfter(store, []byte{0}) {
+			if len(s) > 0 {
+				values = append(values, string(s[:len(s)-1]))
+			}
+		}
+	case Binary:
+		values = append(values, store)
+	}
+
+	return
+}
+
+func (section *Section) Version() int8 {
+	return section.header.version
+}
+
+// Indexes returns the index entries in the order stored in the section
+func (section *Section) Indexes() []SectionHeaderIndex {
+	return section.header.indexes
+}
+
+// Store returns the raw data store of the section
+func (section *Section) Store() []byte {
+	return section.store
+}
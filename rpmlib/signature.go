@@ -3,22 +3,10 @@ package rpmlib
 import (
 	"bytes"
 	"encoding/binary"
-	"os"
+	"io"
 	"fmt"
 )
 
-const (
-	RPMSIGTAG_DSA         = 267
-	RPMSIGTAG_RSA         = 268
-	RPMSIGTAG_SHA1        = 269
-	RPMSIGTAG_SIZE        = 1000
-	RPMSIGTAG_PGP         = 1002
-	RPMSIGTAG_MD5         = 1004
-	RPMSIGTAG_GPG         = 1005
-	RPMSIGTAG_PAYLOADSIZE = 1007
-	RPMSIGTAG_SAH1HEADER  = 1010
-)
-
 type Signature struct {
 	Section
 }
@@ -39,23 +27,24 @@ func (sig *Signature) Size() (size int32, err error) {
 
 func (sig *Signature) MD5() (bin []byte, err error) {
 
-	bin, nsize, err := sig.GetStore(RPMSIGTAG_MD5)
-
-	if nsize != 16 {
-		return nil, fmt.Errorf("Less size for required field 'MD5' %d", nsize)	
-	}
+	bin, _, err = sig.GetStore(RPMSIGTAG_MD5)
 
 	if err != nil {
 		return
 	}
 
+	if len(bin) != 16 {
+		return nil, fmt.Errorf("Less size for required field 'MD5' %d", len(bin))
+	}
+
 	return
 }
 
-func ScanSignature(file *os.File) (signature *Signature, err error) {
+func ScanSignature(file io.Reader) (signature *Signature, err error) {
 
+	// A section which is read but invalid is returned with the error
 	section, err := scanSection(file)
-	if err != nil {
+	if section == nil {
 		return
 	}
 
@@ -65,6 +54,11 @@ func ScanSignature(file *os.File) (signature *Signature, err error) {
 	return
 }
 
+// TagTypeErrors returns errors of known tags which have other data types than expected
+func (sig *Signature) TagTypeErrors() []error {
+	return sig.Section.tagTypeErrors(SignatureTags)
+}
+
 //
 // Optional
 //
@@ -73,7 +67,7 @@ func (sig *Signature) HasPayloadSize() (hasPayloadSize bool) {
 }
 
 func (sig *Signature) HasSAH1() (hasSAH1 bool) {
-	return sig.HasStore(RPMSIGTAG_SAH1HEADER)
+	return sig.HasStore(RPMSIGTAG_SHA1)
 }
 
 func (sig *Signature) PayloadSize() (size int32, err error) {
@@ -89,7 +83,7 @@ func (sig *Signature) PayloadSize() (size int32, err error) {
 
 func (sig *Signature) SAH1() (checksum []byte, err error) {
 
-	checksum, _, err = sig.GetStore(RPMSIGTAG_PAYLOADSIZE)
+	checksum, _, err = sig.GetStore(RPMSIGTAG_SHA1)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,210 @@
+package rpmlib
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//
+// Source packages (SRPM), which have a spec file, sources and patches as files
+//
+
+// IsSource checks the package is a source package by the lead,
+// or by the header if the lead is not available
+func (pkg *PackageFile) IsSource() bool {
+	if pkg.Lead != nil {
+		return pkg.Lead.RpmType() == SourcePackageFileType
+	}
+
+	return pkg.Header.IsSource()
+}
+
+func (header *Header) optionalStringArray(tag int32) (values []string, err error) {
+	if !header.Section.HasStore(tag) {
+		return
+	}
+
+	return header.Section.GetStringArray(tag)
+}
+
+func (header *Header) optionalInt32Array(tag int32) (values []int32, err error) {
+	if !header.Section.HasStore(tag) {
+		return
+	}
+
+	return header.Section.GetInt32Array(tag)
+}
+
+// Sources returns file names of sources, RPMTAG_SOURCE of a source package
+func (header *Header) Sources() (sources []string, err error) {
+	return header.optionalStringArray(RPMTAG_SOURCE)
+}
+
+// Patches returns file names of patches, RPMTAG_PATCH of a source package
+func (header *Header) Patches() (patches []string, err error) {
+	return header.optionalStringArray(RPMTAG_PATCH)
+}
+
+// NoSource returns numbers of sources which are not included in the package, by NoSource: in the spec
+func (header *Header) NoSource() (numbers []int32, err error) {
+	return header.optionalInt32Array(RPMTAG_NOSOURCE)
+}
+
+// NoPatch returns numbers of patches which are not included in the package, by NoPatch: in the spec
+func (header *Header) NoPatch() (numbers []int32, err error) {
+	return header.optionalInt32Array(RPMTAG_NOPATCH)
+}
+
+// BuildRequires returns build dependencies of a source package. They are stored as
+// requires of the source package, with rpmlib() dependencies of rpm which are excluded.
+// Names in RPMTAG_BUILDREQUIRES of old packages are also returned.
+func (header *Header) BuildRequires() (deps []Dependency, err error) {
+	if !header.IsSource() {
+		return nil, fmt.Errorf("%s is not a source package", header.Name())
+	}
+
+	requires, err := header.Requires()
+	if err != nil {
+		return
+	}
+
+	for _, dep := range requires {
+		if !dep.IsRpmlib() {
+			deps = append(deps, dep)
+		}
+	}
+
+	names, err := header.optionalStringArray(RPMTAG_BUILDREQUIRES)
+	for _, name := range names {
+		deps = append(deps, Dependency{Name: name})
+	}
+
+	return
+}
+
+// SpecFile returns the path of the spec file in a source package, the file flagged as
+// RPMFILE_SPECFILE. Old packages without the flag have the spec file as "*.spec".
+func (header *Header) SpecFile() (name string, err error) {
+	files, err := header.Files()
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		if f.Flag&RPMFILE_SPECFILE != 0 {
+			return f.Path, nil
+		}
+	}
+
+	for _, f := range files {
+		if strings.HasSuffix(f.Path, ".spec") {
+			return f.Path, nil
+		}
+	}
+
+	return "", fmt.Errorf("Spec file is not found in %s", header.Name())
+}
+
+// ReadSpec reads the spec file of a source package
+func (pkg *PackageFile) ReadSpec() (name string, data []byte, err error) {
+	name, err = pkg.Header.SpecFile()
+	if err != nil {
+		return
+	}
+
+	f, err := pkg.Open(name)
+	if err != nil {
+		return
+	}
+
+	return name, f.Bytes(), nil
+}
+
+// ExtractSources writes the spec file, sources and patches of a source package to dir,
+// same as rpm -i of a source package into %_sourcedir. Files are written by base names.
+// The payload is read once from the start, instead of opening each file.
+func (pkg *PackageFile) ExtractSources(dir string) (written []string, err error) {
+	if !pkg.IsSource() {
+		return nil, fmt.Errorf("%s is not a source package", pkg.Header.Name())
+	}
+
+	files, err := pkg.Header.Files()
+	if err != nil {
+		return
+	}
+
+	metas := make(map[string]FileMeta)
+	for _, meta := range files {
+		if meta.FileMode().IsRegular() {
+			metas[fsName(meta.Path)] = meta
+		}
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return
+	}
+
+	rd, err := pkg.payloadReader(&PayloadIndex{Compressor: pkg.Header.PayloadCompressor()}, 0)
+	if err != nil {
+		return
+	}
+	defer rd.Close()
+
+	// Data of hard linked files is stored in the last entry of them,
+	// so earlier entries are written with it
+	linked := make(map[uint64][]FileMeta)
+
+	reader := cpio.NewCPIOStreamReader(rd)
+	for {
+		file, read_err := reader.GetFile()
+		if read_err == io.EOF {
+			break
+		}
+		if read_err != nil {
+			return written, read_err
+		}
+
+		meta, found := metas[fsName(file.Name)]
+		if !found {
+			continue
+		}
+
+		if file.Metadata.Nlink > 1 && file.Metadata.Filesize == 0 {
+			linked[file.Metadata.Ino] = append(linked[file.Metadata.Ino], meta)
+			continue
+		}
+
+		for _, m := range append(linked[file.Metadata.Ino], meta) {
+			filename := filepath.Join(dir, path.Base(m.Path))
+			err = ioutil.WriteFile(filename, file.Bytes(), m.FileMode().Perm())
+			if err != nil {
+				return
+			}
+
+			written = append(written, filename)
+		}
+		delete(linked, file.Metadata.Ino)
+	}
+
+	// Hard linked files which are empty
+	for _, metas := range linked {
+		for _, m := range metas {
+			filename := filepath.Join(dir, path.Base(m.Path))
+			err = ioutil.WriteFile(filename, nil, m.FileMode().Perm())
+			if err != nil {
+				return
+			}
+
+			written = append(written, filename)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,116 @@
+package rpmlib_test
+
+import (
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sourceTestPackage() rpmtest.Package {
+	pkg := rpmtest.Package{
+		Name: "foo", Version: "1.0", Release: "1", Arch: "x86_64",
+		Files: []rpmtest.File{
+			{Path: "foo-1.0.tar.gz", Mode: rpmlib.S_IFREG | 0644, Data: "tarball"},
+			{Path: "foo.spec", Mode: rpmlib.S_IFREG | 0644, Data: "Name: foo\n", Flags: rpmlib.RPMFILE_SPECFILE},
+			{Path: "fix.patch", Mode: rpmlib.S_IFREG | 0600, Data: "patch", Inode: 10},
+			{Path: "fix-copy.patch", Mode: rpmlib.S_IFREG | 0600, Data: "patch", Inode: 10},
+			{Path: "empty", Mode: rpmlib.S_IFREG | 0644},
+		},
+	}
+	pkg.Tags = func(b *rpmtest.Builder) {
+		b.AddStringArray(rpmlib.RPMTAG_SOURCE, []string{"foo-1.0.tar.gz"})
+		b.AddStringArray(rpmlib.RPMTAG_PATCH, []string{"fix.patch"})
+		b.AddStringArray(rpmlib.RPMTAG_REQUIRENAME, []string{"rpmlib(CompressedFileNames)", "gcc"})
+		b.AddInt32(rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMSENSE_RPMLIB|rpmlib.RPMSENSE_LESS|rpmlib.RPMSENSE_EQUAL, 0)
+		b.AddStringArray(rpmlib.RPMTAG_REQUIREVERSION, []string{"3.0.4-1", ""})
+	}
+
+	return pkg
+}
+
+func TestSourcePackage(t *testing.T) {
+	pkg := rpmtest.Open(t, sourceTestPackage())
+
+	if !pkg.IsSource() || pkg.NEVRA().String() != "foo-1.0-1.src" {
+		t.Errorf("Source package %s", pkg.NEVRA())
+	}
+
+	sources, err := pkg.Header.Sources()
+	if err != nil || len(sources) != 1 || sources[0] != "foo-1.0.tar.gz" {
+		t.Errorf("Sources() = %v, %v", sources, err)
+	}
+	patches, err := pkg.Header.Patches()
+	if err != nil || len(patches) != 1 || patches[0] != "fix.patch" {
+		t.Errorf("Patches() = %v, %v", patches, err)
+	}
+
+	// rpmlib() dependencies are not build dependencies
+	requires, err := pkg.Header.BuildRequires()
+	if err != nil || len(requires) != 1 || requires[0].Name != "gcc" {
+		t.Errorf("BuildRequires() = %v, %v", requires, err)
+	}
+
+	name, data, err := pkg.ReadSpec()
+	if err != nil || name != "foo.spec" || string(data) != "Name: foo\n" {
+		t.Errorf("ReadSpec() = %s, %q, %v", name, data, err)
+	}
+
+	binary := rpmtest.Open(t, rpmtest.Binary("bar", "1.0", "1"))
+	if _, err := binary.Header.BuildRequires(); err == nil {
+		t.Errorf("BuildRequires() of a binary package succeeded")
+	}
+	if _, err := binary.ExtractSources(t.TempDir()); err == nil {
+		t.Errorf("ExtractSources() of a binary package succeeded")
+	}
+}
+
+func TestExtractSources(t *testing.T) {
+	pkg := rpmtest.Open(t, sourceTestPackage())
+	dir := filepath.Join(t.TempDir(), "SOURCES")
+
+	written, err := pkg.ExtractSources(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 5 {
+		t.Errorf("ExtractSources() = %v", written)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		mode os.FileMode
+	}{
+		{"foo-1.0.tar.gz", "tarball", 0644},
+		{"foo.spec", "Name: foo\n", 0644},
+		// Hard links have the data of the last entry
+		{"fix.patch", "patch", 0600},
+		{"fix-copy.patch", "patch", 0600},
+		{"empty", "", 0644},
+	}
+
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.name)
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if string(data) != test.data {
+			t.Errorf("%s = %q, want %q", test.name, data, test.data)
+		}
+		if info, err := os.Stat(filename); err != nil || info.Mode().Perm()&^0022 != test.mode&^0022 {
+			t.Errorf("%s: mode %v", test.name, info.Mode())
+		}
+	}
+
+	for _, filename := range written {
+		if !strings.HasPrefix(filename, dir) {
+			t.Errorf("%s is written out of %s", filename, dir)
+		}
+	}
+}
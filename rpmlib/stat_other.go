@@ -0,0 +1,19 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package rpmlib
+
+import (
+	"os"
+)
+
+type sysFileStat struct {
+	uid  uint32
+	gid  uint32
+	rdev uint64
+}
+
+// Owners and device numbers are not available
+func sysStat(info os.FileInfo) (st sysFileStat, ok bool) {
+	return
+}
@@ -0,0 +1,24 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rpmlib
+
+import (
+	"os"
+	"syscall"
+)
+
+type sysFileStat struct {
+	uid  uint32
+	gid  uint32
+	rdev uint64
+}
+
+func sysStat(info os.FileInfo) (st sysFileStat, ok bool) {
+	sys, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+
+	return sysFileStat{uid: sys.Uid, gid: sys.Gid, rdev: uint64(sys.Rdev)}, true
+}
@@ -0,0 +1,111 @@
+package rpmlib
+
+//go:generate go run gentags.go
+
+import (
+	"strings"
+)
+
+type TagInfo struct {
+	Tag  int32
+	Name string
+	Type int32
+	// Array is true if the tag has multiple values
+	Array bool
+	// Extension tags are not stored in a header, but computed from other tags
+	Extension bool
+}
+
+// Old or misspelled names, kept for compatibility
+const (
+	RPMTAG_HEADER18NTABLE = RPMTAG_HEADERI18NTABLE
+	RPMTAG_VERNDOR        = RPMTAG_VENDOR
+	RPMTAG_LICENCE        = RPMTAG_LICENSE
+	RPMTAG_FILEMD5S       = RPMTAG_FILEDIGESTS
+	RPMTAG_PAYLOAD_FLAGS  = RPMTAG_PAYLOADFLAGS
+
+	RPMSIGTAG_SAH1HEADER = RPMSIGTAG_SHA1
+)
+
+var typeNames = map[int32]string{
+	Null:        "NULL",
+	Char:        "CHAR",
+	Int8:        "INT8",
+	Int16:       "INT16",
+	Int32:       "INT32",
+	Int64:       "INT64",
+	String:      "STRING",
+	Binary:      "BIN",
+	StringArray: "STRING_ARRAY",
+	I18nString:  "I18NSTRING",
+}
+
+func TypeName(datatype int32) string {
+	name, found := typeNames[datatype]
+	if !found {
+		return "UNKNOWN"
+	}
+
+	return name
+}
+
+func findTagByName(tags []TagInfo, prefix string, name string) (info TagInfo, found bool) {
+	name = strings.ToUpper(name)
+	name = strings.TrimPrefix(name, prefix)
+
+	for _, info := range tags {
+		if info.Name == name {
+			return info, true
+		}
+	}
+
+	return
+}
+
+func findTagByNumber(tags []TagInfo, tag int32) (info TagInfo, found bool) {
+	for _, info := range tags {
+		if info.Tag == tag {
+			return info, true
+		}
+	}
+
+	return
+}
+
+// TagByName finds a header tag by its name, such as "NAME" or "RPMTAG_NAME".
+// Case of the name is ignored.
+func TagByName(name string) (info TagInfo, found bool) {
+	return findTagByName(HeaderTags, "RPMTAG_", name)
+}
+
+func TagByNumber(tag int32) (info TagInfo, found bool) {
+	return findTagByNumber(HeaderTags, tag)
+}
+
+// SignatureTagByName finds a signature tag by its name, such as "SIZE" or "RPMSIGTAG_SIZE".
+func SignatureTagByName(name string) (info TagInfo, found bool) {
+	return findTagByName(SignatureTags, "RPMSIGTAG_", name)
+}
+
+func SignatureTagByNumber(tag int32) (info TagInfo, found bool) {
+	return findTagByNumber(SignatureTags, tag)
+}
+
+// compatibleType checks the type of stored data is allowed for the tag,
+// with the same exceptions as rpm
+func (info *TagInfo) compatibleType(datatype int32) bool {
+	if info.Type == datatype {
+		return true
+	}
+
+	switch {
+	case info.Type == String && datatype == I18nString:
+		return true
+	case info.Type == I18nString && datatype == String:
+		return true
+	case info.Type == I18nString && datatype == StringArray:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,63 @@
+package rpmlib
+
+import (
+	"testing"
+)
+
+func TestTagLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  int32
+		typ  int32
+	}{
+		{"NAME", RPMTAG_NAME, String},
+		{"rpmtag_name", RPMTAG_NAME, String},
+		{"Summary", RPMTAG_SUMMARY, I18nString},
+		{"FILEMODES", RPMTAG_FILEMODES, Int16},
+		{"LONGSIZE", RPMTAG_LONGSIZE, Int64},
+		{"NEVRA", RPMTAG_NEVRA, String},
+	}
+
+	for _, test := range tests {
+		info, found := TagByName(test.name)
+		if !found || info.Tag != test.tag || info.Type != test.typ {
+			t.Errorf("TagByName(%s) = %+v, %v", test.name, info, found)
+		}
+		if byNumber, found := TagByNumber(test.tag); !found || byNumber != info {
+			t.Errorf("TagByNumber(%d) = %+v, %v", test.tag, byNumber, found)
+		}
+	}
+
+	if _, found := TagByName("NOSUCHTAG"); found {
+		t.Errorf("Unknown tag is found")
+	}
+	if info, found := SignatureTagByName("RPMSIGTAG_SIZE"); !found || info.Tag != RPMSIGTAG_SIZE || info.Type != Int32 {
+		t.Errorf("SignatureTagByName(RPMSIGTAG_SIZE) = %+v, %v", info, found)
+	}
+	// Misspelled names are aliases
+	if RPMTAG_VERNDOR != RPMTAG_VENDOR || RPMTAG_LICENCE != RPMTAG_LICENSE {
+		t.Errorf("Old names differ from the tags")
+	}
+}
+
+func TestCompatibleType(t *testing.T) {
+	tests := []struct {
+		tag        int32
+		datatype   int32
+		compatible bool
+	}{
+		{RPMTAG_NAME, String, true},
+		{RPMTAG_NAME, I18nString, true},
+		{RPMTAG_SUMMARY, String, true},
+		{RPMTAG_SUMMARY, StringArray, true},
+		{RPMTAG_NAME, StringArray, false},
+		{RPMTAG_BUILDTIME, Int16, false},
+	}
+
+	for _, test := range tests {
+		info, _ := TagByNumber(test.tag)
+		if info.compatibleType(test.datatype) != test.compatible {
+			t.Errorf("%s of %s: compatible is not %v", info.Name, TypeName(test.datatype), test.compatible)
+		}
+	}
+}
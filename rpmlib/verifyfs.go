@@ -0,0 +1,397 @@
+package rpmlib
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//
+// Verification of installed files on disk against the header, same as rpm -V
+//
+
+// Attributes to verify, as RPMTAG_FILEVERIFYFLAGS
+const (
+	RPMVERIFY_FILEDIGEST = 1 << 0
+	RPMVERIFY_FILESIZE   = 1 << 1
+	RPMVERIFY_LINKTO     = 1 << 2
+	RPMVERIFY_USER       = 1 << 3
+	RPMVERIFY_GROUP      = 1 << 4
+	RPMVERIFY_MTIME      = 1 << 5
+	RPMVERIFY_MODE       = 1 << 6
+	RPMVERIFY_RDEV       = 1 << 7
+	RPMVERIFY_CAPS       = 1 << 8
+	RPMVERIFY_ALL        = ^0
+)
+
+// States of installed files, as RPMTAG_FILESTATES
+const (
+	RPMFILE_STATE_NORMAL       = 0
+	RPMFILE_STATE_REPLACED     = 1
+	RPMFILE_STATE_NOTINSTALLED = 2
+	RPMFILE_STATE_NETSHARED    = 3
+	RPMFILE_STATE_WRONGCOLOR   = 4
+)
+
+// More kinds of verification failure of installed files
+const (
+	VerifyDevice  = "device"
+	VerifyLinkTo  = "linkto"
+	VerifyUser    = "user"
+	VerifyGroup   = "group"
+	VerifyCaps    = "caps"
+	VerifyMissing = "missing"
+)
+
+// Checks in the order of "SM5DLUGTP"
+var verifyChecks = []struct {
+	flag   int32
+	letter byte
+	kind   string
+}{
+	{RPMVERIFY_FILESIZE, 'S', VerifySize},
+	{RPMVERIFY_MODE, 'M', VerifyMode},
+	{RPMVERIFY_FILEDIGEST, '5', VerifyDigest},
+	{RPMVERIFY_RDEV, 'D', VerifyDevice},
+	{RPMVERIFY_LINKTO, 'L', VerifyLinkTo},
+	{RPMVERIFY_USER, 'U', VerifyUser},
+	{RPMVERIFY_GROUP, 'G', VerifyGroup},
+	{RPMVERIFY_MTIME, 'T', VerifyMTime},
+	{RPMVERIFY_CAPS, 'P', VerifyCaps},
+}
+
+// Hash functions of RPMTAG_FILEDIGESTALGO, md5 if the tag is missing
+var fileDigestHashes = map[int32]func() hash.Hash{
+	1:  md5.New,
+	2:  sha1.New,
+	8:  sha256.New,
+	9:  sha512.New384,
+	10: sha512.New,
+	11: sha256.New224,
+}
+
+type VerifyFilesOptions struct {
+	// Root of the file system, such as a mounted image
+	Root string
+	// Skip %config files
+	NoConfig bool
+	// Files under the paths are not verified, same as %_netsharedpath of rpm
+	NetSharedPaths []string
+}
+
+// errNotChecked marks a check which cannot be performed, shown as "?"
+type errNotChecked struct {
+	err error
+}
+
+func (e errNotChecked) Error() string {
+	return e.err.Error()
+}
+
+// VerifyFiles verifies installed files of the header under the root, same as rpm -V.
+// Only files with any failure are returned. Result of each entry is "SM5DLUGTP" with "."
+// for passed checks and "?" for checks which cannot be performed, or "missing".
+// Missing %ghost and %missingok files are not reported.
+func (header *Header) VerifyFiles(options VerifyFilesOptions) (entries []VerifyEntry, err error) {
+	// Packages without files are valid
+	if !header.Section.HasStore(RPMTAG_BASENAMES) && !header.Section.HasStore(RPMTAG_OLDFILENAMES) {
+		return
+	}
+
+	files, err := header.Files()
+	if err != nil {
+		return
+	}
+
+	verifyFlags, _ := header.Section.GetInt32Array(RPMTAG_FILEVERIFYFLAGS)
+	states, _, _ := header.Section.GetStore(RPMTAG_FILESTATES)
+	caps, _ := header.Section.GetStringArray(RPMTAG_FILECAPS)
+
+	newHash := fileDigestHashes[1]
+	if algo, algo_err := header.Section.GetInt32(RPMTAG_FILEDIGESTALGO); algo_err == nil {
+		if newHash = fileDigestHashes[algo]; newHash == nil {
+			return nil, fmt.Errorf("File digest algorithm %d is not supported", algo)
+		}
+	}
+
+	root := options.Root
+	if root == "" {
+		root = "/"
+	}
+	users := readIDNames(filepath.Join(root, "etc/passwd"))
+	groups := readIDNames(filepath.Join(root, "etc/group"))
+
+	for i := range files {
+		meta := &files[i]
+		if options.NoConfig && meta.Flag&RPMFILE_CONFIG != 0 {
+			continue
+		}
+		if isNetShared(meta.Path, options.NetSharedPaths) {
+			continue
+		}
+
+		flags := int32(RPMVERIFY_ALL)
+		if i < len(verifyFlags) {
+			flags = verifyFlags[i]
+		}
+
+		if i < len(states) {
+			switch states[i] {
+			case RPMFILE_STATE_NETSHARED, RPMFILE_STATE_NOTINSTALLED:
+				continue
+			case RPMFILE_STATE_REPLACED:
+				// Only the existence can be verified
+				flags = 0
+			case RPMFILE_STATE_WRONGCOLOR:
+				flags &^= RPMVERIFY_FILEDIGEST | RPMVERIFY_FILESIZE | RPMVERIFY_MTIME | RPMVERIFY_RDEV
+			}
+		}
+
+		var fileCaps string
+		if i < len(caps) {
+			fileCaps = caps[i]
+		}
+
+		entry, failed := verifyFile(root, meta, flags, fileCaps, newHash, users, groups)
+		if failed {
+			entries = append(entries, entry)
+		}
+	}
+
+	return
+}
+
+func isNetShared(filename string, netshared []string) bool {
+	for _, p := range netshared {
+		p = strings.TrimSuffix(p, "/")
+		if p != "" && (filename == p || strings.HasPrefix(filename, p+"/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func verifyFile(root string, meta *FileMeta, flags int32, fileCaps string, newHash func() hash.Hash,
+	users map[uint32]string, groups map[uint32]string) (entry VerifyEntry, failed bool) {
+
+	entry.Path = meta.Path
+	entry.Flags = meta.Flag
+
+	diskPath, err := resolveInRoot(root, meta.Path)
+	var info os.FileInfo
+	if err == nil {
+		info, err = os.Lstat(diskPath)
+	}
+	if err != nil {
+		entry.Result = VerifyMissing
+		entry.Problems = append(entry.Problems, VerifyProblem{VerifyMissing, err.Error()})
+		return entry, meta.Flag&(RPMFILE_GHOST|RPMFILE_MISSINGOK) == 0
+	}
+
+	// Checks which are meaningless for the type on disk
+	mode := info.Mode()
+	switch {
+	case mode.IsDir():
+		flags &^= RPMVERIFY_FILEDIGEST | RPMVERIFY_FILESIZE | RPMVERIFY_MTIME | RPMVERIFY_LINKTO | RPMVERIFY_CAPS
+	case mode&os.ModeSymlink != 0:
+		flags &^= RPMVERIFY_FILEDIGEST | RPMVERIFY_FILESIZE | RPMVERIFY_MTIME | RPMVERIFY_MODE | RPMVERIFY_CAPS
+	case mode&(os.ModeNamedPipe|os.ModeDevice) != 0:
+		flags &^= RPMVERIFY_FILEDIGEST | RPMVERIFY_FILESIZE | RPMVERIFY_MTIME | RPMVERIFY_LINKTO | RPMVERIFY_CAPS
+	default:
+		flags &^= RPMVERIFY_LINKTO
+	}
+	if meta.Flag&RPMFILE_GHOST != 0 {
+		flags &^= RPMVERIFY_FILEDIGEST | RPMVERIFY_FILESIZE | RPMVERIFY_MTIME | RPMVERIFY_LINKTO
+	}
+
+	st, hasStat := sysStat(info)
+
+	for _, c := range verifyChecks {
+		if flags&c.flag == 0 {
+			entry.Result += "."
+			continue
+		}
+
+		var check_err error
+		switch c.flag {
+		case RPMVERIFY_FILESIZE:
+			if info.Size() != int64(uint32(meta.Size)) {
+				check_err = fmt.Errorf("Size %d != %d", info.Size(), uint32(meta.Size))
+			}
+		case RPMVERIFY_MODE:
+			expected, actual := meta.FileMode(), mode
+			// The type of %ghost files is not known until created
+			if meta.Flag&RPMFILE_GHOST != 0 {
+				expected &^= os.ModeType
+				actual &^= os.ModeType
+			}
+			if expected != actual {
+				check_err = fmt.Errorf("Mode %s != %s", actual, expected)
+			}
+		case RPMVERIFY_FILEDIGEST:
+			check_err = verifyDigest(diskPath, meta.MD5, newHash)
+		case RPMVERIFY_RDEV:
+			isDevice := meta.FileMode()&os.ModeDevice != 0
+			if isDevice != (mode&os.ModeDevice != 0) || meta.FileMode()&os.ModeCharDevice != mode&os.ModeCharDevice {
+				check_err = fmt.Errorf("Device type is different")
+			} else if isDevice && !hasStat {
+				check_err = errNotChecked{fmt.Errorf("Device number is not available")}
+			} else if isDevice && uint16(st.rdev) != uint16(meta.RDevice) {
+				check_err = fmt.Errorf("Device %x != %x", uint16(st.rdev), uint16(meta.RDevice))
+			}
+		case RPMVERIFY_LINKTO:
+			linkTo, link_err := os.Readlink(diskPath)
+			if link_err != nil || linkTo != meta.LinkTo {
+				check_err = fmt.Errorf("Link %s != %s", linkTo, meta.LinkTo)
+			}
+		case RPMVERIFY_USER:
+			check_err = verifyOwner("User", meta.User, st.uid, hasStat, users)
+		case RPMVERIFY_GROUP:
+			check_err = verifyOwner("Group", meta.Group, st.gid, hasStat, groups)
+		case RPMVERIFY_MTIME:
+			if info.ModTime().Unix() != int64(uint32(meta.Time)) {
+				check_err = fmt.Errorf("Mtime %d != %d", info.ModTime().Unix(), uint32(meta.Time))
+			}
+		case RPMVERIFY_CAPS:
+			check_err = verifyCaps(diskPath, fileCaps)
+		}
+
+		if check_err == nil {
+			entry.Result += "."
+			continue
+		}
+
+		failed = true
+		if _, ok := check_err.(errNotChecked); ok {
+			entry.Result += "?"
+		} else {
+			entry.Result += string(c.letter)
+		}
+		entry.Problems = append(entry.Problems, VerifyProblem{c.kind, check_err.Error()})
+	}
+
+	return
+}
+
+func verifyDigest(diskPath string, digest string, newHash func() hash.Hash) (err error) {
+	f, err := os.Open(diskPath)
+	if err != nil {
+		return errNotChecked{err}
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err = io.Copy(h, f); err != nil {
+		return errNotChecked{err}
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); actual != digest {
+		return fmt.Errorf("Digest %s != %s", actual, digest)
+	}
+
+	return nil
+}
+
+func verifyOwner(kind string, expected string, id uint32, hasStat bool, names map[uint32]string) error {
+	if !hasStat || names == nil {
+		return errNotChecked{fmt.Errorf("%s of the file is not available", kind)}
+	}
+
+	if names[id] != expected {
+		return fmt.Errorf("%s %s (%d) != %s", kind, names[id], id, expected)
+	}
+
+	return nil
+}
+
+// readIDNames reads names of ids in /etc/passwd or /etc/group of the root,
+// as ids on disk are of the root instead of the host. nil if not readable.
+func readIDNames(filename string) (names map[uint32]string) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	names = make(map[uint32]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 3 {
+			continue
+		}
+
+		id, err := strconv.ParseUint(fields[2], 10, 32)
+		if err != nil {
+			continue
+		}
+		// The first entry of the same id wins, same as getpwuid
+		if _, found := names[uint32(id)]; !found {
+			names[uint32(id)] = fields[0]
+		}
+	}
+
+	return
+}
+
+// resolveInRoot converts the path in the root to the path on disk. Symbolic links of
+// directories in the path are followed inside the root, also absolute ones, so the
+// path never points outside of a mounted image. The last element is not followed.
+func resolveInRoot(root string, filename string) (resolved string, err error) {
+	elements := strings.Split(strings.Trim(path.Clean("/"+filename), "/"), "/")
+	if len(elements) == 1 && elements[0] == "" {
+		return root, nil
+	}
+
+	var current []string
+	follows := 0
+	for len(elements) > 1 {
+		element := elements[0]
+		elements = elements[1:]
+
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			if len(current) > 0 {
+				current = current[:len(current)-1]
+			}
+			continue
+		}
+
+		diskPath := filepath.Join(root, filepath.FromSlash(path.Join(current...)), element)
+		info, lstat_err := os.Lstat(diskPath)
+		if lstat_err != nil || info.Mode()&os.ModeSymlink == 0 {
+			current = append(current, element)
+			continue
+		}
+
+		follows++
+		if follows > maxSymlinkFollow {
+			return "", fmt.Errorf("Too many levels of symbolic links: %s", filename)
+		}
+
+		linkTo, link_err := os.Readlink(diskPath)
+		if link_err != nil {
+			return "", link_err
+		}
+		if strings.HasPrefix(linkTo, "/") {
+			current = nil
+		}
+		elements = append(strings.Split(linkTo, "/"), elements...)
+	}
+
+	return filepath.Join(root, filepath.FromSlash(path.Join(current...)), elements[0]), nil
+}
@@ -0,0 +1,236 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package rpmlib_test
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const verifyMTime = 1600000000
+
+// writeIDNames writes /etc/passwd and /etc/group of the root, in which the user
+// and the group of the test are the names
+func writeIDNames(t *testing.T, root string, user string, group string) {
+	if err := os.MkdirAll(filepath.Join(root, "etc"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	passwd := fmt.Sprintf("%s:x:%d:%d::/:/bin/sh\n", user, os.Getuid(), os.Getgid())
+	if err := ioutil.WriteFile(filepath.Join(root, "etc/passwd"), []byte(passwd), 0644); err != nil {
+		t.Fatal(err)
+	}
+	groups := fmt.Sprintf("%s:x:%d:\n", group, os.Getgid())
+	if err := ioutil.WriteFile(filepath.Join(root, "etc/group"), []byte(groups), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// installFile writes the file of the package under the root, same as installed by rpm
+func installFile(t *testing.T, root string, file rpmtest.File) string {
+	diskPath := filepath.Join(root, file.Path)
+	if err := os.MkdirAll(filepath.Dir(diskPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	switch file.Mode & rpmlib.S_IFMT {
+	case rpmlib.S_IFLNK:
+		err = os.Symlink(file.LinkTo, diskPath)
+	case rpmlib.S_IFDIR:
+		err = os.Mkdir(diskPath, os.FileMode(file.Mode&0777))
+	default:
+		err = ioutil.WriteFile(diskPath, []byte(file.Data), 0644)
+		if err == nil {
+			err = os.Chmod(diskPath, os.FileMode(file.Mode&0777))
+		}
+		if err == nil {
+			err = os.Chtimes(diskPath, time.Unix(verifyMTime, 0), time.Unix(verifyMTime, 0))
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return diskPath
+}
+
+// rewrite changes the content of the file, and keeps the mtime
+func rewrite(data string) func(t *testing.T, diskPath string) {
+	return func(t *testing.T, diskPath string) {
+		err := ioutil.WriteFile(diskPath, []byte(data), 0644)
+		if err == nil {
+			err = os.Chtimes(diskPath, time.Unix(verifyMTime, 0), time.Unix(verifyMTime, 0))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestVerifyFiles(t *testing.T) {
+	regular := rpmtest.Regular("/usr/share/foo/data", "foo")
+	config := rpmtest.Regular("/etc/foo.conf", "option=1\n")
+	config.Flags = rpmlib.RPMFILE_CONFIG
+	ghost := rpmtest.File{Path: "/var/log/foo.log", Mode: rpmlib.S_IFREG | 0644, Flags: rpmlib.RPMFILE_GHOST}
+	netshared := rpmtest.Regular("/srv/net/foo/data", "foo")
+
+	tests := []struct {
+		name    string
+		file    rpmtest.File
+		missing bool
+		modify  func(t *testing.T, diskPath string)
+		options rpmlib.VerifyFilesOptions
+		// Names of the user and the group of the test, root if empty. No /etc/passwd and /etc/group with "-"
+		user, group string
+		// "" if the file is not reported
+		result string
+	}{
+		{name: "unchanged", file: regular, result: ""},
+		{name: "missing", file: regular, missing: true, result: rpmlib.VerifyMissing},
+		{name: "missing ghost", file: ghost, missing: true, result: ""},
+		{name: "ghost", file: ghost, modify: rewrite("log\n"), result: ""},
+		{name: "size", file: regular, modify: rewrite("foo bar"), result: "S.5......"},
+		{name: "mode", file: regular, modify: func(t *testing.T, diskPath string) { os.Chmod(diskPath, 0600) },
+			result: ".M......."},
+		{name: "digest", file: regular, modify: rewrite("bar"), result: "..5......"},
+		{name: "link", file: rpmtest.Symlink("/usr/lib/libfoo.so", "libfoo.so.1"),
+			modify: func(t *testing.T, diskPath string) {
+				os.Remove(diskPath)
+				os.Symlink("libfoo.so.2", diskPath)
+			}, result: "....L...."},
+		{name: "user", file: regular, user: "nobody", result: ".....U..."},
+		{name: "group", file: regular, group: "nobody", result: "......G.."},
+		{name: "mtime", file: regular, modify: func(t *testing.T, diskPath string) {
+			os.Chtimes(diskPath, time.Unix(verifyMTime+1, 0), time.Unix(verifyMTime+1, 0))
+		}, result: ".......T."},
+		{name: "no owners", file: regular, user: "-", result: ".....??.."},
+		{name: "config", file: config, modify: rewrite("option=22\n"), result: "S.5......"},
+		{name: "noconfig", file: config, modify: rewrite("option=2\n"),
+			options: rpmlib.VerifyFilesOptions{NoConfig: true}, result: ""},
+		{name: "netshared", file: netshared, modify: rewrite("changed"),
+			options: rpmlib.VerifyFilesOptions{NetSharedPaths: []string{"/srv/net/"}}, result: ""},
+		{name: "not netshared", file: netshared, modify: rewrite("changed"),
+			options: rpmlib.VerifyFilesOptions{NetSharedPaths: []string{"/srv/ne"}}, result: "S.5......"},
+	}
+
+	for _, test := range tests {
+		root := t.TempDir()
+		switch {
+		case test.user == "-":
+		case test.user != "":
+			writeIDNames(t, root, test.user, "root")
+		case test.group != "":
+			writeIDNames(t, root, "root", test.group)
+		default:
+			writeIDNames(t, root, "root", "root")
+		}
+
+		file := test.file
+		file.Time = verifyMTime
+		if !test.missing {
+			diskPath := installFile(t, root, file)
+			if test.modify != nil {
+				test.modify(t, diskPath)
+			}
+		}
+
+		pkg := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1", file))
+		options := test.options
+		options.Root = root
+		entries, err := pkg.Header.VerifyFiles(options)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		result := ""
+		if len(entries) > 0 {
+			result = entries[0].Result
+		}
+		if len(entries) > 1 || result != test.result {
+			t.Errorf("%s: VerifyFiles() = %q, want %q: %+v", test.name, result, test.result, entries)
+		}
+		if len(entries) == 1 && (entries[0].Path != file.Path || entries[0].Flags != file.Flags) {
+			t.Errorf("%s: VerifyFiles() = %+v", test.name, entries[0])
+		}
+	}
+}
+
+// Files are not verified outside of the root, even if a symbolic link in the root points there
+func TestVerifyFilesOutOfRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	writeIDNames(t, root, "root", "root")
+
+	file := rpmtest.Regular("/opt/foo/data", "foo")
+	file.Time = verifyMTime
+	installFile(t, outside, file)
+	if err := os.Symlink(outside+"/opt", filepath.Join(root, "opt")); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1", file))
+	entries, err := pkg.Header.VerifyFiles(rpmlib.VerifyFilesOptions{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Result != rpmlib.VerifyMissing {
+		t.Errorf("VerifyFiles() = %+v, want the missing file", entries)
+	}
+}
+
+// Symbolic links of directories are followed inside the root, and the file is verified
+// at the path on disk
+func TestVerifyFilesSymlinkedDirs(t *testing.T) {
+	tests := []struct {
+		name string
+		// Symbolic links in the root, and the path where the file is on disk
+		links     map[string]string
+		installed string
+		result    string
+	}{
+		{"relative", map[string]string{"lib": "usr/lib"}, "/usr/lib/foo/data", ""},
+		{"absolute", map[string]string{"lib": "/usr/lib"}, "/usr/lib/foo/data", ""},
+		{"parent of the root", map[string]string{"lib": "../../../../usr/lib"}, "/usr/lib/foo/data", ""},
+		{"nested", map[string]string{"lib": "usr/lib", "usr": "opt/usr"}, "/opt/usr/lib/foo/data", ""},
+		{"loop", map[string]string{"lib": "usr/lib", "usr": "lib"}, "/srv/foo/data", rpmlib.VerifyMissing},
+	}
+
+	for _, test := range tests {
+		root := t.TempDir()
+		writeIDNames(t, root, "root", "root")
+
+		file := rpmtest.Regular("/lib/foo/data", "foo")
+		file.Time = verifyMTime
+		installed := file
+		installed.Path = test.installed
+		installFile(t, root, installed)
+		for name, linkTo := range test.links {
+			if err := os.Symlink(linkTo, filepath.Join(root, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pkg := rpmtest.Open(t, rpmtest.Binary("foo", "1.0", "1", file))
+		entries, err := pkg.Header.VerifyFiles(rpmlib.VerifyFilesOptions{Root: root})
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		result := ""
+		if len(entries) > 0 {
+			result = entries[0].Result
+		}
+		if result != test.result {
+			t.Errorf("%s: VerifyFiles() = %+v, want %q", test.name, entries, test.result)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package rpmlib
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+//
+// Writing package files: the lead, the signature, the header and a gzip compressed cpio payload
+//
+
+// Digest algorithm of RPMTAG_FILEDIGESTALGO and RPMTAG_PAYLOADDIGESTALGO written by rpmbuild
+const PGPHASHALGO_SHA256 = 8
+
+// Bytes returns the section as stored in package files
+func (section *Section) Bytes() []byte {
+	var buffer bytes.Buffer
+
+	buffer.Write(section.magic)
+	binary.Write(&buffer, binary.BigEndian, section.header.version)
+	buffer.Write(make([]byte, SectionHeaderReservedSize))
+	binary.Write(&buffer, binary.BigEndian, section.header.nindex)
+	binary.Write(&buffer, binary.BigEndian, section.header.hsize)
+	for _, index := range section.header.indexes {
+		binary.Write(&buffer, binary.BigEndian, index)
+	}
+	buffer.Write(section.store)
+
+	return buffer.Bytes()
+}
+
+// RegionSection builds the section with a region tag, RPMTAG_HEADERIMMUTABLE of headers
+// or RPMTAG_HEADERSIGNATURES of signatures, which covers all tags same as rpmbuild
+func (b *SectionBuilder) RegionSection(regionTag int32) (section *Section) {
+	b.Remove(regionTag)
+	section = b.Section()
+
+	// The region is the first index, of which data at the end of the store
+	// is the trailer pointing back to the start of indexes
+	nindex := int32(len(section.header.indexes)) + 1
+	trailer := new(bytes.Buffer)
+	binary.Write(trailer, binary.BigEndian, SectionHeaderIndex{
+		Tag:    regionTag,
+		Type:   Binary,
+		Offset: -nindex * 16,
+		Count:  16,
+	})
+
+	region := SectionHeaderIndex{Tag: regionTag, Type: Binary, Offset: int32(len(section.store)), Count: 16}
+	section.header.indexes = append([]SectionHeaderIndex{region}, section.header.indexes...)
+	section.store = append(section.store, trailer.Bytes()...)
+	section.header.nindex = nindex
+	section.header.hsize = int32(len(section.store))
+
+	return
+}
+
+// FileIDs returns the owner and the device number of a file on disk, ok is false if not available
+func FileIDs(info os.FileInfo) (uid uint32, gid uint32, rdev uint64, ok bool) {
+	st, ok := sysStat(info)
+
+	return st.uid, st.gid, st.rdev, ok
+}
+
+// RawFileMode converts os.FileMode to the raw st_mode value, the reverse of FileMeta.FileMode
+func RawFileMode(mode os.FileMode) (raw uint16) {
+	raw = uint16(mode.Perm())
+
+	switch {
+	case mode&os.ModeSocket != 0:
+		raw |= S_IFSOCK
+	case mode&os.ModeSymlink != 0:
+		raw |= S_IFLNK
+	case mode&os.ModeCharDevice != 0:
+		raw |= S_IFCHR
+	case mode&os.ModeDevice != 0:
+		raw |= S_IFBLK
+	case mode&os.ModeDir != 0:
+		raw |= S_IFDIR
+	case mode&os.ModeNamedPipe != 0:
+		raw |= S_IFIFO
+	default:
+		raw |= S_IFREG
+	}
+
+	if mode&os.ModeSetuid != 0 {
+		raw |= S_ISUID
+	}
+	if mode&os.ModeSetgid != 0 {
+		raw |= S_ISGID
+	}
+	if mode&os.ModeSticky != 0 {
+		raw |= S_ISVTX
+	}
+
+	return
+}
+
+// AddFiles adds file lists of the files, which are sorted by paths. Digests of files are SHA256.
+// verifyFlags are RPMVERIFY_* flags of files, all flags if empty.
+func (b *SectionBuilder) AddFiles(files []FileMeta, verifyFlags []int32) {
+	if len(files) == 0 {
+		return
+	}
+
+	var basenames, dirnames, digests, linktos, users, groups, langs []string
+	var dirindexes, sizes, mtimes, flags, devices, inodes, verifies []int32
+	var modes, rdevs []int16
+	dirs := make(map[string]int32)
+
+	for i, file := range files {
+		dir, base := path.Split(file.Path)
+		index, found := dirs[dir]
+		if !found {
+			index = int32(len(dirnames))
+			dirs[dir] = index
+			dirnames = append(dirnames, dir)
+		}
+
+		verify := int32(RPMVERIFY_ALL)
+		if i < len(verifyFlags) {
+			verify = verifyFlags[i]
+		}
+
+		basenames = append(basenames, base)
+		dirindexes = append(dirindexes, index)
+		sizes = append(sizes, file.Size)
+		modes = append(modes, file.Mode)
+		rdevs = append(rdevs, file.RDevice)
+		mtimes = append(mtimes, file.Time)
+		digests = append(digests, file.MD5)
+		linktos = append(linktos, file.LinkTo)
+		flags = append(flags, file.Flag)
+		users = append(users, file.User)
+		groups = append(groups, file.Group)
+		verifies = append(verifies, verify)
+		devices = append(devices, 1)
+		inodes = append(inodes, int32(i+1))
+		langs = append(langs, file.Lang)
+	}
+
+	b.AddStringArray(RPMTAG_BASENAMES, basenames)
+	b.AddStringArray(RPMTAG_DIRNAMES, dirnames)
+	b.AddInt32(RPMTAG_DIRINDEXES, dirindexes...)
+	b.AddInt32(RPMTAG_FILESIZES, sizes...)
+	b.AddInt16(RPMTAG_FILEMODES, modes...)
+	b.AddInt16(RPMTAG_FILERDEVS, rdevs...)
+	b.AddInt32(RPMTAG_FILEMTIMES, mtimes...)
+	b.AddStringArray(RPMTAG_FILEDIGESTS, digests)
+	b.AddStringArray(RPMTAG_FILELINKTOS, linktos)
+	b.AddInt32(RPMTAG_FILEFLAGS, flags...)
+	b.AddStringArray(RPMTAG_FILEUSERNAME, users)
+	b.AddStringArray(RPMTAG_FILEGROUPNAME, groups)
+	b.AddInt32(RPMTAG_FILEVERIFYFLAGS, verifies...)
+	b.AddInt32(RPMTAG_FILEDEVICES, devices...)
+	b.AddInt32(RPMTAG_FILEINODES, inodes...)
+	b.AddStringArray(RPMTAG_FILELANGS, langs)
+	b.AddInt32(RPMTAG_FILEDIGESTALGO, PGPHASHALGO_SHA256)
+}
+
+// PayloadWriter writes a gzip compressed cpio payload to a temporary file,
+// so that large packages are not held in memory. Remove removes the file.
+type PayloadWriter struct {
+	*cpio.CPIOWriter
+	compressed *os.File
+	gzip       *gzip.Writer
+	// Digests of the uncompressed and the compressed payload
+	digest           hash.Hash
+	compressedDigest hash.Hash
+}
+
+func NewPayloadWriter() (w *PayloadWriter, err error) {
+	w = &PayloadWriter{digest: sha256.New(), compressedDigest: sha256.New()}
+	w.compressed, err = ioutil.TempFile("", "gorpm-payload-")
+	if err != nil {
+		return nil, err
+	}
+	w.gzip, _ = gzip.NewWriterLevel(io.MultiWriter(w.compressed, w.compressedDigest), gzip.BestCompression)
+	w.CPIOWriter = cpio.NewCPIOWriter(io.MultiWriter(w.gzip, w.digest))
+
+	return
+}
+
+// Close writes the trailer of cpio and flushes the compressor
+func (w *PayloadWriter) Close() (err error) {
+	err = w.CPIOWriter.Close()
+	if err != nil {
+		return
+	}
+
+	return w.gzip.Close()
+}
+
+// Remove removes the temporary file of the compressed payload
+func (w *PayloadWriter) Remove() error {
+	w.compressed.Close()
+
+	return os.Remove(w.compressed.Name())
+}
+
+// copyCompressed copies the compressed payload from the start of the temporary file
+func (w *PayloadWriter) copyCompressed(dst io.Writer) (err error) {
+	_, err = w.compressed.Seek(0, io.SeekStart)
+	if err != nil {
+		return
+	}
+
+	_, err = io.Copy(dst, w.compressed)
+
+	return
+}
+
+// AddPayloadTags adds tags of the payload to the header, after the payload is closed
+func (w *PayloadWriter) AddPayloadTags(b *SectionBuilder) {
+	b.AddString(RPMTAG_PAYLOADFORMAT, "cpio")
+	b.AddString(RPMTAG_PAYLOADCOMPRESSOR, "gzip")
+	b.AddString(RPMTAG_PAYLOADFLAGS, "9")
+	b.AddStringArray(RPMTAG_PAYLOADDIGEST, []string{hex.EncodeToString(w.compressedDigest.Sum(nil))})
+	b.AddStringArray(RPMTAG_PAYLOADDIGESTALT, []string{hex.EncodeToString(w.digest.Sum(nil))})
+	b.AddInt32(RPMTAG_PAYLOADDIGESTALGO, PGPHASHALGO_SHA256)
+}
+
+// Architecture numbers of the lead, which are not used by rpm any more
+var leadArchNumbers = map[string]uint16{
+	"i386": 1, "i486": 1, "i586": 1, "i686": 1, "x86_64": 1, "noarch": 0,
+	"ppc": 5, "ppc64": 16, "ppc64le": 16, "s390": 14, "s390x": 15, "aarch64": 19,
+	"armv7hl": 12, "riscv64": 22,
+}
+
+func writeLead(w io.Writer, header *Header) (err error) {
+	lead := make([]byte, LeadSize)
+	copy(lead, LeadMagic)
+	lead[4] = SupportedMajorVersion
+	lead[5] = SupportedMinorVersion
+
+	rpmtype := BinaryPackageFileType
+	if header.IsSource() {
+		rpmtype = SourcePackageFileType
+	}
+	binary.BigEndian.PutUint16(lead[6:], rpmtype)
+
+	arch, _ := header.Section.GetString(RPMTAG_ARCH)
+	binary.BigEndian.PutUint16(lead[8:], leadArchNumbers[arch])
+
+	// The name is truncated with the terminating NUL
+	name := fmt.Sprintf("%s-%s-%s", header.Name(), header.Version(), header.Release())
+	if len(name) >= LeadNameSize {
+		name = name[:LeadNameSize-1]
+	}
+	copy(lead[10:], name)
+
+	// Linux and the signature in a header
+	binary.BigEndian.PutUint16(lead[76:], 1)
+	binary.BigEndian.PutUint16(lead[78:], 5)
+
+	_, err = w.Write(lead)
+
+	return
+}
+
+// WritePackageFile writes a package file of the header and the payload which is closed.
+// Tags of the payload and the region are added to the header, and
+// the signature has sizes and digests but no signatures.
+func WritePackageFile(w io.Writer, b *SectionBuilder, payload *PayloadWriter) (err error) {
+	payload.AddPayloadTags(b)
+	headerBytes := b.RegionSection(RPMTAG_HEADERIMMUTABLE).Bytes()
+	header := b.Header()
+
+	info, err := payload.compressed.Stat()
+	if err != nil {
+		return
+	}
+	if int64(len(headerBytes))+info.Size() > 0x7fffffff || payload.Size() > 0x7fffffff {
+		return fmt.Errorf("Packages larger than 2 GiB are not supported")
+	}
+
+	// MD5 covers the header and the payload, so the payload is read twice
+	digest := md5.New()
+	digest.Write(headerBytes)
+	err = payload.copyCompressed(digest)
+	if err != nil {
+		return
+	}
+	sha1sum := sha1.Sum(headerBytes)
+	sha256sum := sha256.Sum256(headerBytes)
+
+	sig := NewSectionBuilder()
+	sig.AddString(RPMSIGTAG_SHA1, hex.EncodeToString(sha1sum[:]))
+	sig.AddString(RPMSIGTAG_SHA256, hex.EncodeToString(sha256sum[:]))
+	sig.AddInt32(RPMSIGTAG_SIZE, int32(int64(len(headerBytes))+info.Size()))
+	sig.AddBinary(RPMSIGTAG_MD5, digest.Sum(nil))
+	sig.AddInt32(RPMSIGTAG_PAYLOADSIZE, int32(payload.Size()))
+	signature := sig.RegionSection(RPMSIGTAG_HEADERSIGNATURES)
+
+	err = writeLead(w, header)
+	if err != nil {
+		return
+	}
+
+	// The header starts at 8 byte boundary
+	sigBytes := signature.Bytes()
+	if len(sigBytes)%8 != 0 {
+		sigBytes = append(sigBytes, make([]byte, 8-len(sigBytes)%8)...)
+	}
+
+	for _, data := range [][]byte{sigBytes, headerBytes} {
+		_, err = w.Write(data)
+		if err != nil {
+			return
+		}
+	}
+
+	return payload.copyCompressed(w)
+}
@@ -0,0 +1,80 @@
+package rpmlib_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWritePackageFile(t *testing.T) {
+	pkg := rpmtest.Binary("foo", "1.0", "1")
+	pkg.Files = append(pkg.Files, rpmtest.File{
+		Path: "/usr/share/foo/large", Mode: rpmlib.S_IFREG | 0644, Data: strings.Repeat("0123456789", 100000),
+	})
+	filename := rpmtest.Write(t, t.TempDir(), pkg)
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	opened, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header and the payload are at the end of the file
+	size, err := opened.Signature.Size()
+	if err != nil || int(size) >= len(data) {
+		t.Fatalf("Size() = %d, %v", size, err)
+	}
+	signed := data[len(data)-int(size):]
+
+	sum := md5.Sum(signed)
+	if digest, _, err := opened.Signature.GetStore(rpmlib.RPMSIGTAG_MD5); err != nil || hex.EncodeToString(digest) != hex.EncodeToString(sum[:]) {
+		t.Errorf("MD5 = %x, %v, want %x", digest, err, sum)
+	}
+
+	nindex := binary.BigEndian.Uint32(signed[8:])
+	hsize := binary.BigEndian.Uint32(signed[12:])
+	payload := signed[16+16*nindex+hsize:]
+
+	compressed := sha256.Sum256(payload)
+	digests, err := opened.Header.Section.GetStringArray(rpmlib.RPMTAG_PAYLOADDIGEST)
+	if err != nil || len(digests) != 1 || digests[0] != hex.EncodeToString(compressed[:]) {
+		t.Errorf("PAYLOADDIGEST = %v, %v", digests, err)
+	}
+
+	f, err := opened.Open("/usr/share/foo/large")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Bytes()) != 1000000 {
+		t.Errorf("Payload has %d bytes of the file", len(f.Bytes()))
+	}
+}
+
+func TestPayloadWriterRemove(t *testing.T) {
+	payload, err := rpmlib.NewPayloadWriter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = payload.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = payload.Remove(); err != nil {
+		t.Errorf("Remove() = %s", err)
+	}
+}
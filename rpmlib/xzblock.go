@@ -0,0 +1,224 @@
+package rpmlib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/xi2/xz"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+)
+
+//
+// Block-level access to xz payloads.
+// Each xz block is compressed independently, so a block can be
+// decompressed alone by wrapping it into a single block stream.
+//
+
+const (
+	xzStreamHeaderSize = 12
+	xzStreamFooterSize = 12
+)
+
+var xzFooterMagic = []byte{'Y', 'Z'}
+
+type XZBlock struct {
+	// Offset of the block from the beginning of the payload
+	Offset int64
+	// Size of the block without padding
+	UnpaddedSize int64
+	// Offset and size of decompressed data
+	UncompressedOffset int64
+	UncompressedSize   int64
+}
+
+func (block *XZBlock) paddedSize() int64 {
+	return (block.UnpaddedSize + 3) &^ 3
+}
+
+func readXZVarint(rd *bytes.Reader) (value int64, err error) {
+	for i := uint(0); i < 9; i++ {
+		b, err := rd.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		value |= int64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("xz variable length integer is too long")
+}
+
+func appendXZVarint(buffer []byte, value int64) []byte {
+	for value >= 0x80 {
+		buffer = append(buffer, byte(value)|0x80)
+		value >>= 7
+	}
+
+	return append(buffer, byte(value))
+}
+
+// readXZBlocks reads the index of the xz stream which is stored in
+// ra from 0 to size. Only single stream without stream padding is supported.
+func readXZBlocks(ra io.ReaderAt, size int64) (streamHeader []byte, blocks []XZBlock, err error) {
+	if size < xzStreamHeaderSize+xzStreamFooterSize {
+		return nil, nil, fmt.Errorf("xz stream is too short")
+	}
+
+	streamHeader = make([]byte, xzStreamHeaderSize)
+	_, err = ra.ReadAt(streamHeader, 0)
+	if err != nil {
+		return
+	}
+
+	footer := make([]byte, xzStreamFooterSize)
+	_, err = ra.ReadAt(footer, size-xzStreamFooterSize)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(footer[10:], xzFooterMagic) {
+		return nil, nil, fmt.Errorf("xz stream footer magic is invalid")
+	}
+
+	if !bytes.Equal(footer[8:10], streamHeader[6:8]) {
+		return nil, nil, fmt.Errorf("xz stream flags are different between header and footer")
+	}
+
+	indexSize := (int64(binary.LittleEndian.Uint32(footer[4:8])) + 1) * 4
+	indexOffset := size - xzStreamFooterSize - indexSize
+	if indexOffset < xzStreamHeaderSize {
+		return nil, nil, fmt.Errorf("xz index size is invalid")
+	}
+
+	index := make([]byte, indexSize)
+	_, err = ra.ReadAt(index, indexOffset)
+	if err != nil {
+		return
+	}
+
+	if crc32.ChecksumIEEE(index[:indexSize-4]) != binary.LittleEndian.Uint32(index[indexSize-4:]) {
+		return nil, nil, fmt.Errorf("xz index checksum is invalid")
+	}
+
+	rd := bytes.NewReader(index[1 : indexSize-4])
+	nrecord, err := readXZVarint(rd)
+	if err != nil {
+		return
+	}
+
+	offset := int64(xzStreamHeaderSize)
+	var uncompressed int64
+	for i := int64(0); i < nrecord; i++ {
+		var block XZBlock
+
+		block.UnpaddedSize, err = readXZVarint(rd)
+		if err != nil {
+			return
+		}
+
+		block.UncompressedSize, err = readXZVarint(rd)
+		if err != nil {
+			return
+		}
+
+		block.Offset = offset
+		block.UncompressedOffset = uncompressed
+
+		offset += block.paddedSize()
+		uncompressed += block.UncompressedSize
+
+		blocks = append(blocks, block)
+	}
+
+	if offset != indexOffset {
+		return nil, nil, fmt.Errorf("xz blocks and index are inconsistent")
+	}
+
+	return
+}
+
+// newXZBlockReader decompresses one block by building a single block stream
+func newXZBlockReader(ra io.ReaderAt, streamHeader []byte, block XZBlock) (rd io.Reader, err error) {
+	index := []byte{0}
+	index = appendXZVarint(index, 1)
+	index = appendXZVarint(index, block.UnpaddedSize)
+	index = appendXZVarint(index, block.UncompressedSize)
+	for len(index)%4 != 0 {
+		index = append(index, 0)
+	}
+	index = append(index, make([]byte, 4)...)
+	binary.LittleEndian.PutUint32(index[len(index)-4:], crc32.ChecksumIEEE(index[:len(index)-4]))
+
+	footer := make([]byte, xzStreamFooterSize)
+	binary.LittleEndian.PutUint32(footer[4:8], uint32(len(index)/4-1))
+	copy(footer[8:10], streamHeader[6:8])
+	copy(footer[10:], xzFooterMagic)
+	binary.LittleEndian.PutUint32(footer[0:4], crc32.ChecksumIEEE(footer[4:10]))
+
+	stream := io.MultiReader(
+		bytes.NewReader(streamHeader),
+		io.NewSectionReader(ra, block.Offset, block.paddedSize()),
+		bytes.NewReader(index),
+		bytes.NewReader(footer),
+	)
+
+	return xz.NewReader(stream, 0)
+}
+
+// xzSeekReader reads decompressed data from the block containing offset
+// to the end of the stream, decompressing blocks one by one
+type xzSeekReader struct {
+	ra           io.ReaderAt
+	streamHeader []byte
+	blocks       []XZBlock
+	current      io.Reader
+}
+
+func newXZSeekReader(ra io.ReaderAt, streamHeader []byte, blocks []XZBlock, offset int64) (rd io.Reader, err error) {
+	for i, block := range blocks {
+		if offset < block.UncompressedOffset+block.UncompressedSize {
+			reader := &xzSeekReader{ra: ra, streamHeader: streamHeader, blocks: blocks[i:]}
+
+			_, err = io.CopyN(ioutil.Discard, reader, offset-block.UncompressedOffset)
+			if err != nil {
+				return
+			}
+
+			return reader, nil
+		}
+	}
+
+	return nil, io.EOF
+}
+
+func (rd *xzSeekReader) Read(b []byte) (n int, err error) {
+	for {
+		if rd.current == nil {
+			if len(rd.blocks) == 0 {
+				return 0, io.EOF
+			}
+
+			rd.current, err = newXZBlockReader(rd.ra, rd.streamHeader, rd.blocks[0])
+			if err != nil {
+				return
+			}
+			rd.blocks = rd.blocks[1:]
+		}
+
+		n, err = rd.current.Read(b)
+		if err == io.EOF {
+			rd.current = nil
+			err = nil
+			if n == 0 {
+				continue
+			}
+		}
+
+		return
+	}
+}
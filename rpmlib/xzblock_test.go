@@ -0,0 +1,87 @@
+package rpmlib
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// testdata/blocks.xz is lines "line 00000" to "line 09999" compressed by
+// xz --block-size=16384, which has 7 blocks
+func xzBlocksData() []byte {
+	var data bytes.Buffer
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&data, "line %05d\n", i)
+	}
+
+	return data.Bytes()
+}
+
+func TestReadXZBlocks(t *testing.T) {
+	compressed, err := ioutil.ReadFile("testdata/blocks.xz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := xzBlocksData()
+
+	ra := bytes.NewReader(compressed)
+	header, blocks, err := readXZBlocks(ra, int64(len(compressed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(header) != xzStreamHeaderSize || len(blocks) != 7 {
+		t.Fatalf("Stream header of %d bytes and %d blocks", len(header), len(blocks))
+	}
+
+	// Blocks cover the data without gaps
+	var offset int64
+	for i, block := range blocks {
+		if block.UncompressedOffset != offset {
+			t.Errorf("Block %d starts at %d, want %d", i, block.UncompressedOffset, offset)
+		}
+		offset += block.UncompressedSize
+	}
+	if offset != int64(len(data)) {
+		t.Errorf("Blocks have %d bytes, want %d", offset, len(data))
+	}
+
+	for _, start := range []int64{0, 1, 16383, 16384, 16385, 50000, 98304, int64(len(data)) - 1} {
+		rd, err := newXZSeekReader(ra, header, blocks, start)
+		if err != nil {
+			t.Errorf("Seek to %d: %s", start, err)
+			continue
+		}
+		rest, err := ioutil.ReadAll(rd)
+		if err != nil {
+			t.Errorf("Read from %d: %s", start, err)
+			continue
+		}
+		if !bytes.Equal(rest, data[start:]) {
+			t.Errorf("Read from %d: %d bytes differ from the data", start, len(rest))
+		}
+	}
+
+	if _, err := newXZSeekReader(ra, header, blocks, int64(len(data))); err != io.EOF {
+		t.Errorf("Seek to the end: %v, want EOF", err)
+	}
+}
+
+func TestReadXZBlocksCorrupt(t *testing.T) {
+	compressed, err := ioutil.ReadFile("testdata/blocks.xz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The footer magic is broken
+	broken := append([]byte{}, compressed...)
+	broken[len(broken)-1] ^= 0xff
+	if _, _, err := readXZBlocks(bytes.NewReader(broken), int64(len(broken))); err == nil {
+		t.Errorf("Broken footer is accepted")
+	}
+
+	if _, _, err := readXZBlocks(bytes.NewReader(compressed[:10]), 10); err == nil {
+		t.Errorf("Truncated stream is accepted")
+	}
+}
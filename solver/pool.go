@@ -0,0 +1,202 @@
+package solver
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+)
+
+// Package is a package in a pool, with dependencies read from the header
+type Package struct {
+	Header   *rpmlib.Header
+	Location string
+	NEVRA    rpmlib.NEVRA
+
+	Provides  []rpmlib.Dependency
+	Requires  []rpmlib.Dependency
+	Conflicts []rpmlib.Dependency
+	Obsoletes []rpmlib.Dependency
+
+	files map[string]bool
+	// Index in the pool, to identify a set of packages
+	id int
+}
+
+func (pkg *Package) String() string {
+	return pkg.NEVRA.String()
+}
+
+// provides checks the package provides the dependency by its provides or files.
+// err is the error of parsing a rich dependency.
+func (pkg *Package) provides(dep rpmlib.Dependency) (provided bool, err error) {
+	if dep.Flags&rpmlib.RPMSENSE_SENSEMASK == 0 && pkg.files[dep.Name] {
+		return true, nil
+	}
+
+	return rpmlib.SatisfiesAny(pkg.Provides, dep)
+}
+
+// obsoletes checks the package obsoletes other, obsoletes are matched with package names
+func (pkg *Package) obsoletes(other *Package) (obsolete rpmlib.Dependency, found bool) {
+	self := rpmlib.Dependency{
+		Name:    other.NEVRA.Name,
+		Flags:   rpmlib.RPMSENSE_EQUAL,
+		Version: other.NEVRA.EVR.String(),
+	}
+
+	for _, obsolete := range pkg.Obsoletes {
+		// Obsoletes are simple dependencies, which are matched without errors
+		if satisfied, _ := rpmlib.Satisfies(self, obsolete); satisfied {
+			return obsolete, true
+		}
+	}
+
+	return
+}
+
+// Arches which can be installed on a machine, in order of preference
+var compatibleArches = map[string][]string{
+	"x86_64":  {"x86_64", "amd64", "ia32e", "athlon", "i686", "i586", "i486", "i386", "noarch"},
+	"i686":    {"i686", "i586", "i486", "i386", "noarch"},
+	"i586":    {"i586", "i486", "i386", "noarch"},
+	"aarch64": {"aarch64", "noarch"},
+	"armv7hl": {"armv7hl", "armv7l", "armv6l", "armv5tel", "noarch"},
+	"ppc64le": {"ppc64le", "noarch"},
+	"ppc64":   {"ppc64", "ppc", "noarch"},
+	"s390x":   {"s390x", "s390", "noarch"},
+	"riscv64": {"riscv64", "noarch"},
+}
+
+// Pool is a set of packages to choose from
+type Pool struct {
+	// Arch of the target machine, empty to allow any arch
+	Arch string
+
+	packages []*Package
+	byHeader map[*rpmlib.Header]*Package
+	byName   map[string][]*Package
+	index    *rpmlib.ProvidesIndex
+}
+
+func NewPool(arch string) (pool *Pool) {
+	pool = new(Pool)
+	pool.Arch = arch
+	pool.byHeader = make(map[*rpmlib.Header]*Package)
+	pool.byName = make(map[string][]*Package)
+	pool.index = rpmlib.NewProvidesIndex()
+
+	return
+}
+
+// Add adds a binary package to the pool, location is informational such as the path to file.
+// Source packages are not added.
+func (pool *Pool) Add(header *rpmlib.Header, location string) (pkg *Package, err error) {
+	if header.IsSource() {
+		return
+	}
+
+	pkg = new(Package)
+	pkg.Header = header
+	pkg.Location = location
+	pkg.NEVRA = header.NEVRA()
+
+	pkg.Provides, err = header.Provides()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.Requires, err = header.Requires()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.Conflicts, err = header.Conflicts()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.Obsoletes, err = header.Obsoletes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Packages provide themselves, even if it is not in the header
+	pkg.Provides = append(pkg.Provides, rpmlib.Dependency{
+		Name:    pkg.NEVRA.Name,
+		Flags:   rpmlib.RPMSENSE_EQUAL,
+		Version: pkg.NEVRA.EVR.String(),
+	})
+
+	pkg.files = make(map[string]bool)
+	if filenames, err := header.FileNames(); err == nil {
+		for _, filename := range filenames {
+			pkg.files[filename] = true
+		}
+	}
+
+	err = pool.index.Add(header)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.id = len(pool.packages)
+	pool.packages = append(pool.packages, pkg)
+	pool.byHeader[header] = pkg
+	pool.byName[pkg.NEVRA.Name] = append(pool.byName[pkg.NEVRA.Name], pkg)
+
+	return
+}
+
+func (pool *Pool) Packages() []*Package {
+	return pool.packages
+}
+
+// archScore returns the preference of arch, smaller is better and -1 for incompatible arch
+func (pool *Pool) archScore(arch string) int {
+	if pool.Arch == "" {
+		if arch == "noarch" {
+			return 1
+		}
+		return 0
+	}
+
+	arches, found := compatibleArches[pool.Arch]
+	if !found {
+		arches = []string{pool.Arch, "noarch"}
+	}
+
+	for i, a := range arches {
+		if a == arch {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// whatProvides returns installable packages which provide the simple dependency
+func (pool *Pool) whatProvides(dep rpmlib.Dependency) (packages []*Package) {
+	for _, header := range pool.index.WhatProvides(dep) {
+		pkg := pool.byHeader[header]
+		if pool.archScore(pkg.NEVRA.Arch) < 0 {
+			continue
+		}
+
+		packages = append(packages, pkg)
+	}
+
+	// Packages without the implicit self provide in the header
+	for _, pkg := range pool.byName[dep.Name] {
+		if provided, _ := pkg.provides(dep); !provided || pool.archScore(pkg.NEVRA.Arch) < 0 {
+			continue
+		}
+
+		found := false
+		for _, p := range packages {
+			found = found || p == pkg
+		}
+		if !found {
+			packages = append(packages, pkg)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,351 @@
+package solver
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//
+// A backtracking solver. Requirements are resolved one by one,
+// trying candidates in order of preference, and the last choice is
+// undone when a requirement cannot be resolved or the chosen packages conflict.
+// Sets of chosen packages which failed are remembered, so that the same set
+// reached by choices in another order is not searched again.
+//
+
+// Problem explains why requested packages cannot be installed.
+// Reason is the conflict which ended the search, after all choices are tried.
+type Problem struct {
+	Reason string
+}
+
+func (problem *Problem) Error() string {
+	return "Cannot solve dependencies: " + problem.Reason
+}
+
+type job struct {
+	require rpmlib.Dependency
+	rich    *rpmlib.RichDependency
+	// Package which requires, or nil for a request
+	by *Package
+}
+
+func (j *job) String() string {
+	if j.rich != nil {
+		return j.rich.String()
+	}
+
+	return j.require.String()
+}
+
+func (j *job) neededBy() string {
+	if j.by == nil {
+		return "requested"
+	}
+
+	return "needed by " + j.by.String()
+}
+
+type solver struct {
+	pool     *Pool
+	selected []*Package
+	names    map[string]*Package
+
+	// Reason of the last failure
+	reason string
+	// Reasons of failed sets of selected packages, by state
+	failed map[string]string
+}
+
+// Solve computes packages to install for requests, such as "bash" or "libfoo >= 2.0".
+// A request is a package name, a provide or a rich dependency.
+// Highest version and best arch are preferred among candidates.
+// It returns *Problem as error if it is impossible.
+func (pool *Pool) Solve(requests []string) (install []*Package, err error) {
+	s := &solver{
+		pool:   pool,
+		names:  make(map[string]*Package),
+		failed: make(map[string]string),
+	}
+
+	var jobs []*job
+	for _, request := range requests {
+		rich, err := rpmlib.ParseRichDependency(request)
+		if err != nil {
+			return nil, err
+		}
+
+		j := &job{require: rich.Dependency}
+		if rich.Op != rpmlib.RichNone {
+			j.rich = rich
+		}
+		jobs = append(jobs, j)
+	}
+
+	if !s.solve(jobs) {
+		return nil, &Problem{s.reason}
+	}
+
+	install = append(install, s.selected...)
+	sort.Slice(install, func(i, j int) bool {
+		return install[i].String() < install[j].String()
+	})
+
+	return
+}
+
+func (s *solver) fail(format string, args ...interface{}) {
+	s.reason = fmt.Sprintf(format, args...)
+}
+
+// state identifies the set of selected packages, regardless of the order of choices
+func (s *solver) state() string {
+	ids := make([]int, len(s.selected))
+	for i, pkg := range s.selected {
+		ids[i] = pkg.id
+	}
+	sort.Ints(ids)
+
+	var builder strings.Builder
+	for _, id := range ids {
+		builder.WriteString(strconv.Itoa(id))
+		builder.WriteByte(',')
+	}
+
+	return builder.String()
+}
+
+// provided checks the simple dependency is provided by selected packages
+func (s *solver) provided(dep rpmlib.Dependency) bool {
+	for _, pkg := range s.selected {
+		if provided, _ := pkg.provides(dep); provided {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *solver) satisfied(j *job) bool {
+	if j.rich != nil {
+		return j.rich.Eval(s.provided)
+	}
+
+	return s.provided(j.require)
+}
+
+// positiveDependencies returns dependencies in rich dependency which may be installed
+// to satisfy it, not including conditions and ones after "without"
+func positiveDependencies(rich *rpmlib.RichDependency) (deps []rpmlib.Dependency) {
+	switch rich.Op {
+	case rpmlib.RichNone:
+		return []rpmlib.Dependency{rich.Dependency}
+	case rpmlib.RichWithout, rpmlib.RichIf, rpmlib.RichUnless:
+		deps = positiveDependencies(rich.Operands[0])
+	default:
+		for _, operand := range rich.Operands {
+			deps = append(deps, positiveDependencies(operand)...)
+		}
+	}
+
+	if rich.Else != nil {
+		deps = append(deps, positiveDependencies(rich.Else)...)
+	}
+
+	return
+}
+
+// candidates returns packages which can resolve the job, in order of preference
+func (s *solver) candidates(j *job) (packages []*Package) {
+	deps := []rpmlib.Dependency{j.require}
+	if j.rich != nil {
+		deps = positiveDependencies(j.rich)
+	}
+
+	found := make(map[*Package]bool)
+	for _, dep := range deps {
+		for _, pkg := range s.pool.whatProvides(dep) {
+			if found[pkg] || s.names[pkg.NEVRA.Name] == pkg {
+				continue
+			}
+			found[pkg] = true
+			packages = append(packages, pkg)
+		}
+	}
+
+	name := j.require.Name
+	sort.SliceStable(packages, func(a, b int) bool {
+		pa, pb := packages[a], packages[b]
+
+		// Package of the required name, then by name
+		if (pa.NEVRA.Name == name) != (pb.NEVRA.Name == name) {
+			return pa.NEVRA.Name == name
+		}
+		if pa.NEVRA.Name != pb.NEVRA.Name {
+			return pa.NEVRA.Name < pb.NEVRA.Name
+		}
+
+		if rc := pa.NEVRA.EVR.Compare(pb.NEVRA.EVR); rc != 0 {
+			return rc > 0
+		}
+
+		return s.pool.archScore(pa.NEVRA.Arch) < s.pool.archScore(pb.NEVRA.Arch)
+	})
+
+	return
+}
+
+// conflict checks the package can be installed with selected packages,
+// and returns the reason if not
+func (s *solver) conflict(pkg *Package) (reason string, found bool) {
+	if other, found := s.names[pkg.NEVRA.Name]; found {
+		return fmt.Sprintf("cannot install both %s and %s", pkg, other), true
+	}
+
+	for _, other := range s.selected {
+		if reason, found := conflicts(pkg, other); found {
+			return reason, true
+		}
+		if reason, found := conflicts(other, pkg); found {
+			return reason, true
+		}
+
+		if obsolete, found := pkg.obsoletes(other); found {
+			return fmt.Sprintf("package %s obsoletes %s provided by %s", pkg, obsolete, other), true
+		}
+		if obsolete, found := other.obsoletes(pkg); found {
+			return fmt.Sprintf("package %s obsoletes %s provided by %s", other, obsolete, pkg), true
+		}
+	}
+
+	return
+}
+
+// conflicts checks conflicts of pkg with other.
+// A rich conflict which cannot be parsed is a conflict, same as rpm.
+func conflicts(pkg *Package, other *Package) (reason string, found bool) {
+	for _, c := range pkg.Conflicts {
+		provided, err := other.provides(c)
+		if err != nil {
+			return fmt.Sprintf("package %s has invalid conflict %s: %s", pkg, c, err), true
+		}
+		if provided {
+			return fmt.Sprintf("package %s conflicts with %s provided by %s", pkg, c, other), true
+		}
+	}
+
+	return
+}
+
+func (s *solver) add(pkg *Package) {
+	s.selected = append(s.selected, pkg)
+	s.names[pkg.NEVRA.Name] = pkg
+}
+
+func (s *solver) remove(pkg *Package) {
+	s.selected = s.selected[:len(s.selected)-1]
+	delete(s.names, pkg.NEVRA.Name)
+}
+
+// jobsOf returns requirements of the package, err is the error of parsing a rich dependency
+func jobsOf(pkg *Package) (jobs []*job, err error) {
+	for _, require := range pkg.Requires {
+		if require.IsRpmlib() || require.Flags&rpmlib.RPMSENSE_MISSINGOK != 0 {
+			continue
+		}
+
+		j := &job{require: require, by: pkg}
+		if require.IsRich() {
+			j.rich, err = require.Rich()
+			if err != nil {
+				return nil, fmt.Errorf("package %s has invalid requirement %s: %s", pkg, require, err)
+			}
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return
+}
+
+// solve resolves pending jobs, chosen packages are kept in s.selected if it succeeds.
+// Pending jobs are requests and requirements of selected packages not resolved yet,
+// so whether it succeeds depends only on the selected packages.
+func (s *solver) solve(pending []*job) (solved bool) {
+	state := s.state()
+	if reason, found := s.failed[state]; found {
+		s.reason = reason
+		return false
+	}
+	defer func() {
+		if !solved {
+			s.failed[state] = s.reason
+		}
+	}()
+
+	for len(pending) > 0 {
+		j := pending[0]
+		pending = pending[1:]
+
+		if s.satisfied(j) {
+			continue
+		}
+
+		candidates := s.candidates(j)
+		if len(candidates) == 0 {
+			s.fail("nothing provides %s %s", j, j.neededBy())
+			return false
+		}
+
+		for _, pkg := range candidates {
+			if reason, found := s.conflict(pkg); found {
+				s.fail("%s, for %s %s", reason, j, j.neededBy())
+				continue
+			}
+
+			jobs, err := jobsOf(pkg)
+			if err != nil {
+				s.fail("%s, for %s %s", err, j, j.neededBy())
+				continue
+			}
+
+			s.add(pkg)
+
+			next := append([]*job{}, pending...)
+			next = append(next, jobs...)
+			// A rich dependency may need more packages
+			if j.rich != nil {
+				next = append(next, j)
+			}
+
+			if s.solve(next) {
+				return true
+			}
+
+			s.remove(pkg)
+		}
+
+		return false
+	}
+
+	// Conditions of rich dependencies may be changed by packages chosen later
+	var unsatisfied []*job
+	for _, pkg := range s.selected {
+		// Requirements of selected packages are parsed when they are chosen
+		jobs, _ := jobsOf(pkg)
+		for _, j := range jobs {
+			if j.rich != nil && !s.satisfied(j) {
+				unsatisfied = append(unsatisfied, j)
+			}
+		}
+	}
+
+	if len(unsatisfied) > 0 {
+		return s.solve(unsatisfied)
+	}
+
+	return true
+}
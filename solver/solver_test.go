@@ -0,0 +1,281 @@
+package solver
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/internal/rpmtest"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testPackage struct {
+	nevr      string
+	arch      string
+	files     []string
+	provides  []string
+	requires  []string
+	conflicts []string
+	obsoletes []string
+}
+
+// addTestDependencies adds dependencies like "foo >= 1.0" or rich ones as they are
+func addTestDependencies(b *rpmtest.Builder, nameTag, flagsTag, versionTag int32, deps []string) {
+	if len(deps) == 0 {
+		return
+	}
+
+	var names, versions []string
+	var flags []int32
+	for _, dep := range deps {
+		fields := strings.Fields(dep)
+		if strings.HasPrefix(dep, "(") || len(fields) != 3 {
+			names, flags, versions = append(names, dep), append(flags, 0), append(versions, "")
+			continue
+		}
+
+		var sense int32
+		for _, c := range fields[1] {
+			sense |= map[rune]int32{'<': rpmlib.RPMSENSE_LESS, '>': rpmlib.RPMSENSE_GREATER, '=': rpmlib.RPMSENSE_EQUAL}[c]
+		}
+		names, flags, versions = append(names, fields[0]), append(flags, sense), append(versions, fields[2])
+	}
+
+	b.AddStringArray(nameTag, names)
+	b.AddInt32(flagsTag, flags...)
+	b.AddStringArray(versionTag, versions)
+}
+
+func testPool(t *testing.T, arch string, packages ...testPackage) *Pool {
+	pool := NewPool(arch)
+
+	for _, p := range packages {
+		fields := strings.Split(p.nevr, "-")
+		pkg := rpmtest.Binary(fields[0], fields[1], fields[2])
+		for _, file := range p.files {
+			pkg.Files = append(pkg.Files, rpmtest.Regular(file, ""))
+		}
+		if p.arch != "" {
+			pkg.Arch = p.arch
+		}
+		p := p
+		pkg.Tags = func(b *rpmtest.Builder) {
+			addTestDependencies(b, rpmlib.RPMTAG_PROVIDENAME, rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMTAG_PROVIDEVERSION, p.provides)
+			addTestDependencies(b, rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION, p.requires)
+			addTestDependencies(b, rpmlib.RPMTAG_CONFLICTNAME, rpmlib.RPMTAG_CONFLICTFLAGS, rpmlib.RPMTAG_CONFLICTVERSION, p.conflicts)
+			addTestDependencies(b, rpmlib.RPMTAG_OBSOLETENAME, rpmlib.RPMTAG_OBSOLETEFLAGS, rpmlib.RPMTAG_OBSOLETEVERSION, p.obsoletes)
+		}
+
+		if _, err := pool.Add(rpmtest.Open(t, pkg).Header, p.nevr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return pool
+}
+
+func installed(packages []*Package) string {
+	var names []string
+	for _, pkg := range packages {
+		names = append(names, pkg.String())
+	}
+
+	return strings.Join(names, " ")
+}
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []testPackage
+		arch     string
+		requests []string
+		install  string
+	}{
+		{
+			"requirements are installed",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"libb", "/usr/bin/c"}},
+				{nevr: "b-1-1", provides: []string{"libb"}},
+				{nevr: "c-1-1", files: []string{"/usr/bin/c"}, requires: []string{"a"}},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch b-1-1.noarch c-1-1.noarch",
+		},
+		{
+			"highest version is preferred",
+			[]testPackage{{nevr: "a-1-1"}, {nevr: "a-2-1"}, {nevr: "a-1.5-1"}},
+			"", []string{"a"},
+			"a-2-1.noarch",
+		},
+		{
+			"versions are required",
+			[]testPackage{{nevr: "a-1-1", requires: []string{"b < 2"}}, {nevr: "b-1-1"}, {nevr: "b-2-1"}},
+			"", []string{"a"},
+			"a-1-1.noarch b-1-1.noarch",
+		},
+		{
+			"best arch is preferred",
+			[]testPackage{{nevr: "a-1-1", arch: "i686"}, {nevr: "a-1-1", arch: "x86_64"}, {nevr: "a-2-1", arch: "aarch64"}},
+			"x86_64", []string{"a"},
+			"a-1-1.x86_64",
+		},
+		{
+			"choices are undone by failures of later requirements",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"b"}},
+				{nevr: "b-2-1", requires: []string{"missing"}},
+				{nevr: "b-1-1"},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch b-1-1.noarch",
+		},
+		{
+			"conflicts select another provider",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"mta"}, conflicts: []string{"exim"}},
+				{nevr: "exim-1-1", provides: []string{"mta"}},
+				{nevr: "postfix-1-1", provides: []string{"mta"}},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch postfix-1-1.noarch",
+		},
+		{
+			"obsoleted packages are not installed together",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"b", "mta"}},
+				{nevr: "b-1-1", obsoletes: []string{"exim < 2"}},
+				{nevr: "exim-1-1", provides: []string{"mta"}},
+				{nevr: "postfix-1-1", provides: []string{"mta"}},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch b-1-1.noarch postfix-1-1.noarch",
+		},
+		{
+			"rich requirements",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"(b or c)", "(d if b)", "(e unless b)"}},
+				{nevr: "b-1-1"},
+				{nevr: "c-1-1"},
+				{nevr: "d-1-1"},
+				{nevr: "e-1-1"},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch b-1-1.noarch d-1-1.noarch",
+		},
+		{
+			"conditions changed by later choices",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"(d if c)", "c"}},
+				{nevr: "c-1-1"},
+				{nevr: "d-1-1"},
+			},
+			"", []string{"a"},
+			"a-1-1.noarch c-1-1.noarch d-1-1.noarch",
+		},
+		{
+			"rich requests",
+			[]testPackage{{nevr: "a-1-1"}, {nevr: "b-1-1"}},
+			"", []string{"(a >= 2 or b)"},
+			"b-1-1.noarch",
+		},
+		{
+			"invalid rich requirements are not installed",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"(b or"}},
+				{nevr: "a-0.9-1"},
+			},
+			"", []string{"a"},
+			"a-0.9-1.noarch",
+		},
+	}
+
+	for _, test := range tests {
+		install, err := testPool(t, test.arch, test.packages...).Solve(test.requests)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if installed(install) != test.install {
+			t.Errorf("%s: installs %s, want %s", test.name, installed(install), test.install)
+		}
+	}
+}
+
+func TestSolveProblem(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []testPackage
+		requests []string
+		reason   string
+	}{
+		{
+			"nothing provides",
+			[]testPackage{{nevr: "a-1-1", requires: []string{"missing >= 2"}}},
+			[]string{"a"},
+			"nothing provides missing >= 2 needed by a-1-1.noarch",
+		},
+		{
+			"the final conflict is reported",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"b"}, conflicts: []string{"b"}},
+				{nevr: "b-2-1", requires: []string{"missing"}},
+				{nevr: "b-1-1"},
+			},
+			[]string{"a"},
+			"package a-1-1.noarch conflicts with b provided by b-1-1.noarch, for b needed by a-1-1.noarch",
+		},
+		{
+			"invalid rich conflicts",
+			[]testPackage{
+				{nevr: "a-1-1", requires: []string{"b"}},
+				{nevr: "b-1-1", conflicts: []string{"(c and"}},
+			},
+			[]string{"a"},
+			"package b-1-1.noarch has invalid conflict (c and",
+		},
+		{
+			"invalid rich requirements",
+			[]testPackage{{nevr: "a-1-1", requires: []string{"(b or"}}},
+			[]string{"a"},
+			"package a-1-1.noarch has invalid requirement (b or",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := testPool(t, "", test.packages...).Solve(test.requests)
+		problem, ok := err.(*Problem)
+		if !ok {
+			t.Errorf("%s: error %v is not a problem", test.name, err)
+			continue
+		}
+		if !strings.HasPrefix(problem.Reason, test.reason) {
+			t.Errorf("%s: reason %q, want %q", test.name, problem.Reason, test.reason)
+		}
+	}
+}
+
+// The same sets of packages chosen in different orders are not searched again.
+// Each of n capabilities has two providers which require each other and the next
+// capability, which would take 2^n tries without remembering failed sets.
+func TestSolveFailedStates(t *testing.T) {
+	var packages []testPackage
+	for i := 0; i < 30; i++ {
+		a, b := fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)
+		next := fmt.Sprintf("cap%d", i+1)
+		packages = append(packages,
+			testPackage{nevr: a + "-1-1", provides: []string{fmt.Sprintf("cap%d", i)}, requires: []string{b, next}},
+			testPackage{nevr: b + "-1-1", provides: []string{fmt.Sprintf("cap%d", i)}, requires: []string{a, next}},
+		)
+	}
+
+	pool := testPool(t, "", packages...)
+
+	start := time.Now()
+	_, err := pool.Solve([]string{"cap0"})
+	if problem, ok := err.(*Problem); !ok || !strings.HasPrefix(problem.Reason, "nothing provides cap30 needed by") {
+		t.Errorf("Solve() = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Solve() took %s", elapsed)
+	}
+}
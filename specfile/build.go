@@ -0,0 +1,524 @@
+package specfile
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/cpio"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Building binary packages of files which are already installed in a build root.
+// Scripts such as %build and %install are not executed.
+//
+
+type BuildOptions struct {
+	// Files of %files are taken from the directory, same as %{buildroot}
+	BuildRoot string
+	// Relative files of %doc and %license and file lists of %files -f are read from the directory
+	BuildDir string
+	// Packages are written to the directory
+	OutputDir string
+	BuildHost string
+	BuildTime time.Time
+}
+
+// packageFile is a file to package, with the metadata stored in the header
+type packageFile struct {
+	meta rpmlib.FileMeta
+	// Path on disk, empty for %ghost files which do not exist
+	source string
+	verify int32
+}
+
+// Dependencies of rpm which packages need for features of files and payloads
+var rpmlibRequires = []rpmlib.Dependency{
+	{Name: "rpmlib(CompressedFileNames)", Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL | rpmlib.RPMSENSE_RPMLIB, Version: "3.0.4-1"},
+	{Name: "rpmlib(FileDigests)", Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL | rpmlib.RPMSENSE_RPMLIB, Version: "4.6.0-1"},
+	{Name: "rpmlib(PayloadFilesHavePrefix)", Flags: rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL | rpmlib.RPMSENSE_RPMLIB, Version: "4.0-1"},
+}
+
+// Build writes binary packages which have %files, and returns their file names.
+// Files in the build root which are not in any package are errors, same as rpmbuild,
+// unless %_unpackaged_files_terminate_build is 0.
+func (spec *Spec) Build(options BuildOptions) (filenames []string, err error) {
+	packaged := make(map[string]bool)
+	files := make(map[*Package][]packageFile)
+	var excludes []string
+
+	for _, pkg := range spec.Packages {
+		if !pkg.HasFiles {
+			continue
+		}
+
+		var pkgExcludes []string
+		files[pkg], pkgExcludes, err = spec.collectFiles(pkg, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", pkg.Name, err)
+		}
+		for _, f := range files[pkg] {
+			packaged[f.meta.Path] = true
+		}
+		excludes = append(excludes, pkgExcludes...)
+	}
+
+	unpackaged, err := findUnpackaged(options.BuildRoot, packaged, excludes)
+	if err != nil {
+		return
+	}
+	if len(unpackaged) > 0 {
+		message := fmt.Sprintf("Installed (but unpackaged) file(s) found:\n   %s", strings.Join(unpackaged, "\n   "))
+		if spec.macro("_unpackaged_files_terminate_build") != "0" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s\n", message)
+	}
+
+	for _, pkg := range spec.Packages {
+		if !pkg.HasFiles {
+			continue
+		}
+
+		filename, write_err := spec.writePackage(pkg, files[pkg], options)
+		if write_err != nil {
+			return filenames, fmt.Errorf("%s: %s", pkg.Name, write_err)
+		}
+		filenames = append(filenames, filename)
+	}
+
+	return
+}
+
+// findUnpackaged returns files and symbolic links in the build root which are not packaged nor excluded
+func findUnpackaged(root string, packaged map[string]bool, excludes []string) (unpackaged []string, err error) {
+	err = filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		if p := "/" + filepath.ToSlash(rel); !packaged[p] && !isExcluded(p, excludes) {
+			unpackaged = append(unpackaged, p)
+		}
+
+		return nil
+	})
+
+	return
+}
+
+// fileLines returns lines of %files and of file lists given by %files -f
+func (spec *Spec) fileLines(pkg *Package, options BuildOptions) (lines []string, err error) {
+	lines = append(lines, pkg.Files...)
+
+	for _, list := range pkg.FileLists {
+		f, open_err := os.Open(filepath.Join(options.BuildDir, list))
+		if open_err != nil {
+			return nil, open_err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, expand_err := spec.Macros.Expand(scanner.Text())
+			if expand_err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s: %s", list, expand_err)
+			}
+			lines = append(lines, line)
+		}
+		f.Close()
+
+		if err = scanner.Err(); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func hasGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// collectFiles finds files of entries of %files in the build root, sorted by paths.
+// Patterns of %exclude are also returned.
+func (spec *Spec) collectFiles(pkg *Package, options BuildOptions) (files []packageFile, excludes []string, err error) {
+	lines, err := spec.fileLines(pkg, options)
+	if err != nil {
+		return
+	}
+
+	entries, err := ParseFiles(lines)
+	if err != nil {
+		return
+	}
+
+	// A file listed twice has attributes of the last entry, same as rpmbuild
+	found := make(map[string]*packageFile)
+	add := func(name string, source string, info os.FileInfo, entry FileEntry) (err error) {
+		f, err := spec.newPackageFile(name, source, info, entry)
+		if err == nil {
+			found[name] = &f
+		}
+		return
+	}
+
+	// addTree adds the file, and files in it if it is a directory without %dir
+	addTree := func(name string, source string, entry FileEntry) error {
+		info, err := os.Lstat(source)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || entry.Dir {
+			return add(name, source, info, entry)
+		}
+
+		return filepath.Walk(source, func(walked string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(source, walked)
+			if err != nil {
+				return err
+			}
+			return add(path.Join(name, filepath.ToSlash(rel)), walked, info, entry)
+		})
+	}
+
+	for _, entry := range entries {
+		switch {
+		case entry.Exclude:
+			excludes = append(excludes, entry.Path)
+		case !strings.HasPrefix(entry.Path, "/"):
+			// Relative %doc and %license files are installed into the directory of the package
+			dir := path.Join(spec.macro("_docdir"), pkg.Name)
+			if entry.Flags&rpmlib.RPMFILE_LICENSE != 0 {
+				dir = path.Join(spec.macro("_licensedir"), pkg.Name)
+			}
+			addDocDir(found, dir, int32(options.BuildTime.Unix()))
+
+			matches, _ := filepath.Glob(filepath.Join(options.BuildDir, filepath.FromSlash(entry.Path)))
+			if len(matches) == 0 {
+				return nil, nil, fmt.Errorf("File not found: %s", filepath.Join(options.BuildDir, entry.Path))
+			}
+			for _, match := range matches {
+				if err = addTree(path.Join(dir, filepath.Base(match)), match, entry); err != nil {
+					return
+				}
+			}
+		default:
+			source := filepath.Join(options.BuildRoot, filepath.FromSlash(entry.Path))
+			matches := []string{source}
+			if hasGlob(entry.Path) {
+				matches, _ = filepath.Glob(source)
+			}
+
+			for _, match := range matches {
+				rel, _ := filepath.Rel(options.BuildRoot, match)
+				name := "/" + filepath.ToSlash(rel)
+
+				_, stat_err := os.Lstat(match)
+				switch {
+				case os.IsNotExist(stat_err) && entry.Flags&rpmlib.RPMFILE_GHOST != 0:
+					err = add(name, "", nil, entry)
+				case stat_err != nil:
+					err = fmt.Errorf("File not found: %s", match)
+				default:
+					err = addTree(name, match, entry)
+				}
+				if err != nil {
+					return
+				}
+			}
+			if len(matches) == 0 {
+				return nil, nil, fmt.Errorf("File not found by glob: %s", source)
+			}
+		}
+	}
+
+	for name, f := range found {
+		if !isExcluded(name, excludes) {
+			files = append(files, *f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].meta.Path < files[j].meta.Path
+	})
+
+	return
+}
+
+// addDocDir adds the directory of %doc or %license files,
+// which does not exist in the build root, as a directory created at the build time.
+func addDocDir(found map[string]*packageFile, dir string, mtime int32) {
+	if _, exists := found[dir]; exists {
+		return
+	}
+
+	found[dir] = &packageFile{
+		meta: rpmlib.FileMeta{
+			Name:  path.Base(dir),
+			Path:  dir,
+			Size:  4096,
+			Mode:  int16(rpmlib.S_IFDIR | 0755),
+			Time:  mtime,
+			User:  "root",
+			Group: "root",
+		},
+		verify: rpmlib.RPMVERIFY_ALL,
+	}
+}
+
+// isExcluded checks the path matches an entry of %exclude, or is in an excluded directory
+func isExcluded(name string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if matched, _ := path.Match(exclude, name); matched {
+			return true
+		}
+		if name == exclude || strings.HasPrefix(name, strings.TrimSuffix(exclude, "/")+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownerNames returns names of the owner of the file on disk, or ids if they have no names
+func ownerNames(info os.FileInfo) (owner string, group string) {
+	uid, gid, _, ok := rpmlib.FileIDs(info)
+	if !ok {
+		return "root", "root"
+	}
+
+	owner, group = strconv.Itoa(int(uid)), strconv.Itoa(int(gid))
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+
+	return
+}
+
+// newPackageFile makes the metadata of the file, by the file on disk and attributes of the entry.
+// info is nil for %ghost files which do not exist.
+func (spec *Spec) newPackageFile(name string, source string, info os.FileInfo, entry FileEntry) (f packageFile, err error) {
+	f.source = source
+	f.verify = entry.Verify
+	f.meta = rpmlib.FileMeta{Name: path.Base(name), Path: name, Flag: entry.Flags, Lang: entry.Lang}
+
+	mode := os.FileMode(0644)
+	if info != nil {
+		mode = info.Mode()
+		f.meta.Time = int32(info.ModTime().Unix())
+	} else {
+		f.source = ""
+	}
+
+	attrMode := entry.Attr.Mode
+	if mode.IsDir() {
+		attrMode = entry.Attr.DirMode
+	}
+	if attrMode >= 0 {
+		mode = mode.Type()
+		for bit, flag := range map[int64]os.FileMode{04000: os.ModeSetuid, 02000: os.ModeSetgid, 01000: os.ModeSticky} {
+			if attrMode&bit != 0 {
+				mode |= flag
+			}
+		}
+		mode |= os.FileMode(attrMode & 0777)
+	}
+	f.meta.Mode = int16(rpmlib.RawFileMode(mode))
+
+	f.meta.User, f.meta.Group = entry.Attr.User, entry.Attr.Group
+	if (f.meta.User == "" || f.meta.Group == "") && info != nil {
+		owner, group := ownerNames(info)
+		if f.meta.User == "" {
+			f.meta.User = owner
+		}
+		if f.meta.Group == "" {
+			f.meta.Group = group
+		}
+	}
+	if f.meta.User == "" {
+		f.meta.User = "root"
+	}
+	if f.meta.Group == "" {
+		f.meta.Group = "root"
+	}
+
+	if info == nil {
+		return
+	}
+
+	switch {
+	case mode&os.ModeSymlink != 0:
+		f.meta.LinkTo, err = os.Readlink(source)
+		f.meta.Size = int32(len(f.meta.LinkTo))
+	case mode&os.ModeDevice != 0:
+		_, _, rdev, _ := rpmlib.FileIDs(info)
+		f.meta.RDevice = int16(rdev)
+	case mode.IsDir():
+		f.meta.Size = int32(info.Size())
+	case mode.IsRegular():
+		if info.Size() > 0x7fffffff {
+			return f, fmt.Errorf("%s is larger than 2 GiB", name)
+		}
+		f.meta.Size = int32(info.Size())
+		f.meta.MD5, err = fileDigest(source)
+	}
+
+	return
+}
+
+func fileDigest(filename string) (digest string, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+// writePayload writes files to the payload. %ghost files are only in the header.
+func writePayload(payload *rpmlib.PayloadWriter, files []packageFile) (err error) {
+	for i, f := range files {
+		if f.meta.Flag&rpmlib.RPMFILE_GHOST != 0 {
+			continue
+		}
+
+		meta := &cpio.Meta{
+			Ino:      uint64(i + 1),
+			Mode:     uint64(uint16(f.meta.Mode)),
+			Nlink:    1,
+			Mtime:    uint64(uint32(f.meta.Time)),
+			Filesize: uint64(f.meta.Size),
+		}
+		mode := f.meta.FileMode()
+		if mode.IsDir() {
+			meta.Nlink = 2
+		}
+		if mode&os.ModeDevice != 0 {
+			meta.Rdevmajor, meta.Rdevminor = uint64(uint16(f.meta.RDevice)>>8), uint64(uint16(f.meta.RDevice)&0xff)
+		}
+		if !mode.IsRegular() && mode&os.ModeSymlink == 0 {
+			meta.Filesize = 0
+		}
+
+		err = payload.WriteHeader(meta, "."+f.meta.Path)
+		if err != nil {
+			return
+		}
+
+		switch {
+		case mode&os.ModeSymlink != 0:
+			_, err = payload.Write([]byte(f.meta.LinkTo))
+		case mode.IsRegular():
+			err = copyFile(payload, f.source, int64(f.meta.Size))
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	return payload.Close()
+}
+
+func copyFile(w io.Writer, filename string, size int64) (err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	n, err := io.Copy(w, io.LimitReader(file, size))
+	if err == nil && n != size {
+		err = fmt.Errorf("%s is changed while packaging", filename)
+	}
+
+	return
+}
+
+// writePackage writes the binary package of the files
+func (spec *Spec) writePackage(pkg *Package, files []packageFile, options BuildOptions) (filename string, err error) {
+	b := spec.HeaderBuilder(pkg)
+
+	nevra := spec.NEVRA(pkg)
+	b.AddString(rpmlib.RPMTAG_BUILDHOST, options.BuildHost)
+	b.AddInt32(rpmlib.RPMTAG_BUILDTIME, int32(options.BuildTime.Unix()))
+	b.AddString(rpmlib.RPMTAG_ENCODING, "utf-8")
+
+	// Packages provide themselves, same as rpmbuild
+	provides := append([]rpmlib.Dependency{}, pkg.Provides...)
+	provides = append(provides, rpmlib.Dependency{Name: pkg.Name, Flags: rpmlib.RPMSENSE_EQUAL, Version: nevra.EVR.String()})
+	addDependencies(b, rpmlib.RPMTAG_PROVIDENAME, rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMTAG_PROVIDEVERSION, provides)
+
+	requires := append(append([]rpmlib.Dependency{}, pkg.Requires...), rpmlibRequires...)
+	addDependencies(b, rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION, requires)
+
+	var metas []rpmlib.FileMeta
+	var verifies []int32
+	var size int64
+	for _, f := range files {
+		metas = append(metas, f.meta)
+		verifies = append(verifies, f.verify)
+		if f.meta.Flag&rpmlib.RPMFILE_GHOST == 0 {
+			size += int64(f.meta.Size)
+		}
+	}
+	b.AddFiles(metas, verifies)
+	if size > 0x7fffffff {
+		b.AddInt64(rpmlib.RPMTAG_LONGSIZE, size)
+	} else {
+		b.AddInt32(rpmlib.RPMTAG_SIZE, int32(size))
+	}
+
+	payload, err := rpmlib.NewPayloadWriter()
+	if err != nil {
+		return
+	}
+	defer payload.Remove()
+
+	err = writePayload(payload, files)
+	if err != nil {
+		return
+	}
+
+	filename = filepath.Join(options.OutputDir, nevra.Filename())
+	out, err := os.Create(filename)
+	if err != nil {
+		return
+	}
+
+	err = rpmlib.WritePackageFile(out, b, payload)
+	if close_err := out.Close(); err == nil {
+		err = close_err
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+
+	return
+}
@@ -0,0 +1,202 @@
+package specfile
+
+import (
+	"github.com/pombredanne/gorpm-1/cpio"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const buildSpec = `Name:		foo
+Version:	1.0
+Release:	1
+Summary:	Foo
+License:	MIT
+BuildArch:	noarch
+
+%description
+Foo.
+
+%files
+%defattr(-,root,root,-)
+%attr(0755,root,root) /usr/bin/foo
+%config(noreplace) %attr(0640,root,adm) /etc/foo.conf
+%dir /var/lib/foo
+%verify(not md5 size mtime) /var/lib/foo/state
+%ghost /var/log/foo.log
+/usr/lib/libfoo.so
+%doc README
+%license LICENSE
+`
+
+// stageFiles writes files in the directory, where directories end with "/"
+// and symbolic links are "-> target"
+func stageFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		isDir := strings.HasSuffix(name, "/")
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		var err error
+		switch {
+		case isDir:
+			err = os.MkdirAll(name, 0755)
+		case strings.HasPrefix(content, "-> "):
+			err = os.Symlink(strings.TrimPrefix(content, "-> "), name)
+		default:
+			err = ioutil.WriteFile(name, []byte(content), 0600)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func buildTestPackage(t *testing.T, buildRoot map[string]string) (filenames []string, err error) {
+	dir := t.TempDir()
+	options := BuildOptions{
+		BuildRoot: filepath.Join(dir, "buildroot"),
+		BuildDir:  filepath.Join(dir, "build"),
+		OutputDir: dir,
+		BuildHost: "build.example.com",
+		BuildTime: time.Unix(1600000000, 0),
+	}
+	stageFiles(t, options.BuildRoot, buildRoot)
+	stageFiles(t, options.BuildDir, map[string]string{"README": "Read me\n", "LICENSE": "MIT\n"})
+
+	spec, err := Parse(strings.NewReader(buildSpec), DefaultMacros("x86_64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return spec.Build(options)
+}
+
+var buildRootFiles = map[string]string{
+	"usr/bin/foo":       "#!/bin/sh\n",
+	"etc/foo.conf":      "option=1\n",
+	"var/lib/foo/":      "",
+	"var/lib/foo/state": "0\n",
+	"usr/lib/libfoo.so": "-> libfoo.so.1",
+	// Not in %files
+	"usr/share/foo/unpackaged": "",
+}
+
+func TestBuild(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range buildRootFiles {
+		if name != "usr/share/foo/unpackaged" {
+			files[name] = content
+		}
+	}
+
+	filenames, err := buildTestPackage(t, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) != 1 || filepath.Base(filenames[0]) != "foo-1.0-1.noarch.rpm" {
+		t.Fatalf("Build() = %v", filenames)
+	}
+
+	file, err := os.Open(filenames[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	pkg, err := rpmlib.OpenPackageFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metas, err := pkg.Header.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifies, err := pkg.Header.Section.GetInt32Array(rpmlib.RPMTAG_FILEVERIFYFLAGS)
+	if err != nil || len(verifies) != len(metas) {
+		t.Fatalf("Verify flags %v: %v", verifies, err)
+	}
+
+	expected := map[string]struct {
+		flags       int32
+		mode        uint16
+		user, group string
+		verify      int32
+	}{
+		"/etc/foo.conf": {rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE, rpmlib.S_IFREG | 0640, "root", "adm", rpmlib.RPMVERIFY_ALL},
+		"/usr/bin/foo":  {0, rpmlib.S_IFREG | 0755, "root", "root", rpmlib.RPMVERIFY_ALL},
+		// Modes of %defattr(-) are taken from the build root
+		"/usr/lib/libfoo.so": {0, rpmlib.S_IFLNK | 0777, "root", "root", rpmlib.RPMVERIFY_ALL},
+		"/var/lib/foo":       {0, rpmlib.S_IFDIR | 0755, "root", "root", rpmlib.RPMVERIFY_ALL},
+		"/var/lib/foo/state": {0, rpmlib.S_IFREG | 0600, "root", "root",
+			rpmlib.RPMVERIFY_ALL &^ (rpmlib.RPMVERIFY_FILEDIGEST | rpmlib.RPMVERIFY_FILESIZE | rpmlib.RPMVERIFY_MTIME)},
+		"/var/log/foo.log": {rpmlib.RPMFILE_GHOST, rpmlib.S_IFREG | 0644, "root", "root", rpmlib.RPMVERIFY_ALL},
+		// Directories of %doc and %license are made at the build time
+		"/usr/share/doc/foo":              {0, rpmlib.S_IFDIR | 0755, "root", "root", rpmlib.RPMVERIFY_ALL},
+		"/usr/share/doc/foo/README":       {rpmlib.RPMFILE_DOC, rpmlib.S_IFREG | 0600, "root", "root", rpmlib.RPMVERIFY_ALL},
+		"/usr/share/licenses/foo":         {0, rpmlib.S_IFDIR | 0755, "root", "root", rpmlib.RPMVERIFY_ALL},
+		"/usr/share/licenses/foo/LICENSE": {rpmlib.RPMFILE_LICENSE, rpmlib.S_IFREG | 0600, "root", "root", rpmlib.RPMVERIFY_ALL},
+	}
+
+	if len(metas) != len(expected) {
+		t.Errorf("%d files, want %d", len(metas), len(expected))
+	}
+	for i, meta := range metas {
+		want, found := expected[meta.Path]
+		if !found {
+			t.Errorf("Unexpected file %s", meta.Path)
+			continue
+		}
+		if meta.Flag != want.flags || uint16(meta.Mode) != want.mode || meta.User != want.user ||
+			meta.Group != want.group || verifies[i] != want.verify {
+			t.Errorf("%s: flags %#x, mode %o, %s:%s, verify %#x", meta.Path, meta.Flag, uint16(meta.Mode),
+				meta.User, meta.Group, verifies[i])
+		}
+		if meta.Path == "/usr/lib/libfoo.so" && meta.LinkTo != "libfoo.so.1" {
+			t.Errorf("%s links to %s", meta.Path, meta.LinkTo)
+		}
+	}
+
+	// %ghost files are only in the header
+	if err = pkg.LoadPayload(); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	reader := cpio.NewCPIOReader(pkg.Payload.Cpio())
+	for {
+		f, read_err := reader.GetFile()
+		if read_err == io.EOF {
+			break
+		}
+		if read_err != nil {
+			t.Fatal(read_err)
+		}
+		names = append(names, f.Name)
+	}
+	if len(names) != len(expected)-1 || strings.Contains(strings.Join(names, " "), "foo.log") {
+		t.Errorf("Payload has %v", names)
+	}
+}
+
+func TestBuildUnpackaged(t *testing.T) {
+	if _, err := buildTestPackage(t, buildRootFiles); err == nil || !strings.Contains(err.Error(), "/usr/share/foo/unpackaged") {
+		t.Errorf("Build() = %v, want an error of the unpackaged file", err)
+	}
+
+	files := make(map[string]string)
+	for name, content := range buildRootFiles {
+		if name != "etc/foo.conf" {
+			files[name] = content
+		}
+	}
+	if _, err := buildTestPackage(t, files); err == nil {
+		t.Errorf("Build() succeeded without a file of %%files")
+	}
+}
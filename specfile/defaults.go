@@ -0,0 +1,148 @@
+package specfile
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+//
+// Default macros of the build environment, a small subset of /usr/lib/rpm/macros
+// which is enough to parse spec files without rpm installed
+//
+
+const defaultMacros = `
+%_target_os		linux
+%_build_os		%{_target_os}
+%_os			%{_target_os}
+%_target_cpu		%{_arch}
+%_build_arch		%{_arch}
+%_target		%{_target_cpu}-%{_target_os}
+%_vendor		redhat
+
+%_usr			/usr
+%_prefix		/usr
+%_exec_prefix		%{_prefix}
+%_bindir		%{_exec_prefix}/bin
+%_sbindir		%{_exec_prefix}/sbin
+%_libexecdir		%{_exec_prefix}/libexec
+%_datarootdir		%{_prefix}/share
+%_datadir		%{_datarootdir}
+%_sysconfdir		/etc
+%_sharedstatedir	/var/lib
+%_localstatedir		/var
+%_includedir		%{_prefix}/include
+%_infodir		%{_datarootdir}/info
+%_mandir		%{_datarootdir}/man
+%_docdir		%{_datadir}/doc
+%_defaultdocdir		%{_docdir}
+%_licensedir		%{_datadir}/licenses
+%_initddir		%{_sysconfdir}/rc.d/init.d
+%_unitdir		/usr/lib/systemd/system
+%_rundir		/run
+%_lib			lib
+%_libdir		%{_exec_prefix}/%{_lib}
+
+%_topdir		%{getenv:HOME}/rpmbuild
+%_builddir		%{_topdir}/BUILD
+%_sourcedir		%{_topdir}/SOURCES
+%_specdir		%{_topdir}/SPECS
+%_rpmdir		%{_topdir}/RPMS
+%_srcrpmdir		%{_topdir}/SRPMS
+%_buildrootdir		%{_topdir}/BUILDROOT
+%buildroot		%{_buildrootdir}/%{NAME}-%{VERSION}-%{RELEASE}.%{_arch}
+
+%ix86			i386 i486 i586 i686 pentium3 pentium4 athlon geode
+%arm32			armv3l armv4b armv4l armv4tl armv5tl armv5tel armv5tejl armv6l armv6hl armv7l armv7hl armv7hnl
+%arm64			aarch64
+%power64		ppc64 ppc64p7 ppc64le
+%mips			mips mipsel mips64 mips64el
+
+%__cc			gcc
+%__cxx			g++
+%__make			/usr/bin/make
+%make_build		%{__make} %{?_smp_mflags}
+%make_install		%{__make} install DESTDIR=%{buildroot}
+%optflags		-O2 -g
+`
+
+// Names of 64 bit architectures, of which %_lib is lib64
+var lib64Arches = map[string]bool{
+	"x86_64": true, "aarch64": true, "ppc64": true, "ppc64le": true, "s390x": true,
+	"riscv64": true, "mips64": true, "mips64el": true, "sparc64": true, "loongarch64": true,
+}
+
+// Architectures of rpm by GOARCH
+var goArches = map[string]string{
+	"amd64": "x86_64", "386": "i686", "arm64": "aarch64", "arm": "armv7hl",
+	"ppc64": "ppc64", "ppc64le": "ppc64le", "s390x": "s390x", "riscv64": "riscv64",
+	"mips64": "mips64", "mips64le": "mips64el", "loong64": "loongarch64",
+}
+
+// HostArch returns the architecture of rpm of the running machine, such as "x86_64"
+func HostArch() string {
+	if arch, found := goArches[runtime.GOARCH]; found {
+		return arch
+	}
+
+	return runtime.GOARCH
+}
+
+// DefaultMacros returns macros of the build environment of the architecture, such as "x86_64"
+func DefaultMacros(arch string) (macros *Macros) {
+	macros = NewMacros()
+	macros.Load(strings.NewReader(defaultMacros))
+
+	macros.Define("_arch", arch)
+	if lib64Arches[arch] {
+		macros.Define("_lib", "lib64")
+	}
+
+	return
+}
+
+// stringList is a flag which may be given more than once, such as -define
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ", ")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// MacroFlags are -define and -macros options of commands, same as rpmbuild
+type MacroFlags struct {
+	Defines    []string
+	MacroFiles []string
+}
+
+// Register adds the options to the flag set
+func (m *MacroFlags) Register(flags *flag.FlagSet) {
+	flags.Var((*stringList)(&m.Defines), "define", "Define a macro as \"name body\". May be given more than once.")
+	flags.Var((*stringList)(&m.MacroFiles), "macros",
+		"Read definitions of macros from the file, such as /usr/lib/rpm/macros. May be given more than once.")
+}
+
+// Macros returns default macros of the architecture, with macro files and definitions of the options
+func (m *MacroFlags) Macros(arch string) (macros *Macros, err error) {
+	macros = DefaultMacros(arch)
+
+	for _, macroFile := range m.MacroFiles {
+		err = macros.LoadFile(macroFile)
+		if err != nil {
+			return
+		}
+	}
+	for _, define := range m.Defines {
+		err = macros.DefineLine(define)
+		if err != nil {
+			return nil, fmt.Errorf("Bad -define %q: %s", define, err)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,343 @@
+package specfile
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strconv"
+	"strings"
+)
+
+//
+// Expressions of %if and %[...], such as "0%{?fedora} >= 38 && "%{_arch}" == "x86_64""
+//
+
+type valueKind int
+
+const (
+	intValue valueKind = iota
+	stringValue
+	versionValue
+)
+
+type exprValue struct {
+	kind valueKind
+	i    int64
+	s    string
+}
+
+func (v exprValue) String() string {
+	if v.kind == intValue {
+		return strconv.FormatInt(v.i, 10)
+	}
+
+	return v.s
+}
+
+// IsTrue checks the value is a nonzero number or a non-empty string
+func (v exprValue) IsTrue() bool {
+	if v.kind == intValue {
+		return v.i != 0
+	}
+
+	return v.s != ""
+}
+
+type exprParser struct {
+	macros *Macros
+	text   string
+	pos    int
+}
+
+// evalExpression evaluates the expression in which macros are already expanded
+func evalExpression(macros *Macros, text string) (value exprValue, err error) {
+	p := &exprParser{macros: macros, text: text}
+
+	value, err = p.ternary()
+	if err != nil {
+		return
+	}
+
+	p.skipSpaces()
+	if p.pos != len(p.text) {
+		return value, fmt.Errorf("Syntax error in expression %q at %d", text, p.pos)
+	}
+
+	return
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t' || p.text[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// accept consumes the operator if it is next
+func (p *exprParser) accept(op string) bool {
+	p.skipSpaces()
+	if !strings.HasPrefix(p.text[p.pos:], op) {
+		return false
+	}
+
+	// "=" shall not match a part of "==" and "<" of "<="
+	rest := p.text[p.pos+len(op):]
+	if (op == "<" || op == ">" || op == "!" || op == "=") && strings.HasPrefix(rest, "=") {
+		return false
+	}
+	if (op == "&" || op == "|") && strings.HasPrefix(rest, op) {
+		return false
+	}
+
+	p.pos += len(op)
+
+	return true
+}
+
+func (p *exprParser) ternary() (value exprValue, err error) {
+	cond, err := p.or()
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+
+	a, err := p.ternary()
+	if err != nil {
+		return
+	}
+	if !p.accept(":") {
+		return value, fmt.Errorf("Syntax error in expression %q, : is expected", p.text)
+	}
+	b, err := p.ternary()
+	if err != nil {
+		return
+	}
+
+	if cond.IsTrue() {
+		return a, nil
+	}
+
+	return b, nil
+}
+
+func boolValue(b bool) exprValue {
+	if b {
+		return exprValue{kind: intValue, i: 1}
+	}
+
+	return exprValue{kind: intValue}
+}
+
+func (p *exprParser) or() (value exprValue, err error) {
+	value, err = p.and()
+	for err == nil && p.accept("||") {
+		var right exprValue
+		right, err = p.and()
+		if value.IsTrue() {
+			continue
+		}
+		value = right
+	}
+
+	return
+}
+
+func (p *exprParser) and() (value exprValue, err error) {
+	value, err = p.comparison()
+	for err == nil && p.accept("&&") {
+		var right exprValue
+		right, err = p.comparison()
+		if !value.IsTrue() {
+			continue
+		}
+		value = right
+	}
+
+	return
+}
+
+func (p *exprParser) comparison() (value exprValue, err error) {
+	value, err = p.additive()
+	if err != nil {
+		return
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.accept(op) {
+			continue
+		}
+
+		right, err := p.additive()
+		if err != nil {
+			return value, err
+		}
+
+		rc, err := compareValues(value, right)
+		if err != nil {
+			return value, err
+		}
+
+		switch op {
+		case "==":
+			return boolValue(rc == 0), nil
+		case "!=":
+			return boolValue(rc != 0), nil
+		case "<=":
+			return boolValue(rc <= 0), nil
+		case ">=":
+			return boolValue(rc >= 0), nil
+		case "<":
+			return boolValue(rc < 0), nil
+		case ">":
+			return boolValue(rc > 0), nil
+		}
+	}
+
+	return
+}
+
+func compareValues(a exprValue, b exprValue) (rc int, err error) {
+	switch {
+	case a.kind != b.kind:
+		return 0, fmt.Errorf("Types of %q and %q are different", a, b)
+	case a.kind == intValue:
+		switch {
+		case a.i < b.i:
+			return -1, nil
+		case a.i > b.i:
+			return 1, nil
+		}
+		return 0, nil
+	case a.kind == versionValue:
+		return rpmlib.CompareEVR(a.s, b.s)
+	}
+
+	return strings.Compare(a.s, b.s), nil
+}
+
+func (p *exprParser) additive() (value exprValue, err error) {
+	value, err = p.multiplicative()
+	for err == nil {
+		var op string
+		if p.accept("+") {
+			op = "+"
+		} else if p.accept("-") {
+			op = "-"
+		} else {
+			return
+		}
+
+		var right exprValue
+		right, err = p.multiplicative()
+		if err != nil {
+			return
+		}
+
+		switch {
+		case value.kind == stringValue && right.kind == stringValue && op == "+":
+			value.s += right.s
+		case value.kind == intValue && right.kind == intValue && op == "+":
+			value.i += right.i
+		case value.kind == intValue && right.kind == intValue:
+			value.i -= right.i
+		default:
+			return value, fmt.Errorf("Operator %s is not supported for %q and %q", op, value, right)
+		}
+	}
+
+	return
+}
+
+func (p *exprParser) multiplicative() (value exprValue, err error) {
+	value, err = p.unary()
+	for err == nil {
+		var op string
+		if p.accept("*") {
+			op = "*"
+		} else if p.accept("/") {
+			op = "/"
+		} else {
+			return
+		}
+
+		var right exprValue
+		right, err = p.unary()
+		if err != nil {
+			return
+		}
+		if value.kind != intValue || right.kind != intValue {
+			return value, fmt.Errorf("Operator %s is not supported for %q and %q", op, value, right)
+		}
+
+		if op == "*" {
+			value.i *= right.i
+		} else if right.i == 0 {
+			return value, fmt.Errorf("Division by zero")
+		} else {
+			value.i /= right.i
+		}
+	}
+
+	return
+}
+
+func (p *exprParser) unary() (value exprValue, err error) {
+	if p.accept("!") {
+		value, err = p.unary()
+		return boolValue(!value.IsTrue()), err
+	}
+	if p.accept("-") {
+		value, err = p.unary()
+		if err == nil && value.kind != intValue {
+			err = fmt.Errorf("Operator - is not supported for %q", value)
+		}
+		value.i = -value.i
+		return
+	}
+
+	return p.primary()
+}
+
+func (p *exprParser) primary() (value exprValue, err error) {
+	p.skipSpaces()
+	if p.pos >= len(p.text) {
+		return value, fmt.Errorf("Unexpected end of expression %q", p.text)
+	}
+
+	c := p.text[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		value, err = p.ternary()
+		if err == nil && !p.accept(")") {
+			err = fmt.Errorf("Unbalanced ( in expression %q", p.text)
+		}
+		return
+	case c == '"' || (c == 'v' && p.pos+1 < len(p.text) && p.text[p.pos+1] == '"'):
+		kind := stringValue
+		if c == 'v' {
+			kind = versionValue
+			p.pos++
+		}
+		end := strings.IndexByte(p.text[p.pos+1:], '"')
+		if end < 0 {
+			return value, fmt.Errorf("Unterminated string in expression %q", p.text)
+		}
+		value = exprValue{kind: kind, s: p.text[p.pos+1 : p.pos+1+end]}
+		p.pos += end + 2
+		return
+	case isDigit(c):
+		start := p.pos
+		for p.pos < len(p.text) && isDigit(p.text[p.pos]) {
+			p.pos++
+		}
+		value.i, err = strconv.ParseInt(p.text[start:p.pos], 10, 64)
+		return
+	case isNameChar(c):
+		// Bare words are compared as strings, same as old rpm
+		start := p.pos
+		for p.pos < len(p.text) && (isNameChar(p.text[p.pos]) || p.text[p.pos] == '.') {
+			p.pos++
+		}
+		return exprValue{kind: stringValue, s: p.text[start:p.pos]}, nil
+	}
+
+	return value, fmt.Errorf("Syntax error in expression %q at %d", p.text, p.pos)
+}
@@ -0,0 +1,272 @@
+package specfile
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strconv"
+	"strings"
+)
+
+//
+// Lines of %files, such as "%attr(0755, root, root) %{_bindir}/foo" or "%config(noreplace) /etc/foo.conf"
+//
+
+// FileAttr is the mode and the owner of %attr or %defattr, where empty values are taken from files
+type FileAttr struct {
+	// -1 if not specified
+	Mode    int64
+	User    string
+	Group   string
+	DirMode int64
+}
+
+// Default attributes of files, same as %defattr(-,root,root,-) of rpm
+var DefaultFileAttr = FileAttr{Mode: -1, User: "root", Group: "root", DirMode: -1}
+
+// FileEntry is a path of %files with its directives
+type FileEntry struct {
+	// An absolute path in the package which may be a glob, or a path relative to
+	// the build directory of %doc or %license
+	Path string
+	// RPMFILE_* flags such as RPMFILE_CONFIG and RPMFILE_DOC
+	Flags int32
+	Attr  FileAttr
+	// Only the directory itself, not files in it, by %dir
+	Dir bool
+	// Removed from the package by %exclude
+	Exclude bool
+	// RPMVERIFY_* flags of %verify, RPMVERIFY_ALL if not specified
+	Verify int32
+	Lang   string
+}
+
+// Names of %verify(not ...)
+var verifyNames = map[string]int32{
+	"md5":        rpmlib.RPMVERIFY_FILEDIGEST,
+	"filedigest": rpmlib.RPMVERIFY_FILEDIGEST,
+	"size":       rpmlib.RPMVERIFY_FILESIZE,
+	"link":       rpmlib.RPMVERIFY_LINKTO,
+	"user":       rpmlib.RPMVERIFY_USER,
+	"owner":      rpmlib.RPMVERIFY_USER,
+	"group":      rpmlib.RPMVERIFY_GROUP,
+	"mtime":      rpmlib.RPMVERIFY_MTIME,
+	"mode":       rpmlib.RPMVERIFY_MODE,
+	"rdev":       rpmlib.RPMVERIFY_RDEV,
+	"caps":       rpmlib.RPMVERIFY_CAPS,
+}
+
+// fileToken is a directive such as "%attr" with arguments, or a path
+type fileToken struct {
+	directive string
+	args      string
+	path      string
+}
+
+func tokenizeFileLine(line string) (tokens []fileToken, err error) {
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '%' && i+1 < len(line) && isAlpha(line[i+1]):
+			start := i + 1
+			i++
+			for i < len(line) && isNameChar(line[i]) {
+				i++
+			}
+			token := fileToken{directive: line[start:i]}
+			if i < len(line) && line[i] == '(' {
+				end := strings.IndexByte(line[i:], ')')
+				if end < 0 {
+					return nil, fmt.Errorf("Unbalanced ( in %s", line)
+				}
+				token.args = line[i+1 : i+end]
+				i += end + 1
+			}
+			tokens = append(tokens, token)
+		case c == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("Unterminated \" in %s", line)
+			}
+			tokens = append(tokens, fileToken{path: line[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			tokens = append(tokens, fileToken{path: line[start:i]})
+		}
+	}
+
+	return
+}
+
+// parseAttr parses arguments of %attr(mode, user, group) or %defattr(mode, user, group, dirmode)
+func parseAttr(directive string, args string, base FileAttr) (attr FileAttr, err error) {
+	fields := strings.Split(args, ",")
+	if len(fields) < 3 || len(fields) > 4 || (directive == "attr" && len(fields) != 3) {
+		return attr, fmt.Errorf("Bad syntax: %%%s(%s)", directive, args)
+	}
+
+	attr = base
+	modes := []*int64{&attr.Mode, nil, nil, &attr.DirMode}
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return attr, fmt.Errorf("Bad syntax: %%%s(%s)", directive, args)
+		}
+
+		switch {
+		case i == 1 || i == 2:
+			if field == "-" {
+				field = ""
+			}
+			if i == 1 {
+				attr.User = field
+			} else {
+				attr.Group = field
+			}
+		case field == "-":
+			*modes[i] = -1
+		default:
+			mode, parse_err := strconv.ParseInt(field, 8, 32)
+			if parse_err != nil || mode > 07777 {
+				return attr, fmt.Errorf("Bad mode in %%%s(%s)", directive, args)
+			}
+			*modes[i] = mode
+		}
+	}
+
+	// The mode of %attr is also of directories
+	if directive == "attr" {
+		attr.DirMode = attr.Mode
+	}
+
+	return
+}
+
+// ParseFiles parses lines of %files into entries. %defattr applies to the following lines.
+func ParseFiles(lines []string) (entries []FileEntry, err error) {
+	defattr := DefaultFileAttr
+
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tokens, tokenize_err := tokenizeFileLine(line)
+		if tokenize_err != nil {
+			return nil, tokenize_err
+		}
+
+		entry := FileEntry{Attr: defattr, Verify: rpmlib.RPMVERIFY_ALL}
+		var paths []string
+		isDefattr, isDocdir := false, false
+
+		for _, token := range tokens {
+			if token.directive == "" {
+				paths = append(paths, token.path)
+				continue
+			}
+
+			switch token.directive {
+			case "defattr":
+				defattr, err = parseAttr(token.directive, token.args, DefaultFileAttr)
+				isDefattr = true
+			case "attr":
+				entry.Attr, err = parseAttr(token.directive, token.args, entry.Attr)
+			case "config":
+				entry.Flags |= rpmlib.RPMFILE_CONFIG
+				for _, arg := range strings.FieldsFunc(token.args, func(r rune) bool { return r == ',' || r == ' ' }) {
+					switch arg {
+					case "noreplace":
+						entry.Flags |= rpmlib.RPMFILE_NOREPLACE
+					case "missingok":
+						entry.Flags |= rpmlib.RPMFILE_MISSINGOK
+					default:
+						err = fmt.Errorf("Invalid %%config(%s)", token.args)
+					}
+				}
+			case "doc":
+				entry.Flags |= rpmlib.RPMFILE_DOC
+			case "license":
+				entry.Flags |= rpmlib.RPMFILE_LICENSE
+			case "readme":
+				entry.Flags |= rpmlib.RPMFILE_README
+			case "ghost":
+				entry.Flags |= rpmlib.RPMFILE_GHOST
+			case "artifact":
+				entry.Flags |= rpmlib.RPMFILE_ARTIFACT
+			case "missingok":
+				entry.Flags |= rpmlib.RPMFILE_MISSINGOK
+			case "dir":
+				entry.Dir = true
+			case "exclude":
+				entry.Exclude = true
+			case "lang":
+				entry.Lang = strings.TrimSpace(token.args)
+			case "verify":
+				entry.Verify, err = parseVerify(token.args)
+			case "docdir":
+				// Files in directories of documents are not marked automatically
+				isDocdir = true
+			default:
+				err = fmt.Errorf("Unknown directive %%%s in %%files", token.directive)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		if isDefattr {
+			if len(paths) > 0 {
+				return nil, fmt.Errorf("%%defattr shall be on a line without files: %s", line)
+			}
+			continue
+		}
+		if isDocdir {
+			continue
+		}
+
+		// %doc and %license may have many files in a line
+		if len(paths) > 1 && entry.Flags&(rpmlib.RPMFILE_DOC|rpmlib.RPMFILE_LICENSE) == 0 {
+			return nil, fmt.Errorf("Only one file is allowed in a line: %s", line)
+		}
+		for _, path := range paths {
+			if !strings.HasPrefix(path, "/") && entry.Flags&(rpmlib.RPMFILE_DOC|rpmlib.RPMFILE_LICENSE) == 0 {
+				return nil, fmt.Errorf("File must begin with \"/\": %s", path)
+			}
+			entry.Path = path
+			entries = append(entries, entry)
+		}
+	}
+
+	return
+}
+
+func parseVerify(args string) (verify int32, err error) {
+	fields := strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' })
+
+	negate := len(fields) > 0 && fields[0] == "not"
+	if negate {
+		fields = fields[1:]
+		verify = rpmlib.RPMVERIFY_ALL
+	}
+
+	for _, field := range fields {
+		flag, found := verifyNames[field]
+		if !found {
+			return 0, fmt.Errorf("Invalid %%verify(%s)", args)
+		}
+		if negate {
+			verify &^= flag
+		} else {
+			verify |= flag
+		}
+	}
+
+	return
+}
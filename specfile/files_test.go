@@ -0,0 +1,104 @@
+package specfile
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"reflect"
+	"testing"
+)
+
+func TestParseFiles(t *testing.T) {
+	all := int32(rpmlib.RPMVERIFY_ALL)
+	defattr := FileAttr{Mode: 0640, User: "foo", Group: "bar", DirMode: 0750}
+
+	tests := []struct {
+		name    string
+		lines   []string
+		entries []FileEntry
+	}{
+		{
+			"defattr carries over",
+			[]string{"%defattr(0640, foo, bar, 0750)", "/usr/share/foo", "", "# comment", "/usr/lib/foo",
+				"%defattr(-,root,root,-)", "/usr/bin/foo"},
+			[]FileEntry{
+				{Path: "/usr/share/foo", Attr: defattr, Verify: all},
+				{Path: "/usr/lib/foo", Attr: defattr, Verify: all},
+				{Path: "/usr/bin/foo", Attr: DefaultFileAttr, Verify: all},
+			},
+		},
+		{
+			// Values of %attr are of the line, and "-" is taken from the file instead of %defattr
+			"attr",
+			[]string{"%defattr(0640,foo,bar,0750)", "%attr(-,baz,-) /usr/bin/foo", "%attr(4755,root,root) /usr/bin/su",
+				"%attr(0600,-,-) %dir /var/lib/foo"},
+			[]FileEntry{
+				{Path: "/usr/bin/foo", Attr: FileAttr{Mode: -1, User: "baz", DirMode: -1}, Verify: all},
+				{Path: "/usr/bin/su", Attr: FileAttr{Mode: 04755, User: "root", Group: "root", DirMode: 04755}, Verify: all},
+				{Path: "/var/lib/foo", Attr: FileAttr{Mode: 0600, DirMode: 0600}, Dir: true, Verify: all},
+			},
+		},
+		{
+			"verify",
+			[]string{"%verify(not md5 size mtime) /var/lib/foo/db", "%verify(mode, owner) /var/lib/foo/state",
+				"%config %verify(not md5) /etc/foo.conf"},
+			[]FileEntry{
+				{Path: "/var/lib/foo/db", Attr: DefaultFileAttr,
+					Verify: all &^ (rpmlib.RPMVERIFY_FILEDIGEST | rpmlib.RPMVERIFY_FILESIZE | rpmlib.RPMVERIFY_MTIME)},
+				{Path: "/var/lib/foo/state", Attr: DefaultFileAttr, Verify: rpmlib.RPMVERIFY_MODE | rpmlib.RPMVERIFY_USER},
+				{Path: "/etc/foo.conf", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_CONFIG,
+					Verify: all &^ rpmlib.RPMVERIFY_FILEDIGEST},
+			},
+		},
+		{
+			"directives",
+			[]string{"%config(noreplace) /etc/foo.conf", "%config(missingok, noreplace) /etc/foo.d/local.conf",
+				"%ghost /var/log/foo.log", "%doc README NEWS", "%license LICENSE", "%lang(de) /usr/share/locale/de/foo.mo",
+				"%exclude /usr/lib/*.la", "%docdir /usr/share/foo/doc", `"/usr/share/foo/with space"`},
+			[]FileEntry{
+				{Path: "/etc/foo.conf", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_NOREPLACE, Verify: all},
+				{Path: "/etc/foo.d/local.conf", Attr: DefaultFileAttr,
+					Flags: rpmlib.RPMFILE_CONFIG | rpmlib.RPMFILE_MISSINGOK | rpmlib.RPMFILE_NOREPLACE, Verify: all},
+				{Path: "/var/log/foo.log", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_GHOST, Verify: all},
+				{Path: "README", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_DOC, Verify: all},
+				{Path: "NEWS", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_DOC, Verify: all},
+				{Path: "LICENSE", Attr: DefaultFileAttr, Flags: rpmlib.RPMFILE_LICENSE, Verify: all},
+				{Path: "/usr/share/locale/de/foo.mo", Attr: DefaultFileAttr, Lang: "de", Verify: all},
+				{Path: "/usr/lib/*.la", Attr: DefaultFileAttr, Exclude: true, Verify: all},
+				{Path: "/usr/share/foo/with space", Attr: DefaultFileAttr, Verify: all},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		entries, err := ParseFiles(test.lines)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, test.entries) {
+			t.Errorf("%s: ParseFiles() =\n%+v\nwant\n%+v", test.name, entries, test.entries)
+		}
+	}
+}
+
+func TestParseFilesErrors(t *testing.T) {
+	for _, line := range []string{
+		"%attr(0755,root) /usr/bin/foo",
+		"%attr(0755,root,root,0755) /usr/bin/foo",
+		"%attr(,root,root) /usr/bin/foo",
+		"%attr(0999,root,root) /usr/bin/foo",
+		"%attr(17777,root,root) /usr/bin/foo",
+		"%attr(0755,root,root /usr/bin/foo",
+		"%defattr(-,root,root,-) /usr/bin/foo",
+		"%defattr(-,root,root,-,-)",
+		"%config(bogus) /etc/foo.conf",
+		"%verify(not bogus) /etc/foo.conf",
+		"%unknown /usr/bin/foo",
+		"/usr/bin/foo /usr/bin/bar",
+		"usr/bin/foo",
+		`"/usr/bin/foo`,
+	} {
+		if entries, err := ParseFiles([]string{line}); err == nil {
+			t.Errorf("ParseFiles(%q) = %+v, want an error", line, entries)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package specfile
+
+import (
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Changelog is an entry of %changelog
+type Changelog struct {
+	Time   time.Time
+	Author string
+	Text   string
+}
+
+// Changelogs parses %changelog, where entries begin with lines such as
+// "* Mon Jan 02 2006 Name <mail> - 1.0-1". They are in order of the spec, the latest first.
+func (spec *Spec) Changelogs() (logs []Changelog, err error) {
+	var current *Changelog
+	var lines []string
+
+	flush := func() {
+		if current != nil {
+			current.Text = strings.Join(trimBlankLines(lines), "\n")
+			logs = append(logs, *current)
+		}
+		lines = nil
+	}
+
+	for _, line := range strings.Split(spec.Changelog, "\n") {
+		if !strings.HasPrefix(line, "*") {
+			if current == nil && strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("%%changelog entries must start with *")
+			}
+			lines = append(lines, line)
+			continue
+		}
+		flush()
+
+		fields := strings.Fields(strings.TrimPrefix(line, "*"))
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("Bad date in %%changelog: %s", line)
+		}
+		date, parse_err := time.Parse("Mon Jan 2 2006", strings.Join(fields[:4], " "))
+		if parse_err != nil {
+			return nil, fmt.Errorf("Bad date in %%changelog: %s", line)
+		}
+		current = &Changelog{Time: date, Author: strings.Join(fields[4:], " ")}
+	}
+	flush()
+
+	return
+}
+
+func addDependencies(b *rpmlib.SectionBuilder, nameTag, flagsTag, versionTag int32, deps []rpmlib.Dependency) {
+	if len(deps) == 0 {
+		return
+	}
+
+	var names, versions []string
+	var flags []int32
+	for _, dep := range deps {
+		names = append(names, dep.Name)
+		flags = append(flags, dep.Flags)
+		versions = append(versions, dep.Version)
+	}
+
+	b.AddStringArray(nameTag, names)
+	b.AddInt32(flagsTag, flags...)
+	b.AddStringArray(versionTag, versions)
+}
+
+// Tags of scripts and of their interpreters
+var scriptTags = map[string][2]int32{
+	"pre":          {rpmlib.RPMTAG_PREIN, rpmlib.RPMTAG_PREINPROG},
+	"post":         {rpmlib.RPMTAG_POSTIN, rpmlib.RPMTAG_POSTINPROG},
+	"preun":        {rpmlib.RPMTAG_PREUN, rpmlib.RPMTAG_PREUNPROG},
+	"postun":       {rpmlib.RPMTAG_POSTUN, rpmlib.RPMTAG_POSTUNPROG},
+	"pretrans":     {rpmlib.RPMTAG_PRETRANS, rpmlib.RPMTAG_PRETRANSPROG},
+	"posttrans":    {rpmlib.RPMTAG_POSTTRANS, rpmlib.RPMTAG_POSTTRANSPROG},
+	"verifyscript": {rpmlib.RPMTAG_VERIFYSCRIPT, rpmlib.RPMTAG_VERIFYSCRIPTPROG},
+}
+
+// addCommon adds tags of both binary and source packages
+func (spec *Spec) addCommon(b *rpmlib.SectionBuilder, pkg *Package, arch string) {
+	b.AddStringArray(rpmlib.RPMTAG_HEADERI18NTABLE, []string{"C"})
+	b.AddString(rpmlib.RPMTAG_NAME, pkg.Name)
+	b.AddString(rpmlib.RPMTAG_VERSION, pkg.Version)
+	b.AddString(rpmlib.RPMTAG_RELEASE, pkg.Release)
+	if epoch, err := strconv.Atoi(pkg.Epoch); err == nil {
+		b.AddInt32(rpmlib.RPMTAG_EPOCH, int32(epoch))
+	}
+	b.AddString(rpmlib.RPMTAG_ARCH, arch)
+	b.AddString(rpmlib.RPMTAG_OS, spec.macro("_target_os"))
+
+	b.AddI18nString(rpmlib.RPMTAG_SUMMARY, pkg.Summary)
+	b.AddI18nString(rpmlib.RPMTAG_DESCRIPTION, pkg.Description)
+	b.AddI18nString(rpmlib.RPMTAG_GROUP, pkg.Group)
+	b.AddString(rpmlib.RPMTAG_LICENSE, pkg.License)
+	for tag, value := range map[int32]string{
+		rpmlib.RPMTAG_URL:          pkg.URL,
+		rpmlib.RPMTAG_VENDOR:       pkg.Tags["vendor"],
+		rpmlib.RPMTAG_PACKAGER:     pkg.Tags["packager"],
+		rpmlib.RPMTAG_DISTRIBUTION: pkg.Tags["distribution"],
+		rpmlib.RPMTAG_BUGURL:       pkg.Tags["bugurl"],
+	} {
+		if value != "" {
+			b.AddString(tag, value)
+		}
+	}
+
+	if len(spec.ExcludeArch) > 0 {
+		b.AddStringArray(rpmlib.RPMTAG_EXCLUDEARCH, spec.ExcludeArch)
+	}
+	if len(spec.ExclusiveArch) > 0 {
+		b.AddStringArray(rpmlib.RPMTAG_EXCLUSIVEARCH, spec.ExclusiveArch)
+	}
+
+	// Changelogs are stored from the latest one, same as the spec
+	if logs, err := spec.Changelogs(); err == nil && len(logs) > 0 {
+		var names, texts []string
+		var times []int32
+		for _, log := range logs {
+			names = append(names, log.Author)
+			texts = append(texts, log.Text)
+			times = append(times, int32(log.Time.Unix()))
+		}
+		b.AddInt32(rpmlib.RPMTAG_CHANGELOGTIME, times...)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGNAME, names)
+		b.AddStringArray(rpmlib.RPMTAG_CHANGELOGTEXT, texts)
+	}
+}
+
+func (spec *Spec) macro(name string) string {
+	value, _ := spec.Macros.Expand("%{?" + name + "}")
+
+	return value
+}
+
+// HeaderBuilder returns a builder of the header of the binary package, with tags of the spec.
+// Files are not added, since they are known only after the package is built.
+func (spec *Spec) HeaderBuilder(pkg *Package) (b *rpmlib.SectionBuilder) {
+	b = rpmlib.NewSectionBuilder()
+	spec.addCommon(b, pkg, spec.Arch(pkg))
+
+	b.AddString(rpmlib.RPMTAG_SOURCERPM, spec.SourceNEVRA().Filename())
+
+	addDependencies(b, rpmlib.RPMTAG_PROVIDENAME, rpmlib.RPMTAG_PROVIDEFLAGS, rpmlib.RPMTAG_PROVIDEVERSION, pkg.Provides)
+	addDependencies(b, rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION, pkg.Requires)
+	addDependencies(b, rpmlib.RPMTAG_CONFLICTNAME, rpmlib.RPMTAG_CONFLICTFLAGS, rpmlib.RPMTAG_CONFLICTVERSION, pkg.Conflicts)
+	addDependencies(b, rpmlib.RPMTAG_OBSOLETENAME, rpmlib.RPMTAG_OBSOLETEFLAGS, rpmlib.RPMTAG_OBSOLETEVERSION, pkg.Obsoletes)
+	addDependencies(b, rpmlib.RPMTAG_RECOMMENDNAME, rpmlib.RPMTAG_RECOMMENDFLAGS, rpmlib.RPMTAG_RECOMMENDVERSION, pkg.Recommends)
+	addDependencies(b, rpmlib.RPMTAG_SUGGESTNAME, rpmlib.RPMTAG_SUGGESTFLAGS, rpmlib.RPMTAG_SUGGESTVERSION, pkg.Suggests)
+	addDependencies(b, rpmlib.RPMTAG_SUPPLEMENTNAME, rpmlib.RPMTAG_SUPPLEMENTFLAGS, rpmlib.RPMTAG_SUPPLEMENTVERSION, pkg.Supplements)
+	addDependencies(b, rpmlib.RPMTAG_ENHANCENAME, rpmlib.RPMTAG_ENHANCEFLAGS, rpmlib.RPMTAG_ENHANCEVERSION, pkg.Enhances)
+
+	for name, script := range pkg.Scripts {
+		tags, found := scriptTags[name]
+		if !found {
+			continue
+		}
+		interpreter := script.Interpreter
+		if interpreter == "" {
+			interpreter = "/bin/sh"
+		}
+		if script.Body != "" {
+			b.AddString(tags[0], script.Body)
+		}
+		b.AddStringArray(tags[1], strings.Fields(interpreter))
+	}
+
+	return
+}
+
+// Header returns the header of the binary package without files, see HeaderBuilder
+func (spec *Spec) Header(pkg *Package) *rpmlib.Header {
+	return spec.HeaderBuilder(pkg).Header()
+}
+
+// SourceHeader returns the header of the source package without files.
+// Build dependencies are stored as requires, same as rpmbuild.
+func (spec *Spec) SourceHeader() *rpmlib.Header {
+	b := rpmlib.NewSectionBuilder()
+	spec.addCommon(b, spec.Packages[0], spec.SourceNEVRA().Arch)
+
+	addDependencies(b, rpmlib.RPMTAG_REQUIRENAME, rpmlib.RPMTAG_REQUIREFLAGS, rpmlib.RPMTAG_REQUIREVERSION, spec.BuildRequires)
+	addDependencies(b, rpmlib.RPMTAG_CONFLICTNAME, rpmlib.RPMTAG_CONFLICTFLAGS, rpmlib.RPMTAG_CONFLICTVERSION, spec.BuildConflicts)
+
+	for _, list := range []struct {
+		sources       []Source
+		tag, noSrcTag int32
+	}{
+		{spec.Sources, rpmlib.RPMTAG_SOURCE, rpmlib.RPMTAG_NOSOURCE},
+		{spec.Patches, rpmlib.RPMTAG_PATCH, rpmlib.RPMTAG_NOPATCH},
+	} {
+		var names []string
+		var nosource []int32
+		for _, s := range list.sources {
+			names = append(names, s.Filename())
+			if s.NoSource {
+				nosource = append(nosource, int32(s.Number))
+			}
+		}
+		if len(names) > 0 {
+			b.AddStringArray(list.tag, names)
+		}
+		if len(nosource) > 0 {
+			b.AddInt32(list.noSrcTag, nosource...)
+		}
+	}
+
+	if arch := spec.Packages[0].BuildArch; arch != "" {
+		b.AddStringArray(rpmlib.RPMTAG_BUILDARCHS, strings.Fields(arch))
+	}
+
+	return b.Header()
+}
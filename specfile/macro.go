@@ -0,0 +1,596 @@
+package specfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Maximum depth of nested macro expansion, same as rpm
+const maxExpansionDepth = 64
+
+// Macro is a definition of a macro. A parametric macro has options
+// such as "ab:", and it is called with arguments as "%name -a x y".
+type Macro struct {
+	Name       string
+	Body       string
+	Opts       string
+	Parametric bool
+}
+
+// Macros is a table of macros, where definitions of the same name are stacked
+// and %undefine pops the last one, same as rpm.
+// Shell expansion %(...) is not executed and %{lua:...} is not supported,
+// both of them are left as they are.
+type Macros struct {
+	table map[string][]*Macro
+}
+
+func NewMacros() (macros *Macros) {
+	macros = &Macros{table: make(map[string][]*Macro)}
+	macros.Define("nil", "")
+
+	return
+}
+
+// Define defines a macro, same as %define. The body is expanded when the macro is used.
+func (macros *Macros) Define(name string, body string) {
+	macros.table[name] = append(macros.table[name], &Macro{Name: name, Body: body})
+}
+
+// DefineParametric defines a parametric macro, same as %define name(opts) body
+func (macros *Macros) DefineParametric(name string, opts string, body string) {
+	macros.table[name] = append(macros.table[name], &Macro{Name: name, Body: body, Opts: opts, Parametric: true})
+}
+
+// Undefine removes the last definition of the macro
+func (macros *Macros) Undefine(name string) {
+	stack := macros.table[name]
+	if len(stack) <= 1 {
+		delete(macros.table, name)
+		return
+	}
+
+	macros.table[name] = stack[:len(stack)-1]
+}
+
+// Get returns the last definition of the macro
+func (macros *Macros) Get(name string) (macro *Macro, found bool) {
+	stack := macros.table[name]
+	if len(stack) == 0 {
+		return nil, false
+	}
+
+	return stack[len(stack)-1], true
+}
+
+func (macros *Macros) IsDefined(name string) bool {
+	_, found := macros.Get(name)
+
+	return found
+}
+
+// Names returns names of all defined macros in order
+func (macros *Macros) Names() (names []string) {
+	for name := range macros.table {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return
+}
+
+// DefineLine defines a macro by "name body" or "name(opts) body",
+// the argument of %define or of rpm --define
+func (macros *Macros) DefineLine(line string) (err error) {
+	name, opts, body, parametric, err := splitDefinition(line)
+	if err != nil {
+		return
+	}
+
+	if parametric {
+		macros.DefineParametric(name, opts, body)
+	} else {
+		macros.Define(name, body)
+	}
+
+	return
+}
+
+// GlobalLine defines a macro same as DefineLine, except the body is expanded now, same as %global
+func (macros *Macros) GlobalLine(line string) (err error) {
+	name, opts, body, parametric, err := splitDefinition(line)
+	if err != nil {
+		return
+	}
+
+	if parametric {
+		macros.DefineParametric(name, opts, body)
+		return
+	}
+
+	body, err = macros.Expand(body)
+	if err != nil {
+		return
+	}
+	macros.Define(name, body)
+
+	return
+}
+
+func splitDefinition(line string) (name string, opts string, body string, parametric bool, err error) {
+	line = strings.TrimLeft(line, " \t")
+
+	end := 0
+	for end < len(line) && isNameChar(line[end]) {
+		end++
+	}
+	name = line[:end]
+	if len(name) < 2 && !(len(name) == 1 && isAlpha(name[0])) {
+		return "", "", "", false, fmt.Errorf("Macro name %q is invalid", line)
+	}
+
+	rest := line[end:]
+	if strings.HasPrefix(rest, "(") {
+		closing := strings.IndexByte(rest, ')')
+		if closing < 0 {
+			return "", "", "", false, fmt.Errorf("Options of macro %s are not closed", name)
+		}
+		opts = rest[1:closing]
+		rest = rest[closing+1:]
+		parametric = true
+	}
+
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", "", "", false, fmt.Errorf("Macro %s has no body", name)
+	}
+	body = strings.TrimSpace(rest)
+
+	// Continued lines of a body are joined with newlines
+	body = strings.Replace(body, "\\\n", "\n", -1)
+
+	return
+}
+
+// Load reads definitions of a macros file, lines of "%name body".
+// Bodies are continued to the next line by a backslash at the end of line.
+func (macros *Macros) Load(r io.Reader) (err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var definition string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if definition != "" {
+			definition += "\n" + line
+		} else {
+			trimmed := strings.TrimSpace(line)
+			if !strings.HasPrefix(trimmed, "%") {
+				continue
+			}
+			definition = trimmed[1:]
+		}
+
+		if strings.HasSuffix(definition, "\\") {
+			definition = strings.TrimSuffix(definition, "\\")
+			continue
+		}
+
+		err = macros.DefineLine(definition)
+		if err != nil {
+			return
+		}
+		definition = ""
+	}
+
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if definition != "" {
+		err = macros.DefineLine(definition)
+	}
+
+	return
+}
+
+// LoadFile reads definitions of a macros file, such as /usr/lib/rpm/macros
+func (macros *Macros) LoadFile(filename string) (err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	err = macros.Load(file)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isNameChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '_'
+}
+
+// Expand expands macros in the text
+func (macros *Macros) Expand(text string) (expanded string, err error) {
+	e := &expander{macros: macros}
+	expanded = e.expand(text)
+
+	return expanded, e.err
+}
+
+type expander struct {
+	macros *Macros
+	depth  int
+	err    error
+}
+
+func (e *expander) fail(format string, args ...interface{}) {
+	if e.err == nil {
+		e.err = fmt.Errorf(format, args...)
+	}
+}
+
+// matching returns the position of the closing character of the opening one at start
+func matching(text string, start int, open byte, close byte) int {
+	level := 0
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case open:
+			level++
+		case close:
+			level--
+			if level == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+func (e *expander) expand(text string) string {
+	if e.depth > maxExpansionDepth {
+		e.fail("Too many levels of recursion in macro expansion")
+		return ""
+	}
+	e.depth++
+	defer func() { e.depth-- }()
+
+	var out strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c != '%' || i+1 >= len(text) {
+			out.WriteByte(c)
+			continue
+		}
+
+		next := text[i+1]
+		switch {
+		case next == '%':
+			out.WriteByte('%')
+			i++
+		case next == '{':
+			end := matching(text, i+1, '{', '}')
+			if end < 0 {
+				e.fail("Unterminated { in %s", text[i:])
+				out.WriteString(text[i:])
+				return out.String()
+			}
+			out.WriteString(e.braced(text[i:end+1], text[i+2:end]))
+			i = end
+		case next == '(':
+			// Shell expansion is not executed
+			end := matching(text, i+1, '(', ')')
+			if end < 0 {
+				end = len(text) - 1
+			}
+			out.WriteString(text[i : end+1])
+			i = end
+		case next == '[':
+			end := matching(text, i+1, '[', ']')
+			if end < 0 {
+				e.fail("Unterminated [ in %s", text[i:])
+				out.WriteString(text[i:])
+				return out.String()
+			}
+			value, err := evalExpression(e.macros, e.expand(text[i+2:end]))
+			if err != nil {
+				e.fail("%s", err)
+			}
+			out.WriteString(value.String())
+			i = end
+		default:
+			consumed, expansion := e.bare(text, i)
+			out.WriteString(expansion)
+			i += consumed - 1
+		}
+	}
+
+	return out.String()
+}
+
+// bare expands a macro without braces at text[start], such as "%name", "%?name", "%1" or "%*".
+// A parametric macro takes the rest of line as arguments.
+func (e *expander) bare(text string, start int) (consumed int, expansion string) {
+	i := start + 1
+	negate, conditional := false, false
+	for i < len(text) && (text[i] == '!' || text[i] == '?') {
+		if text[i] == '!' {
+			negate = !negate
+		} else {
+			conditional = true
+		}
+		i++
+	}
+
+	nameStart := i
+	switch {
+	case i < len(text) && text[i] == '*':
+		i++
+		if i < len(text) && text[i] == '*' {
+			i++
+		}
+	case i < len(text) && text[i] == '#':
+		i++
+	case i < len(text) && text[i] == '-' && i+1 < len(text) && isAlpha(text[i+1]):
+		i += 2
+		if i < len(text) && text[i] == '*' {
+			i++
+		}
+	case i < len(text) && isDigit(text[i]):
+		for i < len(text) && isDigit(text[i]) {
+			i++
+		}
+	default:
+		for i < len(text) && isNameChar(text[i]) {
+			i++
+		}
+	}
+	name := text[nameStart:i]
+
+	if name == "" {
+		return i - start, text[start:i]
+	}
+
+	// Definitions take the rest of line, such as "%{!?foo: %global foo 1}"
+	if !conditional && !negate && (name == "define" || name == "global" || name == "undefine") {
+		end := strings.IndexByte(text[i:], '\n')
+		if end < 0 {
+			end = len(text) - i
+		}
+		e.define(name, text[i:i+end])
+		return i + end - start, ""
+	}
+
+	macro, found := e.macros.Get(name)
+	if conditional {
+		// %!?name expands to nothing in either case
+		if found && !negate {
+			return i - start, e.call(macro, "")
+		}
+		return i - start, ""
+	}
+	if !found {
+		return i - start, text[start:i]
+	}
+
+	if macro.Parametric {
+		end := strings.IndexByte(text[i:], '\n')
+		if end < 0 {
+			end = len(text) - i
+		}
+		args := e.expand(text[i : i+end])
+		return i + end - start, e.call(macro, args)
+	}
+
+	return i - start, e.call(macro, "")
+}
+
+// braced expands "%{...}" of which content is inside
+func (e *expander) braced(whole string, inside string) string {
+	negate, conditional := false, false
+	i := 0
+	for i < len(inside) && (inside[i] == '!' || inside[i] == '?') {
+		if inside[i] == '!' {
+			negate = !negate
+		} else {
+			conditional = true
+		}
+		i++
+	}
+
+	// Arguments of a parametric macro follow a space, such as %{with foo}
+	name, arg, hasArg, args := inside[i:], "", false, ""
+	if sep := strings.IndexAny(name, ": \t"); sep >= 0 && name[sep] == ':' {
+		name, arg, hasArg = name[:sep], name[sep+1:], true
+	} else if sep >= 0 {
+		name, arg = name[:sep], strings.TrimSpace(name[sep+1:])
+	}
+
+	// Options of parametric macros are always conditional, such as %{-f:yes} and %{!-f:no}
+	if strings.HasPrefix(name, "-") {
+		conditional = true
+	}
+
+	// Builtins also take the argument after a space, such as %{defined foo}
+	if !conditional && !negate {
+		if expansion, builtin := e.builtin(name, arg, hasArg || arg != "", whole); builtin {
+			return expansion
+		}
+	}
+
+	if !hasArg {
+		args = e.expand(arg)
+	}
+	macro, found := e.macros.Get(name)
+
+	if conditional {
+		if found == negate {
+			return ""
+		}
+		if hasArg {
+			return e.expand(arg)
+		}
+		if found {
+			return e.call(macro, args)
+		}
+		return ""
+	}
+
+	if !found {
+		return whole
+	}
+
+	return e.call(macro, args)
+}
+
+func (e *expander) builtin(name string, arg string, hasArg bool, whole string) (expansion string, builtin bool) {
+	switch name {
+	case "lua":
+		// Lua is not supported
+		return whole, true
+	case "expand":
+		return e.expand(e.expand(arg)), true
+	case "defined", "undefined":
+		defined := e.macros.IsDefined(strings.TrimSpace(e.expand(arg)))
+		if defined == (name == "defined") {
+			return "1", true
+		}
+		return "0", true
+	case "basename":
+		return path.Base(e.expand(arg)), true
+	case "dirname":
+		return path.Dir(e.expand(arg)), true
+	case "suffix":
+		ext := path.Ext(e.expand(arg))
+		return strings.TrimPrefix(ext, "."), true
+	case "lower":
+		return strings.ToLower(e.expand(arg)), true
+	case "upper":
+		return strings.ToUpper(e.expand(arg)), true
+	case "len":
+		return fmt.Sprint(len(e.expand(arg))), true
+	case "quote":
+		return e.expand(arg), true
+	case "shrink":
+		return strings.Join(strings.Fields(e.expand(arg)), " "), true
+	case "getenv":
+		return os.Getenv(e.expand(arg)), true
+	case "S", "P":
+		// %{S:1} is %{SOURCE1} and %{P:1} is %{PATCH1}
+		prefix := "SOURCE"
+		if name == "P" {
+			prefix = "PATCH"
+		}
+		return e.braced(whole, prefix+strings.TrimSpace(e.expand(arg))), true
+	case "define", "global", "undefine":
+		if !hasArg {
+			return "", false
+		}
+		e.define(name, arg)
+		return "", true
+	case "echo", "warn", "error", "dnl", "trace", "dump", "load", "uncompress":
+		if !hasArg {
+			return "", false
+		}
+		return "", true
+	}
+
+	return "", false
+}
+
+// define processes %define, %global or %undefine in the middle of expansion
+func (e *expander) define(keyword string, args string) {
+	var err error
+	switch keyword {
+	case "define":
+		err = e.macros.DefineLine(args)
+	case "global":
+		err = e.macros.GlobalLine(args)
+	case "undefine":
+		e.macros.Undefine(strings.TrimSpace(args))
+	}
+	if err != nil {
+		e.fail("%s", err)
+	}
+}
+
+// call expands the body of the macro. Arguments of a parametric macro are
+// defined as %1, %2, %*, %** and %#, and options as %{-a} and %{-a*}.
+func (e *expander) call(macro *Macro, args string) string {
+	if !macro.Parametric {
+		return e.expand(macro.Body)
+	}
+
+	defined := []string{"0", "*", "**", "#"}
+	e.macros.Define("0", macro.Name)
+	e.macros.Define("**", args)
+
+	var positional []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if len(field) < 2 || field[0] != '-' || positional != nil {
+			positional = append(positional, field)
+			continue
+		}
+		if field == "--" {
+			positional = append(positional, fields[i+1:]...)
+			break
+		}
+
+		option := field[1:2]
+		position := strings.Index(macro.Opts, option)
+		if position < 0 {
+			e.fail("Unknown option %s of macro %s", field, macro.Name)
+			continue
+		}
+
+		if position+1 < len(macro.Opts) && macro.Opts[position+1] == ':' {
+			value := field[2:]
+			if value == "" && i+1 < len(fields) {
+				i++
+				value = fields[i]
+			}
+			e.macros.Define("-"+option, "-"+option+" "+value)
+			e.macros.Define("-"+option+"*", value)
+			defined = append(defined, "-"+option, "-"+option+"*")
+		} else {
+			e.macros.Define("-"+option, "-"+option)
+			defined = append(defined, "-"+option)
+		}
+	}
+
+	e.macros.Define("*", strings.Join(positional, " "))
+	e.macros.Define("#", fmt.Sprint(len(positional)))
+	for i, arg := range positional {
+		name := fmt.Sprint(i + 1)
+		e.macros.Define(name, arg)
+		defined = append(defined, name)
+	}
+
+	expansion := e.expand(macro.Body)
+
+	for _, name := range defined {
+		e.macros.Undefine(name)
+	}
+
+	return expansion
+}
@@ -0,0 +1,127 @@
+package specfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func testMacros() *Macros {
+	macros := NewMacros()
+	macros.Define("name", "foo")
+	macros.Define("version", "1.2")
+	macros.Define("empty", "")
+	macros.Define("nested", "%{name}-%{version}")
+	macros.DefineParametric("args", "ab:", "[%0|%#|%*|%1|%{-a}|%{-b*}|%{?-a:yes}%{!-a:no}]")
+
+	return macros
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		text     string
+		expanded string
+	}{
+		{"%name", "foo"},
+		{"%{name}", "foo"},
+		{"%{nested}.tar.gz", "foo-1.2.tar.gz"},
+		{"100%%", "100%"},
+		{"%unknown %{unknown}", "%unknown %{unknown}"},
+		{"%{?name}|%{?undefined}|%{!?undefined}", "foo||"},
+		{"%{?name:yes}|%{!?name:yes}|%{!?undefined:no}", "yes||no"},
+		{"%?name|%?undefined", "foo|"},
+		{"%{defined name} %{undefined name}", "1 0"},
+		{"%{basename:/a/b.c} %{dirname:/a/b.c} %{suffix:b.tar.gz}", "b.c /a gz"},
+		{"%{upper:%name} %{lower:ABC} %{len:%name}", "FOO abc 3"},
+		{"%{shrink:  a   b }", "a b"},
+		{"%[1 + 2 * 3] %[%{len:%name} == 3] %[\"a\" < \"b\"]", "7 1 1"},
+		{"%(echo not run)", "%(echo not run)"},
+		{"%{lua: print(1)}", "%{lua: print(1)}"},
+		{"%args x y", "[args|2|x y|x|||no]"},
+		{"%{args -a -b 3 x}", "[args|1|x|x|-a|3|yes]"},
+		// Undefined arguments are kept, same as rpm
+		{"%{args -b3}", "[args|0||%1||3|no]"},
+		{"%{define local 1}%{local}", "1"},
+	}
+
+	for _, test := range tests {
+		expanded, err := testMacros().Expand(test.text)
+		if err != nil {
+			t.Errorf("Expand(%q): %s", test.text, err)
+			continue
+		}
+		if expanded != test.expanded {
+			t.Errorf("Expand(%q) = %q, want %q", test.text, expanded, test.expanded)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	macros := testMacros()
+	macros.Define("loop", "%{loop}")
+
+	tests := []string{
+		"%{name",
+		"%[1 +",
+		"%{loop}",
+		"%{args -c}",
+	}
+
+	for _, text := range tests {
+		if _, err := macros.Expand(text); err == nil {
+			t.Errorf("Expand(%q) succeeded", text)
+		}
+	}
+}
+
+func TestArgumentsAreLocal(t *testing.T) {
+	macros := testMacros()
+	if _, err := macros.Expand("%{args -a x}"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"1", "*", "#", "-a"} {
+		if macros.IsDefined(name) {
+			t.Errorf("%%%s is defined after the call", name)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	macros := NewMacros()
+	err := macros.Load(strings.NewReader("# comment\n%foo\tbar \\\n  baz\n%with(a:)\t%{-a*}\n\n%empty\t%{nil}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	macro, found := macros.Get("foo")
+	if !found || !strings.HasPrefix(macro.Body, "bar") || !strings.HasSuffix(macro.Body, "baz") {
+		t.Errorf("foo = %+v", macro)
+	}
+	macro, found = macros.Get("with")
+	if !found || !macro.Parametric || macro.Opts != "a:" {
+		t.Errorf("with = %+v", macro)
+	}
+	if expanded, err := macros.Expand("%{with -a 1}"); err != nil || expanded != "1" {
+		t.Errorf("%%{with -a 1} = %q, %v", expanded, err)
+	}
+}
+
+func TestDefaultMacros(t *testing.T) {
+	macros := DefaultMacros("x86_64")
+
+	tests := []struct {
+		text     string
+		expanded string
+	}{
+		{"%{_bindir}", "/usr/bin"},
+		{"%{_libdir}", "/usr/lib64"},
+		{"%{_arch}", "x86_64"},
+	}
+
+	for _, test := range tests {
+		expanded, err := macros.Expand(test.text)
+		if err != nil || expanded != test.expanded {
+			t.Errorf("Expand(%q) = %q, %v, want %q", test.text, expanded, err, test.expanded)
+		}
+	}
+}
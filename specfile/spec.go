@@ -0,0 +1,879 @@
+package specfile
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Source is a source or a patch of the spec, such as "Source1: foo.tar.gz"
+type Source struct {
+	Number   int
+	Location string
+	// Not included in the source package, by NoSource: or NoPatch:
+	NoSource bool
+}
+
+// Filename is the file name of the source, the last element of an URL
+func (source Source) Filename() string {
+	return path.Base(source.Location)
+}
+
+// Script is a scriptlet of a package, such as %post
+type Script struct {
+	Interpreter string
+	Body        string
+	// Dependencies of a trigger, after "--"
+	Triggers string
+}
+
+// Package is the main package or a subpackage of the spec
+type Package struct {
+	Name      string
+	Version   string
+	Release   string
+	Epoch     string
+	Summary   string
+	License   string
+	Group     string
+	URL       string
+	BuildArch string
+	// All tags of the preamble by lower case names, such as "vendor"
+	Tags        map[string]string
+	Description string
+
+	Requires    []rpmlib.Dependency
+	Provides    []rpmlib.Dependency
+	Conflicts   []rpmlib.Dependency
+	Obsoletes   []rpmlib.Dependency
+	Recommends  []rpmlib.Dependency
+	Suggests    []rpmlib.Dependency
+	Supplements []rpmlib.Dependency
+	Enhances    []rpmlib.Dependency
+
+	// Lines of %files, and files given by %files -f
+	Files     []string
+	FileLists []string
+	// Only packages with %files are built, same as rpmbuild
+	HasFiles bool
+
+	Scripts map[string]*Script
+}
+
+// Spec is a parsed spec file, where macros are already expanded except %changelog
+type Spec struct {
+	Macros *Macros
+	// The main package is the first one
+	Packages       []*Package
+	Sources        []Source
+	Patches        []Source
+	BuildRequires  []rpmlib.Dependency
+	BuildConflicts []rpmlib.Dependency
+	ExcludeArch    []string
+	ExclusiveArch  []string
+	// Build scripts such as "prep", "build" and "install"
+	Sections  map[string]string
+	Changelog string
+	// Lines of the spec after conditionals and macros are processed, same as rpmspec -P
+	Expanded []string
+}
+
+// Sections of scripts to build
+var buildSections = map[string]bool{
+	"prep": true, "conf": true, "build": true, "install": true, "check": true, "clean": true,
+	"generate_buildrequires": true,
+}
+
+// Scriptlets of packages
+var scriptSections = map[string]bool{
+	"pre": true, "post": true, "preun": true, "postun": true,
+	"pretrans": true, "posttrans": true, "preuntrans": true, "postuntrans": true, "verifyscript": true,
+	"triggerprein": true, "triggerin": true, "triggerun": true, "triggerpostun": true,
+	"filetriggerin": true, "filetriggerun": true, "filetriggerpostun": true,
+	"transfiletriggerin": true, "transfiletriggerun": true, "transfiletriggerpostun": true,
+}
+
+var otherSections = map[string]bool{
+	"package": true, "description": true, "files": true, "changelog": true,
+	"sourcelist": true, "patchlist": true,
+}
+
+// Flags of Requires(qualifier)
+var requireQualifiers = map[string]int32{
+	"pre":       rpmlib.RPMSENSE_SCRIPT_PRE,
+	"post":      rpmlib.RPMSENSE_SCRIPT_POST,
+	"preun":     rpmlib.RPMSENSE_SCRIPT_PREUN,
+	"postun":    rpmlib.RPMSENSE_SCRIPT_POSTUN,
+	"pretrans":  rpmlib.RPMSENSE_PRETRANS,
+	"posttrans": rpmlib.RPMSENSE_POSTTRANS,
+	"verify":    rpmlib.RPMSENSE_SCRIPT_VERIFY,
+	"interp":    rpmlib.RPMSENSE_INTERP,
+	"meta":      rpmlib.RPMSENSE_META,
+}
+
+// Tags which are defined as macros, such as %{summary}. License is not, as %license is a directive of %files.
+var macroTags = map[string]bool{
+	"name": true, "version": true, "release": true, "epoch": true, "summary": true,
+	"group": true, "url": true, "vendor": true, "packager": true, "distribution": true, "bugurl": true,
+}
+
+// Tags which subpackages inherit from the main package
+var inheritedTags = []string{"version", "release", "epoch", "license", "url", "vendor", "packager", "distribution", "bugurl", "group"}
+
+var tagPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*)\s*(?:\(([^)]*)\))?\s*:\s*(.*)$`)
+var sourcePattern = regexp.MustCompile(`^(source|patch)([0-9]*)$`)
+
+// Section being parsed
+type section struct {
+	name string
+	pkg  *Package
+	// Lines of a script or description
+	lines  []string
+	script *Script
+}
+
+// Conditional being parsed, such as %if
+type conditional struct {
+	keyword string
+	// Lines of the current branch are used
+	active bool
+	// Any branch is already taken
+	taken bool
+	// Lines of the enclosing branch are used
+	parent  bool
+	hasElse bool
+}
+
+type parser struct {
+	spec         *Spec
+	lineno       int
+	current      *section
+	conditionals []*conditional
+}
+
+// Parse parses the spec file with macros, which are defined by the spec.
+// Macros of the build environment, such as %_target_cpu, shall be defined before.
+func Parse(r io.Reader, macros *Macros) (spec *Spec, err error) {
+	spec = &Spec{Macros: macros, Sections: make(map[string]string)}
+	p := &parser{spec: spec}
+	p.current = &section{name: "package", pkg: p.newPackage("")}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var continued string
+	for scanner.Scan() {
+		p.lineno++
+		line := continued + scanner.Text()
+		continued = ""
+
+		// Definitions of macros are continued by a backslash at the end of line
+		if isDefinition(line) && strings.HasSuffix(line, "\\") {
+			continued = line + "\n"
+			continue
+		}
+
+		err = p.parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", p.lineno, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if continued != "" {
+		if err = p.parseLine(strings.TrimSuffix(continued, "\n")); err != nil {
+			return nil, fmt.Errorf("line %d: %s", p.lineno, err)
+		}
+	}
+
+	if len(p.conditionals) > 0 {
+		return nil, fmt.Errorf("Unclosed %%%s", p.conditionals[len(p.conditionals)-1].keyword)
+	}
+
+	err = p.endSection()
+	if err != nil {
+		return
+	}
+
+	err = p.finish()
+
+	return
+}
+
+// ParseFile parses the spec file, see Parse
+func ParseFile(filename string, macros *Macros) (spec *Spec, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	spec, err = Parse(file, macros)
+	if err != nil {
+		err = fmt.Errorf("%s: %s", filename, err)
+	}
+
+	return
+}
+
+func isDefinition(line string) bool {
+	keyword, _ := splitKeyword(strings.TrimSpace(line))
+
+	return keyword == "define" || keyword == "global"
+}
+
+// splitKeyword splits a line such as "%files -n foo" to "files" and "-n foo"
+func splitKeyword(line string) (keyword string, args string) {
+	if !strings.HasPrefix(line, "%") {
+		return
+	}
+
+	end := 1
+	for end < len(line) && isNameChar(line[end]) {
+		end++
+	}
+	if end < len(line) && line[end] != ' ' && line[end] != '\t' {
+		return
+	}
+
+	return line[1:end], strings.TrimSpace(line[end:])
+}
+
+func (p *parser) newPackage(name string) (pkg *Package) {
+	pkg = &Package{Name: name, Tags: make(map[string]string), Scripts: make(map[string]*Script)}
+	p.spec.Packages = append(p.spec.Packages, pkg)
+
+	return
+}
+
+func (p *parser) skipping() bool {
+	if len(p.conditionals) == 0 {
+		return false
+	}
+	top := p.conditionals[len(p.conditionals)-1]
+
+	return !top.parent || !top.active
+}
+
+func (p *parser) parseLine(line string) (err error) {
+	trimmed := strings.TrimSpace(line)
+	keyword, args := splitKeyword(trimmed)
+
+	handled, err := p.parseConditional(keyword, args)
+	if handled || err != nil || p.skipping() {
+		return
+	}
+
+	// Lines of changelog are kept as they are
+	if p.current.name == "changelog" && !otherSections[keyword] && !buildSections[keyword] && !scriptSections[keyword] {
+		p.current.lines = append(p.current.lines, line)
+		p.spec.Expanded = append(p.spec.Expanded, line)
+		return
+	}
+
+	switch keyword {
+	case "define":
+		return p.spec.Macros.DefineLine(args)
+	case "global":
+		return p.spec.Macros.GlobalLine(args)
+	case "undefine":
+		p.spec.Macros.Undefine(strings.TrimSpace(args))
+		return
+	case "dnl":
+		return
+	case "include":
+		return fmt.Errorf("%%include is not supported")
+	}
+
+	expanded, err := p.spec.Macros.Expand(line)
+	if err != nil {
+		return
+	}
+	p.spec.Expanded = append(p.spec.Expanded, expanded)
+
+	keyword, args = splitKeyword(strings.TrimSpace(expanded))
+	if otherSections[keyword] || buildSections[keyword] || scriptSections[keyword] {
+		return p.startSection(keyword, args)
+	}
+
+	if p.current.name == "package" {
+		return p.parseTag(p.current.pkg, expanded)
+	}
+
+	p.current.lines = append(p.current.lines, expanded)
+
+	return
+}
+
+func (p *parser) parseConditional(keyword string, args string) (handled bool, err error) {
+	switch keyword {
+	case "if", "ifarch", "ifnarch", "ifos", "ifnos":
+		parent := !p.skipping()
+		cond := &conditional{keyword: keyword, parent: parent}
+		if parent {
+			cond.active, err = p.evalCondition(keyword, args)
+		}
+		cond.taken = cond.active
+		p.conditionals = append(p.conditionals, cond)
+		return true, err
+	case "elif", "elifarch", "elifos":
+		if len(p.conditionals) == 0 {
+			return true, fmt.Errorf("%%%s without %%if", keyword)
+		}
+		cond := p.conditionals[len(p.conditionals)-1]
+		if cond.hasElse {
+			return true, fmt.Errorf("%%%s after %%else", keyword)
+		}
+		cond.active = false
+		if cond.parent && !cond.taken {
+			cond.active, err = p.evalCondition(strings.Replace(keyword, "elif", "if", 1), args)
+			cond.taken = cond.active
+		}
+		return true, err
+	case "else":
+		if len(p.conditionals) == 0 {
+			return true, fmt.Errorf("%%else without %%if")
+		}
+		cond := p.conditionals[len(p.conditionals)-1]
+		if cond.hasElse {
+			return true, fmt.Errorf("Duplicated %%else")
+		}
+		cond.hasElse = true
+		cond.active = !cond.taken
+		cond.taken = true
+		return true, nil
+	case "endif":
+		if len(p.conditionals) == 0 {
+			return true, fmt.Errorf("%%endif without %%if")
+		}
+		p.conditionals = p.conditionals[:len(p.conditionals)-1]
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (p *parser) evalCondition(keyword string, args string) (result bool, err error) {
+	expanded, err := p.spec.Macros.Expand(args)
+	if err != nil {
+		return
+	}
+
+	switch keyword {
+	case "if":
+		value, err := evalExpression(p.spec.Macros, expanded)
+		return value.IsTrue(), err
+	case "ifarch", "ifnarch":
+		result = containsWord(expanded, p.spec.macro("_target_cpu"))
+		return result == (keyword == "ifarch"), nil
+	case "ifos", "ifnos":
+		result = containsWord(expanded, p.spec.macro("_target_os"))
+		return result == (keyword == "ifos"), nil
+	}
+
+	return false, fmt.Errorf("Unknown conditional %%%s", keyword)
+}
+
+func containsWord(list string, word string) bool {
+	for _, w := range strings.FieldsFunc(list, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+		if w == word {
+			return true
+		}
+	}
+
+	return false
+}
+
+// packageOf finds the package of the arguments of a section, such as "devel" or "-n foo".
+// The rest of arguments are returned as options.
+func (p *parser) packageOf(keyword string, args string, create bool) (pkg *Package, options map[string]string, err error) {
+	options = make(map[string]string)
+	fields := strings.Fields(args)
+
+	var suffix, fullname string
+	for i := 0; i < len(fields); i++ {
+		switch field := fields[i]; {
+		case field == "--":
+			options["--"] = strings.Join(fields[i+1:], " ")
+			i = len(fields)
+		case field == "-n" || field == "-f" || field == "-p" || field == "-P":
+			if i+1 >= len(fields) {
+				return nil, nil, fmt.Errorf("%%%s %s needs an argument", keyword, field)
+			}
+			i++
+			if field == "-n" {
+				fullname = fields[i]
+			} else {
+				options[field] = strings.TrimSpace(options[field] + " " + fields[i])
+			}
+		case strings.HasPrefix(field, "-"):
+			// Other options such as -l of %files are ignored
+		case suffix == "" && fullname == "":
+			suffix = field
+		default:
+			return nil, nil, fmt.Errorf("Too many names for %%%s: %s", keyword, args)
+		}
+	}
+
+	main := p.spec.Packages[0]
+	name := main.Name
+	if fullname != "" {
+		name = fullname
+	} else if suffix != "" {
+		name = main.Name + "-" + suffix
+	}
+
+	for _, pkg := range p.spec.Packages {
+		if pkg.Name == name {
+			if create && pkg != main {
+				return nil, nil, fmt.Errorf("Package %s already exists", name)
+			}
+			return pkg, options, nil
+		}
+	}
+
+	if create {
+		return p.newPackage(name), options, nil
+	}
+
+	return nil, nil, fmt.Errorf("Package %s does not exist", name)
+}
+
+func (p *parser) startSection(keyword string, args string) (err error) {
+	err = p.endSection()
+	if err != nil {
+		return
+	}
+
+	p.current = &section{name: keyword}
+
+	switch {
+	case keyword == "package":
+		if p.spec.Packages[0].Name == "" {
+			return fmt.Errorf("Name is not defined before %%package")
+		}
+		if strings.TrimSpace(args) == "" {
+			return fmt.Errorf("%%package needs a name")
+		}
+		p.current.pkg, _, err = p.packageOf(keyword, args, true)
+	case keyword == "description":
+		p.current.pkg, _, err = p.packageOf(keyword, args, false)
+	case keyword == "files":
+		var options map[string]string
+		p.current.pkg, options, err = p.packageOf(keyword, args, false)
+		if err == nil {
+			p.current.pkg.HasFiles = true
+			if options["-f"] != "" {
+				p.current.pkg.FileLists = append(p.current.pkg.FileLists, strings.Fields(options["-f"])...)
+			}
+		}
+	case scriptSections[keyword]:
+		var options map[string]string
+		p.current.pkg, options, err = p.packageOf(keyword, args, false)
+		if err == nil {
+			p.current.script = &Script{Interpreter: options["-p"], Triggers: options["--"]}
+			p.current.pkg.Scripts[keyword] = p.current.script
+		}
+	}
+
+	return
+}
+
+// endSection stores lines of the section being parsed
+func (p *parser) endSection() (err error) {
+	text := strings.Join(trimBlankLines(p.current.lines), "\n")
+
+	switch name := p.current.name; {
+	case name == "description":
+		p.current.pkg.Description = text
+	case name == "files":
+		for _, line := range p.current.lines {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				p.current.pkg.Files = append(p.current.pkg.Files, line)
+			}
+		}
+	case name == "changelog":
+		p.spec.Changelog = text
+	case name == "sourcelist" || name == "patchlist":
+		for _, line := range p.current.lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			err = p.addSource(strings.TrimSuffix(name, "list"), -1, line)
+			if err != nil {
+				return
+			}
+		}
+	case buildSections[name]:
+		p.spec.Sections[name] = text
+	case scriptSections[name]:
+		p.current.script.Body = text
+	}
+
+	return
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+
+	return lines
+}
+
+func (p *parser) parseTag(pkg *Package, line string) (err error) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return
+	}
+
+	match := tagPattern.FindStringSubmatch(trimmed)
+	if match == nil {
+		return fmt.Errorf("Unknown tag: %s", trimmed)
+	}
+	tag, qualifier, value := strings.ToLower(match[1]), strings.ToLower(match[2]), strings.TrimSpace(match[3])
+	main := pkg == p.spec.Packages[0]
+
+	if source := sourcePattern.FindStringSubmatch(tag); source != nil {
+		if !main {
+			return fmt.Errorf("%s is only allowed in the main package", match[1])
+		}
+		number := -1
+		if source[2] != "" {
+			number, _ = strconv.Atoi(source[2])
+		}
+		return p.addSource(source[1], number, value)
+	}
+
+	switch tag {
+	case "name":
+		if !main {
+			return fmt.Errorf("Name is only allowed in the main package, use %%package")
+		}
+		pkg.Name = value
+	case "version":
+		if strings.ContainsAny(value, "- \t") {
+			return fmt.Errorf("Version %q has illegal characters", value)
+		}
+		pkg.Version = value
+	case "release":
+		if strings.ContainsAny(value, "- \t") {
+			return fmt.Errorf("Release %q has illegal characters", value)
+		}
+		pkg.Release = value
+	case "epoch", "serial":
+		if _, atoi_err := strconv.Atoi(value); atoi_err != nil {
+			return fmt.Errorf("Epoch %q is not a number", value)
+		}
+		tag = "epoch"
+		pkg.Epoch = value
+	case "summary":
+		pkg.Summary = value
+	case "license", "copyright":
+		tag = "license"
+		pkg.License = value
+	case "group":
+		pkg.Group = value
+	case "url":
+		pkg.URL = value
+	case "buildarch", "buildarchitectures":
+		tag = "buildarch"
+		pkg.BuildArch = value
+	case "nosource", "nopatch":
+		return p.markNoSource(tag, value)
+	case "buildrequires", "buildprereq", "buildconflicts":
+		deps, dep_err := parseDependencies(value, 0)
+		if dep_err != nil {
+			return dep_err
+		}
+		if tag == "buildconflicts" {
+			p.spec.BuildConflicts = append(p.spec.BuildConflicts, deps...)
+		} else {
+			p.spec.BuildRequires = append(p.spec.BuildRequires, deps...)
+		}
+		return
+	case "requires", "prereq", "provides", "conflicts", "obsoletes",
+		"recommends", "suggests", "supplements", "enhances":
+		return p.addDependencies(pkg, tag, qualifier, value)
+	case "excludearch":
+		p.spec.ExcludeArch = append(p.spec.ExcludeArch, strings.Fields(value)...)
+	case "exclusivearch":
+		p.spec.ExclusiveArch = append(p.spec.ExclusiveArch, strings.Fields(value)...)
+	}
+
+	pkg.Tags[tag] = value
+
+	if macroTags[tag] {
+		p.spec.Macros.Define(tag, value)
+		if main {
+			p.spec.Macros.Define(strings.ToUpper(tag), value)
+		}
+	}
+
+	return
+}
+
+func (p *parser) addSource(kind string, number int, location string) (err error) {
+	list := &p.spec.Sources
+	macro := "SOURCE"
+	if kind == "patch" {
+		list = &p.spec.Patches
+		macro = "PATCH"
+	}
+
+	// Unnumbered sources are numbered after the last one
+	if number < 0 {
+		number = 0
+		for _, s := range *list {
+			if s.Number >= number {
+				number = s.Number + 1
+			}
+		}
+	}
+	for _, s := range *list {
+		if s.Number == number {
+			return fmt.Errorf("%s%d is defined twice", macro, number)
+		}
+	}
+
+	source := Source{Number: number, Location: location}
+	*list = append(*list, source)
+
+	// %{SOURCE1} is the path of the file in %{_sourcedir}, same as rpmbuild
+	p.spec.Macros.Define(macro+strconv.Itoa(number), "%{_sourcedir}/"+source.Filename())
+	p.spec.Macros.Define(kind+"_num", strconv.Itoa(number))
+
+	return
+}
+
+func (p *parser) markNoSource(tag string, value string) (err error) {
+	list := p.spec.Sources
+	if tag == "nopatch" {
+		list = p.spec.Patches
+	}
+
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+		number, atoi_err := strconv.Atoi(field)
+		if atoi_err != nil {
+			return fmt.Errorf("%s %q is not a number", tag, field)
+		}
+
+		found := false
+		for i := range list {
+			if list[i].Number == number {
+				list[i].NoSource = true
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s %d is not defined", tag, number)
+		}
+	}
+
+	return
+}
+
+func (p *parser) addDependencies(pkg *Package, tag string, qualifier string, value string) (err error) {
+	var flags int32
+	if qualifier != "" {
+		if tag != "requires" && tag != "prereq" {
+			return fmt.Errorf("%s does not have qualifiers", tag)
+		}
+		for _, q := range strings.Split(qualifier, ",") {
+			flag, found := requireQualifiers[strings.TrimSpace(q)]
+			if !found {
+				return fmt.Errorf("Unknown qualifier of %s: %s", tag, q)
+			}
+			flags |= flag
+		}
+	}
+	if tag == "prereq" {
+		flags |= rpmlib.RPMSENSE_PREREQ
+	}
+
+	deps, err := parseDependencies(value, flags)
+	if err != nil {
+		return
+	}
+
+	switch tag {
+	case "requires", "prereq":
+		pkg.Requires = append(pkg.Requires, deps...)
+	case "provides":
+		pkg.Provides = append(pkg.Provides, deps...)
+	case "conflicts":
+		pkg.Conflicts = append(pkg.Conflicts, deps...)
+	case "obsoletes":
+		pkg.Obsoletes = append(pkg.Obsoletes, deps...)
+	case "recommends":
+		pkg.Recommends = append(pkg.Recommends, deps...)
+	case "suggests":
+		pkg.Suggests = append(pkg.Suggests, deps...)
+	case "supplements":
+		pkg.Supplements = append(pkg.Supplements, deps...)
+	case "enhances":
+		pkg.Enhances = append(pkg.Enhances, deps...)
+	}
+
+	return
+}
+
+var dependencyOperators = map[string]int32{
+	"<":  rpmlib.RPMSENSE_LESS,
+	">":  rpmlib.RPMSENSE_GREATER,
+	"=":  rpmlib.RPMSENSE_EQUAL,
+	"==": rpmlib.RPMSENSE_EQUAL,
+	"<=": rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL,
+	"=<": rpmlib.RPMSENSE_LESS | rpmlib.RPMSENSE_EQUAL,
+	">=": rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL,
+	"=>": rpmlib.RPMSENSE_GREATER | rpmlib.RPMSENSE_EQUAL,
+}
+
+// parseDependencies parses a value of dependency tags, such as "foo >= 1.0, bar, (baz or qux)"
+func parseDependencies(value string, flags int32) (deps []rpmlib.Dependency, err error) {
+	isSeparator := func(c byte) bool { return c == ' ' || c == '\t' || c == ',' }
+
+	i := 0
+	token := func() string {
+		for i < len(value) && isSeparator(value[i]) {
+			i++
+		}
+		start := i
+		for i < len(value) && !isSeparator(value[i]) {
+			i++
+		}
+		return value[start:i]
+	}
+
+	for {
+		for i < len(value) && isSeparator(value[i]) {
+			i++
+		}
+		if i >= len(value) {
+			return
+		}
+
+		// Rich dependencies are enclosed by parentheses
+		if value[i] == '(' {
+			end := matching(value, i, '(', ')')
+			if end < 0 {
+				return nil, fmt.Errorf("Unbalanced ( in %s", value)
+			}
+			rich := value[i : end+1]
+			if _, err = rpmlib.ParseRichDependency(rich); err != nil {
+				return
+			}
+			deps = append(deps, rpmlib.Dependency{Name: rich, Flags: flags})
+			i = end + 1
+			continue
+		}
+
+		dep := rpmlib.Dependency{Name: token(), Flags: flags}
+
+		// The next token may be an operator followed by a version
+		saved := i
+		op := token()
+		if sense, found := dependencyOperators[op]; found {
+			version := token()
+			if version == "" {
+				return nil, fmt.Errorf("Version is missing in %s", value)
+			}
+			dep.Flags |= sense
+			dep.Version = version
+		} else {
+			i = saved
+		}
+
+		deps = append(deps, dep)
+	}
+}
+
+// finish checks required tags and copies inherited tags to subpackages
+func (p *parser) finish() (err error) {
+	main := p.spec.Packages[0]
+	for _, required := range []struct{ tag, value string }{
+		{"Name", main.Name}, {"Version", main.Version}, {"Release", main.Release},
+	} {
+		if required.value == "" {
+			return fmt.Errorf("%s field must be present in package", required.tag)
+		}
+	}
+
+	for _, pkg := range p.spec.Packages[1:] {
+		for _, tag := range inheritedTags {
+			if _, found := pkg.Tags[tag]; !found && main.Tags[tag] != "" {
+				pkg.Tags[tag] = main.Tags[tag]
+			}
+		}
+		pkg.Version = pkg.Tags["version"]
+		pkg.Release = pkg.Tags["release"]
+		pkg.Epoch = pkg.Tags["epoch"]
+		pkg.License = pkg.Tags["license"]
+		pkg.URL = pkg.Tags["url"]
+		pkg.Group = pkg.Tags["group"]
+		if pkg.BuildArch == "" {
+			pkg.BuildArch = main.BuildArch
+		}
+	}
+
+	return
+}
+
+// Package finds the package by the name
+func (spec *Spec) Package(name string) (pkg *Package, found bool) {
+	for _, pkg := range spec.Packages {
+		if pkg.Name == name {
+			return pkg, true
+		}
+	}
+
+	return nil, false
+}
+
+// Arch returns the architecture of the package, the BuildArch or %{_target_cpu}
+func (spec *Spec) Arch(pkg *Package) string {
+	if pkg.BuildArch != "" {
+		return pkg.BuildArch
+	}
+
+	return spec.macro("_target_cpu")
+}
+
+// NEVRA of the binary package
+func (spec *Spec) NEVRA(pkg *Package) (nevra rpmlib.NEVRA) {
+	nevra.Name = pkg.Name
+	nevra.EVR.Version = pkg.Version
+	nevra.EVR.Release = pkg.Release
+	if epoch, err := strconv.ParseInt(pkg.Epoch, 10, 32); err == nil {
+		nevra.EVR.Epoch = int32(epoch)
+		nevra.EVR.HasEpoch = true
+	}
+	nevra.Arch = spec.Arch(pkg)
+
+	return
+}
+
+// SourceNEVRA is the NEVRA of the source package, of which arch is "nosrc" if any source is not included
+func (spec *Spec) SourceNEVRA() (nevra rpmlib.NEVRA) {
+	nevra = spec.NEVRA(spec.Packages[0])
+	nevra.Arch = "src"
+	for _, s := range append(append([]Source{}, spec.Sources...), spec.Patches...) {
+		if s.NoSource {
+			nevra.Arch = "nosrc"
+		}
+	}
+
+	return
+}
@@ -0,0 +1,238 @@
+package specfile
+
+import (
+	"github.com/pombredanne/gorpm-1/rpmlib"
+	"strings"
+	"testing"
+)
+
+const testSpec = `%global major 1
+%define minor 2
+%global with_docs 1
+
+Name:		foo
+Version:	%{major}.%{minor}
+Release:	3%{?dist}
+Epoch:		1
+Summary:	The %{name} package
+License:	MIT
+URL:		https://example.com/%{name}
+Source0:	%{url}/%{name}-%{version}.tar.gz
+Source1:	%{name}.conf
+Patch:		fix.patch
+BuildRequires:	gcc, make >= 4
+Requires(pre):	shadow-utils
+Requires(posttrans,verify):	coreutils
+Requires(interp,meta):	bash
+Requires:	(bar >= 2 if baz)
+
+%if 0%{?with_docs}
+Recommends:	%{name}-doc = %{version}-%{release}
+%else
+Recommends:	broken
+%endif
+
+%if 0%{?rhel}
+Requires:	rhel-only
+%endif
+
+%description
+Foo is %{summary}.
+
+%package devel
+Summary:	Headers of %{name}
+Requires:	%{name}%{?_isa} = %{epoch}:%{version}-%{release}
+
+%description devel
+Headers.
+
+%prep
+%setup -q
+
+%build
+make
+
+%pre -p /usr/bin/sh
+exit 0
+
+%post -p <lua>
+print("ok")
+
+%files
+%license LICENSE
+%doc README
+%{_bindir}/%{name}
+
+%files devel
+%{_includedir}/%{name}.h
+
+%changelog
+* Mon Oct 19 2026 Foo Bar <foo@example.com> - 1:1.2-3
+- Initial package
+`
+
+func parseTestSpec(t *testing.T) *Spec {
+	spec, err := Parse(strings.NewReader(testSpec), DefaultMacros("x86_64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return spec
+}
+
+func TestParse(t *testing.T) {
+	spec := parseTestSpec(t)
+
+	if len(spec.Packages) != 2 {
+		t.Fatalf("Packages = %d", len(spec.Packages))
+	}
+	main, devel := spec.Packages[0], spec.Packages[1]
+
+	if nevra := spec.NEVRA(main).String(); nevra != "foo-1:1.2-3.x86_64" {
+		t.Errorf("NEVRA = %s", nevra)
+	}
+	if nevra := spec.SourceNEVRA().String(); nevra != "foo-1:1.2-3.src" {
+		t.Errorf("SourceNEVRA = %s", nevra)
+	}
+	if main.Summary != "The foo package" || main.Description != "Foo is The foo package." {
+		t.Errorf("Summary = %q, Description = %q", main.Summary, main.Description)
+	}
+
+	// Subpackages inherit tags of the main package
+	if devel.Name != "foo-devel" || devel.Version != "1.2" || devel.License != "MIT" || devel.URL != main.URL {
+		t.Errorf("devel = %+v", devel)
+	}
+	if len(devel.Requires) != 1 || devel.Requires[0].String() != "foo = 1:1.2-3" {
+		t.Errorf("devel requires %v", devel.Requires)
+	}
+
+	if len(spec.Sources) != 2 || spec.Sources[0].Filename() != "foo-1.2.tar.gz" || spec.Sources[1].Number != 1 {
+		t.Errorf("Sources = %+v", spec.Sources)
+	}
+	if len(spec.Patches) != 1 || spec.Patches[0].Number != 0 {
+		t.Errorf("Patches = %+v", spec.Patches)
+	}
+	if len(spec.BuildRequires) != 2 || spec.BuildRequires[1].String() != "make >= 4" {
+		t.Errorf("BuildRequires = %v", spec.BuildRequires)
+	}
+
+	if len(main.Recommends) != 1 || main.Recommends[0].Name != "foo-doc" {
+		t.Errorf("Recommends = %v", main.Recommends)
+	}
+	for _, require := range main.Requires {
+		if require.Name == "rhel-only" {
+			t.Errorf("Requires of a false %%if: %v", require)
+		}
+	}
+
+	// %license is a directive of %files, not the License tag
+	if len(main.Files) != 3 || main.Files[0] != "%license LICENSE" || main.Files[2] != "/usr/bin/foo" {
+		t.Errorf("Files = %q", main.Files)
+	}
+	if !devel.HasFiles || len(devel.Files) != 1 || devel.Files[0] != "/usr/include/foo.h" {
+		t.Errorf("devel Files = %q", devel.Files)
+	}
+
+	if spec.Sections["build"] != "make" {
+		t.Errorf("%%build = %q", spec.Sections["build"])
+	}
+	if script := main.Scripts["pre"]; script == nil || script.Interpreter != "/usr/bin/sh" || script.Body != "exit 0" {
+		t.Errorf("%%pre = %+v", script)
+	}
+}
+
+func TestRequireQualifiers(t *testing.T) {
+	spec := parseTestSpec(t)
+
+	flags := make(map[string]int32)
+	for _, require := range spec.Packages[0].Requires {
+		flags[require.Name] = require.Flags
+	}
+
+	tests := []struct {
+		name  string
+		flags int32
+	}{
+		{"shadow-utils", rpmlib.RPMSENSE_SCRIPT_PRE},
+		{"coreutils", rpmlib.RPMSENSE_POSTTRANS | rpmlib.RPMSENSE_SCRIPT_VERIFY},
+		{"bash", rpmlib.RPMSENSE_INTERP | rpmlib.RPMSENSE_META},
+		{"(bar >= 2 if baz)", 0},
+	}
+
+	for _, test := range tests {
+		got, found := flags[test.name]
+		if !found {
+			t.Errorf("%s is not required", test.name)
+			continue
+		}
+		if got != test.flags {
+			t.Errorf("Flags of %s = %#x, want %#x", test.name, got, test.flags)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"Name: foo\n%if 1\n",
+		"Name: foo\n%endif\n",
+		"%package devel\n",
+		"Name: foo\n%package\n",
+		"Name: foo\nRequires(unknown): bar\n",
+		"Name: foo\n%files bar\n",
+	}
+
+	for _, text := range tests {
+		if _, err := Parse(strings.NewReader(text), DefaultMacros("x86_64")); err == nil {
+			t.Errorf("Parse(%q) succeeded", text)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	spec := parseTestSpec(t)
+	header := spec.Header(spec.Packages[0])
+
+	if header.NEVRA().String() != "foo-1:1.2-3.x86_64" {
+		t.Errorf("NEVRA = %s", header.NEVRA())
+	}
+	if license, err := header.Section.GetString(rpmlib.RPMTAG_LICENSE); err != nil || license != "MIT" {
+		t.Errorf("License = %q, %v", license, err)
+	}
+
+	// Interpreters are string arrays, with arguments
+	tests := []struct {
+		tag  int32
+		prog []string
+	}{
+		{rpmlib.RPMTAG_PREINPROG, []string{"/usr/bin/sh"}},
+		{rpmlib.RPMTAG_POSTINPROG, []string{"<lua>"}},
+	}
+	for _, test := range tests {
+		prog, err := header.Section.GetStringArray(test.tag)
+		if err != nil || strings.Join(prog, " ") != strings.Join(test.prog, " ") {
+			t.Errorf("Tag %d = %q, %v", test.tag, prog, err)
+		}
+	}
+
+	requires, err := header.Requires()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requires) != len(spec.Packages[0].Requires) {
+		t.Errorf("Requires = %v", requires)
+	}
+
+	source := spec.SourceHeader()
+	if !source.IsSource() {
+		t.Errorf("SourceHeader is not of a source package")
+	}
+	buildRequires, err := source.BuildRequires()
+	if err != nil || len(buildRequires) != 2 {
+		t.Errorf("BuildRequires = %v, %v", buildRequires, err)
+	}
+
+	logs, err := spec.Changelogs()
+	if err != nil || len(logs) != 1 || logs[0].Author != "Foo Bar <foo@example.com> - 1:1.2-3" {
+		t.Errorf("Changelogs = %+v, %v", logs, err)
+	}
+}
@@ -0,0 +1,519 @@
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+//
+// Read only access to tables of SQLite 3 database file.
+// Only table b-trees are read, indexes are not supported.
+// Committed pages in the WAL file are read, but the WAL file is not checkpointed.
+//
+
+const (
+	HeaderSize = 100
+
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+var HeaderMagic = []byte("SQLite format 3\x00")
+
+type Database struct {
+	data     []byte
+	pageSize int
+	usable   int
+	encoding int
+	tables   map[string]*Table
+	// Pages in the WAL file, newer than pages in data
+	wal map[int][]byte
+}
+
+// Table has values of columns in the order of CREATE TABLE statement
+type Table struct {
+	db       *Database
+	Name     string
+	Columns  []string
+	rootPage int
+	// Column of "INTEGER PRIMARY KEY", which is an alias of rowid, or -1
+	rowidColumn int
+}
+
+// Open reads a whole database file into memory, with "-wal" file if exists
+func Open(path string) (db *Database, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	wal, err := ioutil.ReadFile(path + "-wal")
+	if err != nil && !os.IsNotExist(err) {
+		return
+	}
+
+	return NewWithWAL(data, wal)
+}
+
+func New(data []byte) (db *Database, err error) {
+	return NewWithWAL(data, nil)
+}
+
+// NewWithWAL reads the database with the content of WAL file, which may be empty
+func NewWithWAL(data []byte, wal []byte) (db *Database, err error) {
+	if len(data) < HeaderSize || !bytes.Equal(data[:len(HeaderMagic)], HeaderMagic) {
+		return nil, fmt.Errorf("Not a SQLite 3 database")
+	}
+
+	db = new(Database)
+	db.data = data
+
+	db.pageSize = int(binary.BigEndian.Uint16(data[16:18]))
+	if db.pageSize == 1 {
+		db.pageSize = 65536
+	}
+	if db.pageSize < 512 || db.pageSize&(db.pageSize-1) != 0 {
+		return nil, fmt.Errorf("Invalid page size %d", db.pageSize)
+	}
+
+	db.usable = db.pageSize - int(data[20])
+	db.encoding = int(binary.BigEndian.Uint32(data[56:60]))
+
+	db.wal = readWAL(wal, db.pageSize)
+
+	err = db.readSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// readWAL reads pages of committed transactions in the WAL file.
+// Frames are valid while their salts are same as the WAL header, checksums are not verified.
+func readWAL(wal []byte, pageSize int) (pages map[int][]byte) {
+	pages = make(map[int][]byte)
+
+	if len(wal) < walHeaderSize {
+		return
+	}
+	magic := binary.BigEndian.Uint32(wal)
+	if magic&^1 != 0x377f0682 || int(binary.BigEndian.Uint32(wal[8:])) != pageSize {
+		return
+	}
+	salt := wal[16:24]
+
+	pending := make(map[int][]byte)
+	for offset := walHeaderSize; offset+walFrameHeaderSize+pageSize <= len(wal); offset += walFrameHeaderSize + pageSize {
+		frame := wal[offset : offset+walFrameHeaderSize]
+		if !bytes.Equal(frame[8:16], salt) {
+			break
+		}
+
+		number := int(binary.BigEndian.Uint32(frame))
+		pending[number] = wal[offset+walFrameHeaderSize : offset+walFrameHeaderSize+pageSize]
+
+		// A commit frame has the size of database after the commit
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			for number, page := range pending {
+				pages[number] = page
+			}
+			pending = make(map[int][]byte)
+		}
+	}
+
+	return
+}
+
+func (db *Database) page(number int) (page []byte, err error) {
+	if page, found := db.wal[number]; found {
+		return page, nil
+	}
+
+	start := (number - 1) * db.pageSize
+	if number < 1 || start+db.pageSize > len(db.data) {
+		return nil, fmt.Errorf("Page %d is out of database", number)
+	}
+
+	return db.data[start : start+db.pageSize], nil
+}
+
+// readSchema reads tables from sqlite_master, whose root page is 1
+func (db *Database) readSchema() (err error) {
+	db.tables = make(map[string]*Table)
+
+	master := &Table{db: db, Name: "sqlite_master", rootPage: 1, rowidColumn: -1,
+		Columns: []string{"type", "name", "tbl_name", "rootpage", "sql"}}
+
+	return master.Rows(func(rowid int64, values []interface{}) error {
+		kind, _ := values[0].(string)
+		name, _ := values[1].(string)
+		rootpage, _ := values[3].(int64)
+		sql, _ := values[4].(string)
+
+		if kind != "table" {
+			return nil
+		}
+
+		table := &Table{db: db, Name: name, rootPage: int(rootpage), rowidColumn: -1}
+		table.Columns, table.rowidColumn = parseColumns(sql)
+		db.tables[strings.ToLower(name)] = table
+
+		return nil
+	})
+}
+
+// Tables returns names of all tables
+func (db *Database) Tables() (names []string) {
+	for _, table := range db.tables {
+		names = append(names, table.Name)
+	}
+
+	return
+}
+
+// Table finds a table by name, ignoring case
+func (db *Database) Table(name string) (table *Table, err error) {
+	table, found := db.tables[strings.ToLower(name)]
+	if !found {
+		return nil, fmt.Errorf("Table %s not found", name)
+	}
+
+	return
+}
+
+func unquote(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) >= 2 {
+		switch name[0] {
+		case '"', '`', '\'':
+			return name[1 : len(name)-1]
+		case '[':
+			return strings.TrimSuffix(name[1:], "]")
+		}
+	}
+
+	return name
+}
+
+// parseColumns takes column names from CREATE TABLE statement
+func parseColumns(sql string) (columns []string, rowidColumn int) {
+	rowidColumn = -1
+
+	start := strings.IndexByte(sql, '(')
+	end := strings.LastIndexByte(sql, ')')
+	if start < 0 || end < start {
+		return
+	}
+
+	// Split by commas not in parentheses
+	var defs []string
+	depth, last := 0, start+1
+	for i := start + 1; i < end; i++ {
+		switch sql[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				defs = append(defs, sql[last:i])
+				last = i + 1
+			}
+		}
+	}
+	defs = append(defs, sql[last:end])
+
+	for _, def := range defs {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PRIMARY", "UNIQUE", "CHECK", "FOREIGN", "CONSTRAINT":
+			continue
+		}
+
+		upper := strings.ToUpper(strings.Join(fields[1:], " "))
+		if strings.HasPrefix(upper, "INTEGER PRIMARY KEY") {
+			rowidColumn = len(columns)
+		}
+
+		columns = append(columns, unquote(fields[0]))
+	}
+
+	return
+}
+
+// ColumnIndex returns the index of column in values, or -1 if not found
+func (table *Table) ColumnIndex(name string) int {
+	for i, column := range table.Columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Rows calls fn for each row in the order of rowid.
+// values are int64, float64, string, []byte or nil, as stored in the file without
+// column affinities, so integral values of REAL columns are int64.
+func (table *Table) Rows(fn func(rowid int64, values []interface{}) error) error {
+	return table.walk(table.rootPage, 0, fn)
+}
+
+func (table *Table) walk(number int, depth int, fn func(rowid int64, values []interface{}) error) (err error) {
+	// A broken file may have loops in the tree
+	if depth > 64 {
+		return fmt.Errorf("B-tree of table %s is too deep", table.Name)
+	}
+
+	page, err := table.db.page(number)
+	if err != nil {
+		return
+	}
+
+	offset := 0
+	if number == 1 {
+		offset = HeaderSize
+	}
+
+	if offset+8 > len(page) {
+		return fmt.Errorf("Invalid page %d", number)
+	}
+	kind := page[offset]
+	ncells := int(binary.BigEndian.Uint16(page[offset+3:]))
+
+	headerSize := 8
+	if kind == pageInteriorTable {
+		headerSize = 12
+	} else if kind != pageLeafTable {
+		return fmt.Errorf("Page %d of table %s is not a table b-tree page", number, table.Name)
+	}
+
+	pointers := offset + headerSize
+	if pointers+2*ncells > len(page) {
+		return fmt.Errorf("Invalid page %d", number)
+	}
+
+	for i := 0; i < ncells; i++ {
+		cell := int(binary.BigEndian.Uint16(page[pointers+2*i:]))
+		if cell >= len(page) {
+			return fmt.Errorf("Invalid cell in page %d", number)
+		}
+
+		if kind == pageInteriorTable {
+			if cell+4 > len(page) {
+				return fmt.Errorf("Invalid cell in page %d", number)
+			}
+			child := int(binary.BigEndian.Uint32(page[cell:]))
+			err = table.walk(child, depth+1, fn)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		var rowid int64
+		var values []interface{}
+		rowid, values, err = table.leafCell(page, cell)
+		if err != nil {
+			return
+		}
+
+		err = fn(rowid, values)
+		if err != nil {
+			return
+		}
+	}
+
+	if kind == pageInteriorTable {
+		right := int(binary.BigEndian.Uint32(page[offset+8:]))
+		err = table.walk(right, depth+1, fn)
+	}
+
+	return
+}
+
+func (table *Table) leafCell(page []byte, cell int) (rowid int64, values []interface{}, err error) {
+	size, n := varint(page[cell:])
+	cell += n
+	rowid, n = varint(page[cell:])
+	cell += n
+
+	payload, err := table.db.payload(page, cell, int(size))
+	if err != nil {
+		return
+	}
+
+	values, err = table.db.record(payload)
+	if err != nil {
+		return
+	}
+
+	if table.rowidColumn >= 0 && table.rowidColumn < len(values) && values[table.rowidColumn] == nil {
+		values[table.rowidColumn] = rowid
+	}
+
+	// Columns added by ALTER TABLE are missing in old rows
+	for len(values) < len(table.Columns) {
+		values = append(values, nil)
+	}
+
+	return
+}
+
+// payload reads the payload of a table leaf cell, following overflow pages
+func (db *Database) payload(page []byte, cell int, size int) (payload []byte, err error) {
+	maxLocal := db.usable - 35
+	local := size
+	if size > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (size-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+
+	if cell+local > len(page) {
+		return nil, fmt.Errorf("Cell overruns the page")
+	}
+	payload = append(payload, page[cell:cell+local]...)
+
+	if local == size {
+		return
+	}
+
+	if cell+local+4 > len(page) {
+		return nil, fmt.Errorf("Cell overruns the page")
+	}
+	next := int(binary.BigEndian.Uint32(page[cell+local:]))
+
+	for len(payload) < size {
+		var overflow []byte
+		overflow, err = db.page(next)
+		if err != nil {
+			return
+		}
+
+		rest := size - len(payload)
+		if rest > db.usable-4 {
+			rest = db.usable - 4
+		}
+		payload = append(payload, overflow[4:4+rest]...)
+		next = int(binary.BigEndian.Uint32(overflow))
+	}
+
+	return
+}
+
+// varint decodes a variable length integer of SQLite, and returns its size
+func varint(data []byte) (value int64, n int) {
+	var v uint64
+	for n < 9 && n < len(data) {
+		b := data[n]
+		n++
+
+		if n == 9 {
+			v = v<<8 | uint64(b)
+			break
+		}
+
+		v = v<<7 | uint64(b&0x7f)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	return int64(v), n
+}
+
+func (db *Database) text(data []byte) string {
+	switch db.encoding {
+	case 2, 3:
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if db.encoding == 2 {
+				units[i] = binary.LittleEndian.Uint16(data[2*i:])
+			} else {
+				units[i] = binary.BigEndian.Uint16(data[2*i:])
+			}
+		}
+		return string(utf16.Decode(units))
+	}
+
+	return string(data)
+}
+
+// record decodes values in a record
+func (db *Database) record(payload []byte) (values []interface{}, err error) {
+	headerSize, n := varint(payload)
+	if headerSize < int64(n) || headerSize > int64(len(payload)) {
+		return nil, fmt.Errorf("Invalid record header")
+	}
+
+	var types []int64
+	for pos := n; pos < int(headerSize); {
+		t, n := varint(payload[pos:])
+		types = append(types, t)
+		pos += n
+	}
+
+	body := payload[headerSize:]
+	for _, t := range types {
+		var size int
+		switch {
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6 || t == 7:
+			size = 8
+		case t >= 12:
+			size = int(t-12) / 2
+		}
+
+		if size > len(body) {
+			return nil, fmt.Errorf("Record overruns the payload")
+		}
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t >= 1 && t <= 6:
+			// Big endian signed integer
+			v := int64(int8(data[0]))
+			for _, b := range data[1:] {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(data)))
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12 && t%2 == 0:
+			values = append(values, append([]byte{}, data...))
+		case t >= 13:
+			values = append(values, db.text(data))
+		default:
+			return nil, fmt.Errorf("Unknown serial type %d", t)
+		}
+	}
+
+	return
+}
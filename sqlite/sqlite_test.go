@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"testing"
+)
+
+// Databases in testdata are generated by testdata/generate.py
+
+func readRows(t *testing.T, db *Database, name string) (rows map[int64][]interface{}) {
+	table, err := db.Table(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows = make(map[int64][]interface{})
+	last := int64(-1)
+	err = table.Rows(func(rowid int64, values []interface{}) error {
+		if rowid <= last {
+			t.Errorf("Row %d after %d", rowid, last)
+		}
+		last = rowid
+		rows[rowid] = values
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestTable(t *testing.T) {
+	db, err := Open("testdata/test.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := db.Table("T")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns := []string{"id", "name", "value", "n", "f", "z", "added"}
+	if len(table.Columns) != len(columns) {
+		t.Fatalf("Columns = %v", table.Columns)
+	}
+	for i, column := range columns {
+		if table.ColumnIndex(column) != i {
+			t.Errorf("ColumnIndex(%s) = %d", column, table.ColumnIndex(column))
+		}
+	}
+
+	rows := readRows(t, db, "t")
+	if len(rows) != 502 {
+		t.Fatalf("%d rows, want 502", len(rows))
+	}
+
+	numbers := []int64{0, 1, -1, 127, -128, 32767, 65536, -8388608, 2147483647, 1 << 40, -(1 << 47), 1 << 62}
+	for i := int64(1); i <= 500; i++ {
+		values := rows[i]
+		// Integral reals are stored as integers
+		var f interface{} = float64(i) / 2
+		if i%2 == 0 {
+			f = i / 2
+		}
+		if values[0] != i || values[1] != "row "+strconv.FormatInt(i, 10) || values[3] != numbers[i%int64(len(numbers))] ||
+			values[4] != f || values[5] != nil {
+			t.Errorf("Row %d = %v", i, values)
+			continue
+		}
+		if value, ok := values[2].([]byte); !ok || !bytes.Equal(value, bytes.Repeat([]byte{byte(i % 256)}, int(i%7))) {
+			t.Errorf("Row %d: value %v", i, values[2])
+		}
+		// The column added by ALTER TABLE is missing in old rows
+		if values[6] != nil {
+			t.Errorf("Row %d: added %v", i, values[6])
+		}
+	}
+
+	// The blob overflows into other pages
+	large, ok := rows[1000][2].([]byte)
+	if !ok || len(large) != 5120 || large[0] != 0 || large[5119] != 255 {
+		t.Errorf("Large blob of %d bytes", len(large))
+	}
+	if rows[1000][5] != "text" || rows[1001][6] != "yes" {
+		t.Errorf("Rows 1000 and 1001 = %v, %v", rows[1000][3:], rows[1001][3:])
+	}
+
+	quoted := readRows(t, db, "quoted table")
+	if len(quoted) != 1 || quoted[1][0] != "x" || quoted[1][1] != int64(1) {
+		t.Errorf("Rows of quoted table = %v", quoted)
+	}
+
+	if _, err := db.Table("t_name"); err == nil {
+		t.Errorf("Index is read as a table")
+	}
+}
+
+func TestUTF16(t *testing.T) {
+	db, err := Open("testdata/utf16.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := readRows(t, db, "t")
+	if len(rows) != 1 || rows[1][0] != "héllo 世界" {
+		t.Errorf("Rows = %v", rows)
+	}
+}
+
+func TestWAL(t *testing.T) {
+	db, err := Open("testdata/wal.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readRows(t, db, "t")
+	if len(rows) != 5 || rows[1][1] != "updated" || rows[5][1] != "row 5" {
+		t.Errorf("Rows with WAL = %v", rows)
+	}
+
+	// Without the WAL file, only checkpointed rows are read
+	data, err := ioutil.ReadFile("testdata/wal.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err = New(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows = readRows(t, db, "t")
+	if len(rows) != 3 || rows[1][1] != "row 1" {
+		t.Errorf("Rows without WAL = %v", rows)
+	}
+}
+
+func TestBroken(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/test.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(data[:50]); err == nil {
+		t.Errorf("Truncated header is accepted")
+	}
+
+	broken := append([]byte{}, data...)
+	broken[16], broken[17] = 0x03, 0x00
+	if _, err := New(broken); err == nil {
+		t.Errorf("Page size 768 is accepted")
+	}
+
+	// Pages after the schema are missing
+	db, err := New(data[:1024])
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := db.Table("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := table.Rows(func(int64, []interface{}) error { return nil }); err == nil {
+		t.Errorf("Rows of missing pages are read")
+	}
+}
@@ -0,0 +1,52 @@
+# Generates the test databases with the sqlite3 module of python
+import os
+import shutil
+import sqlite3
+import sys
+
+os.chdir(os.path.dirname(os.path.abspath(__file__)))
+
+for name in ["test.sqlite", "utf16.sqlite", "wal.sqlite", "wal.sqlite-wal"]:
+    if os.path.exists(name):
+        os.remove(name)
+
+# Small pages, to have interior pages and overflow pages
+db = sqlite3.connect("test.sqlite")
+db.execute("PRAGMA page_size = 1024")
+db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, value BLOB, n INTEGER, f REAL, z)")
+db.execute('CREATE TABLE "quoted table" ("a b" TEXT, [c] INTEGER, CHECK (c > 0))')
+db.execute("CREATE INDEX t_name ON t (name)")
+numbers = [0, 1, -1, 127, -128, 32767, 65536, -8388608, 2147483647, 2 ** 40, -(2 ** 47), 2 ** 62]
+for i in range(1, 501):
+    db.execute("INSERT INTO t VALUES (?, ?, ?, ?, ?, NULL)",
+               (i, "row %d" % i, bytes([i % 256]) * (i % 7), numbers[i % len(numbers)], i / 2))
+db.execute("INSERT INTO t VALUES (1000, 'large', ?, NULL, NULL, 'text')", (bytes(range(256)) * 20,))
+db.execute("""INSERT INTO "quoted table" VALUES ('x', 1)""")
+db.execute("ALTER TABLE t ADD COLUMN added TEXT DEFAULT 'none'")
+db.execute("INSERT INTO t (id, name, added) VALUES (1001, 'after alter', 'yes')")
+db.commit()
+db.close()
+
+db = sqlite3.connect("utf16.sqlite")
+db.execute("PRAGMA encoding = 'UTF-16le'")
+db.execute("CREATE TABLE t (name TEXT)")
+db.execute("INSERT INTO t VALUES ('héllo 世界')")
+db.commit()
+db.close()
+
+# Rows 1 to 3 are checkpointed into the database, and rows 4 and 5 are only in the WAL file
+db = sqlite3.connect("wal.sqlite", isolation_level=None)
+db.execute("PRAGMA journal_mode = WAL")
+db.execute("PRAGMA wal_autocheckpoint = 0")
+db.execute("CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT)")
+for i in range(1, 4):
+    db.execute("INSERT INTO t VALUES (?, ?)", (i, "row %d" % i))
+db.execute("PRAGMA wal_checkpoint(TRUNCATE)")
+for i in range(4, 6):
+    db.execute("INSERT INTO t VALUES (?, ?)", (i, "row %d" % i))
+db.execute("UPDATE t SET name = 'updated' WHERE id = 1")
+shutil.copy("wal.sqlite", "wal.sqlite.tmp")
+shutil.copy("wal.sqlite-wal", "wal.sqlite-wal.tmp")
+db.close()
+os.rename("wal.sqlite.tmp", "wal.sqlite")
+os.rename("wal.sqlite-wal.tmp", "wal.sqlite-wal")